@@ -3,6 +3,7 @@ package arm
 import (
 	"fmt"
 	ct "proj/golite/context"
+	"proj/golite/arm/regalloc"
 	"proj/golite/ir"
 	"proj/golite/parser"
 	"proj/golite/sa"
@@ -34,7 +35,7 @@ func Test1(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -64,7 +65,7 @@ func Test6(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -94,7 +95,7 @@ func Test7(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -124,7 +125,7 @@ func Test8(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -154,7 +155,7 @@ func Test9(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -184,7 +185,7 @@ func Test10(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -214,7 +215,7 @@ func Test11(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -244,7 +245,7 @@ func Test12(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -274,7 +275,7 @@ func Test13(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -304,7 +305,7 @@ func Test14(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -334,7 +335,7 @@ func Test15(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -364,7 +365,7 @@ func Test16(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -394,7 +395,7 @@ func Test17(t *testing.T) {
 	//		fmt.Println(instruction.String())
 	//	}
 	//}
-	resStr := TranslateToAssembly(globalFuncFrag, globalSymTable)
+	resStr := TranslateToAssembly(globalFuncFrag, regalloc.Linear)
 	for _, line := range resStr {
 		fmt.Println(line)
 	}
@@ -0,0 +1,224 @@
+// Package asm models the Armv8 assembly golite produces: instructions of an opcode, a condition
+// and operands, labels and directives, which Emit prints in the syntax of the GNU assembler.
+// The translators of proj/golite/ir build these values, and proj/golite/arm/peephole rewrites them.
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line is a line of assembly: an Instr, a Label or a Directive
+type Line interface {
+	String() string
+	line()
+}
+
+// Opcode is the mnemonic of an instruction, without its condition
+type Opcode string
+
+const (
+	Mov  Opcode = "mov"
+	Add  Opcode = "add"
+	Adds Opcode = "adds"
+	Sub  Opcode = "sub"
+	Subs Opcode = "subs"
+	Mul  Opcode = "mul"
+	Sdiv Opcode = "sdiv"
+	And  Opcode = "and"
+	Orr  Opcode = "orr"
+	Cmp  Opcode = "cmp"
+	Cmn  Opcode = "cmn"
+	Cset Opcode = "cset"
+	Ldr  Opcode = "ldr"
+	Str  Opcode = "str"
+	Ldp  Opcode = "ldp"
+	Stp  Opcode = "stp"
+	Adrp Opcode = "adrp"
+	B    Opcode = "b"
+	Bl   Opcode = "bl"
+	Ret  Opcode = "ret"
+)
+
+// Cond is a condition code, AL for an instruction without condition
+type Cond string
+
+const (
+	AL Cond = ""
+	EQ Cond = "eq"
+	NE Cond = "ne"
+	LT Cond = "lt"
+	GE Cond = "ge"
+	GT Cond = "gt"
+	LE Cond = "le"
+	HI Cond = "hi"
+	LS Cond = "ls"
+	HS Cond = "hs"
+	LO Cond = "lo"
+	MI Cond = "mi"
+	PL Cond = "pl"
+	VS Cond = "vs"
+	VC Cond = "vc"
+)
+
+var inverses = map[Cond]Cond{
+	EQ: NE, NE: EQ, LT: GE, GE: LT, GT: LE, LE: GT, HI: LS, LS: HI, HS: LO, LO: HS, MI: PL, PL: MI, VS: VC, VC: VS,
+}
+
+// Invert returns the condition holding exactly when cond does not, AL for AL
+func (cond Cond) Invert() Cond { return inverses[cond] }
+
+// Operand is an operand of an instruction: a Reg, an Imm, a Mem, an Indexed, a Symbol or a Lo12
+type Operand interface {
+	String() string
+	operand()
+}
+
+// Reg is a 64-bit register, x0 to x30, SP or XZR
+type Reg int
+
+const (
+	FP  Reg = 29 // the frame pointer, x29
+	LR  Reg = 30 // the link register, x30
+	SP  Reg = 31
+	XZR Reg = 32
+)
+
+// X returns the register xn
+func X(n int) Reg { return Reg(n) }
+
+func (reg Reg) String() string {
+	switch reg {
+	case SP:
+		return "sp"
+	case XZR:
+		return "xzr"
+	}
+	return fmt.Sprintf("x%v", int(reg))
+}
+
+// Imm is an immediate
+type Imm int
+
+func (imm Imm) String() string { return fmt.Sprintf("#%v", int(imm)) }
+
+// Mem is the address at Offset bytes from the register Base
+type Mem struct {
+	Base   Reg
+	Offset int
+}
+
+func (mem Mem) String() string {
+	if mem.Offset == 0 {
+		return fmt.Sprintf("[%v]", mem.Base)
+	}
+	return fmt.Sprintf("[%v,#%v]", mem.Base, mem.Offset)
+}
+
+// Indexed is the address of the word at index Index from the register Base, [Base,Index,lsl #3]
+type Indexed struct {
+	Base  Reg
+	Index Reg
+}
+
+func (indexed Indexed) String() string {
+	return fmt.Sprintf("[%v,%v,lsl #3]", indexed.Base, indexed.Index)
+}
+
+// Symbol is a label or a symbol, e.g. the target of a branch or the page of a global variable
+type Symbol string
+
+func (symbol Symbol) String() string { return string(symbol) }
+
+// Lo12 is the low 12 bits of the address of a symbol, added to its page
+type Lo12 string
+
+func (lo12 Lo12) String() string { return ":lo12:" + string(lo12) }
+
+func (Reg) operand()     {}
+func (Imm) operand()     {}
+func (Mem) operand()     {}
+func (Indexed) operand() {}
+func (Symbol) operand()  {}
+func (Lo12) operand()    {}
+
+// Instr is an instruction. The condition of a branch is a suffix of its opcode, b.ne, and that of
+// cset the operand after the others, cset x9,ne.
+type Instr struct {
+	Op       Opcode
+	Cond     Cond
+	Operands []Operand
+}
+
+// Label is the definition of a label
+type Label string
+
+// Directive is an assembler directive, e.g. .size with the arguments main and (.-main)
+type Directive struct {
+	Name string
+	Args []string
+}
+
+func (Instr) line()     {}
+func (Label) line()     {}
+func (Directive) line() {}
+
+// NewInstr returns the instruction op with the operands
+func NewInstr(op Opcode, operands ...Operand) Instr {
+	return Instr{Op: op, Operands: operands}
+}
+
+// NewCondInstr returns the instruction op with the condition cond and the operands
+func NewCondInstr(op Opcode, cond Cond, operands ...Operand) Instr {
+	return Instr{Op: op, Cond: cond, Operands: operands}
+}
+
+// NewDirective returns the directive name with the arguments
+func NewDirective(name string, args ...string) Directive {
+	return Directive{name, args}
+}
+
+// MovImm returns the instruction moving value into reg: a mov if a movz or movn can encode it,
+// the load of a literal otherwise
+func MovImm(reg Reg, value int) Instr {
+	if value >= -65536 && value <= 65535 {
+		return NewInstr(Mov, reg, Imm(value))
+	}
+	return NewInstr(Ldr, reg, Symbol(fmt.Sprintf("=%v", value)))
+}
+
+func (instr Instr) String() string {
+	mnemonic := string(instr.Op)
+	operands := []string{}
+	for _, operand := range instr.Operands {
+		operands = append(operands, operand.String())
+	}
+	if instr.Op == B && instr.Cond != AL {
+		mnemonic += "." + string(instr.Cond)
+	} else if instr.Cond != AL {
+		operands = append(operands, string(instr.Cond))
+	}
+	if len(operands) == 0 {
+		return "\t" + mnemonic
+	}
+	return "\t" + mnemonic + " " + strings.Join(operands, ",")
+}
+
+func (label Label) String() string { return string(label) + ":" }
+
+func (directive Directive) String() string {
+	if len(directive.Args) == 0 {
+		return "\t" + directive.Name
+	}
+	return "\t" + directive.Name + " " + strings.Join(directive.Args, ",")
+}
+
+// Emit prints the lines in the syntax of the GNU assembler, one instruction, label or directive
+// per line
+func Emit(lines []Line) []string {
+	text := []string{}
+	for _, line := range lines {
+		text = append(text, line.String())
+	}
+	return text
+}
@@ -0,0 +1,67 @@
+package asm
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test1(t *testing.T) {
+	src, err := os.ReadFile("test1_asm.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+	lines, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(Emit(lines), "\n"); got != strings.Join(text, "\n") {
+		t.Errorf("\nExpected: the lines printed back; Got\n%v\n", got)
+	}
+
+	// the address updating sp is left as a symbol
+	expected := map[int]Line{
+		5:  NewDirective(".p2align", "2"),
+		6:  Label("main"),
+		10: NewInstr(Add, X(8), X(8), Lo12("p1")),
+		11: NewInstr(Ldr, X(9), Mem{Base: X(8)}),
+		12: NewInstr(Ldr, X(10), Mem{Base: FP, Offset: -16}),
+		15: NewCondInstr(Cset, GE, X(12)),
+		16: NewCondInstr(B, NE, Symbol(".Lmain_epilogue")),
+		17: NewInstr(Str, X(12), Symbol("[sp,#-16]!")),
+		19: NewInstr(Ldr, X(13), Indexed{Base: X(9), Index: X(10)}),
+		24: NewDirective(".asciz", `"%ld,%ld\n"`),
+	}
+	for i, line := range expected {
+		if lines[i].String() != line.String() {
+			t.Errorf("\nExpected: %v; Got %v\n", line, lines[i])
+		} else if instr, isInstr := line.(Instr); isInstr && lines[i].(Instr).Cond != instr.Cond {
+			t.Errorf("\nExpected: the condition %q; Got %q\n", instr.Cond, lines[i].(Instr).Cond)
+		}
+	}
+	if _, err := Parse([]string{"main"}); err == nil {
+		t.Errorf("\nExpected: an error for a line neither a label nor indented\n")
+	}
+}
+
+func Test2(t *testing.T) {
+	movs := map[int]string{
+		0:         "\tmov x0,#0",
+		65535:     "\tmov x0,#65535",
+		-65536:    "\tmov x0,#-65536",
+		65536:     "\tldr x0,=65536",
+		-70000:    "\tldr x0,=-70000",
+		123456789: "\tldr x0,=123456789",
+	}
+	for value, expected := range movs {
+		if got := MovImm(X(0), value).String(); got != expected {
+			t.Errorf("\nExpected: %q; Got %q\n", expected, got)
+		}
+	}
+	for cond, inverse := range map[Cond]Cond{EQ: NE, LT: GE, GT: LE, HS: LO, AL: AL} {
+		if cond.Invert() != inverse {
+			t.Errorf("\nExpected: %q inverted to %q; Got %q\n", cond, inverse, cond.Invert())
+		}
+	}
+}
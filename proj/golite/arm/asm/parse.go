@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse reads back the lines printed by Emit. An operand it does not know, such as an address
+// updating its base register, is read as a Symbol, so that it prints back the same.
+func Parse(text []string) ([]Line, error) {
+	lines := []Line{}
+	for i, textLine := range text {
+		line, err := parseLine(textLine)
+		if err != nil {
+			return lines, fmt.Errorf("line %v: %v", i+1, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func parseLine(text string) (Line, error) {
+	if !strings.HasPrefix(text, "\t") {
+		if !strings.HasSuffix(text, ":") || strings.ContainsAny(text, " \t") {
+			return nil, fmt.Errorf("%q is neither a label nor indented", text)
+		}
+		return Label(strings.TrimSuffix(text, ":")), nil
+	}
+	mnemonic, rest := text[1:], ""
+	if space := strings.Index(mnemonic, " "); space >= 0 {
+		mnemonic, rest = mnemonic[:space], mnemonic[space+1:]
+	}
+	args := []string{}
+	if rest != "" {
+		args = splitArgs(rest)
+	}
+	if strings.HasPrefix(mnemonic, ".") {
+		return Directive{mnemonic, args}, nil
+	}
+
+	instr := Instr{Op: Opcode(mnemonic)}
+	if dot := strings.Index(mnemonic, "."); dot >= 0 {
+		instr.Op, instr.Cond = Opcode(mnemonic[:dot]), Cond(mnemonic[dot+1:])
+	} else if len(args) > 0 && inverses[Cond(args[len(args)-1])] != "" {
+		instr.Cond, args = Cond(args[len(args)-1]), args[:len(args)-1]
+	}
+	for _, arg := range args {
+		instr.Operands = append(instr.Operands, parseOperand(arg))
+	}
+	return instr, nil
+}
+
+// splitArgs splits the operands of an instruction, or the arguments of a directive, at the commas
+// outside of brackets, parentheses and strings
+func splitArgs(text string) []string {
+	args := []string{}
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(args, text[start:])
+}
+
+func parseOperand(text string) Operand {
+	if reg, isReg := parseReg(text); isReg {
+		return reg
+	}
+	if strings.HasPrefix(text, "#") {
+		if value, err := strconv.Atoi(text[1:]); err == nil {
+			return Imm(value)
+		}
+	}
+	if strings.HasPrefix(text, ":lo12:") {
+		return Lo12(strings.TrimPrefix(text, ":lo12:"))
+	}
+	if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+		parts := strings.Split(text[1:len(text)-1], ",")
+		if base, isReg := parseReg(parts[0]); isReg && len(parts) == 1 {
+			return Mem{base, 0}
+		} else if isReg && len(parts) == 2 && strings.HasPrefix(parts[1], "#") {
+			if offset, err := strconv.Atoi(parts[1][1:]); err == nil && offset != 0 {
+				return Mem{base, offset}
+			}
+		} else if index, isIndex := parseReg(parts[1]); isReg && isIndex && len(parts) == 3 && parts[2] == "lsl #3" {
+			return Indexed{base, index}
+		}
+	}
+	return Symbol(text)
+}
+
+// parseReg reads a 64-bit register, x0 to x30, sp or xzr
+func parseReg(text string) (Reg, bool) {
+	switch text {
+	case "sp":
+		return SP, true
+	case "xzr":
+		return XZR, true
+	}
+	if !strings.HasPrefix(text, "x") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(text[1:])
+	if err != nil || n < 0 || n > 30 || strconv.Itoa(n) != text[1:] {
+		return 0, false
+	}
+	return X(n), true
+}
@@ -0,0 +1,26 @@
+	.arch armv8-a
+	.comm p1,8,8
+	.text
+	.type main,%function
+	.global main
+	.p2align 2
+main:
+	sub sp,sp,#16
+	stp x29,x30,[sp]
+	adrp x8,p1
+	add x8,x8,:lo12:p1
+	ldr x9,[x8]
+	ldr x10,[x29,#-16]
+	ldr x11,=123456789
+	cmp x9,x10
+	cset x12,ge
+	b.ne .Lmain_epilogue
+	str x12,[sp,#-16]!
+	bl printf
+	ldr x13,[x9,x10,lsl #3]
+.Lmain_epilogue:
+	ret
+	.size main,(.-main)
+.PRINT:
+	.asciz "%ld,%ld\n"
+	.size .PRINT,9
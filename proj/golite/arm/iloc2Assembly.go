@@ -1,94 +1,116 @@
 package arm
 
 import (
-	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/arm/regalloc"
 	"proj/golite/ir"
-	st "proj/golite/symboltable"
+	"proj/golite/ir/cfg"
 	"proj/golite/utility"
-	"strings"
 )
 
+// TranslateToAssembly translates the ILOC of a program into Armv8 assembly, the registers of each
+// function being allocated by strategy
+func TranslateToAssembly(funcfrags []*ir.FuncFrag, strategy regalloc.Strategy) []string {
+	return asm.Emit(Translate(funcfrags, strategy))
+}
 
-
-func TranslateToAssembly(funcfrags []*ir.FuncFrag, symTable *st.SymbolTable) []string {
-
-	armInstructions := []string{}
+// Translate is TranslateToAssembly, giving the lines of the assembly as instructions, labels and
+// directives
+func Translate(funcfrags []*ir.FuncFrag, strategy regalloc.Strategy) []asm.Line {
+	armInstructions := []asm.Line{}
 	utility.RegInit()
 	utility.IOInit()
 
 	// program title
-	armInstructions = append(armInstructions, "\t.arch armv8-a")
+	armInstructions = append(armInstructions, asm.NewDirective(".arch", "armv8-a"))
 	// global variables
-	if len(funcfrags) > 0 && strings.Contains(funcfrags[0].Label, "Global Variable") {
+	remainfuncFrags := funcfrags
+	if len(funcfrags) > 0 && funcfrags[0].IsGlobal() {
+		remainfuncFrags = funcfrags[1:]
 		for _, instruction := range funcfrags[0].Body {
 			if instruction.GetSourceString() != "" {
 				varName := instruction.GetSourceString()
-				armInstructions = append(armInstructions, fmt.Sprintf("\t.comm %v,8,8", varName))
+				armInstructions = append(armInstructions, asm.NewDirective(".comm", varName, "8", "8"))
 			}
 		}
 	}
 	// code
-	armInstructions = append(armInstructions, "\t.text")
+	armInstructions = append(armInstructions, asm.NewDirective(".text"))
 
-	remainfuncFrags := funcfrags[1:]
 	for _, funcfrag := range remainfuncFrags {
-		//funcEntry := symTable.Contains(funcfrag.Label)
-		//scopeSt := funcEntry.GetScopeST() // symbol table for the current scope
-		//countVar := 0                     // count the variables inside this scope
-
-		offset := 0
-		funcVarDict := make(map[int]int) // variable name -> offset, e.g. a -> -8, b -> -16
-		for _, instruction := range funcfrag.Body {
-			if instruction.GetTargets() != nil && len(instruction.GetTargets()) > 0 {
-				offset -= 8
-				funcVarDict[instruction.GetTargets()[0]] = offset
-			}
+		alloc := regalloc.Allocate(cfg.Build(funcfrag), strategy)
+		funcVarDict := alloc.Slots // register -> offset of its stack slot, e.g. r4 -> -8, r5 -> -16
+		regIds := alloc.Regs       // register -> Arm register, e.g. r6 -> 9 for x9
+		epilogueLabel := asm.Symbol(".L" + funcfrag.Label + "_epilogue")
+
+		armInstructions = append(armInstructions, asm.NewDirective(".type", funcfrag.Label, "%function"))
+		armInstructions = append(armInstructions, asm.NewDirective(".global", funcfrag.Label))
+		armInstructions = append(armInstructions, asm.NewDirective(".p2align", "2"))
+
+		funcSize := alloc.FrameSize
+		armInstructions = append(armInstructions, asm.Label(funcfrag.Label))
+		armInstructions = append(armInstructions, prologue(funcSize)...)
+		for i, savedReg := range alloc.Saved {
+			armInstructions = append(armInstructions, asm.NewInstr(asm.Str, asm.X(savedReg), savedSlot(i)))
 		}
 
-		entry := symTable.Contains(funcfrag.Label)
-		scopeSt := entry.GetScopeST()
-		paramRegIds := make(map[int]int)
-		for  id, paramName := range scopeSt.ScopeParamNames {
-			paramEntry := scopeSt.Contains(paramName)
-			paramRegIds[paramEntry.GetRegId()] = id
-			utility.OccupyReg(id)
-		}
-		if funcfrag.Label == "main" {
-			utility.OccupyReg(0)
-		}
-
-		armInstructions = append(armInstructions, "\t.type "+funcfrag.Label+",%function")
-		armInstructions = append(armInstructions, "\t.global "+funcfrag.Label)
-		armInstructions = append(armInstructions, "\t.p2align\t\t2")
-
-		funcSize := offset
-		if funcSize%16 != 0 {
-			funcSize -= 8
+		// the parameters arrive in x0 to x7 in the order of funcfrag.Params, the next ones on the
+		// stack of the caller, and move to their own register or slot, x0 to x7 being needed by
+		// the calls
+		for id, paramReg := range funcfrag.Params {
+			regId, allocated := regIds[paramReg]
+			offset, spilled := funcVarDict[paramReg]
+			switch {
+			case id < ir.RegArgs && allocated:
+				armInstructions = append(armInstructions, asm.NewInstr(asm.Mov, asm.X(regId), asm.X(id)))
+			case id < ir.RegArgs && spilled:
+				scratch := utility.NextAvailReg()
+				armInstructions = append(armInstructions, ir.StoreSlot(asm.X(id), offset, asm.X(scratch))...)
+				utility.ReleaseReg(scratch)
+			case allocated:
+				armInstructions = append(armInstructions, asm.NewInstr(asm.Ldr, asm.X(regId), ir.StackParam(id)))
+			case spilled:
+				scratch, index := utility.NextAvailReg(), utility.NextAvailReg()
+				armInstructions = append(armInstructions, asm.NewInstr(asm.Ldr, asm.X(scratch), ir.StackParam(id)))
+				armInstructions = append(armInstructions, ir.StoreSlot(asm.X(scratch), offset, asm.X(index))...)
+				utility.ReleaseReg(scratch)
+				utility.ReleaseReg(index)
+			}
 		}
-		armInstructions = append(armInstructions, fmt.Sprintf("%v:", funcfrag.Label))
-		armInstructions = append(armInstructions, prologue(-funcSize)...)
 
-		// find regID of parameters from symtable
-		// save matching in a map
 		remainingInstruction := funcfrag.Body[1:]
-		for _, instruction := range remainingInstruction {
+		for i, instruction := range remainingInstruction {
 			//armInstructions = append(armInstructions, "ILOC: " + instruction.String())
-			armInstructions = append(armInstructions, instruction.TranslateToAssembly(funcVarDict, paramRegIds)...)
+			armInstructions = append(armInstructions, instruction.TranslateToAssembly(funcVarDict, regIds)...)
+			// a return before the end of the body goes to the epilogue
+			if _, isRet := instruction.(*ir.Ret); isRet && i < len(remainingInstruction)-1 {
+				armInstructions = append(armInstructions, asm.NewInstr(asm.B, epilogueLabel))
+			}
 		}
 
-		armInstructions = append(armInstructions, epilogue(-funcSize)...)
-		armInstructions = append(armInstructions, "\t.size "+funcfrag.Label+",(.-"+funcfrag.Label+")")
-
-
-
-		for  id, _ := range scopeSt.ScopeParamNames {
-			utility.ReleaseReg(id)
-		}
+		armInstructions = append(armInstructions, asm.Label(epilogueLabel))
 		if funcfrag.Label == "main" {
-			utility.ReleaseReg(0)
+			// main returns 0, the exit status of the program
+			armInstructions = append(armInstructions, asm.NewInstr(asm.Mov, asm.X(0), asm.Imm(0)))
 		}
+		for i, savedReg := range alloc.Saved {
+			armInstructions = append(armInstructions, asm.NewInstr(asm.Ldr, asm.X(savedReg), savedSlot(i)))
+		}
+		armInstructions = append(armInstructions, epilogue(funcSize)...)
+		armInstructions = append(armInstructions, asm.NewDirective(".size", funcfrag.Label, "(.-"+funcfrag.Label+")"))
 	}
 
+	if utility.GetBounds() {
+		armInstructions = append(armInstructions, ir.BoundsArmRoutine()...)
+	}
+	if utility.GetStrCat() {
+		armInstructions = append(armInstructions, ir.StrCatArmRoutine()...)
+	}
+	// the string literals and the formats of printf and scanf are only read
+	if len(utility.GetStrings()) > 0 || utility.GetPrint() || utility.GetPrintln() || utility.GetScan() {
+		armInstructions = append(armInstructions, asm.NewDirective(".section", ".rodata"))
+	}
+	armInstructions = append(armInstructions, ir.StringArmData(utility.GetStrings())...)
 	if utility.GetPrint() {
 		armInstructions = append(armInstructions, ir.PrintArmFormat()...)
 	}
@@ -102,20 +124,35 @@ func TranslateToAssembly(funcfrags []*ir.FuncFrag, symTable *st.SymbolTable) []s
 	return armInstructions
 }
 
-func prologue(size int) []string {
-	proInst := []string{}
-	proInst = append(proInst, "\tsub sp,sp,16")
-	proInst = append(proInst, "\tstp x29,x30,[sp]")
-	proInst = append(proInst, "\tmov x29,sp")
-	proInst = append(proInst, fmt.Sprintf("\tsub sp,sp,#%v", size))
-	return proInst
+// savedSlot returns the address the i-th callee-saved register is saved to
+func savedSlot(i int) asm.Mem {
+	return asm.Mem{Base: asm.FP, Offset: -8 * (i + 1)}
 }
 
-func epilogue(size int) []string {
-	epiInst := []string{}
-	epiInst = append(epiInst, fmt.Sprintf( "\tadd sp,sp,#%v", size))
-	epiInst = append(epiInst, "\tldp x29,x30,[sp]")
-	epiInst = append(epiInst, "\tadd sp,sp,16")
-	epiInst = append(epiInst, "\tret")
-	return epiInst
-}
\ No newline at end of file
+func prologue(size int) []asm.Line {
+	return append([]asm.Line{
+		asm.NewInstr(asm.Sub, asm.SP, asm.SP, asm.Imm(16)),
+		asm.NewInstr(asm.Stp, asm.FP, asm.LR, asm.Mem{Base: asm.SP}),
+		asm.NewInstr(asm.Mov, asm.FP, asm.SP),
+	}, frameSize(asm.Sub, size)...)
+}
+
+func epilogue(size int) []asm.Line {
+	return append(frameSize(asm.Add, size),
+		asm.NewInstr(asm.Ldp, asm.FP, asm.LR, asm.Mem{Base: asm.SP}),
+		asm.NewInstr(asm.Add, asm.SP, asm.SP, asm.Imm(16)),
+		asm.NewInstr(asm.Ret),
+	)
+}
+
+// maxAddImm is the largest immediate of add and sub
+const maxAddImm = 4095
+
+// frameSize returns the sub (or add, for op asm.Add) of the size of the frame to sp, which goes
+// through the scratch register x16 past maxAddImm
+func frameSize(op asm.Opcode, size int) []asm.Line {
+	if size <= maxAddImm {
+		return []asm.Line{asm.NewInstr(op, asm.SP, asm.SP, asm.Imm(size))}
+	}
+	return []asm.Line{asm.MovImm(asm.X(16), size), asm.NewInstr(op, asm.SP, asm.SP, asm.X(16))}
+}
@@ -0,0 +1,185 @@
+package peephole
+
+import (
+	"proj/golite/arm/asm"
+	"strings"
+)
+
+// regSet is a set of the registers x0 to x30, sp, xzr and the condition flags
+type regSet uint64
+
+// flags stands for the condition flags in a regSet, after the registers
+const flags = int(asm.XZR) + 1
+
+const allRegs = ^regSet(0)
+
+func (s regSet) has(reg int) bool { return s&(1<<uint(reg)) != 0 }
+
+func regsOf(regs ...int) regSet {
+	s := regSet(0)
+	for _, reg := range regs {
+		s |= 1 << uint(reg)
+	}
+	return s
+}
+
+// operandRegs returns the registers of the operands, the base registers of addresses included
+func operandRegs(operands []asm.Operand) regSet {
+	s := regSet(0)
+	for _, operand := range operands {
+		switch operand := operand.(type) {
+		case asm.Reg:
+			s |= regsOf(int(operand))
+		case asm.Mem:
+			s |= regsOf(int(operand.Base))
+		case asm.Indexed:
+			s |= regsOf(int(operand.Base), int(operand.Index))
+		}
+	}
+	return s
+}
+
+// the instructions writing their first operand from the others, without other effects
+var pure = map[asm.Opcode]bool{
+	asm.Mov: true, asm.Add: true, asm.Sub: true, asm.And: true, asm.Orr: true, asm.Mul: true,
+	asm.Adrp: true, asm.Cset: true,
+}
+
+// defsUses returns the registers a line writes and reads, known being false if the line is an
+// instruction the optimizer does not know, which may then read every register
+func defsUses(line asm.Line) (defs regSet, uses regSet, known bool) {
+	instr, isInstr := line.(asm.Instr)
+	if !isInstr {
+		return 0, 0, true
+	}
+	first := regSet(0)
+	if len(instr.Operands) > 0 {
+		if reg, isReg := instr.Operands[0].(asm.Reg); isReg {
+			first = regsOf(int(reg))
+		}
+	}
+
+	switch instr.Op {
+	case asm.Mov, asm.Add, asm.Sub, asm.Mul, asm.Sdiv, asm.And, asm.Orr, asm.Adrp, asm.Ldr:
+		if first != 0 {
+			return first, operandRegs(instr.Operands[1:]), true
+		}
+	case asm.Adds, asm.Subs:
+		if first != 0 {
+			return first | regsOf(flags), operandRegs(instr.Operands[1:]), true
+		}
+	case asm.Cset:
+		if first != 0 {
+			return first, regsOf(flags), true
+		}
+	case asm.Ldp:
+		if len(instr.Operands) == 3 {
+			second, isReg := instr.Operands[1].(asm.Reg)
+			if _, isMem := instr.Operands[2].(asm.Mem); first != 0 && isReg && isMem {
+				return first | regsOf(int(second)), operandRegs(instr.Operands[2:]), true
+			}
+		}
+	case asm.Str, asm.Stp:
+		if len(instr.Operands) > 1 {
+			switch instr.Operands[len(instr.Operands)-1].(type) {
+			case asm.Mem, asm.Indexed:
+				return 0, operandRegs(instr.Operands), true
+			}
+		}
+	case asm.Cmp, asm.Cmn:
+		return regsOf(flags), operandRegs(instr.Operands), true
+	case asm.B:
+		if instr.Cond != asm.AL {
+			return 0, regsOf(flags), true
+		}
+		return 0, 0, true
+	case asm.Bl:
+		// the arguments are in x0 to x7, the result in x0
+		return regsOf(0, int(asm.LR)), regsOf(0, 1, 2, 3, 4, 5, 6, 7), true
+	case asm.Ret:
+		// the result and the registers the caller expects to be preserved
+		return 0, regsOf(0, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, int(asm.FP), int(asm.LR), int(asm.SP)), true
+	}
+	return 0, allRegs, false
+}
+
+// isControl returns true for the instructions that may not go on with the next one
+func isControl(instr asm.Instr) bool {
+	switch instr.Op {
+	case asm.B, asm.Bl, asm.Ret, "br", "blr", "cbz", "cbnz", "tbz", "tbnz":
+		return true
+	}
+	return false
+}
+
+func readsMemory(instr asm.Instr) bool {
+	return strings.HasPrefix(string(instr.Op), "ld") || instr.Op == asm.Bl
+}
+
+func writesMemory(instr asm.Instr) bool {
+	return strings.HasPrefix(string(instr.Op), "st") || instr.Op == asm.Bl
+}
+
+// mayAlias returns false if the 8 bytes at the two addresses cannot overlap, as they are at
+// different offsets from the same base register
+func mayAlias(a asm.Mem, b asm.Mem) bool {
+	return a.Base != b.Base || (a.Offset-b.Offset < 8 && b.Offset-a.Offset < 8)
+}
+
+// liveness returns the registers live after each line, the flags included. A branch to a label
+// that is not in lines may go anywhere, after which every register is live.
+func liveness(lines []asm.Line) []regSet {
+	labels := map[asm.Operand]int{}
+	for i, line := range lines {
+		if label, isLabel := line.(asm.Label); isLabel {
+			labels[asm.Symbol(label)] = i
+		}
+	}
+	successors := make([][]int, len(lines))
+	unknown := make([]bool, len(lines))
+	for i, line := range lines {
+		next := []int{}
+		if i+1 < len(lines) {
+			next = append(next, i+1)
+		}
+		instr, isInstr := line.(asm.Instr)
+		switch {
+		case !isInstr:
+		case instr.Op == asm.Ret:
+			next = nil
+		case instr.Op == asm.B && len(instr.Operands) == 1:
+			to, found := labels[instr.Operands[0]]
+			unknown[i] = !found
+			if instr.Cond == asm.AL {
+				next = nil
+			}
+			if found {
+				next = append(next, to)
+			}
+		case isControl(instr) && instr.Op != asm.Bl:
+			unknown[i] = true
+		}
+		successors[i] = next
+	}
+
+	liveIn := make([]regSet, len(lines))
+	liveOut := make([]regSet, len(lines))
+	for changed := true; changed; {
+		changed = false
+		for i := len(lines) - 1; i >= 0; i-- {
+			out := regSet(0)
+			if unknown[i] {
+				out = allRegs
+			}
+			for _, succ := range successors[i] {
+				out |= liveIn[succ]
+			}
+			defs, uses, _ := defsUses(lines[i])
+			in := uses | (out &^ defs)
+			if in != liveIn[i] || out != liveOut[i] {
+				liveIn[i], liveOut[i], changed = in, out, true
+			}
+		}
+	}
+	return liveOut
+}
@@ -0,0 +1,346 @@
+// Package peephole rewrites the Armv8 assembly of proj/golite/arm, whose instructions are
+// translated one ILOC instruction at a time, into shorter code:
+//   - a mov of a register into itself, an add or sub of #0, a branch to the label that follows
+//     and the code no label leads to are removed,
+//   - a load from an address whose value is already in a register becomes a mov, or goes away,
+//     a store of the value already at its address, or overwritten before being read, goes away,
+//   - a mov of an immediate into a register only read by the next add, sub or cmp is folded
+//     into it,
+//   - "mov xT,#0; ...; b.ge L; mov xT,#1; L:", which conditional movs translate into, becomes
+//     "...; cset xT,lt",
+//   - an instruction without side effects whose result is never read is removed.
+//
+// The loads and stores are only rewritten within straight-line code.
+package peephole
+
+import "proj/golite/arm/asm"
+
+// Optimize rewrites the lines of assembly, and returns the new lines with the number of
+// instructions removed
+func Optimize(lines []asm.Line) ([]asm.Line, int) {
+	before := countInstructions(lines)
+	lines = compact(lines)
+	for changed := true; changed; {
+		changed = false
+		for _, p := range passes {
+			if p(lines) {
+				changed = true
+			}
+			lines = compact(lines)
+		}
+	}
+	return lines, before - countInstructions(lines)
+}
+
+// pass rewrites the lines in place, setting those it removes to nil, and returns true if it
+// changed any
+type pass func(lines []asm.Line) bool
+
+var passes = []pass{removeNoops, forwardLoads, removeStores, foldImmediates, mergeCsets, removeDead}
+
+// compact returns the lines that are not nil, in a new slice
+func compact(lines []asm.Line) []asm.Line {
+	kept := []asm.Line{}
+	for _, line := range lines {
+		if line != nil {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+func countInstructions(lines []asm.Line) int {
+	count := 0
+	for _, line := range lines {
+		if _, isInstr := line.(asm.Instr); isInstr {
+			count++
+		}
+	}
+	return count
+}
+
+// match returns the instruction of a line if it is op with n operands
+func match(line asm.Line, op asm.Opcode, n int) (asm.Instr, bool) {
+	instr, isInstr := line.(asm.Instr)
+	return instr, isInstr && instr.Op == op && len(instr.Operands) == n
+}
+
+// regs returns the registers of the operands, false if one is not a register
+func regs(operands ...asm.Operand) ([]asm.Reg, bool) {
+	regs := []asm.Reg{}
+	for _, operand := range operands {
+		reg, isReg := operand.(asm.Reg)
+		if !isReg {
+			return nil, false
+		}
+		regs = append(regs, reg)
+	}
+	return regs, true
+}
+
+// access returns the register and the address of "ldr xR,[addr]" or "str xR,[addr]", for op
+// Ldr or Str
+func access(line asm.Line, op asm.Opcode) (asm.Reg, asm.Mem, bool) {
+	instr, matched := match(line, op, 2)
+	if !matched {
+		return 0, asm.Mem{}, false
+	}
+	reg, isReg := instr.Operands[0].(asm.Reg)
+	mem, isMem := instr.Operands[1].(asm.Mem)
+	return reg, mem, isReg && isMem
+}
+
+// removeNoops removes the movs of a register into itself, the adds and subs of #0 to a register
+// into itself, the branches to a label that follows, and the instructions after a branch or a ret
+// that no label leads to
+func removeNoops(lines []asm.Line) bool {
+	changed := false
+	reachable := true
+	for i, line := range lines {
+		instr, isInstr := line.(asm.Instr)
+		if !isInstr {
+			reachable = true
+			continue
+		}
+		noop := !reachable
+		reachable = !(instr.Op == asm.Ret || (instr.Op == asm.B && instr.Cond == asm.AL))
+		if mov, matched := match(line, asm.Mov, 2); matched {
+			r, isRegs := regs(mov.Operands...)
+			noop = noop || (isRegs && r[0] == r[1])
+		} else if (instr.Op == asm.Add || instr.Op == asm.Sub) && len(instr.Operands) == 3 {
+			r, isRegs := regs(instr.Operands[:2]...)
+			noop = noop || (isRegs && r[0] == r[1] && instr.Operands[2] == asm.Imm(0))
+		} else if _, matched := match(line, asm.B, 1); matched && instr.Cond == asm.AL {
+			for next := i + 1; next < len(lines); next++ {
+				label, isLabel := lines[next].(asm.Label)
+				if !isLabel {
+					break
+				}
+				noop = noop || asm.Symbol(label) == instr.Operands[0]
+			}
+		}
+		if noop {
+			lines[i], changed = nil, true
+		}
+	}
+	return changed
+}
+
+// valueAt returns the register holding the value at the address addr right before lines[i], as
+// stored or loaded by an instruction of the same straight-line code
+func valueAt(lines []asm.Line, i int, addr asm.Mem) (asm.Reg, bool) {
+	written := regSet(0) // the registers written after the instruction scanned
+	for j := i - 1; j >= 0; j-- {
+		if lines[j] == nil {
+			continue
+		}
+		prev, isInstr := lines[j].(asm.Instr)
+		defs, _, known := defsUses(lines[j])
+		if !isInstr || !known || isControl(prev) {
+			return 0, false
+		}
+		reg, mem, isStr := access(prev, asm.Str)
+		loadReg, loadMem, isLdr := access(prev, asm.Ldr)
+		if isLdr {
+			reg, mem = loadReg, loadMem
+		}
+		if (isStr || isLdr) && mem == addr {
+			if written.has(int(reg)) || (isLdr && reg == addr.Base) {
+				return 0, false
+			}
+			return reg, true
+		}
+		if writesMemory(prev) && (!isStr || mayAlias(mem, addr)) {
+			return 0, false
+		}
+		written |= defs
+		if written.has(int(addr.Base)) {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// forwardLoads replaces the loads from an address whose value is already in a register by a mov
+// of that register, or removes them if it is the register loaded
+func forwardLoads(lines []asm.Line) bool {
+	changed := false
+	for i, line := range lines {
+		dest, addr, isLdr := access(line, asm.Ldr)
+		if !isLdr {
+			continue
+		}
+		if src, found := valueAt(lines, i, addr); found {
+			if src == dest {
+				lines[i] = nil
+			} else {
+				lines[i] = asm.NewInstr(asm.Mov, dest, src)
+			}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeStores removes the stores of the value already at their address, and those overwritten
+// by a store to the same address before anything may read it
+func removeStores(lines []asm.Line) bool {
+	changed := false
+	for i, line := range lines {
+		src, addr, isStr := access(line, asm.Str)
+		if !isStr {
+			continue
+		}
+		if held, found := valueAt(lines, i, addr); found && held == src {
+			lines[i], changed = nil, true
+			continue
+		}
+		for k := i + 1; k < len(lines); k++ {
+			if lines[k] == nil {
+				continue
+			}
+			next, isInstr := lines[k].(asm.Instr)
+			defs, _, known := defsUses(lines[k])
+			if !isInstr || !known || isControl(next) {
+				break
+			}
+			_, mem, nextIsStr := access(next, asm.Str)
+			if nextIsStr && mem == addr {
+				lines[i], changed = nil, true
+				break
+			}
+			_, loadMem, nextIsLdr := access(next, asm.Ldr)
+			if readsMemory(next) && (!nextIsLdr || mayAlias(loadMem, addr)) {
+				break
+			}
+			if writesMemory(next) && (!nextIsStr || mayAlias(mem, addr)) {
+				break
+			}
+			if defs.has(int(addr.Base)) {
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// foldImmediates folds "mov xT,#imm" into the add, sub, adds, subs or cmp right after it, if it is
+// the only instruction reading xT and the immediate fits in 12 bits
+func foldImmediates(lines []asm.Line) bool {
+	changed := false
+	live := liveness(lines)
+	for i := 0; i+1 < len(lines); i++ {
+		mov, isMov := match(lines[i], asm.Mov, 2)
+		next, isInstr := lines[i+1].(asm.Instr)
+		if !isMov || !isInstr {
+			continue
+		}
+		t, isReg := mov.Operands[0].(asm.Reg)
+		value, isImm := mov.Operands[1].(asm.Imm)
+		r, isRegs := regs(next.Operands...)
+		if !isReg || !isImm || !isRegs || next.Cond != asm.AL {
+			continue
+		}
+		var folded asm.Line
+		switch {
+		case len(r) == 3 && (next.Op == asm.Add || next.Op == asm.Sub || next.Op == asm.Adds || next.Op == asm.Subs) &&
+			r[2] == t && r[1] != t:
+			folded = addImmediate(next.Op, r[0], r[1], int(value))
+		case len(r) == 3 && (next.Op == asm.Add || next.Op == asm.Adds) && r[1] == t && r[2] != t:
+			folded = addImmediate(next.Op, r[0], r[2], int(value))
+		case len(r) == 2 && next.Op == asm.Cmp && r[1] == t && r[0] != t && r[0] != asm.XZR:
+			if value >= 0 && value < 4096 {
+				folded = asm.NewInstr(asm.Cmp, r[0], value)
+			} else if value < 0 && value > -4096 {
+				folded = asm.NewInstr(asm.Cmn, r[0], -value)
+			}
+		}
+		// xT must not be read afterwards, unless the add or sub writes it
+		if folded == nil || (live[i+1].has(int(t)) && (next.Op == asm.Cmp || r[0] != t)) {
+			continue
+		}
+		lines[i], lines[i+1] = nil, folded
+		changed = true
+		i++
+	}
+	return changed
+}
+
+// addImmediate returns "op dest,src,#value" with a 12-bit immediate, a negative value turning an
+// add into a sub and a sub into an add, adds and subs alike, nil if the value does not fit or src
+// is xzr, which is sp in the immediate form
+func addImmediate(op asm.Opcode, dest asm.Reg, src asm.Reg, value int) asm.Line {
+	if src == asm.XZR {
+		return nil
+	}
+	if value < 0 {
+		value = -value
+		op = map[asm.Opcode]asm.Opcode{asm.Add: asm.Sub, asm.Sub: asm.Add, asm.Adds: asm.Subs, asm.Subs: asm.Adds}[op]
+	}
+	if value >= 4096 {
+		return nil
+	}
+	return asm.NewInstr(op, dest, src, asm.Imm(value))
+}
+
+// mergeCsets turns "mov xT,#0; ...; b.c L; mov xT,#1; L:" into "...; cset xT,!c", if only that
+// branch goes to L and the instructions in between leave xT alone
+func mergeCsets(lines []asm.Line) bool {
+	changed := false
+	references := map[asm.Operand]int{}
+	for _, line := range lines {
+		if instr, isInstr := line.(asm.Instr); isInstr {
+			for _, operand := range instr.Operands {
+				if _, isSymbol := operand.(asm.Symbol); isSymbol {
+					references[operand]++
+				}
+			}
+		}
+	}
+	for i := 0; i+2 < len(lines); i++ {
+		branch, isBranch := match(lines[i], asm.B, 1)
+		mov, isMov := match(lines[i+1], asm.Mov, 2)
+		label, isLabel := lines[i+2].(asm.Label)
+		if !isBranch || branch.Cond.Invert() == asm.AL || !isMov || mov.Operands[1] != asm.Imm(1) ||
+			!isLabel || branch.Operands[0] != asm.Symbol(label) || references[asm.Symbol(label)] != 1 {
+			continue
+		}
+		t, isReg := mov.Operands[0].(asm.Reg)
+		if !isReg {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if lines[j] == nil {
+				continue
+			}
+			if zero, matched := match(lines[j], asm.Mov, 2); matched && zero.Operands[0] == t && zero.Operands[1] == asm.Imm(0) {
+				cset := asm.NewCondInstr(asm.Cset, branch.Cond.Invert(), t)
+				lines[j], lines[i], lines[i+1], lines[i+2] = nil, cset, nil, nil
+				changed = true
+				break
+			}
+			prev, isInstr := lines[j].(asm.Instr)
+			defs, uses, known := defsUses(lines[j])
+			if !isInstr || !known || isControl(prev) || defs.has(int(t)) || uses.has(int(t)) {
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// removeDead removes the instructions without side effects whose result is never read
+func removeDead(lines []asm.Line) bool {
+	changed := false
+	live := liveness(lines)
+	for i, line := range lines {
+		instr, isInstr := line.(asm.Instr)
+		if !isInstr || !pure[instr.Op] || len(instr.Operands) == 0 {
+			continue
+		}
+		if reg, isReg := instr.Operands[0].(asm.Reg); isReg && reg < asm.FP && !live[i].has(int(reg)) {
+			lines[i], changed = nil, true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,66 @@
+package peephole
+
+import (
+	"os"
+	"proj/golite/arm/asm"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, path string) []asm.Line {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := asm.Parse(strings.Split(strings.TrimSuffix(string(src), "\n"), "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func Test1(t *testing.T) {
+	lines, removed := Optimize(load(t, "test1_peephole.asm"))
+	// the load of x0 becomes a mov, the conditional mov a cset, the store to [x29,#-16] overwritten
+	// goes away with the add computing it, and the mov of x11 into itself, the mov of x14 never
+	// read and the branch to the epilogue that follows are removed
+	expected := []string{
+		"\t.text",
+		"\t.type f,%function",
+		"\t.global f",
+		"f:",
+		"\tsub sp,sp,#16",
+		"\tstp x29,x30,[sp]",
+		"\tmov x29,sp",
+		"\tsub sp,sp,#16",
+		"\tstr x0,[x29,#-8]",
+		"\tmov x9,x0",
+		"\tcmp x9,#3",
+		"\tcset x10,lt",
+		"\tmov x13,x10",
+		"\tstr x13,[x29,#-16]",
+		"\tmov x0,x13",
+		".Lf_epilogue:",
+		"\tadd sp,sp,#16",
+		"\tldp x29,x30,[sp]",
+		"\tadd sp,sp,#16",
+		"\tret",
+		"\t.size f,(.-f)",
+	}
+	if got, want := strings.Join(asm.Emit(lines), "\n"), strings.Join(expected, "\n"); got != want {
+		t.Errorf("\nExpected:\n%v\nGot:\n%v\n", want, got)
+	}
+	if removed != 10 {
+		t.Errorf("\nExpected: 10 instructions removed; Got %v\n", removed)
+	}
+}
+
+func Test2(t *testing.T) {
+	// nothing to rewrite: [x9] may be [x29,#-8], x8 is read again after the cmp, 5000 does not fit
+	// in an add, the load of [x29,#-16] is after a label, and svc may read x1
+	lines := load(t, "test2_peephole.asm")
+	got, removed := Optimize(lines)
+	if expected := strings.Join(asm.Emit(lines), "\n"); strings.Join(asm.Emit(got), "\n") != expected || removed != 0 {
+		t.Errorf("\nExpected: the code unchanged; Got %v removed\n%v\n", removed, strings.Join(asm.Emit(got), "\n"))
+	}
+}
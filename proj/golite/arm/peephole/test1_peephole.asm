@@ -0,0 +1,32 @@
+	.text
+	.type f,%function
+	.global f
+f:
+	sub sp,sp,#16
+	stp x29,x30,[sp]
+	mov x29,sp
+	sub sp,sp,#16
+	str x0,[x29,#-8]
+	ldr x9,[x29,#-8]
+	mov x10,#0
+	mov x8,#3
+	cmp x9,x8
+	b.ge skipMov_L1
+	mov x10,#1
+skipMov_L1:
+	mov x11,x11
+	mov x8,#-2
+	add x12,x9,x8
+	str x12,[x29,#-16]
+	str x10,[x29,#-16]
+	ldr x13,[x29,#-16]
+	str x13,[x29,#-16]
+	mov x14,#7
+	mov x0,x13
+	b .Lf_epilogue
+.Lf_epilogue:
+	add sp,sp,#16
+	ldp x29,x30,[sp]
+	add sp,sp,#16
+	ret
+	.size f,(.-f)
@@ -0,0 +1,20 @@
+	.text
+g:
+	str x9,[x29,#-8]
+	str x10,[x9]
+	ldr x11,[x29,#-8]
+	mov x8,#5
+	cmp x11,x8
+	add x12,x8,x11
+	mov x8,#5000
+	add x13,x12,x8
+	str x13,[x29,#-16]
+loop_L1:
+	ldr x14,[x29,#-16]
+	mov x0,x14
+	mov x1,x12
+	bl h
+	b.lt loop_L1
+	mov x1,#0
+	svc #0
+	ret
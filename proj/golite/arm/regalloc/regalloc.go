@@ -0,0 +1,342 @@
+// Package regalloc assigns the virtual registers of the ILOC of a function to Armv8 registers,
+// by linear scan or by coloring the interference graph, and spills to stack slots the registers
+// that do not fit.
+package regalloc
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+	"sort"
+	"strings"
+)
+
+// Strategy selects how the registers are allocated
+type Strategy int
+
+const (
+	Linear Strategy = iota // linear scan over the live intervals, the default
+	Graph                  // coloring of the interference graph
+	None                   // every register spilled to its stack slot
+)
+
+var strategyNames = []string{"linear", "graph", "none"}
+
+func (s Strategy) String() string { return strategyNames[s] }
+
+// ParseStrategy returns the strategy of a name printed by String, false if there is none
+func ParseStrategy(name string) (Strategy, bool) {
+	for s, strategyName := range strategyNames {
+		if name == strategyName {
+			return Strategy(s), true
+		}
+	}
+	return Linear, false
+}
+
+// StrategyNames lists the names of the strategies, e.g. for the help of a flag
+func StrategyNames() string {
+	return strings.Join(strategyNames, "|")
+}
+
+// CallerSaved are the registers given to the values that are not live across a call, first. The
+// arguments x0 to x7, x8, x16 and x17 of utility.ScratchRegs and the platform register x18 are
+// never allocated.
+var CallerSaved = []int{9, 10, 11, 12, 13, 14, 15}
+
+// CalleeSaved are the registers a function saves before using them, the only ones that keep a value
+// across a call
+var CalleeSaved = []int{19, 20, 21, 22, 23, 24, 25, 26, 27, 28}
+
+// Allocation tells where each register of a function lives. The frame below x29 starts with the
+// callee-saved registers used, Saved[i] at x29-8*(i+1), followed by the slots of the spilled
+// registers.
+type Allocation struct {
+	Regs      map[int]int // the Arm register of each register allocated to one
+	Slots     map[int]int // the stack slot of each spilled register, as an offset from x29
+	Saved     []int       // the callee-saved registers used, in increasing order
+	FrameSize int         // the bytes of the frame below x29, a multiple of 16
+}
+
+// Allocate assigns the registers of the function of a graph
+func Allocate(g *cfg.Graph, strategy Strategy) *Allocation {
+	l := analyze(g)
+	var regs map[int]int
+	switch strategy {
+	case Linear:
+		regs = l.linearScan()
+	case Graph:
+		regs = l.color()
+	default:
+		regs = map[int]int{}
+	}
+	return l.frame(regs)
+}
+
+// live holds what the strategies know of the registers of a function
+type live struct {
+	g          *cfg.Graph
+	regs       []int                // every register of the function, in increasing order
+	params     map[int]bool         // the parameters, defined on entry
+	start      map[int]int          // the first position a register is live or mentioned at, -1 for the parameters
+	end        map[int]int          // the last one
+	acrossCall map[int]bool         // the registers live across a call, to be kept in callee-saved registers
+	cost       map[int]float64      // the uses and definitions of each register, weighted by 10 per enclosing loop
+	edges      map[int]map[int]bool // the interference graph: the registers live at the same time
+}
+
+// isCall returns true for the instructions translated to a call, which may overwrite the
+// caller-saved registers
+func isCall(instr ir.Instruction) bool {
+	switch instr.(type) {
+	case *ir.Bl, *ir.Print, *ir.Println, *ir.Read, *ir.New, *ir.NewArr, *ir.Delete,
+		*ir.StrLen, *ir.StrCat, *ir.Printf:
+		return true
+	}
+	return false
+}
+
+func analyze(g *cfg.Graph) *live {
+	l := &live{g: g, params: map[int]bool{}, start: map[int]int{}, end: map[int]int{},
+		acrossCall: map[int]bool{}, cost: map[int]float64{}, edges: map[int]map[int]bool{}}
+	mention := func(reg int, position int) {
+		if _, seen := l.start[reg]; !seen {
+			l.regs = append(l.regs, reg)
+			l.start[reg], l.end[reg] = position, position
+			l.edges[reg] = map[int]bool{}
+		}
+		if position < l.start[reg] {
+			l.start[reg] = position
+		}
+		if position > l.end[reg] {
+			l.end[reg] = position
+		}
+	}
+	for _, param := range g.Frag.Params {
+		l.params[param] = true
+		mention(param, -1)
+	}
+
+	liveness := dataflow.Liveness(g)
+	for _, b := range g.Blocks {
+		weight := 1.0
+		for _, loop := range g.Loops {
+			if loop.Contains(b) {
+				weight *= 10
+			}
+		}
+		before, after := liveness.Before(b), liveness.After(b)
+		for i, instr := range b.Instructions {
+			position := b.Start + i
+			for _, reg := range append(instr.GetTargets(), instr.GetSources()...) {
+				mention(reg, position)
+				l.cost[reg] += weight
+			}
+			for _, reg := range before[i].Elements() {
+				mention(reg, position)
+			}
+			for _, reg := range after[i].Elements() {
+				mention(reg, position)
+			}
+			l.interfere(instr, after[i])
+			if isCall(instr) {
+				for reg := range after[i] {
+					if !contains(instr.GetTargets(), reg) {
+						l.acrossCall[reg] = true
+					}
+				}
+			}
+		}
+	}
+	// the parameters are all defined on entry, where the registers live-in are live as well
+	entry := liveness.In[g.Entry.Index].Copy()
+	for param := range l.params {
+		entry[param] = true
+	}
+	for reg := range entry {
+		mention(reg, -1)
+		for other := range entry {
+			l.addEdge(reg, other)
+		}
+	}
+	sort.Ints(l.regs)
+	return l
+}
+
+// interfere adds the edges between the registers an instruction writes and those live after it,
+// but for the source of a mov, which may share the register of its target
+func (l *live) interfere(instr ir.Instruction, after dataflow.Set) {
+	copied := -1
+	if mov, isMov := instr.(*ir.Mov); isMov && mov.GetFlag() == ir.AL && !mov.GetRetFlag() && mov.GetImmediate() == nil {
+		copied = mov.GetSources()[0]
+	}
+	for _, target := range instr.GetTargets() {
+		for reg := range after {
+			if reg != copied {
+				l.addEdge(target, reg)
+			}
+		}
+	}
+}
+
+func (l *live) addEdge(a int, b int) {
+	if a == b {
+		return
+	}
+	for _, reg := range []int{a, b} {
+		if l.edges[reg] == nil {
+			l.edges[reg] = map[int]bool{}
+		}
+	}
+	l.edges[a][b] = true
+	l.edges[b][a] = true
+}
+
+// candidates returns the Arm registers a register may be given, in order of preference
+func (l *live) candidates(reg int) []int {
+	if l.acrossCall[reg] {
+		return CalleeSaved
+	}
+	return append(append([]int{}, CallerSaved...), CalleeSaved...)
+}
+
+// linearScan walks the live intervals by increasing start. A register is given the first free
+// candidate; if there is none, the active interval ending last and holding a candidate is spilled
+// instead of it, unless it ends first.
+func (l *live) linearScan() map[int]int {
+	intervals := append([]int{}, l.regs...)
+	sort.SliceStable(intervals, func(i, j int) bool { return l.start[intervals[i]] < l.start[intervals[j]] })
+	regs := map[int]int{}
+	holder := map[int]int{} // the register holding each Arm register of the active intervals
+	active := []int{}
+	for _, reg := range intervals {
+		// expire the intervals ending before this one starts
+		remaining := []int{}
+		for _, other := range active {
+			if l.end[other] < l.start[reg] {
+				delete(holder, regs[other])
+			} else {
+				remaining = append(remaining, other)
+			}
+		}
+		active = remaining
+
+		assigned := false
+		for _, armReg := range l.candidates(reg) {
+			if _, busy := holder[armReg]; !busy {
+				regs[reg], holder[armReg] = armReg, reg
+				active = append(active, reg)
+				assigned = true
+				break
+			}
+		}
+		if assigned {
+			continue
+		}
+		victim := -1
+		for _, other := range active {
+			if contains(l.candidates(reg), regs[other]) && (victim < 0 || l.end[other] > l.end[victim]) {
+				victim = other
+			}
+		}
+		if victim >= 0 && l.end[victim] > l.end[reg] {
+			armReg := regs[victim]
+			delete(regs, victim)
+			regs[reg], holder[armReg] = armReg, reg
+			for i, other := range active {
+				if other == victim {
+					active[i] = reg
+				}
+			}
+		}
+	}
+	return regs
+}
+
+// color allocates by the optimistic coloring of Briggs: the registers having fewer neighbors than
+// candidates are removed from the graph first, then the cheapest ones for their number of
+// neighbors, and the registers are given the first candidate left by their colored neighbors in
+// the reverse order. A register left without one is spilled.
+func (l *live) color() map[int]int {
+	degree := map[int]int{}
+	removed := map[int]bool{}
+	for _, reg := range l.regs {
+		degree[reg] = len(l.edges[reg])
+	}
+	stack := []int{}
+	for len(stack) < len(l.regs) {
+		next := -1
+		for _, reg := range l.regs {
+			if !removed[reg] && degree[reg] < len(l.candidates(reg)) {
+				next = reg
+				break
+			}
+		}
+		if next < 0 {
+			for _, reg := range l.regs {
+				if !removed[reg] && (next < 0 || l.cost[reg]/float64(degree[reg]) < l.cost[next]/float64(degree[next])) {
+					next = reg
+				}
+			}
+		}
+		removed[next] = true
+		stack = append(stack, next)
+		for neighbor := range l.edges[next] {
+			degree[neighbor]--
+		}
+	}
+
+	regs := map[int]int{}
+	for i := len(stack) - 1; i >= 0; i-- {
+		reg := stack[i]
+		taken := map[int]bool{}
+		for neighbor := range l.edges[reg] {
+			if armReg, colored := regs[neighbor]; colored {
+				taken[armReg] = true
+			}
+		}
+		for _, armReg := range l.candidates(reg) {
+			if !taken[armReg] {
+				regs[reg] = armReg
+				break
+			}
+		}
+	}
+	return regs
+}
+
+// frame lays out the callee-saved registers used and the slots of the registers left without an
+// Arm register
+func (l *live) frame(regs map[int]int) *Allocation {
+	a := &Allocation{Regs: regs, Slots: map[int]int{}}
+	used := map[int]bool{}
+	for _, armReg := range regs {
+		used[armReg] = true
+	}
+	for _, armReg := range CalleeSaved {
+		if used[armReg] {
+			a.Saved = append(a.Saved, armReg)
+		}
+	}
+	offset := -8 * len(a.Saved)
+	for _, reg := range l.regs {
+		if _, allocated := regs[reg]; !allocated {
+			offset -= 8
+			a.Slots[reg] = offset
+		}
+	}
+	a.FrameSize = -offset
+	if a.FrameSize%16 != 0 {
+		a.FrameSize += 8
+	}
+	return a
+}
+
+func contains(list []int, elem int) bool {
+	for _, e := range list {
+		if e == elem {
+			return true
+		}
+	}
+	return false
+}
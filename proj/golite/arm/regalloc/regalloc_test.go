@@ -0,0 +1,89 @@
+package regalloc
+
+import (
+	"os"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+	"proj/golite/ir/parse"
+	"testing"
+)
+
+func build(t *testing.T, path string) []*cfg.Graph {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frags, errors := parse.Parse(string(src))
+	if len(errors) != 0 {
+		t.Fatalf("\nExpected: no errors; Got %v\n", errors)
+	}
+	return cfg.BuildAll(frags)
+}
+
+// check verifies that the registers live at the same time do not share an Arm register or a
+// slot, and that only callee-saved registers hold values across a call
+func check(t *testing.T, g *cfg.Graph, a *Allocation, strategy Strategy) {
+	live := dataflow.Liveness(g)
+	for _, b := range g.Blocks {
+		for i, after := range live.After(b) {
+			instr := b.Instructions[i]
+			regs := after.Union(dataflow.NewSet(instr.GetTargets()...))
+			owners := map[int]int{}
+			for _, reg := range regs.Elements() {
+				location, allocated := a.Regs[reg]
+				if !allocated {
+					location = a.Slots[reg]
+				}
+				if other, taken := owners[location]; taken {
+					t.Errorf("\n%v: r%v and r%v are both live after %v in %v\n", strategy, other, reg, instr, g.Frag.Label)
+				}
+				owners[location] = reg
+				if isCall(instr) && after[reg] && !contains(instr.GetTargets(), reg) && contains(CallerSaved, a.Regs[reg]) {
+					t.Errorf("\n%v: r%v is live across %v in the caller-saved x%v\n", strategy, reg, instr, a.Regs[reg])
+				}
+			}
+		}
+	}
+	if a.FrameSize%16 != 0 || a.FrameSize < 8*(len(a.Saved)+len(a.Slots)) {
+		t.Errorf("\n%v: a frame of %v bytes for %v saved registers and %v slots\n", strategy, a.FrameSize, len(a.Saved), len(a.Slots))
+	}
+}
+
+func Test1(t *testing.T) {
+	graphs := build(t, "test1_regalloc.iloc")
+	for _, strategy := range []Strategy{Linear, Graph, None} {
+		for _, g := range graphs {
+			check(t, g, Allocate(g, strategy), strategy)
+		}
+	}
+}
+
+func Test2(t *testing.T) {
+	graphs := build(t, "test1_regalloc.iloc")
+	f, g := graphs[0], graphs[1]
+	for _, strategy := range []Strategy{Linear, Graph} {
+		// only the values live across the call that do not fit in callee-saved registers spill
+		a := Allocate(f, strategy)
+		if len(a.Saved) != len(CalleeSaved) || len(a.Slots) == 0 || len(a.Slots) > 12 {
+			t.Errorf("\n%v: Expected all the callee-saved registers and about 10 slots; Got %v and %v\n", strategy, a.Saved, len(a.Slots))
+		}
+		// the loop of g needs no slot, and no callee-saved register since it makes no call
+		if a := Allocate(g, strategy); len(a.Slots) != 0 || len(a.Saved) != 0 || a.FrameSize != 0 {
+			t.Errorf("\n%v: Expected g in caller-saved registers; Got %v, slots %v\n", strategy, a.Regs, a.Slots)
+		}
+	}
+	if a := Allocate(g, None); len(a.Regs) != 0 || len(a.Slots) != 2 || a.FrameSize != 16 {
+		t.Errorf("\nnone: Expected r2 and r3 in 2 slots; Got %v and %v\n", a.Regs, a.Slots)
+	}
+}
+
+func Test3(t *testing.T) {
+	for _, strategy := range []Strategy{Linear, Graph, None} {
+		if parsed, ok := ParseStrategy(strategy.String()); !ok || parsed != strategy {
+			t.Errorf("\nExpected: %v; Got %v\n", strategy, parsed)
+		}
+	}
+	if _, ok := ParseStrategy("greedy"); ok {
+		t.Errorf("\nExpected: greedy to be unknown\n")
+	}
+}
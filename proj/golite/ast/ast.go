@@ -2,18 +2,21 @@ package ast
 
 import (
 	"bytes"
-	"fmt"
+	"proj/golite/diag"
 	"proj/golite/ir"
 	st "proj/golite/symboltable"
 	"proj/golite/token"
 	"proj/golite/types"
+	"strconv"
+	"strings"
 )
 
 // Node The base Node interface that all ast nodes have to access
 type Node interface {
 	TokenLiteral() string
+	GetSpan() token.Span // the range of source the node has been parsed from
 	String() string
-	TypeCheck([]string, *st.SymbolTable) []string
+	TypeCheck([]diag.Diagnostic, *st.SymbolTable) []diag.Diagnostic
 	TranslateToILoc([]ir.Instruction, *st.SymbolTable) []ir.Instruction
 }
 
@@ -27,7 +30,7 @@ type Expr interface {
 // Stmt All statement nodes implement this interface
 type Stmt interface {
 	Node
-	PerformSABuild([]string, *st.SymbolTable) []string
+	PerformSABuild([]diag.Diagnostic, *st.SymbolTable) []diag.Diagnostic
 }
 
 // Func prog, funcs, func nodes need to implement this interface
@@ -41,6 +44,7 @@ type Func interface {
 
 type Program struct {
 	Token *token.Token
+	Span  token.Span
 	st    *st.SymbolTable
 
 	Package      *Package
@@ -50,6 +54,8 @@ type Program struct {
 	Functions    *Functions
 }
 
+func (p *Program) GetSpan() token.Span { return p.Span }
+
 func (p *Program) TokenLiteral() string {
 	if p.Token != nil {
 		return p.Token.Literal
@@ -58,14 +64,19 @@ func (p *Program) TokenLiteral() string {
 }
 func (p *Program) String() string {
 	out := bytes.Buffer{}
-	out.WriteString(p.Package.String())
-	out.WriteString(p.Import.String())
+	// the package and import clauses are missing from a program with syntax errors
+	if p.Package != nil {
+		out.WriteString(p.Package.String())
+	}
+	if p.Import != nil {
+		out.WriteString(p.Import.String())
+	}
 	out.WriteString(p.Types.String())
 	out.WriteString(p.Declarations.String())
 	out.WriteString(p.Functions.String())
 	return out.String()
 }
-func (p *Program) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (p *Program) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	p.st = symTable
 	errors = p.Package.PerformSABuild(errors, symTable)
 	errors = p.Import.PerformSABuild(errors, symTable)
@@ -73,30 +84,40 @@ func (p *Program) PerformSABuild(errors []string, symTable *st.SymbolTable) []st
 	errors = p.Declarations.PerformSABuild(errors, symTable)
 
 	// Add **new** global functions for creating an instance of a declared struct
+	// new and delete are type checked by checkNew and checkDelete, not by their signature
 	newScopeSt := st.New(symTable, "new")
 	newScopeSt.ScopeParamNames = append(newScopeSt.ScopeParamNames, "structName")
-	newScopeSt.ScopeParamTys = append(newScopeSt.ScopeParamTys, types.StructTySig)
+	newScopeSt.ScopeParamTys = append(newScopeSt.ScopeParamTys, types.UnknownTySig)
 	var structEntry st.Entry
-	structEntry = st.NewStructEntry(nil)
+	structEntry = st.NewStructEntry(types.UnknownTySig, nil)
 	newScopeSt.Insert("structName", &structEntry)
 	var newEntry st.Entry
-	newEntry = st.NewFuncEntry(types.StructTySig, newScopeSt)
+	newEntry = st.NewFuncEntry(types.UnknownTySig, newScopeSt)
 	symTable.Insert("new", &newEntry)
 
 	// Add **delete** global functions for deleting/releasing an instance of a declared struct
 	deleteScopeSt := st.New(symTable, "delete")
 	deleteScopeSt.ScopeParamNames = append(deleteScopeSt.ScopeParamNames, "structName")
-	deleteScopeSt.ScopeParamTys = append(deleteScopeSt.ScopeParamTys, types.StructTySig)
-	structEntry = st.NewStructEntry(nil)
+	deleteScopeSt.ScopeParamTys = append(deleteScopeSt.ScopeParamTys, types.UnknownTySig)
+	structEntry = st.NewStructEntry(types.UnknownTySig, nil)
 	deleteScopeSt.Insert("structName", &structEntry)
 	var deleteEntry st.Entry
-	deleteEntry = st.NewFuncEntry(types.StructTySig, deleteScopeSt)
+	deleteEntry = st.NewFuncEntry(types.VoidTySig, deleteScopeSt)
 	symTable.Insert("delete", &deleteEntry)
 
+	// Add **len** and **append** global functions for arrays, slices and strings, type checked by
+	// checkLen and checkAppend, append returning the type of its first argument
+	var lenEntry st.Entry
+	lenEntry = st.NewFuncEntry(types.IntTySig, st.New(symTable, "len"))
+	symTable.Insert("len", &lenEntry)
+	var appendEntry st.Entry
+	appendEntry = st.NewFuncEntry(types.UnknownTySig, st.New(symTable, "append"))
+	symTable.Insert("append", &appendEntry)
+
 	errors = p.Functions.PerformSABuild(errors, symTable)
 	return errors
 }
-func (p *Program) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (p *Program) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = p.Package.TypeCheck(errors, symTable)
 	errors = p.Import.TypeCheck(errors, symTable)
 	errors = p.Types.TypeCheck(errors, symTable)
@@ -107,6 +128,24 @@ func (p *Program) TypeCheck(errors []string, symTable *st.SymbolTable) []string
 func (p *Program) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *st.SymbolTable) []*ir.FuncFrag {
 	funcFrag = p.Declarations.TranslateToILocFunc(funcFrag, symTable)
 	funcFrag = p.Functions.TranslateToILocFunc(funcFrag, symTable)
+	// the global arrays, slices and strings are set by main before its statements, the fragment
+	// of the global variables only declaring them
+	zero := []ir.Instruction{}
+	for _, dec := range p.Declarations.Declarations {
+		for _, id := range dec.Ids.Idents {
+			ty := symTable.Contains(id.Id).GetEntryType()
+			if hasZero(ty) {
+				reg := ir.NewRegister()
+				zero = translateZero(zero, reg, ty)
+				zero = append(zero, ir.NewStr(reg, -1, -1, id.Id, ir.GLOBALVAR))
+			}
+		}
+	}
+	for _, frag := range funcFrag {
+		if frag.Label == "main" && len(zero) > 0 {
+			frag.Body = append(frag.Body[:1], append(zero, frag.Body[1:]...)...)
+		}
+	}
 	return funcFrag
 }
 func (p *Program) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -115,10 +154,13 @@ func (p *Program) TranslateToILoc(instructions []ir.Instruction, symTable *st.Sy
 
 type Package struct {
 	Token *token.Token
+	Span  token.Span
 	//st    *st.SymbolTable
 	Ident IdentLiteral
 }
 
+func (pkg *Package) GetSpan() token.Span { return pkg.Span }
+
 func (pkg *Package) TokenLiteral() string {
 	if pkg.Token != nil {
 		return pkg.Token.Literal
@@ -134,12 +176,12 @@ func (pkg *Package) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (pkg *Package) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (pkg *Package) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	return errors
 }
-func (pkg *Package) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (pkg *Package) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	if pkg.Ident.TokenLiteral() != "main" {
-		errors = append(errors, fmt.Sprintf("Only package main is allowed"))
+		errors = append(errors, diag.Errorf(diag.PackageNotMain, diag.At(pkg.Ident.Token), "only package main is allowed, found package %v", pkg.Ident.TokenLiteral()))
 	}
 	return errors
 }
@@ -149,9 +191,12 @@ func (pkg *Package) TranslateToILoc(instrcs []ir.Instruction, symTable *st.Symbo
 
 type Import struct {
 	Token *token.Token
+	Span  token.Span
 	Ident IdentLiteral
 }
 
+func (imp *Import) GetSpan() token.Span { return imp.Span }
+
 func (imp *Import) TokenLiteral() string {
 	if imp.Token != nil {
 		return imp.Token.Literal
@@ -169,10 +214,10 @@ func (imp *Import) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (imp *Import) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (imp *Import) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	return errors
 }
-func (imp *Import) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (imp *Import) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	return errors
 }
 func (imp *Import) TranslateToILoc(instrcs []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -181,10 +226,13 @@ func (imp *Import) TranslateToILoc(instrcs []ir.Instruction, symTable *st.Symbol
 
 type Types struct {
 	Token *token.Token
+	Span  token.Span
 	//st    *st.SymbolTable
 	TypeDeclarations []TypeDeclaration
 }
 
+func (tys *Types) GetSpan() token.Span { return tys.Span }
+
 func (tys *Types) TokenLiteral() string {
 	if tys.Token != nil {
 		return tys.Token.Literal
@@ -199,13 +247,13 @@ func (tys *Types) String() string {
 	}
 	return out.String()
 }
-func (tys *Types) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (tys *Types) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	for _, typedec := range tys.TypeDeclarations {
 		errors = typedec.PerformSABuild(errors, symTable)
 	}
 	return errors
 }
-func (tys *Types) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (tys *Types) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	for _, typedec := range tys.TypeDeclarations {
 		errors = typedec.TypeCheck(errors, symTable)
 	}
@@ -217,11 +265,14 @@ func (tys *Types) TranslateToILoc(instrcs []ir.Instruction, symTable *st.SymbolT
 
 type TypeDeclaration struct {
 	Token  *token.Token
+	Span   token.Span
 	st     *st.SymbolTable
 	Ident  IdentLiteral
 	Fields *Fields
 }
 
+func (td *TypeDeclaration) GetSpan() token.Span { return td.Span }
+
 func (td *TypeDeclaration) TokenLiteral() string {
 	if td.Token != nil {
 		return td.Token.Literal
@@ -242,29 +293,33 @@ func (td *TypeDeclaration) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (td *TypeDeclaration) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (td *TypeDeclaration) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: find duplicate structures
 	structName := td.Ident.TokenLiteral()
 	scopeSymTable := st.New(symTable, structName)
 	td.st = scopeSymTable
 
 	if entry := symTable.Contains(structName); entry != nil {
-		errors = append(errors, fmt.Sprintf("[%v]: struct %v already declared", td.Token.LineNum, structName))
+		errors = append(errors, diag.Errorf(diag.Redeclared, diag.At(td.Ident.Token), "struct %v already declared", structName))
 	} else {
+		// the fields of the struct type are set by TypeCheck, once every struct is declared
 		var entry st.Entry
-		entry = st.NewStructEntry(td.st)
+		entry = st.NewStructEntry(&types.NamedStructType{Name: structName}, td.st)
 		symTable.Insert(structName, &entry)
 		errors = td.Fields.PerformSABuild(errors, td.st)
 	}
 	return errors
 }
-func (td *TypeDeclaration) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
-	// objective: none
+func (td *TypeDeclaration) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	// objective: set the fields of the struct type
 	//errors2 := td.Fields.TypeCheck(errors, td.st)
-	scopeSymTable := symTable.Contains(td.Ident.TokenLiteral()).GetScopeST()
-	errors2 := td.Fields.TypeCheck(errors, scopeSymTable)
-
-	errors = append(errors, errors2...)
+	entry := symTable.Contains(td.Ident.TokenLiteral())
+	scopeSymTable := entry.GetScopeST()
+	errors = td.Fields.TypeCheck(errors, scopeSymTable)
+	structTy := entry.GetEntryType().(*types.NamedStructType)
+	for idx, fieldName := range scopeSymTable.ScopeParamNames {
+		structTy.Fields = append(structTy.Fields, types.Field{Name: fieldName, Ty: scopeSymTable.ScopeParamTys[idx]})
+	}
 	return errors
 }
 func (td *TypeDeclaration) TranslateToILoc(instrcs []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -273,9 +328,12 @@ func (td *TypeDeclaration) TranslateToILoc(instrcs []ir.Instruction, symTable *s
 
 type Fields struct {
 	Token *token.Token
+	Span  token.Span
 	Decls []Decl
 }
 
+func (fields *Fields) GetSpan() token.Span { return fields.Span }
+
 func (fields *Fields) TokenLiteral() string {
 	if fields.Token != nil {
 		return fields.Token.Literal
@@ -293,14 +351,14 @@ func (fields *Fields) String() string {
 	}
 	return out.String()
 }
-func (fields *Fields) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (fields *Fields) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, decl := range fields.Decls {
 		errors = decl.PerformSABuild(errors, symTable)
 	}
 	return errors
 }
-func (fields *Fields) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (fields *Fields) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, decl := range fields.Decls {
 		errors = decl.TypeCheck(errors, symTable)
@@ -313,10 +371,13 @@ func (fields *Fields) TranslateToILoc(instrcs []ir.Instruction, symTable *st.Sym
 
 type Decl struct {
 	Token *token.Token
+	Span  token.Span
 	Ident IdentLiteral
 	Ty    *Type
 }
 
+func (decl *Decl) GetSpan() token.Span { return decl.Span }
+
 func (decl *Decl) TokenLiteral() string {
 	if decl.Token != nil {
 		return decl.Token.Literal
@@ -330,11 +391,11 @@ func (decl *Decl) String() string {
 	out.WriteString(decl.Ty.String())
 	return out.String()
 }
-func (decl *Decl) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (decl *Decl) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: find duplicate declarations in functions / structures
 	varName := decl.Ident.TokenLiteral()
 	if entry := symTable.Contains(varName); entry != nil {
-		errors = append(errors, fmt.Sprintf("[%v]: variable %v already declared", decl.Token.LineNum, varName))
+		errors = append(errors, diag.Errorf(diag.Redeclared, diag.At(decl.Token), "variable %v already declared", varName))
 	} else {
 		var entry st.Entry
 		entry = st.NewVarEntry()
@@ -342,11 +403,12 @@ func (decl *Decl) PerformSABuild(errors []string, symTable *st.SymbolTable) []st
 	}
 	return errors
 }
-func (decl *Decl) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (decl *Decl) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: set type for the variable id
 
 	// Decl = 'id' Type
 	// get the type from Type
+	errors = decl.Ty.TypeCheck(errors, symTable)
 	varType := decl.Ty.GetType(symTable)
 	// update / set type of 'id' in the symbol table
 	entry := symTable.Contains(decl.Ident.TokenLiteral())
@@ -364,9 +426,12 @@ func (decl *Decl) TranslateToILoc(instrcs []ir.Instruction, symTable *st.SymbolT
 
 type Declarations struct {
 	Token        *token.Token
+	Span         token.Span
 	Declarations []Declaration
 }
 
+func (ds *Declarations) GetSpan() token.Span { return ds.Span }
+
 func (ds *Declarations) TokenLiteral() string {
 	if ds.Token != nil {
 		return ds.Token.Literal
@@ -381,14 +446,14 @@ func (ds *Declarations) String() string {
 	}
 	return out.String()
 }
-func (ds *Declarations) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (ds *Declarations) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, dec := range ds.Declarations {
 		errors = dec.PerformSABuild(errors, symTable)
 	}
 	return errors
 }
-func (ds *Declarations) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (ds *Declarations) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, dec := range ds.Declarations {
 		errors = dec.TypeCheck(errors, symTable)
@@ -400,7 +465,7 @@ func (ds *Declarations) TranslateToILoc(instrcs []ir.Instruction, symTable *st.S
 }
 func (ds *Declarations) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *st.SymbolTable) []*ir.FuncFrag {
 	var frag ir.FuncFrag
-	frag.Label = ir.NewLabelWithPre("Global Variable")
+	frag.Label = ir.NewLabelWithPre(ir.GlobalFragPrefix)
 	funcLabelInstruct := ir.NewLabelStmt(frag.Label)
 	frag.Body = append(frag.Body, funcLabelInstruct)
 
@@ -414,10 +479,13 @@ func (ds *Declarations) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *s
 
 type Declaration struct {
 	Token *token.Token
+	Span  token.Span
 	Ids   *Ids
 	Ty    *Type
 }
 
+func (d *Declaration) GetSpan() token.Span { return d.Span }
+
 func (d *Declaration) TokenLiteral() string {
 	if d.Token != nil {
 		return d.Token.Literal
@@ -434,51 +502,19 @@ func (d *Declaration) String() string {
 	out.WriteString(";")
 	return out.String()
 }
-func (d *Declaration) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
-	//if d.Ty.GetType(symTable) == types.StructTySig {
-	//	// we want to construct scope table for it/them at this point
-	//	structName := d.Ty.TypeLiteral[1:]  // remove the * at position 0; e.g. *foo -> foo
-	//	//fmt.Println(structName)
-	//
-	//	// get the symbol table of the prototype of the struct
-	//	if protoStructEntry := symTable.PowerContains(structName); protoStructEntry != nil {
-	//		protoScopeSt := protoStructEntry.GetScopeST()
-	//		for _, id := range d.Ids.Idents {
-	//			duplicateScopeSt := protoScopeSt.GetCopy(id.String(), symTable)
-	//			var duplicateEntry st.Entry
-	//			duplicateEntry = st.NewStructEntry(duplicateScopeSt)
-	//			symTable.Insert(id.String(), &duplicateEntry)
-	//		}
-	//	} else {
-	//		errors = append(errors, fmt.Sprintf("[%v]: struct %v has not been defined", d.Token.LineNum, structName))
-	//	}
-	//} else {
-	//	// objective: none, duplicate definitions are examined in d.Ids.PerformSABuild()
-	//	errors = d.Ids.PerformSABuild(errors, symTable)
-	//}
-
+func (d *Declaration) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none, duplicate definitions are examined in d.Ids.PerformSABuild()
 	errors = d.Ids.PerformSABuild(errors, symTable)
 
 	return errors
 }
-func (d *Declaration) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (d *Declaration) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: set type for ids, symbol table only
+	errors = d.Ty.TypeCheck(errors, symTable)
 	decType := d.Ty.GetType(symTable)
 	for _, id := range d.Ids.Idents {
 		entry := symTable.Contains(id.TokenLiteral())
 		entry.SetType(decType)
-		if entry.GetEntryType() == types.StructTySig {
-			structName := d.Ty.TypeLiteral[1:]
-			protoStructEntry := symTable.PowerContains(structName)
-			protoScopeSt := protoStructEntry.GetScopeST()
-			for _, id := range d.Ids.Idents {
-				duplicateScopeSt := protoScopeSt.GetCopy(id.String(), symTable)
-				var duplicateEntry st.Entry
-				duplicateEntry = st.NewStructEntry(duplicateScopeSt)
-				symTable.Insert(id.String(), &duplicateEntry)
-			}
-		}
 	}
 	return errors
 }
@@ -489,9 +525,12 @@ func (d *Declaration) TranslateToILoc(instrcs []ir.Instruction, symTable *st.Sym
 
 type Ids struct {
 	Token  *token.Token
+	Span   token.Span
 	Idents []IdentLiteral
 }
 
+func (ids *Ids) GetSpan() token.Span { return ids.Span }
+
 func (ids *Ids) TokenLiteral() string {
 	if ids.Token != nil {
 		return ids.Token.Literal
@@ -508,12 +547,12 @@ func (ids *Ids) String() string {
 	}
 	return out.String()
 }
-func (ids *Ids) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (ids *Ids) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// Objective: find duplicate declarations
 	for _, id := range ids.Idents {
 		varName := id.TokenLiteral()
 		if entry := symTable.Contains(varName); entry != nil {
-			errors = append(errors, fmt.Sprintf("[%v]: variable [%v] already declared", id.Token.LineNum, varName))
+			errors = append(errors, diag.Errorf(diag.Redeclared, diag.At(id.Token), "variable %v already declared", varName))
 		} else {
 			var entry st.Entry
 			entry = st.NewVarEntry()
@@ -522,7 +561,7 @@ func (ids *Ids) PerformSABuild(errors []string, symTable *st.SymbolTable) []stri
 	}
 	return errors
 }
-func (ids *Ids) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (ids *Ids) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none, accomplished in Declaration
 	return errors
 }
@@ -540,9 +579,12 @@ func (ids *Ids) TranslateToILoc(instructions []ir.Instruction, symTable *st.Symb
 
 type Functions struct {
 	Token     *token.Token
+	Span      token.Span
 	Functions []Function
 }
 
+func (fs *Functions) GetSpan() token.Span { return fs.Span }
+
 func (fs *Functions) TokenLiteral() string {
 	if fs.Token != nil {
 		return fs.Token.Literal
@@ -556,14 +598,14 @@ func (fs *Functions) String() string {
 	}
 	return out.String()
 }
-func (fs *Functions) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (fs *Functions) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, fun := range fs.Functions {
 		errors = fun.PerformSABuild(errors, symTable)
 	}
 	return errors
 }
-func (fs *Functions) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (fs *Functions) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	for _, fun := range fs.Functions {
 		errors = fun.TypeCheck(errors, symTable)
 	}
@@ -583,6 +625,7 @@ func (fs *Functions) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *st.S
 
 type Function struct {
 	Token        *token.Token
+	Span         token.Span
 	st           *st.SymbolTable
 	Ident        IdentLiteral
 	Parameters   *Parameters
@@ -591,6 +634,8 @@ type Function struct {
 	Statements   *Statements
 }
 
+func (f *Function) GetSpan() token.Span { return f.Span }
+
 func (f *Function) TokenLiteral() string {
 	if f.Token != nil {
 		return f.Token.Literal
@@ -615,21 +660,14 @@ func (f *Function) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (f *Function) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (f *Function) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// Objective: find duplicate function definitions
 	funcName := f.Ident.TokenLiteral()
 	scopeSymTable := st.New(symTable, funcName)
 	f.st = scopeSymTable
 
-	// built-in delete
-	// in each function, append the parent of delete to the current scope st
-	// so that from the start to the end of the current function scope, we look for
-	// struct names inside this current scope (delete still in global st)
-	deleteEntry := symTable.PowerContains("delete")
-	deleteEntry.GetScopeST().Parent = scopeSymTable
-
 	if entry := symTable.Contains(funcName); entry != nil {
-		errors = append(errors, fmt.Sprintf("[%v]: function [%v] has been declared", f.Token.LineNum, funcName))
+		errors = append(errors, diag.Errorf(diag.Redeclared, diag.At(f.Ident.Token), "function %v has been declared", funcName))
 	} else {
 		var entry st.Entry
 		entry = st.NewFuncEntry(f.ReturnType.GetType(symTable), f.st)
@@ -640,16 +678,41 @@ func (f *Function) PerformSABuild(errors []string, symTable *st.SymbolTable) []s
 	}
 	return errors
 }
-func (f *Function) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (f *Function) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// Objective: add parameters, return type to function symbol table and entry in the outer symbol table
 	// parameters are added to both inner symbol table and function signature in the outer symbol table by Decl invoked next line
-	currScopeSt := symTable.Contains(f.Ident.TokenLiteral()).GetScopeST()
-	f.st = currScopeSt
+	entry := symTable.Contains(f.Ident.TokenLiteral())
+	f.st = entry.GetScopeST()
+	errors = f.ReturnType.TypeCheck(errors, symTable)
 	errors = f.Parameters.TypeCheck(errors, f.st)
+	entry.SetType(&types.FuncType{Params: f.st.ScopeParamTys, Result: entry.GetReturnTy()})
 	errors = f.Declarations.TypeCheck(errors, f.st)
 	errors = f.Statements.TypeCheck(errors, f.st)
+	// a function returning a value may not reach the end of its body, reported at its closing brace
+	if retTy := entry.GetReturnTy(); retTy != types.VoidTySig && !terminating(f.Statements) {
+		end := diag.Pos{Line: f.Span.End.Line, Col: f.Span.End.Col - 1}
+		errors = append(errors, diag.Errorf(diag.MissingReturn, end, "missing return at the end of %v", f.Ident.TokenLiteral()).
+			WithNote("function %v is declared to return %v", f.Ident.TokenLiteral(), retTy.GetName()))
+	}
 	return errors
 }
+
+// terminating returns true if the statements end with a return, or with a block or an if and
+// else whose statements all do, so that the end of the function cannot be reached after them
+func terminating(stmts *Statements) bool {
+	if stmts == nil || len(stmts.Statements) == 0 {
+		return false
+	}
+	switch stmt := stmts.Statements[len(stmts.Statements)-1].Stmt.(type) {
+	case *Return:
+		return true
+	case *Block:
+		return terminating(stmt.Statements)
+	case *Conditional:
+		return stmt.ElseBlock != nil && terminating(stmt.Block.Statements) && terminating(stmt.ElseBlock.Statements)
+	}
+	return false
+}
 func (f *Function) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
 	return instructions
 }
@@ -657,8 +720,23 @@ func (f *Function) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *st.Sym
 	var frag ir.FuncFrag
 	// function label
 	frag.Label = f.Ident.TokenLiteral()
+	for _, param := range symTable.ScopeParamNames {
+		frag.Params = append(frag.Params, symTable.Contains(param).GetRegId())
+	}
 	funcLabelInstruct := ir.NewLabelStmt(frag.Label)
 	frag.Body = append(frag.Body, funcLabelInstruct)
+	// the locals start as their zero value: a block of zeroes, an empty slice and "" for the
+	// arrays, slices and strings, and 0 for the ints, bools and pointers
+	for _, dec := range f.Declarations.Declarations {
+		for _, id := range dec.Ids.Idents {
+			entry := symTable.Contains(id.Id)
+			if hasZero(entry.GetEntryType()) {
+				frag.Body = translateZero(frag.Body, entry.GetRegId(), entry.GetEntryType())
+			} else {
+				frag.Body = append(frag.Body, ir.NewMov(entry.GetRegId(), 0, ir.AL, ir.IMMEDIATE))
+			}
+		}
+	}
 	// push values in registers associated with the registers to stack
 	//pushReg := []int{}
 	//params := symTable.ScopeParamNames
@@ -682,6 +760,10 @@ func (f *Function) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *st.Sym
 	//}
 	// translate function statements
 	frag.Body = f.Statements.TranslateToILoc(frag.Body, symTable)
+	// a function returning nothing may reach the end of its body, which returns
+	if _, isRet := frag.Body[len(frag.Body)-1].(*ir.Ret); !isRet && f.ReturnType.GetType(symTable) == types.VoidTySig {
+		frag.Body = append(frag.Body, ir.NewRet(-1, ir.VOID))
+	}
 	// pop the previously pushed values in registers associated with parameters
 	//if len(pushReg) != 0 {
 	//	popInst := ir.NewPop(pushReg)
@@ -694,9 +776,12 @@ func (f *Function) TranslateToILocFunc(funcFrag []*ir.FuncFrag, symTable *st.Sym
 
 type Parameters struct {
 	Token *token.Token
+	Span  token.Span
 	Decls []Decl
 }
 
+func (params *Parameters) GetSpan() token.Span { return params.Span }
+
 func (params *Parameters) TokenLiteral() string {
 	if params.Token != nil {
 		return params.Token.Literal
@@ -718,14 +803,14 @@ func (params *Parameters) String() string {
 	out.WriteString(")")
 	return out.String()
 }
-func (params *Parameters) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (params *Parameters) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, decl := range params.Decls {
 		errors = decl.PerformSABuild(errors, symTable)
 	}
 	return errors
 }
-func (params *Parameters) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (params *Parameters) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, decl := range params.Decls {
 		errors = decl.TypeCheck(errors, symTable)
@@ -735,9 +820,12 @@ func (params *Parameters) TypeCheck(errors []string, symTable *st.SymbolTable) [
 
 type Statements struct {
 	Token      *token.Token
+	Span       token.Span
 	Statements []Statement
 }
 
+func (stmts *Statements) GetSpan() token.Span { return stmts.Span }
+
 func (stmts *Statements) TokenLiteral() string {
 	if stmts.Token != nil {
 		return stmts.Token.Literal
@@ -753,14 +841,14 @@ func (stmts *Statements) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (stmts *Statements) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (stmts *Statements) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, stmt := range stmts.Statements {
 		errors = stmt.PerformSABuild(errors, symTable)
 	}
 	return errors
 }
-func (stmts *Statements) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (stmts *Statements) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	for _, stmt := range stmts.Statements {
 		errors = stmt.TypeCheck(errors, symTable)
@@ -776,9 +864,12 @@ func (stmts *Statements) TranslateToILoc(instructions []ir.Instruction, symTable
 
 type Statement struct {
 	Token *token.Token
+	Span  token.Span
 	Stmt  Stmt
 }
 
+func (s *Statement) GetSpan() token.Span { return s.Span }
+
 func (s *Statement) TokenLiteral() string {
 	if s.Token != nil {
 		return s.Token.Literal
@@ -790,12 +881,12 @@ func (s *Statement) String() string {
 	out.WriteString(s.Stmt.String())
 	return out.String()
 }
-func (s *Statement) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (s *Statement) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	errors = s.Stmt.PerformSABuild(errors, symTable)
 	return errors
 }
-func (s *Statement) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (s *Statement) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	errors = s.Stmt.TypeCheck(errors, symTable)
 	return errors
@@ -807,9 +898,12 @@ func (s *Statement) TranslateToILoc(instructions []ir.Instruction, symTable *st.
 
 type Block struct {
 	Token      *token.Token
+	Span       token.Span
 	Statements *Statements
 }
 
+func (b *Block) GetSpan() token.Span { return b.Span }
+
 func (b *Block) TokenLiteral() string {
 	if b.Token != nil {
 		return b.Token.Literal
@@ -824,12 +918,12 @@ func (b *Block) String() string {
 	out.WriteString("}")
 	return out.String()
 }
-func (b *Block) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (b *Block) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	errors = b.Statements.PerformSABuild(errors, symTable)
 	return errors
 }
-func (b *Block) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (b *Block) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	errors = b.Statements.TypeCheck(errors, symTable)
 	return errors
@@ -841,10 +935,13 @@ func (b *Block) TranslateToILoc(instructions []ir.Instruction, symTable *st.Symb
 
 type Assignment struct {
 	Token  *token.Token
+	Span   token.Span
 	Lvalue *LValue
 	Expr   *Expression
 }
 
+func (a *Assignment) GetSpan() token.Span { return a.Span }
+
 func (a *Assignment) TokenLiteral() string {
 	if a.Token != nil {
 		return a.Token.Literal
@@ -862,65 +959,47 @@ func (a *Assignment) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (a *Assignment) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (a *Assignment) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	return errors
 }
-func (a *Assignment) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (a *Assignment) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: matching of types on both sides of the assignment statement
+	numErrors := len(errors)
 	errors = a.Lvalue.TypeCheck(errors, symTable)
 	errors = a.Expr.TypeCheck(errors, symTable)
-	if len(errors) == 0 {
+	if len(errors) == numErrors {
 		leftType := a.Lvalue.GetType(symTable)
 		rightType := a.Expr.GetType(symTable)
-		if leftType != rightType {
-			errors = append(errors, fmt.Sprintf("[%v]: type mismatch: Cannot assign %v (Type %v) to %v (Type %v)",
-				a.Token.LineNum, a.Expr.String(), rightType.GetName(), a.Lvalue.String(), leftType.GetName()))
+		if !types.AssignableTo(rightType, leftType) {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.AtSpan(a.Expr.Span), "type mismatch: cannot assign %v (type %v) to %v (type %v)",
+				a.Expr.String(), rightType.GetName(), a.Lvalue.String(), leftType.GetName()))
 			return errors
 		}
-		if leftType != types.IntTySig && leftType != types.BoolTySig && leftType != types.StructTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: %v is not assignable", a.Token.LineNum, a.Lvalue.String()))
+		_, isPointer := leftType.(*types.PointerType)
+		if _, isElems := types.ElemOf(leftType); leftType != types.IntTySig && leftType != types.BoolTySig && leftType != types.StringTySig && !isPointer && !isElems {
+			errors = append(errors, diag.Errorf(diag.NotAssignable, diag.At(a.Token), "%v is not assignable", a.Lvalue.String()))
 			return errors
 		}
 		if leftType == types.IntTySig && a.Lvalue.Token.Type == token.NUM {
-			errors = append(errors, fmt.Sprintf("[%v]: %v is not assignable", a.Token.LineNum, a.Lvalue.String()))
+			errors = append(errors, diag.Errorf(diag.NotAssignable, diag.At(a.Token), "%v is not assignable", a.Lvalue.String()))
 			return errors
 		}
 		if leftType == types.BoolTySig && (a.Lvalue.Token.Type == token.TRUE || a.Lvalue.Token.Type == token.FALSE) {
-			errors = append(errors, fmt.Sprintf("[%v]: %v is not assignable", a.Token.LineNum, a.Lvalue.String()))
+			errors = append(errors, diag.Errorf(diag.NotAssignable, diag.At(a.Token), "%v is not assignable", a.Lvalue.String()))
 			return errors
 		}
-		// Ignore the following case here:
-		// new(foo) = new(foo2)
-		//if leftType == types.StructTySig {
-		//	// TO-DO :
-		//	structName := a.Expr.String()
-		//	fmt.Println(structName)
-		//	ent := a.Lvalue.getStructEntry(symTable)
-		//	fmt.Println(ent.GetScopeST())
-		//
-		//}
-
-		entry := a.Lvalue.getStructEntry(symTable)
-		if entry != nil {
-			entry.SetValue(a.Expr.TokenLiteral())
-		}
-		// to-discuss: the value to be assigned to the variable in the symbol table
 	}
 	return errors
 }
 
 func (a *Assignment) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
 	instructions = a.Lvalue.TranslateToILoc(instructions, symTable)
-	exprStr := a.Expr.String()
-	if symTable.CheckGlobalVariable(a.Lvalue.String()) && len(exprStr) > 5 && exprStr[:3] == "new" {
-		instructions = instructions[:len(instructions)-1] // remove ldr global variable
-	}
-
 	instructions = a.Expr.TranslateToILoc(instructions, symTable)
 	var instruction ir.Instruction
-	exprReg := a.Expr.GetTargetReg()
-	if a.Lvalue.Idents == nil || len(a.Lvalue.Idents) == 0 {
+	// an array is copied, the variable or field assigned getting a block of its own
+	instructions, exprReg := translateCopy(instructions, a.Expr.GetTargetReg(), a.Expr.GetType(symTable))
+	if len(a.Lvalue.Selectors) == 0 {
 		varName := a.Lvalue.Ident.String()
 		if symTable.CheckGlobalVariable(varName) {
 			//ldrInst := ir.NewLdr(a.Lvalue.Ident.targetReg, -1, -1, a.Lvalue.Ident.Id, ir.GLOBALVAR)
@@ -932,23 +1011,16 @@ func (a *Assignment) TranslateToILoc(instructions []ir.Instruction, symTable *st
 			lvReg := a.Lvalue.GetTargetReg()
 			instruction = ir.NewMov(lvReg, exprReg, ir.AL, ir.REGISTER)
 		}
+	} else if last := a.Lvalue.Selectors[len(a.Lvalue.Selectors)-1]; last.Index != nil {
+		// element assignment, to the block of the array or slice the lvalue loads
+		if a.Lvalue.bounds != nil {
+			instructions = append(instructions, a.Lvalue.bounds)
+		}
+		instruction = ir.NewStrIdx(exprReg, a.Lvalue.GetTargetReg(), a.Lvalue.indexReg)
 	} else {
-		// struct assignment
+		// struct assignment, to the last field of the struct the lvalue loads
 		structAddr := a.Lvalue.GetTargetReg()
-		field := a.Lvalue.Idents[len(a.Lvalue.Idents)-1].TokenLiteral()
-		structSt := symTable.PowerContains(a.Lvalue.Ident.String()).GetScopeST()
-		for i := 0; i < len(a.Lvalue.Idents)-1; i++ {
-			structSt = symTable.Contains(a.Lvalue.Idents[i].String()).GetScopeST()
-		}
-
-		countFields := 0
-		for _, currField := range structSt.ScopeParamNames {
-			if field == currField {
-				break
-			}
-			countFields += 1
-		}
-		instruction = ir.NewStrRef(exprReg, structAddr, field, countFields)
+		instruction = ir.NewStrRef(exprReg, structAddr, last.Ident.Id, fieldIndex(a.Lvalue.structType, last.Ident.Id))
 	}
 	instructions = append(instructions, instruction)
 	return instructions
@@ -956,9 +1028,12 @@ func (a *Assignment) TranslateToILoc(instructions []ir.Instruction, symTable *st
 
 type Read struct {
 	Token *token.Token
+	Span  token.Span
 	Ident IdentLiteral
 }
 
+func (r *Read) GetSpan() token.Span { return r.Span }
+
 func (r *Read) TokenLiteral() string {
 	if r.Token != nil {
 		return r.Token.Literal
@@ -978,16 +1053,18 @@ func (r *Read) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (r *Read) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (r *Read) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	return errors
 }
-func (r *Read) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (r *Read) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: verify the variable is declared
 	varName := r.Ident.TokenLiteral()
 	entry := symTable.Contains(varName)
 	if entry == nil {
-		errors = append(errors, fmt.Sprintf("[%v]: variable %v has not been declared", r.Token.LineNum, varName))
+		errors = append(errors, diag.Errorf(diag.Undefined, diag.At(r.Ident.Token), "variable %v has not been declared", varName))
+	} else if _, isElems := types.ElemOf(entry.GetEntryType()); isElems || entry.GetEntryType() == types.StringTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(r.Ident.Token), "cannot read into %v (type %v)", varName, entry.GetEntryType().GetName()))
 	}
 	return errors
 }
@@ -1000,10 +1077,16 @@ func (r *Read) TranslateToILoc(instructions []ir.Instruction, symTable *st.Symbo
 
 type Print struct {
 	Token       *token.Token
+	Span        token.Span
 	printMethod string // "Print" | "Println"
-	Ident       IdentLiteral
+	Args        *Arguments
 }
 
+func (p *Print) GetSpan() token.Span { return p.Span }
+
+// Method returns the function of fmt called, "Print" or "Println"
+func (p *Print) Method() string { return p.printMethod }
+
 func (p *Print) TokenLiteral() string {
 	if p.Token != nil {
 		return p.Token.Literal
@@ -1015,48 +1098,82 @@ func (p *Print) String() string {
 	out.WriteString("fmt")
 	out.WriteString(".")
 	out.WriteString(p.printMethod)
-	out.WriteString("(")
-	out.WriteString(p.Ident.String())
-	out.WriteString(")")
+	out.WriteString(p.Args.String())
 	out.WriteString(";")
 	out.WriteString("\n")
 	return out.String()
 }
-func (p *Print) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (p *Print) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	return errors
 }
-func (p *Print) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
-	// objective: verify the variable is declared
-	varName := p.Ident.TokenLiteral()
-	entry := symTable.Contains(varName)
-	if entry == nil {
-		errors = append(errors, fmt.Sprintf("[%v]: variable %v has not been declared", p.Token.LineNum, varName))
+func (p *Print) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	// objective: verify every argument is an int, a bool or a string
+	for _, expr := range p.Args.Exprs {
+		numErrors := len(errors)
+		errors = expr.TypeCheck(errors, symTable)
+		exprTy := expr.GetType(symTable)
+		if len(errors) == numErrors && exprTy != types.IntTySig && exprTy != types.BoolTySig && exprTy != types.StringTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(expr.Token), "cannot print %v (type %v)", expr.String(), exprTy.GetName()))
+		}
 	}
 	return errors
 }
+
+// TranslateToILoc prints the arguments with a single printf, whose format spaces them as Go does:
+// Println separates all of them and ends the line, Print only separates two of them neither of
+// which is a string. A string literal is part of the format, a bool prints "true" or "false".
 func (p *Print) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
-	var instruction ir.Instruction
-	entry := symTable.Contains(p.Ident.TokenLiteral())
-	reg := entry.GetRegId()
+	format := bytes.Buffer{}
+	sources := []int{}
+	prevString := false
+	for idx := range p.Args.Exprs {
+		expr := &p.Args.Exprs[idx]
+		exprTy := expr.GetType(symTable)
+		isString := exprTy == types.StringTySig
+		if idx > 0 && (p.printMethod == "Println" || !isString && !prevString) {
+			format.WriteString(" ")
+		}
+		prevString = isString
 
-	if p.printMethod == "Print" {
-		instruction = ir.NewPrint(reg)
-	} else {
-		instruction = ir.NewPrintln(reg)
+		if literal, isLiteral := stringLiteralOf(expr); isLiteral {
+			format.WriteString(strings.ReplaceAll(literal.Value, "%", "%%"))
+			continue
+		}
+		instructions = expr.TranslateToILoc(instructions, symTable)
+		switch exprTy {
+		case types.IntTySig:
+			format.WriteString("%ld")
+			sources = append(sources, expr.targetReg)
+		case types.BoolTySig:
+			text, trueText := ir.NewRegister(), ir.NewRegister()
+			instructions = append(instructions, ir.NewLoadStr(text, "false"))
+			instructions = append(instructions, ir.NewLoadStr(trueText, "true"))
+			instructions = append(instructions, ir.NewCmp(expr.targetReg, 0, ir.IMMEDIATE))
+			instructions = append(instructions, ir.NewMov(text, trueText, ir.NE, ir.REGISTER))
+			format.WriteString("%s")
+			sources = append(sources, text)
+		default:
+			format.WriteString("%s")
+			sources = append(sources, expr.targetReg)
+		}
 	}
-
-	instructions = append(instructions, instruction)
-	return instructions
+	if p.printMethod == "Println" {
+		format.WriteString("\n")
+	}
+	return append(instructions, ir.NewPrintf(format.String(), sources))
 }
 
 type Conditional struct {
 	Token     *token.Token
+	Span      token.Span
 	Expr      *Expression
 	Block     *Block
 	ElseBlock *Block
 }
 
+func (cond *Conditional) GetSpan() token.Span { return cond.Span }
+
 func (cond *Conditional) TokenLiteral() string {
 	if cond.Token != nil {
 		return cond.Token.Literal
@@ -1078,7 +1195,7 @@ func (cond *Conditional) String() string {
 	}
 	return out.String()
 }
-func (cond *Conditional) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (cond *Conditional) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	errors = cond.Block.PerformSABuild(errors, symTable)
 	if cond.ElseBlock != nil {
@@ -1086,19 +1203,17 @@ func (cond *Conditional) PerformSABuild(errors []string, symTable *st.SymbolTabl
 	}
 	return errors
 }
-func (cond *Conditional) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (cond *Conditional) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: boolean expression as the conditional expression surrounded by parenthesis
-	condType := cond.Expr.GetType(symTable)
+	numErrors := len(errors)
 	errors = cond.Expr.TypeCheck(errors, symTable)
+	if condType := cond.Expr.GetType(symTable); len(errors) == numErrors && condType != types.BoolTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(cond.Expr.Token), "boolean expression is desired, received %v (type %v)", cond.Expr.String(), condType.GetName()))
+	}
 	errors = cond.Block.TypeCheck(errors, symTable)
 	if cond.ElseBlock != nil {
 		errors = cond.ElseBlock.TypeCheck(errors, symTable)
 	}
-	if len(errors) == 0 {
-		if condType != types.BoolTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: boolean expression is desired, received %v Type %v", cond.Token.LineNum, cond.Expr.String(), condType.GetName()))
-		}
-	}
 	return errors
 }
 func (cond *Conditional) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -1135,10 +1250,13 @@ func (cond *Conditional) TranslateToILoc(instructions []ir.Instruction, symTable
 
 type Loop struct {
 	Token *token.Token
+	Span  token.Span
 	Expr  *Expression
 	Block *Block
 }
 
+func (lp *Loop) GetSpan() token.Span { return lp.Span }
+
 func (lp *Loop) TokenLiteral() string {
 	if lp.Token != nil {
 		return lp.Token.Literal
@@ -1156,22 +1274,20 @@ func (lp *Loop) String() string {
 	out.WriteString(lp.Block.String())
 	return out.String()
 }
-func (lp *Loop) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (lp *Loop) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	errors = lp.Block.PerformSABuild(errors, symTable)
 	return errors
 }
-func (lp *Loop) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (lp *Loop) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: boolean expression as the conditional expression surrounded by parenthesis
-	condType := lp.Expr.GetType(symTable)
+	numErrors := len(errors)
 	errors = lp.Expr.TypeCheck(errors, symTable)
-	errors = lp.Block.TypeCheck(errors, symTable)
-	if len(errors) == 0 {
-		if condType != types.BoolTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: boolean expression is desired, received %v Type %v",
-				lp.Token.LineNum, lp.Expr.String(), condType.GetName()))
-		}
+	if condType := lp.Expr.GetType(symTable); len(errors) == numErrors && condType != types.BoolTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(lp.Expr.Token), "boolean expression is desired, received %v (type %v)",
+			lp.Expr.String(), condType.GetName()))
 	}
+	errors = lp.Block.TypeCheck(errors, symTable)
 	return errors
 }
 func (lp *Loop) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -1200,9 +1316,12 @@ func (lp *Loop) TranslateToILoc(instructions []ir.Instruction, symTable *st.Symb
 
 type Return struct {
 	Token *token.Token // "RETURN"
-	Expr  *Expression  // the return type, nil if not exists
+	Span  token.Span
+	Expr  *Expression // the return type, nil if not exists
 }
 
+func (ret *Return) GetSpan() token.Span { return ret.Span }
+
 func (ret *Return) TokenLiteral() string {
 	if ret.Token != nil {
 		return ret.Token.Literal
@@ -1219,20 +1338,25 @@ func (ret *Return) String() string {
 	out.WriteString(";")
 	return out.String()
 }
-func (ret *Return) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (ret *Return) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	return errors
 }
-func (ret *Return) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (ret *Return) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: match return type with signature
-	errors = ret.Expr.TypeCheck(errors, symTable)
+	var actRetType types.Type = types.VoidTySig // a bare "return;"
+	numErrors := len(errors)
+	if ret.Expr != nil {
+		errors = ret.Expr.TypeCheck(errors, symTable)
+		actRetType = ret.Expr.GetType(symTable)
+	}
 	// go to outer symbol table and retrieve the entry
 	funcEntry := symTable.Parent.Contains(symTable.ScopeName) // must exist
 	decRetType := funcEntry.GetReturnTy()                     // must exist
-	actRetType := ret.Expr.GetType(symTable)
-	if len(errors) == 0 {
-		if actRetType != decRetType {
-			errors = append(errors, fmt.Sprintf("[%v]: return type expected %v, found %v", ret.Token.LineNum, decRetType, actRetType))
+	if len(errors) == numErrors {
+		if !types.AssignableTo(actRetType, decRetType) {
+			errors = append(errors, diag.Errorf(diag.ReturnType, diag.At(ret.Token), "return type expected %v, found %v", decRetType.GetName(), actRetType.GetName()).
+				WithNote("function %v is declared to return %v", symTable.ScopeName, decRetType.GetName()))
 		}
 	}
 	return errors
@@ -1244,7 +1368,10 @@ func (ret *Return) TranslateToILoc(instructions []ir.Instruction, symTable *st.S
 		retInst = ir.NewRet(-1, ir.VOID)
 	} else {
 		instructions = ret.Expr.TranslateToILoc(instructions, symTable)
-		retInst = ir.NewRet(ret.Expr.targetReg, ir.REGISTER)
+		// an array is copied, the caller getting a block of its own
+		var retReg int
+		instructions, retReg = translateCopy(instructions, ret.Expr.targetReg, ret.Expr.GetType(symTable))
+		retInst = ir.NewRet(retReg, ir.REGISTER)
 	}
 	instructions = append(instructions, retInst)
 	return instructions
@@ -1253,10 +1380,13 @@ func (ret *Return) TranslateToILoc(instructions []ir.Instruction, symTable *st.S
 // Invocation Statement, compared with InvocExpr
 type Invocation struct {
 	Token *token.Token
+	Span  token.Span
 	Ident IdentLiteral
 	Args  *Arguments
 }
 
+func (invoc *Invocation) GetSpan() token.Span { return invoc.Span }
+
 func (invoc *Invocation) TokenLiteral() string {
 	if invoc.Token != nil {
 		return invoc.Token.Literal
@@ -1271,85 +1401,43 @@ func (invoc *Invocation) String() string {
 	out.WriteString("\n")
 	return out.String()
 }
-func (invoc *Invocation) PerformSABuild(errors []string, symTable *st.SymbolTable) []string {
+func (invoc *Invocation) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// objective: none
 	return errors
 }
-func (invoc *Invocation) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
-	if invoc.Ident.String() == "delete" { // built-in delete
-		// TO-DO : check the struct name has been declared
-		// OK if unmodified
-	}
-
+func (invoc *Invocation) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// check whether function is declared
 	funcName := invoc.Ident.TokenLiteral()
 	entry := invoc.getFuncEntry(symTable)
 	if entry == nil {
-		errors = append(errors, fmt.Sprintf("[%v]: function %v has not been defined", invoc.Token.LineNum, funcName))
+		errors = append(errors, diag.Errorf(diag.Undefined, diag.At(invoc.Token), "function %v has not been defined", funcName))
+	} else if funcName == "delete" { // built-in delete
+		errors = invoc.Args.checkDelete(errors, symTable)
+	} else if funcName == "new" {
+		errors = invoc.Args.checkNew(errors, symTable)
+	} else if funcName == "len" || funcName == "append" {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(invoc.Token), "%v%v is not used", funcName, invoc.Args.String()))
 	} else {
-		symTable = entry.GetScopeST()
-		errors = invoc.Args.TypeCheck(errors, symTable)
+		errors = invoc.Args.checkCall(errors, symTable, funcName, entry)
 	}
 	return errors
 }
 func (invoc *Invocation) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
 	if invoc.Ident.TokenLiteral() == "delete" {
-		instanceName := invoc.Args.Exprs[0].TokenLiteral()
-		entry := symTable.PowerContains(instanceName)
-		if symTable.CheckGlobalVariable(instanceName) {
-			// load the address of the global struct instance
-			ldrGlobalInst := ir.NewLdr(entry.GetRegId(), -1, -1, instanceName, ir.GLOBALVAR)
-			instructions = append(instructions, ldrGlobalInst)
-		}
-		delInst := ir.NewDelete(entry.GetRegId())
+		// the struct to delete, of any expression pointing to one
+		instance := invoc.Args.Exprs[0]
+		instructions = instance.TranslateToILoc(instructions, symTable)
+		delInst := ir.NewDelete(instance.GetTargetReg())
 		instructions = append(instructions, delInst)
 		return instructions
 	}
-	// push register values to stack, make space for parameter passing
-	arguments := invoc.Args.Exprs
-	pushReg := []int{}
-	for i := 0; i < len(arguments); i++ {
-		pushReg = append(pushReg, i+1)
-	}
-	if len(pushReg) != 0 {
-		pushInstruct := ir.NewPush(pushReg, invoc.Ident.String())
-		instructions = append(instructions, pushInstruct)
-		// reversed for future pop
-		for i, j := 0, len(pushReg)-1; i < j; i, j = i+1, j-1 {
-			pushReg[i], pushReg[j] = pushReg[j], pushReg[i]
-		}
-	}
-
-	// branch to function
-	branchInstruct := ir.NewBl(invoc.Ident.TokenLiteral())
-	instructions = append(instructions, branchInstruct)
+	instructions, pushReg := invoc.Args.translateCall(instructions, symTable, invoc.Ident.TokenLiteral())
 
 	// after returning from the function
 	// pop from stack to restore the previously pushed values
 	popInstruct := ir.NewPop(pushReg, invoc.Ident.String())
 	instructions = append(instructions, popInstruct)
 	return instructions
-
-	//// arguments to be passed in the invocation
-	//arguments := invoc.Args.Exprs
-	//// formal parameters in the function definition
-	//entry := invoc.getFuncEntry(symTable)
-	//scopeSymTable := entry.GetScopeST()
-	//paramNames := scopeSymTable.ScopeParamNames
-	//// move the values of each argument to the registers associated with parameters
-	//if arguments != nil && len(arguments) != 0 {
-	//	for idx, arg := range arguments {
-	//		entry := scopeSymTable.Contains(paramNames[idx])
-	//		targetReg := entry.GetRegId()
-	//		// targetReg : register with parameter
-	//		// sourceReg : register with arguments
-	//		passParamInstruct := ir.NewMov(targetReg, arg.targetReg, ir.AL, ir.REGISTER)
-	//		instructions = append(instructions, passParamInstruct)
-	//	}
-	//}
-	//branchInstruct := ir.NewBl(invoc.Ident.TokenLiteral())
-	//instructions = append(instructions, branchInstruct)
-	//return instructions
 }
 func (invoc *Invocation) getFuncEntry(symTable *st.SymbolTable) st.Entry {
 	var entry st.Entry
@@ -1368,56 +1456,91 @@ func (invoc *Invocation) getFuncEntry(symTable *st.SymbolTable) st.Entry {
 	return entry
 }
 
+// BadStmt stands for a statement with syntax errors, the parser has already reported them
+// and skipped its tokens
+type BadStmt struct {
+	Token *token.Token // the first token skipped
+	Span  token.Span
+}
+
+func (bad *BadStmt) GetSpan() token.Span { return bad.Span }
+
+func (bad *BadStmt) TokenLiteral() string {
+	if bad.Token != nil {
+		return bad.Token.Literal
+	}
+	panic("Could not determine token literal for bad statement.")
+}
+func (bad *BadStmt) String() string {
+	return "/* bad statement */\n"
+}
+func (bad *BadStmt) PerformSABuild(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	return errors
+}
+func (bad *BadStmt) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	return errors
+}
+func (bad *BadStmt) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
+	return instructions
+}
+
 func NewProgram(pac *Package, imp *Import, typ *Types, decs *Declarations, funs *Functions) *Program {
-	return &Program{nil, nil, pac, imp, typ, decs, funs}
+	return &Program{nil, token.Span{}, nil, pac, imp, typ, decs, funs}
 }
 func NewPackage(ident IdentLiteral) *Package {
-	return &Package{nil, ident}
+	return &Package{nil, token.Span{}, ident}
 }
-func NewImport(ident IdentLiteral) *Import      { return &Import{nil, ident} }
-func NewTypes(typdecs []TypeDeclaration) *Types { return &Types{nil, typdecs} }
+func NewImport(ident IdentLiteral) *Import      { return &Import{nil, token.Span{}, ident} }
+func NewTypes(typdecs []TypeDeclaration) *Types { return &Types{nil, token.Span{}, typdecs} }
 func NewTypeDeclaration(ident IdentLiteral, fields *Fields) *TypeDeclaration {
-	return &TypeDeclaration{nil, nil, ident, fields}
-}
-func NewFields(decls []Decl) *Fields                   { return &Fields{nil, decls} }
-func NewDecl(ident IdentLiteral, ty *Type) *Decl       { return &Decl{nil, ident, ty} }
-func NewDeclarations(decs []Declaration) *Declarations { return &Declarations{nil, decs} }
-func NewDeclaration(ids *Ids, Type *Type) *Declaration { return &Declaration{nil, ids, Type} }
-func NewIds(idents []IdentLiteral) *Ids                { return &Ids{nil, idents} }
-func NewFunctions(funs []Function) *Functions          { return &Functions{nil, funs} }
+	return &TypeDeclaration{nil, token.Span{}, nil, ident, fields}
+}
+func NewFields(decls []Decl) *Fields                   { return &Fields{nil, token.Span{}, decls} }
+func NewDecl(ident IdentLiteral, ty *Type) *Decl       { return &Decl{nil, token.Span{}, ident, ty} }
+func NewDeclarations(decs []Declaration) *Declarations { return &Declarations{nil, token.Span{}, decs} }
+func NewDeclaration(ids *Ids, Type *Type) *Declaration {
+	return &Declaration{nil, token.Span{}, ids, Type}
+}
+func NewIds(idents []IdentLiteral) *Ids       { return &Ids{nil, token.Span{}, idents} }
+func NewFunctions(funs []Function) *Functions { return &Functions{nil, token.Span{}, funs} }
 func NewFunction(ident IdentLiteral, params *Parameters, returnType *ReturnType,
 	declarations *Declarations, statements *Statements) *Function {
-	return &Function{nil, nil, ident, params, returnType, declarations, statements}
-}
-func NewParameters(decls []Decl) *Parameters      { return &Parameters{nil, decls} }
-func NewReturnType(str string) *ReturnType        { return &ReturnType{nil, NewType(str)} }
-func NewStatements(stmts []Statement) *Statements { return &Statements{nil, stmts} }
-func NewStatement(stmt Stmt) *Statement           { return &Statement{nil, stmt} }
-func NewBlock(statement *Statements) *Block       { return &Block{nil, statement} }
+	return &Function{nil, token.Span{}, nil, ident, params, returnType, declarations, statements}
+}
+func NewParameters(decls []Decl) *Parameters      { return &Parameters{nil, token.Span{}, decls} }
+func NewReturnType(str string) *ReturnType        { return &ReturnType{nil, token.Span{}, NewType(str)} }
+func NewStatements(stmts []Statement) *Statements { return &Statements{nil, token.Span{}, stmts} }
+func NewStatement(stmt Stmt) *Statement           { return &Statement{nil, token.Span{}, stmt} }
+func NewBadStmt(tok *token.Token) *BadStmt        { return &BadStmt{tok, token.Span{}} }
+func NewBlock(statement *Statements) *Block       { return &Block{nil, token.Span{}, statement} }
 func NewAssignment(lvalue *LValue, expr *Expression) *Assignment {
-	return &Assignment{nil, lvalue, expr}
+	return &Assignment{nil, token.Span{}, lvalue, expr}
 }
-func NewRead(ident IdentLiteral) *Read { return &Read{nil, ident} }
-func NewPrint(printMethod string, ident IdentLiteral) *Print {
-	return &Print{nil, printMethod, ident}
+func NewRead(ident IdentLiteral) *Read { return &Read{nil, token.Span{}, ident} }
+func NewPrint(printMethod string, args *Arguments) *Print {
+	return &Print{nil, token.Span{}, printMethod, args}
 }
 func NewConditional(expr *Expression, block *Block, elseBlock *Block) *Conditional {
-	return &Conditional{nil, expr, block, elseBlock}
+	return &Conditional{nil, token.Span{}, expr, block, elseBlock}
 }
-func NewLoop(expr *Expression, block *Block) *Loop { return &Loop{nil, expr, block} }
-func NewReturn(expr *Expression) *Return           { return &Return{nil, expr} }
+func NewLoop(expr *Expression, block *Block) *Loop { return &Loop{nil, token.Span{}, expr, block} }
+func NewReturn(expr *Expression) *Return           { return &Return{nil, token.Span{}, expr} }
 func NewInvocation(ident IdentLiteral, args *Arguments) *Invocation {
-	return &Invocation{nil, ident, args}
+	return &Invocation{nil, token.Span{}, ident, args}
 }
 
 /***************** Expr : Expression *******************/
 
 type Type struct {
 	Token *token.Token
-	// either "int"/"bool"/"*id", where id will actually be the literal for the struct name being defined.
+	Span  token.Span
+	// either "int"/"bool"/"*id", where id will actually be the literal for the struct name being defined,
+	// or "[N]elem"/"[]elem" for an array or a slice of the type literal elem.
 	TypeLiteral string
 }
 
+func (t *Type) GetSpan() token.Span { return t.Span }
+
 func (t *Type) TokenLiteral() string {
 	if t.Token != nil {
 		return t.Token.Literal
@@ -1428,16 +1551,71 @@ func (t *Type) String() string {
 	return t.TypeLiteral
 }
 func (t *Type) GetType(symTable *st.SymbolTable) types.Type {
-	if t.TypeLiteral == "int" {
+	return literalType(t.TypeLiteral, symTable)
+}
+func (t *Type) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	// objective: verify the length and the elements of an array or slice, and the struct a
+	// pointer points to are declared
+	if t.TypeLiteral == "" {
+		return errors
+	}
+	literal := t.TypeLiteral
+	for strings.HasPrefix(literal, "[") {
+		end := strings.Index(literal, "]")
+		if length := literal[1:end]; length != "" {
+			if _, err := strconv.Atoi(length); err != nil {
+				errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.AtSpan(t.Span), "invalid array length %v", length))
+				return errors
+			}
+		}
+		literal = literal[end+1:]
+		if elemTy := literalType(literal, symTable); elemTy != types.UnknownTySig && !types.IsElem(elemTy) {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.AtSpan(t.Span), "invalid element type %v", elemTy.GetName()).
+				WithNote("arrays and slices hold int, bool or pointers"))
+			return errors
+		}
+	}
+	if literalType(literal, symTable) == types.UnknownTySig {
+		errors = append(errors, diag.Errorf(diag.Undefined, diag.AtSpan(t.Span), "struct %v has not been defined", strings.TrimPrefix(literal, "*")))
+	}
+	return errors
+}
+
+// literalType returns the type of a type literal, "int", "bool", "string", "*id", "[N]elem" or "[]elem",
+// UnknownTySig if it names a struct that has not been declared or an invalid length
+func literalType(literal string, symTable *st.SymbolTable) types.Type {
+	if literal == "int" {
 		return types.IntTySig
-	} else if t.TypeLiteral == "bool" {
+	} else if literal == "bool" {
 		return types.BoolTySig
-	} else {
-		return types.StructTySig
+	} else if literal == "string" {
+		return types.StringTySig
 	}
-}
-func (t *Type) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
-	return errors
+	if strings.HasPrefix(literal, "[") {
+		end := strings.Index(literal, "]")
+		elemTy := literalType(literal[end+1:], symTable)
+		if elemTy == types.UnknownTySig {
+			return types.UnknownTySig
+		}
+		if end == 1 {
+			return &types.SliceType{Elem: elemTy}
+		}
+		length, err := strconv.Atoi(literal[1:end])
+		if err != nil {
+			return types.UnknownTySig
+		}
+		return &types.ArrayType{Len: length, Elem: elemTy}
+	}
+	// *id, the structs are declared in the global symbol table
+	for symTable.Parent != nil {
+		symTable = symTable.Parent
+	}
+	if entry := symTable.Contains(strings.TrimPrefix(literal, "*")); entry != nil {
+		if structTy, isStruct := entry.GetEntryType().(*types.NamedStructType); isStruct {
+			return &types.PointerType{Elem: structTy}
+		}
+	}
+	return types.UnknownTySig
 }
 func (t *Type) TranslateToILoc(instrcs []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
 	return instrcs
@@ -1448,9 +1626,12 @@ func (t *Type) GetTargetReg() int {
 
 type ReturnType struct {
 	Token *token.Token
+	Span  token.Span
 	Ty    *Type
 }
 
+func (rt *ReturnType) GetSpan() token.Span { return rt.Span }
+
 func (rt *ReturnType) TokenLiteral() string {
 	if rt.Token != nil {
 		return rt.Token.Literal
@@ -1469,7 +1650,7 @@ func (rt *ReturnType) GetType(symTable *st.SymbolTable) types.Type {
 		return rt.Ty.GetType(symTable)
 	}
 }
-func (rt *ReturnType) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (rt *ReturnType) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = rt.Ty.TypeCheck(errors, symTable)
 	return errors
 }
@@ -1482,10 +1663,13 @@ func (rt *ReturnType) GetTargetReg() int {
 
 type Arguments struct {
 	Token     *token.Token
+	Span      token.Span
 	Exprs     []Expression // MARKING
 	targetReg int
 }
 
+func (args *Arguments) GetSpan() token.Span { return args.Span }
+
 func (args *Arguments) TokenLiteral() string {
 	if args.Token != nil {
 		return args.Token.Literal
@@ -1509,24 +1693,114 @@ func (args *Arguments) String() string {
 func (args *Arguments) GetType(symTable *st.SymbolTable) types.Type {
 	return types.VoidTySig
 }
-func (args *Arguments) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
-	if symTable.ScopeName == "delete" { // built-in delete as a special case
+func (args *Arguments) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	for _, expr := range args.Exprs {
+		errors = expr.TypeCheck(errors, symTable)
+	}
+	return errors
+}
 
+// checkCall type checks the arguments, in the scope of the caller, against the signature of the
+// function funcName of the entry
+func (args *Arguments) checkCall(errors []diag.Diagnostic, symTable *st.SymbolTable, funcName string, entry st.Entry) []diag.Diagnostic {
+	funcTy, isFunc := entry.GetEntryType().(*types.FuncType)
+	if !isFunc {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(args.Token), "cannot call %v (type %v)", funcName, entry.GetEntryType().GetName()))
+		return errors
 	}
 	// used as parameters for calling a function
-	expectedTys := symTable.ScopeParamTys
-	paramNames := symTable.ScopeParamNames
+	expectedTys := funcTy.Params
+	paramNames := entry.GetScopeST().ScopeParamNames
 	if len(expectedTys) != len(args.Exprs) {
-		errors = append(errors, fmt.Sprintf("[%v]: Prompted %v parameters, found %v given parameters",
-			args.Token.LineNum, len(expectedTys), len(args.Exprs)))
+		errors = append(errors, diag.Errorf(diag.ArgCount, diag.At(args.Token), "%v expects %v arguments, found %v",
+			funcName, len(expectedTys), len(args.Exprs)))
 		return errors
 	}
 	for idx, expr := range args.Exprs {
+		numErrors := len(errors)
 		errors = expr.TypeCheck(errors, symTable)
 		givenParamTy := expr.GetType(symTable)
-		if givenParamTy != expectedTys[idx] {
-			errors = append(errors, fmt.Sprintf("[%v]: Expected parameter %v type %v; given parameter %v type %v",
-				args.Token.LineNum, paramNames[idx], expectedTys[idx].GetName(), expr.String(), givenParamTy.GetName()))
+		if len(errors) == numErrors && !types.AssignableTo(givenParamTy, expectedTys[idx]) {
+			errors = append(errors, diag.Errorf(diag.ArgType, diag.At(expr.Token), "cannot use %v (type %v) as argument %v of %v",
+				expr.String(), givenParamTy.GetName(), idx+1, funcName).
+				WithNote("parameter %v is declared as %v", paramNames[idx], expectedTys[idx].GetName()))
+		}
+	}
+	return errors
+}
+
+// checkNew type checks the argument of new, the name of a struct
+func (args *Arguments) checkNew(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	if len(args.Exprs) != 1 {
+		errors = append(errors, diag.Errorf(diag.ArgCount, diag.At(args.Token), "new expects 1 arguments, found %v", len(args.Exprs)))
+		return errors
+	}
+	expr := args.Exprs[0]
+	if _, isStruct := expr.GetType(symTable).(*types.NamedStructType); !isStruct {
+		errors = append(errors, diag.Errorf(diag.ArgType, diag.At(expr.Token), "new expects the name of a struct, found %v", expr.String()))
+	}
+	return errors
+}
+
+// checkDelete type checks the argument of delete, a pointer to a struct
+func (args *Arguments) checkDelete(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	if len(args.Exprs) != 1 {
+		errors = append(errors, diag.Errorf(diag.ArgCount, diag.At(args.Token), "delete expects 1 arguments, found %v", len(args.Exprs)))
+		return errors
+	}
+	expr := args.Exprs[0]
+	numErrors := len(errors)
+	errors = expr.TypeCheck(errors, symTable)
+	exprTy := expr.GetType(symTable)
+	if _, isStruct := types.StructOf(exprTy); !isStruct && len(errors) == numErrors {
+		errors = append(errors, diag.Errorf(diag.ArgType, diag.At(expr.Token), "cannot delete %v (type %v), which does not point to a struct",
+			expr.String(), exprTy.GetName()))
+	}
+	return errors
+}
+
+// checkLen type checks the argument of len, an array, a slice or a string
+func (args *Arguments) checkLen(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	if len(args.Exprs) != 1 {
+		errors = append(errors, diag.Errorf(diag.ArgCount, diag.At(args.Token), "len expects 1 arguments, found %v", len(args.Exprs)))
+		return errors
+	}
+	expr := args.Exprs[0]
+	numErrors := len(errors)
+	errors = expr.TypeCheck(errors, symTable)
+	exprTy := expr.GetType(symTable)
+	if _, isElems := types.ElemOf(exprTy); !isElems && exprTy != types.StringTySig && len(errors) == numErrors {
+		errors = append(errors, diag.Errorf(diag.ArgType, diag.At(expr.Token), "invalid argument %v (type %v) for len, which expects an array, a slice or a string",
+			expr.String(), exprTy.GetName()))
+	}
+	return errors
+}
+
+// checkAppend type checks the arguments of append, a slice followed by the values appended to it
+func (args *Arguments) checkAppend(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	if len(args.Exprs) == 0 {
+		errors = append(errors, diag.Errorf(diag.ArgCount, diag.At(args.Token), "append expects at least 1 arguments, found 0"))
+		return errors
+	}
+	numErrors := len(errors)
+	errors = args.Exprs[0].TypeCheck(errors, symTable)
+	sliceTy, isSlice := args.Exprs[0].GetType(symTable).(*types.SliceType)
+	if len(errors) > numErrors {
+		return errors
+	} else if !isSlice {
+		expr := args.Exprs[0]
+		errors = append(errors, diag.Errorf(diag.ArgType, diag.At(expr.Token), "invalid argument %v (type %v) for append, which expects a slice",
+			expr.String(), expr.GetType(symTable).GetName()))
+		return errors
+	}
+	for idx, expr := range args.Exprs[1:] {
+		numErrors := len(errors)
+		errors = expr.TypeCheck(errors, symTable)
+		valueTy := expr.GetType(symTable)
+		if len(errors) == numErrors && !types.AssignableTo(valueTy, sliceTy.Elem) {
+			errors = append(errors, diag.Errorf(diag.ArgType, diag.At(expr.Token), "cannot use %v (type %v) as argument %v of append",
+				expr.String(), valueTy.GetName(), idx+2).
+				WithNote("the elements of %v are %v", args.Exprs[0].String(), sliceTy.Elem.GetName()))
 		}
 	}
 	return errors
@@ -1541,13 +1815,84 @@ func (args *Arguments) GetTargetReg() int {
 	return args.targetReg
 }
 
+// translateCall evaluates the arguments of a call to funcName, pushes them and branches to the
+// function, returning the registers pushed, which the caller pops after the call
+func (args *Arguments) translateCall(instructions []ir.Instruction, symTable *st.SymbolTable, funcName string) ([]ir.Instruction, []int) {
+	// push register values to stack, make space for parameter passing
+	pushReg := []int{}
+	for i := range args.Exprs {
+		// retrieve reg id of the arguments from the symbol table
+		// Two Cases : primitive value or variable
+		// an array is copied, the function getting a block of its own
+		argTy := args.Exprs[i].GetType(symTable)
+		if _, isArray := argTy.(*types.ArrayType); !isArray && symTable.Contains(args.Exprs[i].String()) != nil {
+			// passed in a variable
+			pushReg = append(pushReg, symTable.Contains(args.Exprs[i].String()).GetRegId())
+		} else { // passed in any other expression
+			instructions = args.Exprs[i].TranslateToILoc(instructions, symTable)
+			var argReg int
+			instructions, argReg = translateCopy(instructions, args.Exprs[i].targetReg, argTy)
+			pushReg = append(pushReg, argReg)
+		}
+	}
+	if len(pushReg) != 0 {
+		pushInstruct := ir.NewPush(pushReg, funcName)
+		instructions = append(instructions, pushInstruct)
+	}
+
+	// branch to function
+	branchInstruct := ir.NewBl(funcName)
+	instructions = append(instructions, branchInstruct)
+	return instructions, pushReg
+}
+
+// translateAppend appends the values following the slice of the first argument, and puts the
+// header of the slice returned in target. The values are stored in the block of the slice while
+// its capacity allows, in a block twice as large, of at least one element, once it is full.
+func (args *Arguments) translateAppend(instructions []ir.Instruction, symTable *st.SymbolTable, target int) []ir.Instruction {
+	for i := range args.Exprs {
+		instructions = args.Exprs[i].TranslateToILoc(instructions, symTable)
+	}
+	length, capacity, elems := ir.NewRegister(), ir.NewRegister(), ir.NewRegister()
+	instructions = append(instructions, ir.NewLoadRef(length, args.Exprs[0].targetReg, "len", sliceLen))
+	instructions = append(instructions, ir.NewLoadRef(capacity, args.Exprs[0].targetReg, "cap", sliceCap))
+	instructions = append(instructions, ir.NewLoadRef(elems, args.Exprs[0].targetReg, "data", sliceData))
+	for _, value := range args.Exprs[1:] {
+		storeLabel := ir.NewLabelWithPre("appendStore")
+		instructions = append(instructions, ir.NewCmp(length, capacity, ir.REGISTER))
+		instructions = append(instructions, ir.NewBranch(ir.LT, storeLabel))
+		// the block is full
+		grown := ir.NewRegister()
+		instructions = append(instructions, ir.NewAdd(capacity, capacity, capacity, ir.REGISTER))
+		instructions = append(instructions, ir.NewCmp(capacity, 0, ir.IMMEDIATE))
+		instructions = append(instructions, ir.NewMov(capacity, 1, ir.EQ, ir.IMMEDIATE))
+		instructions = append(instructions, ir.NewNewArr(grown, capacity, ir.REGISTER))
+		instructions = translateCopyLoop(instructions, elems, grown, length, ir.REGISTER)
+		instructions = append(instructions, ir.NewMov(elems, grown, ir.AL, ir.REGISTER))
+		instructions = append(instructions, ir.NewLabelStmt(storeLabel))
+		instructions = append(instructions, ir.NewStrIdx(value.targetReg, elems, length))
+		instructions = append(instructions, ir.NewAdd(length, length, 1, ir.IMMEDIATE))
+	}
+	instructions = append(instructions, ir.NewNewArr(target, sliceHeader, ir.IMMEDIATE))
+	instructions = append(instructions, ir.NewStrRef(length, target, "len", sliceLen))
+	instructions = append(instructions, ir.NewStrRef(capacity, target, "cap", sliceCap))
+	instructions = append(instructions, ir.NewStrRef(elems, target, "data", sliceData))
+	return instructions
+}
+
 type LValue struct {
-	Token     *token.Token
-	Ident     IdentLiteral
-	Idents    []IdentLiteral
-	targetReg int
+	Token      *token.Token
+	Span       token.Span
+	Ident      IdentLiteral
+	Selectors  []Selector
+	targetReg  int
+	structType types.Type     // the type of the pointer in targetReg, whose last field is assigned
+	indexReg   int            // the index of the element assigned, in the block of targetReg
+	bounds     ir.Instruction // checks indexReg once the value assigned is evaluated, if not nil
 }
 
+func (lv *LValue) GetSpan() token.Span { return lv.Span }
+
 func (lv *LValue) TokenLiteral() string {
 	if lv.Token != nil {
 		return lv.Token.Literal
@@ -1557,92 +1902,60 @@ func (lv *LValue) TokenLiteral() string {
 func (lv *LValue) String() string {
 	out := bytes.Buffer{}
 	out.WriteString(lv.Ident.String())
-	for _, id := range lv.Idents {
-		out.WriteString(".")
-		out.WriteString(id.String())
+	for i := range lv.Selectors {
+		out.WriteString(lv.Selectors[i].String())
 	}
 	return out.String()
 }
 func (lv *LValue) GetType(symTable *st.SymbolTable) types.Type {
-	entry := lv.getStructEntry(symTable)
-	if entry != nil {
-		return entry.GetEntryType()
-	} else {
-		return types.UnknownTySig
+	ty := lv.Ident.GetType(symTable)
+	for i := range lv.Selectors {
+		ty = selectorType(ty, &lv.Selectors[i])
 	}
+	return ty
 }
-func (lv *LValue) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
-	if lv.GetType(symTable) == types.UnknownTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: (LValue) inner field has not been defined", lv.Token.LineNum))
+func (lv *LValue) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	numErrors := len(errors)
+	errors = checkSelectors(errors, symTable, lv.Ident.GetType(symTable), lv.Ident.String(), lv.Selectors)
+	if lv.GetType(symTable) == types.UnknownTySig && len(errors) == numErrors {
+		errors = append(errors, diag.Errorf(diag.UnknownField, diag.At(lv.Token), "%v does not name a declared variable or field", lv.String()))
 	}
 	return errors
 }
-func (lv *LValue) getStructEntry(symTable *st.SymbolTable) st.Entry {
-	var entry st.Entry
-	varName := lv.Ident.TokenLiteral()
-	for {
-		if entry = symTable.Contains(varName); entry == nil {
-			if symTable.Parent == nil {
-				return nil
-			} else {
-				symTable = symTable.Parent
-			}
-		} else {
-			break
-		}
-	}
-	if lv.Idents == nil {
-		return entry
-	}
-	// here entry is the entry of the first id in Idents
-	symTable = entry.GetScopeST() // TO-DO : DEBUG the following
-	//remaining := lv.Idents[1:]
-	remaining := lv.Idents
-
-	for idx, id := range remaining {
-		if entry = symTable.Contains(id.String()); entry == nil {
-			return nil
-		} else {
-			if idx == len(lv.Idents)-1 {
-				break
-			}
-			symTable = entry.GetScopeST()
-		}
-	}
-	return entry
-}
 func (lv *LValue) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
+	if len(lv.Selectors) == 0 && symTable.CheckGlobalVariable(lv.Ident.Id) {
+		// the assignment stores to the global variable, which is not loaded
+		return instructions
+	}
 	instructions = lv.Ident.TranslateToILoc(instructions, symTable)
-
-	//if symTable.CheckGlobalVariable(lv.Ident.String()) { // ldr global variable
-	//	ldrInst := ir.NewLdr(lv.Ident.targetReg, -1, -1, lv.Ident.Id, ir.GLOBALVAR)
-	//	instructions = append(instructions, ldrInst)
-	//}
-
 	lv.targetReg = lv.Ident.targetReg
-	if lv.Idents == nil || len(lv.Idents) == 0 {
+	if len(lv.Selectors) == 0 {
 		return instructions
 	}
-	// id.id / id.id.id / ...
-	remainingBeforeLast := lv.Idents[:len(lv.Idents)-1]
-	source := lv.Ident.targetReg
-	for _, ident := range remainingBeforeLast {
-		instructions = ident.TranslateToILoc(instructions, symTable)
+	// id.id / id[index] / id.id[index].id / ...: load the struct or the element of every selector
+	// but the last, which is assigned
+	ty := lv.Ident.GetType(symTable)
+	for i := range lv.Selectors[:len(lv.Selectors)-1] {
+		sel := &lv.Selectors[i]
 		target := ir.NewRegister()
-		field := ident.Id
-		countFields := 0
-		for _, currField := range symTable.ScopeParamNames {
-			if field == currField {
-				break
+		if sel.Index == nil {
+			instructions = append(instructions, ir.NewLoadRef(target, lv.targetReg, sel.Ident.Id, fieldIndex(ty, sel.Ident.Id)))
+		} else {
+			var elems, index int
+			var bounds ir.Instruction
+			instructions, elems, index, bounds = translateIndex(instructions, symTable, lv.targetReg, ty, sel.Index)
+			if bounds != nil {
+				instructions = append(instructions, bounds)
 			}
-			countFields += 1
+			instructions = append(instructions, ir.NewLoadIdx(target, elems, index))
 		}
-		symTable = symTable.Contains(field).GetScopeST()
-		instruction := ir.NewLoadRef(target, source, ident.Id, countFields)
-		instructions = append(instructions, instruction)
-		source = target
+		ty = selectorType(ty, sel)
 		lv.targetReg = target
 	}
+	if last := &lv.Selectors[len(lv.Selectors)-1]; last.Index != nil {
+		instructions, lv.targetReg, lv.indexReg, lv.bounds = translateIndex(instructions, symTable, lv.targetReg, ty, last.Index)
+	}
+	lv.structType = ty
 	return instructions
 }
 func (lv *LValue) GetTargetReg() int {
@@ -1651,11 +1964,14 @@ func (lv *LValue) GetTargetReg() int {
 
 type Expression struct {
 	Token     *token.Token
+	Span      token.Span
 	Left      *BoolTerm
 	Rights    []BoolTerm
 	targetReg int // bind the result of the current Expression to a target register id
 }
 
+func (exp *Expression) GetSpan() token.Span { return exp.Span }
+
 func (exp *Expression) TokenLiteral() string {
 	if exp.Token != nil {
 		return exp.Token.Literal
@@ -1688,14 +2004,14 @@ func (exp *Expression) GetType(symTable *st.SymbolTable) types.Type {
 	}
 	return leftType
 }
-func (exp *Expression) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (exp *Expression) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = exp.Left.TypeCheck(errors, symTable)
 	leftMostTy := exp.Left.GetType(symTable)
 	if len(exp.Rights) != 0 {
 		// OR operation, needs bool types on both sides
-		if leftMostTy != types.BoolTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: (Expression) expected bool type, found %v (%v)",
-				exp.Token.LineNum, leftMostTy.GetName(), exp.Left.String()))
+		if leftMostTy != types.BoolTySig && leftMostTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(exp.Left.Token), "operator || expects bool, found %v (type %v)",
+				exp.Left.String(), leftMostTy.GetName()))
 			return errors
 		}
 	}
@@ -1703,9 +2019,9 @@ func (exp *Expression) TypeCheck(errors []string, symTable *st.SymbolTable) []st
 	for _, curr := range exp.Rights {
 		errors = curr.TypeCheck(errors, symTable)
 		currTy := curr.GetType(symTable)
-		if currTy != leftMostTy {
-			errors = append(errors, fmt.Sprintf("[%v]: (Expression) expected %v type, found %v (%v)",
-				exp.Token.LineNum, leftMostTy.GetName(), currTy.GetName(), curr.String()))
+		if currTy != leftMostTy && currTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(curr.Token), "operator || expects %v, found %v (type %v)",
+				leftMostTy.GetName(), curr.String(), currTy.GetName()))
 			break
 		}
 	}
@@ -1736,11 +2052,14 @@ func (exp *Expression) GetTargetReg() int {
 
 type BoolTerm struct {
 	Token     *token.Token
+	Span      token.Span
 	Left      *EqualTerm
 	Rights    []EqualTerm
 	targetReg int
 }
 
+func (bt *BoolTerm) GetSpan() token.Span { return bt.Span }
+
 func (bt *BoolTerm) TokenLiteral() string {
 	if bt.Token != nil {
 		return bt.Token.Literal
@@ -1773,14 +2092,14 @@ func (bt *BoolTerm) GetType(symTable *st.SymbolTable) types.Type {
 	}
 	return leftType
 }
-func (bt *BoolTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (bt *BoolTerm) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = bt.Left.TypeCheck(errors, symTable)
 	leftMostTy := bt.Left.GetType(symTable)
 	if len(bt.Rights) != 0 {
 		// OR operation, needs bool types on both sides
-		if leftMostTy != types.BoolTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: (BoolTerm) expected bool type, found %v (%v)",
-				bt.Token.LineNum, leftMostTy.GetName(), bt.Left.String()))
+		if leftMostTy != types.BoolTySig && leftMostTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(bt.Left.Token), "operator && expects bool, found %v (type %v)",
+				bt.Left.String(), leftMostTy.GetName()))
 			return errors
 		}
 	}
@@ -1788,9 +2107,9 @@ func (bt *BoolTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []strin
 	for _, curr := range bt.Rights {
 		errors = curr.TypeCheck(errors, symTable)
 		currTy := curr.GetType(symTable)
-		if currTy != leftMostTy {
-			errors = append(errors, fmt.Sprintf("[%v]: (BoolTerm) expected %v type, found %v (%v)",
-				bt.Token.LineNum, leftMostTy.GetName(), currTy.GetName(), curr.String()))
+		if currTy != leftMostTy && currTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(curr.Token), "operator && expects %v, found %v (type %v)",
+				leftMostTy.GetName(), curr.String(), currTy.GetName()))
 			break
 		}
 	}
@@ -1821,12 +2140,15 @@ func (bt *BoolTerm) GetTargetReg() int {
 
 type EqualTerm struct {
 	Token         *token.Token
+	Span          token.Span
 	Left          *RelationTerm
 	EqualOperator []string // '=='|'!='
 	Rights        []RelationTerm
 	targetReg     int
 }
 
+func (et *EqualTerm) GetSpan() token.Span { return et.Span }
+
 func (et *EqualTerm) TokenLiteral() string {
 	if et.Token != nil {
 		return et.Token.Literal
@@ -1848,7 +2170,7 @@ func (et *EqualTerm) GetType(symTable *st.SymbolTable) types.Type {
 
 	for _, rTerm := range et.Rights {
 		rightType := rTerm.GetType(symTable)
-		if leftType != rightType {
+		if !types.Comparable(leftType, rightType) {
 			return types.UnknownTySig
 		}
 	}
@@ -1858,10 +2180,18 @@ func (et *EqualTerm) GetType(symTable *st.SymbolTable) types.Type {
 		return leftType
 	}
 }
-func (et *EqualTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (et *EqualTerm) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = et.Left.TypeCheck(errors, symTable)
-	for _, rTerm := range et.Rights {
+	// with == or != operations, every RelationTerm should be comparable with the left one
+	leftMostTy := et.Left.GetType(symTable)
+	for idx, rTerm := range et.Rights {
 		errors = rTerm.TypeCheck(errors, symTable)
+		currTy := rTerm.GetType(symTable)
+		if !types.Comparable(leftMostTy, currTy) && leftMostTy != types.UnknownTySig && currTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(rTerm.Token), "operator %v cannot compare %v (type %v) with %v (type %v)",
+				et.EqualOperator[idx], et.Left.String(), leftMostTy.GetName(), rTerm.String(), currTy.GetName()))
+			return errors
+		}
 	}
 	return errors
 }
@@ -1898,12 +2228,15 @@ func (et *EqualTerm) GetTargetReg() int {
 
 type RelationTerm struct {
 	Token             *token.Token
+	Span              token.Span
 	Left              *SimpleTerm
 	RelationOperators []string // '>'| '<' | '<=' | '>='
 	Rights            []SimpleTerm
 	targetReg         int
 }
 
+func (rt *RelationTerm) GetSpan() token.Span { return rt.Span }
+
 func (rt *RelationTerm) TokenLiteral() string {
 	if rt.Token != nil {
 		return rt.Token.Literal
@@ -1937,24 +2270,24 @@ func (rt *RelationTerm) GetType(symTable *st.SymbolTable) types.Type {
 	}
 	return leftType
 }
-func (rt *RelationTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (rt *RelationTerm) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = rt.Left.TypeCheck(errors, symTable)
 	if len(rt.Rights) == 0 {
 		return errors
 	}
 	// with + or - operations, every Term should be int type
 	leftMostTy := rt.Left.GetType(symTable)
-	if leftMostTy != types.IntTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: (RelationTerm) expected int, found %v (%v)",
-			rt.Token.LineNum, leftMostTy.GetName(), rt.Left.String()))
+	if leftMostTy != types.IntTySig && leftMostTy != types.UnknownTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(rt.Left.Token), "operator %v expects int, found %v (type %v)",
+			rt.RelationOperators[0], rt.Left.String(), leftMostTy.GetName()))
 		return errors
 	}
-	for _, rTerm := range rt.Rights {
+	for idx, rTerm := range rt.Rights {
 		errors = rTerm.TypeCheck(errors, symTable)
 		currTy := rTerm.GetType(symTable)
-		if currTy != types.IntTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: (RelationTerm) expected int, found %v (%v)",
-				rTerm.Token.LineNum, currTy.GetName(), rTerm.String()))
+		if currTy != types.IntTySig && currTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(rTerm.Token), "operator %v expects int, found %v (type %v)",
+				rt.RelationOperators[idx], rTerm.String(), currTy.GetName()))
 			return errors
 		}
 	}
@@ -1998,12 +2331,15 @@ func (rt *RelationTerm) GetTargetReg() int {
 
 type SimpleTerm struct {
 	Token               *token.Token
+	Span                token.Span
 	Left                *Term
 	SimpleTermOperators []string // '+' | '-'
 	Rights              []Term
 	targetReg           int
 }
 
+func (st *SimpleTerm) GetSpan() token.Span { return st.Span }
+
 func (st *SimpleTerm) TokenLiteral() string {
 	if st.Token != nil {
 		return st.Token.Literal
@@ -2022,39 +2358,55 @@ func (st *SimpleTerm) String() string {
 }
 func (st *SimpleTerm) GetType(symTable *st.SymbolTable) types.Type {
 	leftType := st.Left.GetType(symTable)
+	operandTy := simpleTermOperandType(leftType)
 
-	for _, rTerm := range st.Rights {
+	for idx, rTerm := range st.Rights {
 		rightType := rTerm.GetType(symTable)
-		if rightType != types.IntTySig {
+		if rightType != operandTy || operandTy == types.StringTySig && st.SimpleTermOperators[idx] != "+" {
 			return types.UnknownTySig
 		}
 	}
 	if st.Rights != nil && len(st.Rights) > 0 {
-		if leftType == types.IntTySig {
-			return types.IntTySig
+		if leftType == operandTy {
+			return operandTy
 		}
 		return types.UnknownTySig
 	}
 	return leftType
 }
-func (st *SimpleTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+
+// simpleTermOperandType returns the type of the terms of a + or -: strings after a string, which
+// + concatenates, ints otherwise
+func simpleTermOperandType(leftType types.Type) types.Type {
+	if leftType == types.StringTySig {
+		return types.StringTySig
+	}
+	return types.IntTySig
+}
+func (st *SimpleTerm) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = st.Left.TypeCheck(errors, symTable)
 	if len(st.Rights) == 0 {
 		return errors
 	}
-	// with + or - operations, every Term should be int type
+	// with + or - operations, every Term should be int type, or string type with + only
 	leftMostTy := st.Left.GetType(symTable)
-	if leftMostTy != types.IntTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: (SimpleTerm) expected int, found %v (%v)",
-			st.Token.LineNum, leftMostTy.GetName(), st.Left.String()))
+	operandTy := simpleTermOperandType(leftMostTy)
+	if leftMostTy != operandTy && leftMostTy != types.UnknownTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(st.Left.Token), "operator %v expects int, found %v (type %v)",
+			st.SimpleTermOperators[0], st.Left.String(), leftMostTy.GetName()))
 		return errors
 	}
-	for _, rTerm := range st.Rights {
+	for idx, rTerm := range st.Rights {
+		if operandTy == types.StringTySig && st.SimpleTermOperators[idx] != "+" {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(st.Left.Token), "operator %v expects int, found %v (type %v)",
+				st.SimpleTermOperators[idx], st.Left.String(), leftMostTy.GetName()))
+			return errors
+		}
 		errors = rTerm.TypeCheck(errors, symTable)
 		currTy := rTerm.GetType(symTable)
-		if currTy != types.IntTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: (SimpleTerm) expected int, found %v (%v)",
-				rTerm.Token.LineNum, currTy.GetName(), rTerm.String()))
+		if currTy != operandTy && currTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(rTerm.Token), "operator %v expects %v, found %v (type %v)",
+				st.SimpleTermOperators[idx], operandTy.GetName(), rTerm.String(), currTy.GetName()))
 			return errors
 		}
 	}
@@ -2069,11 +2421,14 @@ func (st *SimpleTerm) TranslateToILoc(instructions []ir.Instruction, symTable *s
 		return instructions
 	}
 
+	isString := st.Left.GetType(symTable) == types.StringTySig
 	for idx, rTerm := range st.Rights {
 		instructions = rTerm.TranslateToILoc(instructions, symTable)
 		target := ir.NewRegister()
 		var instruction ir.Instruction
-		if st.SimpleTermOperators[idx] == "+" {
+		if isString {
+			instruction = ir.NewStrCat(target, leftSource, rTerm.targetReg)
+		} else if st.SimpleTermOperators[idx] == "+" {
 			instruction = ir.NewAdd(target, leftSource, rTerm.targetReg, ir.REGISTER)
 		} else { // "-"
 			instruction = ir.NewSub(target, leftSource, rTerm.targetReg, ir.REGISTER)
@@ -2091,12 +2446,15 @@ func (st *SimpleTerm) GetTargetReg() int {
 
 type Term struct {
 	Token         *token.Token
+	Span          token.Span
 	Left          *UnaryTerm
 	TermOperators []string // '*' | '/'
 	Rights        []UnaryTerm
 	targetReg     int
 }
 
+func (t *Term) GetSpan() token.Span { return t.Span }
+
 func (t *Term) TokenLiteral() string {
 	if t.Token != nil {
 		return t.Token.Literal
@@ -2130,24 +2488,24 @@ func (t *Term) GetType(symTable *st.SymbolTable) types.Type {
 	}
 	return leftType
 }
-func (t *Term) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (t *Term) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = t.Left.TypeCheck(errors, symTable)
 	if len(t.Rights) == 0 {
 		return errors
 	}
 	// with * or / operations, every UnaryTerm should be int type
 	leftMostTy := t.Left.GetType(symTable)
-	if leftMostTy != types.IntTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: (Term) expected int, found %v (%v)",
-			t.Token.LineNum, leftMostTy.GetName(), t.Left.String()))
+	if leftMostTy != types.IntTySig && leftMostTy != types.UnknownTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(t.Left.Token), "operator %v expects int, found %v (type %v)",
+			t.TermOperators[0], t.Left.String(), leftMostTy.GetName()))
 		return errors
 	}
-	for _, rTerm := range t.Rights {
+	for idx, rTerm := range t.Rights {
 		errors = rTerm.TypeCheck(errors, symTable)
 		currTy := rTerm.GetType(symTable)
-		if currTy != types.IntTySig {
-			errors = append(errors, fmt.Sprintf("[%v]: (Term) expected int, found %v (%v)",
-				rTerm.Token.LineNum, currTy.GetName(), rTerm.String()))
+		if currTy != types.IntTySig && currTy != types.UnknownTySig {
+			errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(rTerm.Token), "operator %v expects int, found %v (type %v)",
+				t.TermOperators[idx], rTerm.String(), currTy.GetName()))
 			return errors
 		}
 	}
@@ -2182,11 +2540,14 @@ func (t *Term) GetTargetReg() int {
 
 type UnaryTerm struct {
 	Token         *token.Token
+	Span          token.Span
 	UnaryOperator string // '!' | '-' | '' <- default
 	SelectorTerm  *SelectorTerm
 	targetReg     int
 }
 
+func (ut *UnaryTerm) GetSpan() token.Span { return ut.Span }
+
 func (ut *UnaryTerm) TokenLiteral() string {
 	if ut.Token != nil {
 		return ut.Token.Literal
@@ -2216,10 +2577,12 @@ func (ut *UnaryTerm) GetType(symTable *st.SymbolTable) types.Type {
 		return ut.SelectorTerm.GetType(symTable)
 	}
 }
-func (ut *UnaryTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (ut *UnaryTerm) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = ut.SelectorTerm.TypeCheck(errors, symTable)
-	if ut.GetType(symTable) == types.UnknownTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: (UnaryTerm) Unkown type", ut.Token.LineNum))
+	// an unknown operand has already been reported by the SelectorTerm
+	if ut.GetType(symTable) == types.UnknownTySig && ut.SelectorTerm.GetType(symTable) != types.UnknownTySig {
+		errors = append(errors, diag.Errorf(diag.TypeMismatch, diag.At(ut.Token), "operator %v cannot be applied to %v (type %v)",
+			ut.UnaryOperator, ut.SelectorTerm.String(), ut.SelectorTerm.GetType(symTable).GetName()))
 	}
 	return errors
 }
@@ -2248,11 +2611,14 @@ func (ut *UnaryTerm) GetTargetReg() int {
 
 type SelectorTerm struct {
 	Token     *token.Token
+	Span      token.Span
 	Fact      *Factor
-	Idents    []IdentLiteral
+	Selectors []Selector
 	targetReg int
 }
 
+func (selt *SelectorTerm) GetSpan() token.Span { return selt.Span }
+
 func (selt *SelectorTerm) TokenLiteral() string {
 	if selt.Token != nil {
 		return selt.Token.Literal
@@ -2262,84 +2628,54 @@ func (selt *SelectorTerm) TokenLiteral() string {
 func (selt *SelectorTerm) String() string {
 	out := bytes.Buffer{}
 	out.WriteString(selt.Fact.String())
-	for _, id := range selt.Idents {
-		out.WriteString(".")
-		out.WriteString(id.String())
+	for i := range selt.Selectors {
+		out.WriteString(selt.Selectors[i].String())
 	}
 	return out.String()
 }
 func (selt *SelectorTerm) GetType(symTable *st.SymbolTable) types.Type {
-	facType := selt.Fact.GetType(symTable)
-	if len(selt.Idents) == 0 {
-		return facType
-	} else if facType == types.StructTySig {
-		var entry st.Entry
-		varName := selt.Fact.String()
-		for {
-			if entry = symTable.Contains(varName); entry == nil {
-				if symTable.Parent == nil {
-					return types.UnknownTySig
-				} else {
-					symTable = symTable.Parent
-				}
-			} else {
-				break
-			}
-		}
-		if selt.Idents == nil {
-			return entry.GetEntryType()
-		}
-		// here entry is the entry of the first id in Idents
-		symTable = entry.GetScopeST()
-		//remaining := selt.Idents[1:]  // comment here for passing sa test of the Twiddleedee benchmark
-		remaining := selt.Idents
-		for idx, id := range remaining {
-			if entry = symTable.Contains(id.String()); entry == nil {
-				return types.UnknownTySig
-			} else {
-				if idx == len(selt.Idents)-1 {
-					break
-				}
-				symTable = entry.GetScopeST()
-			}
-		}
-		return entry.GetEntryType()
+	ty := selt.Fact.GetType(symTable)
+	for i := range selt.Selectors {
+		ty = selectorType(ty, &selt.Selectors[i])
 	}
-	return types.UnknownTySig
+	return ty
 }
-func (selt *SelectorTerm) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (selt *SelectorTerm) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	numErrors := len(errors)
 	errors = selt.Fact.TypeCheck(errors, symTable)
-	if selt.GetType(symTable) == types.UnknownTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: (SelectorTerm) Unknown type", selt.Fact.Token.LineNum))
+	if len(errors) == numErrors {
+		errors = checkSelectors(errors, symTable, selt.Fact.GetType(symTable), selt.Fact.String(), selt.Selectors)
+	}
+	if selt.GetType(symTable) == types.UnknownTySig && len(errors) == numErrors {
+		errors = append(errors, diag.Errorf(diag.UnknownField, diag.At(selt.Fact.Token), "%v does not name a declared field", selt.String()))
 		return errors
 	}
 	return errors
 }
 func (selt *SelectorTerm) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
 	instructions = selt.Fact.TranslateToILoc(instructions, symTable)
-	if selt.Idents == nil || len(selt.Idents) == 0 {
-		selt.targetReg = selt.Fact.targetReg
-		return instructions
-	}
-	// Factor.id / Factor.id.id / ...
-	source := selt.Fact.targetReg
-	symTable = symTable.PowerContains(selt.Fact.String()).GetScopeST()
-	for _, ident := range selt.Idents {
-		instructions = ident.TranslateToILoc(instructions, symTable)
-
-		target := ir.NewRegister()
-		field := ident.Id
-		countFields := 0
-		for _, currField := range symTable.ScopeParamNames {
-			if field == currField {
-				break
+	selt.targetReg = selt.Fact.targetReg
+	// Factor.id / Factor[index] / Factor.id[index].id / ...
+	ty := selt.Fact.GetType(symTable)
+	for i := range selt.Selectors {
+		sel := &selt.Selectors[i]
+		if sel.Index == nil {
+			target := ir.NewRegister()
+			instruction := ir.NewLoadRef(target, selt.targetReg, sel.Ident.Id, fieldIndex(ty, sel.Ident.Id))
+			instructions = append(instructions, instruction)
+			selt.targetReg = target
+		} else {
+			var elems, index int
+			var bounds ir.Instruction
+			instructions, elems, index, bounds = translateIndex(instructions, symTable, selt.targetReg, ty, sel.Index)
+			if bounds != nil {
+				instructions = append(instructions, bounds)
 			}
-			countFields += 1
+			target := ir.NewRegister()
+			instructions = append(instructions, ir.NewLoadIdx(target, elems, index))
+			selt.targetReg = target
 		}
-		instruction := ir.NewLoadRef(target, source, field, countFields)
-		instructions = append(instructions, instruction)
-		source = target
-		selt.targetReg = target
+		ty = selectorType(ty, sel)
 	}
 	return instructions
 }
@@ -2347,12 +2683,218 @@ func (selt *SelectorTerm) GetTargetReg() int {
 	return selt.targetReg
 }
 
+// Selector follows the factor of a selector term or the identifier of an lvalue: either a field
+// ".Ident" of the struct its operand points to, or an element "[Index]" of the array or slice
+type Selector struct {
+	Token *token.Token // "." or "["
+	Span  token.Span
+	Ident *IdentLiteral // the field, nil for an index
+	Index *Expression   // the index, nil for a field
+}
+
+func (sel *Selector) GetSpan() token.Span { return sel.Span }
+
+func (sel *Selector) String() string {
+	if sel.Index != nil {
+		return "[" + sel.Index.String() + "]"
+	}
+	return "." + sel.Ident.String()
+}
+
+// selectorType returns the type of what sel selects from an operand of type ty, UnknownTySig if
+// ty has no such field or no elements
+func selectorType(ty types.Type, sel *Selector) types.Type {
+	if sel.Index == nil {
+		return fieldType(ty, sel.Ident.Id)
+	}
+	if elem, isElems := types.ElemOf(ty); isElems {
+		return elem
+	}
+	return types.UnknownTySig
+}
+
+// checkSelectors type checks the indexes of the selectors following an operand of type ty,
+// operand being its source. An unknown field is left to the caller, which names the whole term.
+func checkSelectors(errors []diag.Diagnostic, symTable *st.SymbolTable, ty types.Type, operand string, sels []Selector) []diag.Diagnostic {
+	for i := range sels {
+		sel := &sels[i]
+		if sel.Index != nil {
+			numErrors := len(errors)
+			errors = sel.Index.TypeCheck(errors, symTable)
+			if len(errors) > numErrors {
+				return errors
+			}
+			indexTy := sel.Index.GetType(symTable)
+			_, isElems := types.ElemOf(ty)
+			if !isElems && ty != types.UnknownTySig {
+				return append(errors, diag.Errorf(diag.BadIndex, diag.At(sel.Token), "cannot index %v (type %v)", operand, ty.GetName()))
+			} else if indexTy != types.IntTySig {
+				return append(errors, diag.Errorf(diag.TypeMismatch, diag.AtSpan(sel.Index.Span), "index %v (type %v) must be int",
+					sel.Index.String(), indexTy.GetName()))
+			}
+			if value, isConstant := constantIndex(sel.Index); isConstant && value < 0 {
+				return append(errors, diag.Errorf(diag.BadIndex, diag.AtSpan(sel.Index.Span), "index %v must not be negative", value))
+			} else if arrayTy, isArray := ty.(*types.ArrayType); isArray && isConstant && value >= int64(arrayTy.Len) {
+				return append(errors, diag.Errorf(diag.BadIndex, diag.AtSpan(sel.Index.Span), "index %v out of bounds [0:%v]", value, arrayTy.Len))
+			}
+		}
+		ty = selectorType(ty, sel)
+		operand += sel.String()
+	}
+	return errors
+}
+
+// constantIndex returns the value of an index which is an integer literal, possibly negated,
+// false for any other expression
+func constantIndex(expr *Expression) (int64, bool) {
+	unary, isUnary := singleUnary(expr)
+	if !isUnary || (unary.UnaryOperator != "" && unary.UnaryOperator != "-") || len(unary.SelectorTerm.Selectors) > 0 {
+		return 0, false
+	}
+	literal, isInt := unary.SelectorTerm.Fact.Expr.(*IntLiteral)
+	if !isInt {
+		return 0, false
+	}
+	if unary.UnaryOperator == "-" {
+		return -literal.Value, true
+	}
+	return literal.Value, true
+}
+
+// stringLiteralOf returns the literal an expression consists of, false for any other expression
+func stringLiteralOf(expr *Expression) (*StringLiteral, bool) {
+	unary, isUnary := singleUnary(expr)
+	if !isUnary || unary.UnaryOperator != "" || len(unary.SelectorTerm.Selectors) > 0 {
+		return nil, false
+	}
+	literal, isString := unary.SelectorTerm.Fact.Expr.(*StringLiteral)
+	return literal, isString
+}
+
+// singleUnary returns the unary term of an expression with no binary operator, false for any
+// other expression
+func singleUnary(expr *Expression) (*UnaryTerm, bool) {
+	if len(expr.Rights) > 0 || len(expr.Left.Rights) > 0 {
+		return nil, false
+	}
+	relation := expr.Left.Left
+	if len(relation.Rights) > 0 || len(relation.Left.Rights) > 0 {
+		return nil, false
+	}
+	term := relation.Left.Left
+	if len(term.Rights) > 0 || len(term.Left.Rights) > 0 {
+		return nil, false
+	}
+	return term.Left.Left, true
+}
+
+// the words of the header of a slice, read and written with loadRef and strRef
+const (
+	sliceLen    = 0
+	sliceCap    = 1
+	sliceData   = 2
+	sliceHeader = 3 // the number of words
+)
+
+// translateIndex evaluates the index of an element of the array or slice of type ty in source,
+// and returns the registers of the block of the elements and of the index, and the bounds
+// checking the index against the length, nil for a constant index of an array which has been
+// checked already
+func translateIndex(instructions []ir.Instruction, symTable *st.SymbolTable, source int, ty types.Type, index *Expression) ([]ir.Instruction, int, int, ir.Instruction) {
+	instructions = index.TranslateToILoc(instructions, symTable)
+	if arrayTy, isArray := ty.(*types.ArrayType); isArray {
+		if _, isConstant := constantIndex(index); isConstant {
+			return instructions, source, index.targetReg, nil
+		}
+		return instructions, source, index.targetReg, ir.NewBounds(index.targetReg, arrayTy.Len, ir.IMMEDIATE)
+	}
+	length, elems := ir.NewRegister(), ir.NewRegister()
+	instructions = append(instructions, ir.NewLoadRef(length, source, "len", sliceLen))
+	instructions = append(instructions, ir.NewLoadRef(elems, source, "data", sliceData))
+	return instructions, elems, index.targetReg, ir.NewBounds(index.targetReg, length, ir.REGISTER)
+}
+
+// translateZero allocates the zero value of an array, a slice or a string of type ty into target:
+// a block of zeroes for an array, the header of an empty slice for a slice and the literal "" for
+// a string. The other types need none.
+func translateZero(instructions []ir.Instruction, target int, ty types.Type) []ir.Instruction {
+	switch ty := ty.(type) {
+	case *types.ArrayType:
+		instructions = append(instructions, ir.NewNewArr(target, ty.Len, ir.IMMEDIATE))
+	case *types.SliceType:
+		instructions = append(instructions, ir.NewNewArr(target, sliceHeader, ir.IMMEDIATE))
+	case *types.StringTy:
+		instructions = append(instructions, ir.NewLoadStr(target, ""))
+	}
+	return instructions
+}
+
+// hasZero returns true if translateZero gives the zero value of ty, which is 0 for the others:
+// the globals are zeroed by the assembler, the fields by new and the locals by a mov
+func hasZero(ty types.Type) bool {
+	_, isElems := types.ElemOf(ty)
+	return isElems || ty == types.StringTySig
+}
+
+// translateCopy copies a value of type ty in source when it is an array, which is copied when
+// assigned, passed or returned, and returns the register of the copy, source for other types
+func translateCopy(instructions []ir.Instruction, source int, ty types.Type) ([]ir.Instruction, int) {
+	arrayTy, isArray := ty.(*types.ArrayType)
+	if !isArray {
+		return instructions, source
+	}
+	target := ir.NewRegister()
+	instructions = append(instructions, ir.NewNewArr(target, arrayTy.Len, ir.IMMEDIATE))
+	instructions = translateCopyLoop(instructions, source, target, arrayTy.Len, ir.IMMEDIATE)
+	return instructions, target
+}
+
+// translateCopyLoop copies the first words of the block in source to the block in target, their
+// number being the operand
+func translateCopyLoop(instructions []ir.Instruction, source int, target int, operand int, opty ir.OperandTy) []ir.Instruction {
+	condLabel := ir.NewLabelWithPre("copyCond")
+	bodyLabel := ir.NewLabelWithPre("copyBody")
+	index, elem := ir.NewRegister(), ir.NewRegister()
+	instructions = append(instructions, ir.NewMov(index, 0, ir.AL, ir.IMMEDIATE))
+	instructions = append(instructions, ir.NewBranch(ir.AL, condLabel))
+	instructions = append(instructions, ir.NewLabelStmt(bodyLabel))
+	instructions = append(instructions, ir.NewLoadIdx(elem, source, index))
+	instructions = append(instructions, ir.NewStrIdx(elem, target, index))
+	instructions = append(instructions, ir.NewAdd(index, index, 1, ir.IMMEDIATE))
+	instructions = append(instructions, ir.NewLabelStmt(condLabel))
+	instructions = append(instructions, ir.NewCmp(index, operand, opty))
+	instructions = append(instructions, ir.NewBranch(ir.LT, bodyLabel))
+	return instructions
+}
+
+// fieldType returns the type of the field name of the struct ty points to, UnknownTySig if ty does
+// not point to a struct with such a field
+func fieldType(ty types.Type, name string) types.Type {
+	if structTy, isStruct := types.StructOf(ty); isStruct {
+		if field, _, found := structTy.Field(name); found {
+			return field.Ty
+		}
+	}
+	return types.UnknownTySig
+}
+
+// fieldIndex returns the index of the field name in the layout of the struct ty points to, which
+// loadRef and strRef take, for a type checked selector
+func fieldIndex(ty types.Type, name string) int {
+	structTy, _ := types.StructOf(ty)
+	_, idx, _ := structTy.Field(name)
+	return idx
+}
+
 type Factor struct {
 	Token     *token.Token
+	Span      token.Span
 	Expr      Expr
 	targetReg int
 }
 
+func (f *Factor) GetSpan() token.Span { return f.Span }
+
 func (f *Factor) TokenLiteral() string {
 	if f.Token != nil {
 		return f.Token.Literal
@@ -2367,7 +2909,7 @@ func (f *Factor) String() string {
 func (f *Factor) GetType(symTable *st.SymbolTable) types.Type {
 	return f.Expr.GetType(symTable)
 }
-func (f *Factor) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (f *Factor) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = f.Expr.TypeCheck(errors, symTable)
 	return errors
 }
@@ -2380,45 +2922,52 @@ func (f *Factor) GetTargetReg() int {
 	return f.targetReg
 }
 
-func NewType(typeLit string) *Type          { return &Type{nil, typeLit} }
-func NewArgs(exprs []Expression) *Arguments { return &Arguments{nil, exprs, -1} }
-func NewLvalue(ident IdentLiteral, idents []IdentLiteral) *LValue {
-	return &LValue{nil, ident, idents, -1}
+func NewType(typeLit string) *Type          { return &Type{nil, token.Span{}, typeLit} }
+func NewArgs(exprs []Expression) *Arguments { return &Arguments{nil, token.Span{}, exprs, -1} }
+func NewLvalue(ident IdentLiteral, sels []Selector) *LValue {
+	return &LValue{nil, token.Span{}, ident, sels, -1, nil, -1, nil}
 }
 func NewExpression(l *BoolTerm, rs []BoolTerm) *Expression {
-	return &Expression{nil, l, rs, -1}
+	return &Expression{nil, token.Span{}, l, rs, -1}
+}
+func NewBoolTerm(l *EqualTerm, rs []EqualTerm) *BoolTerm {
+	return &BoolTerm{nil, token.Span{}, l, rs, -1}
 }
-func NewBoolTerm(l *EqualTerm, rs []EqualTerm) *BoolTerm { return &BoolTerm{nil, l, rs, -1} }
 func NewEqualTerm(l *RelationTerm, operators []string, rs []RelationTerm) *EqualTerm {
-	return &EqualTerm{nil, l, operators, rs, -1}
+	return &EqualTerm{nil, token.Span{}, l, operators, rs, -1}
 }
 func NewRelationTerm(l *SimpleTerm, operators []string, rs []SimpleTerm) *RelationTerm {
-	return &RelationTerm{nil, l, operators, rs, -1}
+	return &RelationTerm{nil, token.Span{}, l, operators, rs, -1}
 }
 func NewSimpleTerm(l *Term, operators []string, rs []Term) *SimpleTerm {
-	return &SimpleTerm{nil, l, operators, rs, -1}
+	return &SimpleTerm{nil, token.Span{}, l, operators, rs, -1}
 }
 func NewTerm(l *UnaryTerm, operators []string, rs []UnaryTerm) *Term {
-	return &Term{nil, l, operators, rs, -1}
+	return &Term{nil, token.Span{}, l, operators, rs, -1}
 }
 func NewUnaryTerm(operator string, selectorTerm *SelectorTerm) *UnaryTerm {
-	return &UnaryTerm{nil, operator, selectorTerm, -1}
+	return &UnaryTerm{nil, token.Span{}, operator, selectorTerm, -1}
 }
-func NewSelectorTerm(factor *Factor, idents []IdentLiteral) *SelectorTerm {
-	return &SelectorTerm{nil, factor, idents, -1}
+func NewSelectorTerm(factor *Factor, sels []Selector) *SelectorTerm {
+	return &SelectorTerm{nil, token.Span{}, factor, sels, -1}
 }
-func NewFactor(expr *Expr) *Factor { return &Factor{nil, *expr, -1} }
+func NewFieldSelector(ident *IdentLiteral) *Selector { return &Selector{nil, token.Span{}, ident, nil} }
+func NewIndexSelector(index *Expression) *Selector   { return &Selector{nil, token.Span{}, nil, index} }
+func NewFactor(expr *Expr) *Factor                   { return &Factor{nil, token.Span{}, *expr, -1} }
 
 /********************************* Expr inside Factor ***************************************/
 
 // InvocExpr invocation in Factor ('id' [Arguments])
 type InvocExpr struct {
 	Token     *token.Token
+	Span      token.Span
 	Ident     IdentLiteral
 	InnerArgs *Arguments
 	targetReg int
 }
 
+func (ie *InvocExpr) GetSpan() token.Span { return ie.Span }
+
 func (ie *InvocExpr) TokenLiteral() string {
 	if ie.Token != nil {
 		return ie.Token.Literal
@@ -2432,24 +2981,44 @@ func (ie *InvocExpr) String() string {
 	return out.String()
 }
 func (ie *InvocExpr) GetType(symTable *st.SymbolTable) types.Type {
+	if ie.Ident.Id == "new" { // new(id) points to the struct id
+		if len(ie.InnerArgs.Exprs) == 1 {
+			if structTy, isStruct := ie.InnerArgs.Exprs[0].GetType(symTable).(*types.NamedStructType); isStruct {
+				return &types.PointerType{Elem: structTy}
+			}
+		}
+		return types.UnknownTySig
+	} else if ie.Ident.Id == "append" { // append(s, ...) returns the type of s
+		if len(ie.InnerArgs.Exprs) > 0 {
+			if sliceTy, isSlice := ie.InnerArgs.Exprs[0].GetType(symTable).(*types.SliceType); isSlice {
+				return sliceTy
+			}
+		}
+		return types.UnknownTySig
+	}
 	if funcEntry := symTable.PowerContains(ie.Ident.Id); funcEntry != nil {
-		return funcEntry.GetReturnTy()
+		if funcTy, isFunc := funcEntry.GetEntryType().(*types.FuncType); isFunc {
+			return funcTy.Result
+		}
 	}
 	return types.UnknownTySig
 }
-func (ie *InvocExpr) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (ie *InvocExpr) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	// refer from Invocation.TypeCheck
 	funcName := ie.Ident.TokenLiteral()
 	entry := symTable.PowerContains(funcName)
 	if entry == nil {
-		errors = append(errors, fmt.Sprintf("[%v]: function %v has not been defined", ie.Token.LineNum, funcName))
+		errors = append(errors, diag.Errorf(diag.Undefined, diag.At(ie.Token), "function %v has not been defined", funcName))
+	} else if funcName == "new" {
+		errors = ie.InnerArgs.checkNew(errors, symTable)
+	} else if funcName == "delete" {
+		errors = ie.InnerArgs.checkDelete(errors, symTable)
+	} else if funcName == "len" {
+		errors = ie.InnerArgs.checkLen(errors, symTable)
+	} else if funcName == "append" {
+		errors = ie.InnerArgs.checkAppend(errors, symTable)
 	} else {
-		scopeSymTable := entry.GetScopeST()
-		if symTable.ScopeName == "main" { // special case : when calling a function in main,
-			// attach the symbol table of main as the parent of the symbol table of the function
-			scopeSymTable.Parent = symTable
-		}
-		errors = ie.InnerArgs.TypeCheck(errors, scopeSymTable)
+		errors = ie.InnerArgs.checkCall(errors, symTable, funcName, entry)
 	}
 	return errors
 }
@@ -2460,43 +3029,36 @@ func (ie *InvocExpr) TranslateToILoc(instructions []ir.Instruction, symTable *st
 		//instructions = instructions[:len(instructions)-1] // remove the ldr in lvalue
 
 		// examine the size of the struct
-		structName := ie.InnerArgs.Exprs[0].String() // must be a single arg in new
-		structSt := symTable.PowerContains(structName).GetScopeST()
-		countFields := 0
-		for _, _ = range structSt.ScopeParamNames {
-			countFields += 1
-		}
-		newInst := ir.NewNew(ie.GetTargetReg(), ie.InnerArgs.Exprs[0].TokenLiteral(), countFields)
+		structTy, _ := types.StructOf(ie.GetType(symTable))
+		newInst := ir.NewNew(ie.GetTargetReg(), structTy.Name, len(structTy.Fields))
 		instructions = append(instructions, newInst)
+		// the array, slice and string fields start as a block of zeroes, an empty slice and ""
+		for idx, field := range structTy.Fields {
+			if hasZero(field.Ty) {
+				fieldReg := ir.NewRegister()
+				instructions = translateZero(instructions, fieldReg, field.Ty)
+				instructions = append(instructions, ir.NewStrRef(fieldReg, ie.targetReg, field.Name, idx))
+			}
+		}
 		return instructions
-	}
-	// push register values to stack, make space for parameter passing
-	arguments := ie.InnerArgs.Exprs
-	pushReg := []int{}
-	for i := 0; i < len(arguments); i++ {
-		// retrieve reg id of the arguments from the symbol table
-		// Two Cases : primitive value or variable
-		if entry := symTable.Contains(arguments[i].String()); entry != nil {
-			// passed in a variable
-			pushReg = append(pushReg, entry.GetRegId())
-		} else { // passed in a primitive type (int/bool)
-			instructions = arguments[i].TranslateToILoc(instructions, symTable)
-			pushReg = append(pushReg, arguments[i].targetReg)
+	} else if ie.Ident.String() == "len" {
+		// the length of an array is its type's, the one of a slice is in its header, the one of a
+		// string counts its bytes
+		arg := &ie.InnerArgs.Exprs[0]
+		instructions = arg.TranslateToILoc(instructions, symTable)
+		if arrayTy, isArray := arg.GetType(symTable).(*types.ArrayType); isArray {
+			instructions = append(instructions, ir.NewMov(ie.targetReg, arrayTy.Len, ir.AL, ir.IMMEDIATE))
+		} else if arg.GetType(symTable) == types.StringTySig {
+			instructions = append(instructions, ir.NewStrLen(ie.targetReg, arg.targetReg))
+		} else {
+			instructions = append(instructions, ir.NewLoadRef(ie.targetReg, arg.targetReg, "len", sliceLen))
 		}
-		//pushReg = append(pushReg, i+1)
-	}
-	if len(pushReg) != 0 {
-		pushInstruct := ir.NewPush(pushReg, ie.Ident.String())
-		instructions = append(instructions, pushInstruct)
-		// reversed for future pop
-		//for i, j := 0, len(pushReg)-1; i < j; i, j = i+1, j-1 {
-		//	pushReg[i], pushReg[j] = pushReg[j], pushReg[i]
-		//}
+		return instructions
+	} else if ie.Ident.String() == "append" {
+		return ie.InnerArgs.translateAppend(instructions, symTable, ie.targetReg)
 	}
-
-	// branch to function
-	branchInstruct := ir.NewBl(ie.Ident.TokenLiteral())
-	instructions = append(instructions, branchInstruct)
+	var pushReg []int
+	instructions, pushReg = ie.InnerArgs.translateCall(instructions, symTable, ie.Ident.TokenLiteral())
 
 	// move the return value from the function to the target
 	// by default the return value stored in r0
@@ -2517,10 +3079,13 @@ func (ie *InvocExpr) GetTargetReg() int {
 // PriorityExpression : '(' Expression ')' (inside Factor)
 type PriorityExpression struct {
 	Token           *token.Token
+	Span            token.Span
 	InnerExpression *Expression
 	targetReg       int
 }
 
+func (pe *PriorityExpression) GetSpan() token.Span { return pe.Span }
+
 func (pe *PriorityExpression) TokenLiteral() string {
 	if pe.Token != nil {
 		return pe.Token.Literal
@@ -2537,7 +3102,7 @@ func (pe *PriorityExpression) String() string {
 func (pe *PriorityExpression) GetType(symTable *st.SymbolTable) types.Type {
 	return pe.InnerExpression.GetType(symTable)
 }
-func (pe *PriorityExpression) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (pe *PriorityExpression) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	errors = pe.InnerExpression.TypeCheck(errors, symTable)
 	return errors
 }
@@ -2555,17 +3120,22 @@ type NilNode struct {
 	// TO-DO  : how to assign a targetReg to NilNode
 	// Updated: currently we represent a NIL literal as just equal to 0 (discussed on Ed)
 	Token     *token.Token
+	Span      token.Span
 	targetReg int
 }
 
+func (n *NilNode) GetSpan() token.Span { return n.Span }
+
 func (n *NilNode) TokenLiteral() string                        { return n.Token.Literal }
 func (n *NilNode) String() string                              { return n.Token.Literal }
-func (n *NilNode) GetType(symTable *st.SymbolTable) types.Type { return types.VoidTySig }
-func (n *NilNode) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (n *NilNode) GetType(symTable *st.SymbolTable) types.Type { return types.NilTySig }
+func (n *NilNode) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	return errors
 }
 func (n *NilNode) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
 	n.targetReg = ir.NewRegister()
+	instruction := ir.NewMov(n.targetReg, 0, ir.AL, ir.IMMEDIATE)
+	instructions = append(instructions, instruction)
 	return instructions
 }
 func (n *NilNode) GetTargetReg() int {
@@ -2575,14 +3145,17 @@ func (n *NilNode) GetTargetReg() int {
 // BoolLiteral : True/False
 type BoolLiteral struct {
 	Token     *token.Token
+	Span      token.Span
 	Value     bool
 	targetReg int
 }
 
+func (bl *BoolLiteral) GetSpan() token.Span { return bl.Span }
+
 func (bl *BoolLiteral) TokenLiteral() string                        { return bl.Token.Literal }
 func (bl *BoolLiteral) String() string                              { return bl.Token.Literal }
 func (bl *BoolLiteral) GetType(symTable *st.SymbolTable) types.Type { return types.BoolTySig }
-func (bl *BoolLiteral) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (bl *BoolLiteral) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	return errors
 }
 func (bl *BoolLiteral) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -2602,14 +3175,17 @@ func (bl *BoolLiteral) GetTargetReg() int {
 // IntLiteral : number (integer)
 type IntLiteral struct {
 	Token     *token.Token
+	Span      token.Span
 	Value     int64
 	targetReg int
 }
 
+func (il *IntLiteral) GetSpan() token.Span { return il.Span }
+
 func (il *IntLiteral) TokenLiteral() string                        { return il.Token.Literal }
 func (il *IntLiteral) String() string                              { return il.Token.Literal }
 func (il *IntLiteral) GetType(symTable *st.SymbolTable) types.Type { return types.IntTySig }
-func (il *IntLiteral) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (il *IntLiteral) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	return errors
 }
 func (il *IntLiteral) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
@@ -2623,13 +3199,42 @@ func (il *IntLiteral) GetTargetReg() int {
 	return il.targetReg
 }
 
+// StringLiteral : "text", Value holding the text with its escapes decoded
+type StringLiteral struct {
+	Token     *token.Token
+	Span      token.Span
+	Value     string
+	targetReg int
+}
+
+func (sl *StringLiteral) GetSpan() token.Span { return sl.Span }
+
+func (sl *StringLiteral) TokenLiteral() string                        { return sl.Token.Literal }
+func (sl *StringLiteral) String() string                              { return sl.Token.Literal }
+func (sl *StringLiteral) GetType(symTable *st.SymbolTable) types.Type { return types.StringTySig }
+func (sl *StringLiteral) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
+	return errors
+}
+func (sl *StringLiteral) TranslateToILoc(instructions []ir.Instruction, symTable *st.SymbolTable) []ir.Instruction {
+	sl.targetReg = ir.NewRegister()
+	instruction := ir.NewLoadStr(sl.targetReg, sl.Value)
+	instructions = append(instructions, instruction)
+	return instructions
+}
+func (sl *StringLiteral) GetTargetReg() int {
+	return sl.targetReg
+}
+
 // IdentLiteral : identifier
 type IdentLiteral struct {
 	Token     *token.Token
+	Span      token.Span
 	Id        string
 	targetReg int
 }
 
+func (idl *IdentLiteral) GetSpan() token.Span { return idl.Span }
+
 func (idl *IdentLiteral) TokenLiteral() string { return idl.Token.Literal }
 func (idl *IdentLiteral) String() string       { return idl.Token.Literal }
 func (idl *IdentLiteral) GetType(symTable *st.SymbolTable) types.Type {
@@ -2647,9 +3252,9 @@ func (idl *IdentLiteral) GetType(symTable *st.SymbolTable) types.Type {
 	}
 	return entry.GetEntryType()
 }
-func (idl *IdentLiteral) TypeCheck(errors []string, symTable *st.SymbolTable) []string {
+func (idl *IdentLiteral) TypeCheck(errors []diag.Diagnostic, symTable *st.SymbolTable) []diag.Diagnostic {
 	if idl.GetType(symTable) == types.UnknownTySig {
-		errors = append(errors, fmt.Sprintf("[%v]: %v has not been defined.", idl.Token.LineNum, idl.Id))
+		errors = append(errors, diag.Errorf(diag.Undefined, diag.At(idl.Token), "%v has not been defined", idl.Id))
 	}
 	return errors
 }
@@ -2675,4 +3280,6 @@ func (idl *IdentLiteral) GetTargetReg() int {
 	return idl.targetReg
 }
 
-func NewIdentLiteral(tok *token.Token, id string) IdentLiteral { return IdentLiteral{tok, id, -1} }
+func NewIdentLiteral(tok *token.Token, id string) IdentLiteral {
+	return IdentLiteral{tok, tok.Span(), id, -1}
+}
@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/token"
+	"reflect"
+	"strings"
+)
+
+// spanned is implemented by every node, including the ones that are not a full Node
+type spanned interface {
+	GetSpan() token.Span
+}
+
+var spannedType = reflect.TypeOf((*spanned)(nil)).Elem()
+
+// Dump returns the tree of nodes under node, one node per line along with the span it covers
+func Dump(node spanned) string {
+	out := bytes.Buffer{}
+	dump(&out, reflect.ValueOf(node), 0)
+	return out.String()
+}
+
+func dump(out *bytes.Buffer, v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			dump(out, v.Elem(), depth)
+		}
+	case reflect.Ptr:
+		if v.IsNil() || !v.Type().Implements(spannedType) || !v.CanInterface() {
+			return
+		}
+		node := v.Interface().(spanned)
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(fmt.Sprintf("%v %v", v.Elem().Type().Name(), node.GetSpan()))
+		switch leaf := node.(type) {
+		case *IdentLiteral, *IntLiteral, *BoolLiteral, *StringLiteral:
+			out.WriteString(fmt.Sprintf(" %v", leaf.(Node).String()))
+		case *Type:
+			out.WriteString(fmt.Sprintf(" %v", leaf.TypeLiteral))
+		}
+		out.WriteString("\n")
+		elem := v.Elem()
+		for i := 0; i < elem.NumField(); i++ {
+			dump(out, elem.Field(i), depth+1)
+		}
+	case reflect.Struct:
+		// nodes stored by value, e.g. in slices, a zero one being absent
+		if v.CanAddr() && !v.IsZero() {
+			dump(out, v.Addr(), depth)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			dump(out, v.Index(i), depth)
+		}
+	}
+}
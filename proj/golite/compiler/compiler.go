@@ -0,0 +1,312 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"proj/golite/arm"
+	"proj/golite/arm/asm"
+	"proj/golite/arm/peephole"
+	"proj/golite/arm/regalloc"
+	"proj/golite/ast"
+	ct "proj/golite/context"
+	"proj/golite/diag"
+	"proj/golite/ir"
+	"proj/golite/ir/parse"
+	"proj/golite/opt"
+	ps "proj/golite/parser"
+	"proj/golite/sa"
+	sc "proj/golite/scanner"
+	st "proj/golite/symboltable"
+	"proj/golite/token"
+	"sync"
+)
+
+// compiling serializes the compilations: the generators of registers and labels of
+// proj/golite/ir and the tables of proj/golite/utility the translators fill are package state
+var compiling sync.Mutex
+
+// Stage identifies a phase of the compilation pipeline
+type Stage int
+
+const (
+	StageLex      Stage = iota // scanning only
+	StageParse                 // scanning and parsing
+	StageSemantic              // up to (and including) semantic analysis
+	StageILoc                  // up to the translation into ILOC
+	StageAssembly              // the whole pipeline, down to Armv8 assembly
+)
+
+func (stage Stage) String() string {
+	switch stage {
+	case StageLex:
+		return "scanning"
+	case StageParse:
+		return "parsing"
+	case StageSemantic:
+		return "semantic analysis"
+	case StageILoc:
+		return "ILOC generation"
+	default:
+		return "assembly generation"
+	}
+}
+
+// Failure tells why the pipeline stopped, each kind of failure has its own exit code
+type Failure int
+
+const (
+	Success       Failure = iota // every requested stage has been run without errors
+	SyntaxError                  // the scanner or the parser reported errors
+	SemanticError                // semantic analysis reported errors
+	InternalError                // a stage crashed, which is a bug of the compiler
+	InputError                   // the source could not be read
+)
+
+// ExitCode returns the status golite exits with: 0 on success, 1 for syntax errors,
+// 2 for semantic errors, 3 for internal compiler errors and 4 for unreadable sources
+func (f Failure) ExitCode() int { return int(f) }
+
+// Options configures a single invocation of Compile
+type Options struct {
+	StopAfter  Stage             // the last stage to run; the zero value only scans the source
+	RegAlloc   regalloc.Strategy // how the registers of the Arm code are allocated, linear scan by default
+	OptLevel   opt.Level         // the optimizations of the ILOC, none by default
+	PrintAfter string            // the pass after which the ILOC is kept in Result.PrintedAfter, "all" for every pass
+	Peephole   bool              // rewrite the Arm code with proj/golite/arm/peephole
+}
+
+// Result holds everything produced by the stages that have been run
+type Result struct {
+	Tokens       []token.Token   // all tokens produced by the scanner, including EOF
+	Program      *ast.Program    // the AST, partial if parsing failed and nil if it was not requested
+	SymbolTable  *st.SymbolTable // the global symbol table, nil if semantic analysis failed or was not requested
+	FuncFrags    []*ir.FuncFrag  // the ILOC of the program, the first fragment holds global variables
+	PrintedAfter []string        // the ILOC after the passes named by Options.PrintAfter, each listing headed by a comment
+	Assembly     []string        // the lines of Armv8 assembly
+	Removed      int             // the instructions of the Arm code removed by the peephole optimizer
+	Diagnostics  diag.List       // errors collected from every stage that has been run, sorted by position
+
+	Failure     Failure // why the pipeline stopped, Success if it ran up to Options.StopAfter
+	FailedStage Stage   // the stage that failed, only meaningful if Failure is not Success
+}
+
+// HasErrors returns true if any stage reported an error
+func (res *Result) HasErrors() bool { return res.Diagnostics.HasErrors() }
+
+// Compile runs the pipeline on the golite program at sourcePath until opts.StopAfter is reached
+// or a stage fails, and returns the artifacts produced so far. A stage only runs if all the
+// previous ones succeeded, and a crash of a stage is reported as an internal error, as is ILOC
+// generated wrong in the builds with the debug tag, which check it with ir.Verify.
+// A path ending in .iloc holds ILOC text instead, which is given to CompileILoc.
+//
+// Compile and its variants are not reentrant: the compiler keeps its registers, labels, string
+// literals and scratch registers in package state, so concurrent calls run one after the other.
+// The passes of proj/golite/opt and proj/golite/ir/ssa, which create registers too, must not run
+// during a compilation.
+func Compile(sourcePath string, opts Options) *Result {
+	if filepath.Ext(sourcePath) == ".iloc" {
+		return compileILocFile(sourcePath, opts, func() (io.ReadCloser, error) { return os.Open(sourcePath) })
+	}
+	ctx := ct.New(false, false, false, false, sourcePath)
+	return compile(sourcePath, sc.New(*ctx), opts)
+}
+
+// CompileReader is Compile for a program read from reader, name is only used in diagnostics
+func CompileReader(name string, reader io.Reader, opts Options) *Result {
+	return compile(name, sc.NewFromReader(reader), opts)
+}
+
+// CompileString is Compile for the program src, name is only used in diagnostics
+func CompileString(name string, src string, opts Options) *Result {
+	return compile(name, sc.NewFromString(src), opts)
+}
+
+// CompileFS is Compile for the program in the file name of the filesystem fsys
+func CompileFS(fsys fs.FS, name string, opts Options) *Result {
+	if filepath.Ext(name) == ".iloc" {
+		return compileILocFile(name, opts, func() (io.ReadCloser, error) { return fsys.Open(name) })
+	}
+	return compile(name, sc.NewFromFS(fsys, name), opts)
+}
+
+// CompileILoc runs the backend on the ILOC text read from reader, in the format printed by
+// golite -iloc, instead of a golite program. Reading the text is the ILOC generation stage, whose
+// errors are syntax errors, ILOC rejected by ir.Verify included in the builds with the debug tag,
+// and only StageAssembly goes further. Tokens, Program and SymbolTable
+// stay nil.
+func CompileILoc(name string, reader io.Reader, opts Options) *Result {
+	compiling.Lock()
+	defer compiling.Unlock()
+	res := &Result{}
+	defer res.finishDiagnostics(name)
+	ir.ResetGenerators()
+
+	ok := res.run(StageILoc, func() []diag.Diagnostic {
+		frags, errors := parse.ParseReader(reader)
+		res.FuncFrags = frags
+		if len(errors) == 0 && ir.Debug {
+			for _, err := range ir.Verify(frags) {
+				errors = append(errors, diag.Errorf(diag.InvalidILoc, diag.Pos{}, "%v", err))
+			}
+		}
+		return errors
+	})
+	if !ok {
+		if res.Failure == SemanticError {
+			res.Failure = SyntaxError
+		}
+		return res
+	}
+	parse.Reserve(res.FuncFrags)
+	ok = res.run(StageILoc, func() []diag.Diagnostic {
+		res.optimize(opts)
+		return nil
+	})
+	if !ok || opts.StopAfter < StageAssembly {
+		return res
+	}
+
+	res.run(StageAssembly, func() []diag.Diagnostic {
+		res.translate(opts)
+		return nil
+	})
+	return res
+}
+
+// compileILocFile is CompileILoc for the file that open opens, failing to open it is an input error
+func compileILocFile(name string, opts Options, open func() (io.ReadCloser, error)) *Result {
+	file, err := open()
+	if err != nil {
+		res := &Result{}
+		res.run(StageILoc, func() []diag.Diagnostic {
+			return []diag.Diagnostic{diag.Errorf(diag.Unreadable, diag.Pos{}, "%v", err)}
+		})
+		res.finishDiagnostics(name)
+		return res
+	}
+	defer file.Close()
+	return CompileILoc(name, file, opts)
+}
+
+func compile(name string, scanner *sc.Scanner, opts Options) *Result {
+	compiling.Lock()
+	defer compiling.Unlock()
+	res := &Result{}
+	defer res.finishDiagnostics(name)
+	ir.ResetGenerators()
+
+	ok := res.run(StageLex, func() []diag.Diagnostic {
+		res.Tokens = scanner.ScanAll()
+		return scanner.Errors()
+	})
+	if !ok || opts.StopAfter < StageParse {
+		return res
+	}
+
+	ok = res.run(StageParse, func() []diag.Diagnostic {
+		parser := ps.NewFromTokens(res.Tokens)
+		res.Program = parser.Parse()
+		return parser.Errors()
+	})
+	if !ok || opts.StopAfter < StageSemantic {
+		return res
+	}
+
+	ok = res.run(StageSemantic, func() []diag.Diagnostic {
+		globalSymTable, errors := sa.Analyze(res.Program)
+		if len(errors) == 0 {
+			res.SymbolTable = globalSymTable
+		}
+		return errors
+	})
+	if !ok || opts.StopAfter < StageILoc {
+		return res
+	}
+
+	ok = res.run(StageILoc, func() []diag.Diagnostic {
+		res.FuncFrags = res.Program.TranslateToILocFunc([]*ir.FuncFrag{}, res.SymbolTable)
+		if ir.Debug {
+			if errors := ir.Verify(res.FuncFrags); len(errors) > 0 {
+				panic(fmt.Sprintf("invalid ILOC generated: %v", errors))
+			}
+		}
+		res.optimize(opts)
+		return nil
+	})
+	if !ok || opts.StopAfter < StageAssembly {
+		return res
+	}
+
+	res.run(StageAssembly, func() []diag.Diagnostic {
+		res.translate(opts)
+		return nil
+	})
+	return res
+}
+
+// optimize runs the passes of opts.OptLevel over the ILOC of the result
+func (res *Result) optimize(opts Options) {
+	manager := &opt.Manager{Level: opts.OptLevel, PrintAfter: opts.PrintAfter}
+	manager.Run(res.FuncFrags)
+	res.PrintedAfter = manager.Printed
+}
+
+// translate translates the ILOC of the result into Arm code, rewritten by the peephole optimizer
+// if opts.Peephole is set
+func (res *Result) translate(opts Options) {
+	lines := arm.Translate(res.FuncFrags, opts.RegAlloc)
+	if opts.Peephole {
+		lines, res.Removed = peephole.Optimize(lines)
+	}
+	res.Assembly = asm.Emit(lines)
+}
+
+// run runs a single stage and collects its diagnostics, it returns false if the stage reported
+// errors or crashed, in which case the failure is recorded in the result
+func (res *Result) run(stage Stage, fn func() []diag.Diagnostic) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			res.Diagnostics = append(res.Diagnostics,
+				diag.Errorf(diag.Internal, diag.Pos{}, "internal compiler error during %v: %v", stage, r).
+					WithNote("this is a bug of golite, please report it along with the source file"))
+			res.Failure, res.FailedStage = InternalError, stage
+			ok = false
+		}
+	}()
+
+	errors := diag.List(fn())
+	res.Diagnostics = append(res.Diagnostics, errors...)
+	if !errors.HasErrors() {
+		return true
+	}
+	res.Failure, res.FailedStage = SemanticError, stage
+	if stage < StageSemantic {
+		res.Failure = SyntaxError
+	}
+	for _, d := range errors {
+		if d.Code == diag.Unreadable {
+			res.Failure = InputError
+		}
+	}
+	return false
+}
+
+// finishDiagnostics attributes the diagnostics to the source file, sorts them and drops duplicates
+func (res *Result) finishDiagnostics(sourcePath string) {
+	res.Diagnostics.SetFile(sourcePath)
+	res.Diagnostics.Sort()
+	res.Diagnostics = res.Diagnostics.Dedup()
+}
+
+// ILocLines returns the textual ILOC of the result, one instruction per line, which
+// CompileILoc reads back
+func (res *Result) ILocLines() []string {
+	lines := []string{}
+	for _, funcFrag := range res.FuncFrags {
+		lines = append(lines, funcFrag.Lines()...)
+	}
+	return lines
+}
@@ -0,0 +1,206 @@
+package compiler
+
+import (
+	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/arm/regalloc"
+	"proj/golite/diag"
+	"proj/golite/ir"
+	"proj/golite/token"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func Test1(t *testing.T) {
+	res := Compile("test1_compiler.golite", Options{StopAfter: StageAssembly})
+	if res.HasErrors() {
+		t.Fatalf("\nExpected: no diagnostics; Got %v\n", res.Diagnostics)
+	}
+	if res.Program == nil || res.SymbolTable == nil {
+		t.Errorf("\nExpected: AST and symbol table; Got %v and %v\n", res.Program, res.SymbolTable)
+	}
+	if len(res.FuncFrags) != 3 { // global variables, Add, main
+		t.Errorf("\nExpected: 3 FuncFrags; Got %v\n", len(res.FuncFrags))
+	}
+	if len(res.Assembly) == 0 {
+		t.Errorf("\nExpected: assembly; Got nothing\n")
+	}
+}
+
+func Test2(t *testing.T) {
+	res := Compile("test1_compiler.golite", Options{StopAfter: StageLex})
+	if len(res.Tokens) == 0 || res.Tokens[len(res.Tokens)-1].Type != token.EOF {
+		t.Fatalf("\nExpected: tokens ending with EOF; Got %v\n", res.Tokens)
+	}
+	if res.Program != nil || res.FuncFrags != nil || res.Assembly != nil {
+		t.Errorf("\nExpected: only tokens; Got later stages too\n")
+	}
+}
+
+func Test3(t *testing.T) {
+	res := Compile("test2_compiler.golite", Options{StopAfter: StageAssembly})
+	if !res.HasErrors() {
+		t.Fatalf("\nExpected: semantic errors; Got none\n")
+	}
+	if res.SymbolTable != nil || res.FuncFrags != nil || res.Assembly != nil {
+		t.Errorf("\nExpected: pipeline stopped at semantic analysis; Got later stages\n")
+	}
+}
+
+func Test4(t *testing.T) {
+	// compiling twice must not depend on the state left by the first compilation
+	first := Compile("test1_compiler.golite", Options{StopAfter: StageILoc})
+	second := Compile("test1_compiler.golite", Options{StopAfter: StageILoc})
+	firstLines, secondLines := first.ILocLines(), second.ILocLines()
+	if len(firstLines) != len(secondLines) {
+		t.Fatalf("\nExpected: identical ILOC; Got %v and %v lines\n", len(firstLines), len(secondLines))
+	}
+	for i := range firstLines {
+		if firstLines[i] != secondLines[i] {
+			t.Errorf("\nExpected: %v\nGot: %v\n", firstLines[i], secondLines[i])
+		}
+	}
+}
+
+func Test5(t *testing.T) {
+	res := Compile("test2_compiler.golite", Options{StopAfter: StageAssembly})
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("\nExpected: 1 diagnostic; Got %v\n", res.Diagnostics)
+	}
+	d := res.Diagnostics[0]
+	if d.Code != diag.Undefined || d.Pos.File != "test2_compiler.golite" || d.Pos.Line != 7 {
+		t.Errorf("\nExpected: S003 at test2_compiler.golite:7; Got %v\n", d)
+	}
+}
+
+func Test6(t *testing.T) {
+	files := []string{"test1_compiler.golite", "test3_compiler.golite", "test2_compiler.golite"}
+	failures := []Failure{Success, SyntaxError, SemanticError}
+	stages := []Stage{StageAssembly, StageParse, StageSemantic}
+	for i, file := range files {
+		res := Compile(file, Options{StopAfter: StageAssembly})
+		if res.Failure != failures[i] || (res.Failure != Success && res.FailedStage != stages[i]) {
+			t.Errorf("\nExpected: %v failure %v at %v; Got %v at %v\n", file, failures[i], stages[i], res.Failure, res.FailedStage)
+		}
+		if res.Failure.ExitCode() != i {
+			t.Errorf("\nExpected: exit code %v; Got %v\n", i, res.Failure.ExitCode())
+		}
+	}
+}
+
+func Test7(t *testing.T) {
+	// a crashing stage is reported instead of taking the whole compiler down
+	res := &Result{}
+	ok := res.run(StageILoc, func() []diag.Diagnostic {
+		var frag *ir.FuncFrag
+		return []diag.Diagnostic{diag.Errorf(diag.Internal, diag.Pos{}, "%v", frag.Body)}
+	})
+	if ok || res.Failure != InternalError || res.FailedStage != StageILoc {
+		t.Fatalf("\nExpected: internal error during ILOC generation; Got %v at %v\n", res.Failure, res.FailedStage)
+	}
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Code != diag.Internal {
+		t.Errorf("\nExpected: 1 internal error diagnostic; Got %v\n", res.Diagnostics)
+	}
+	if res.Failure.ExitCode() != 3 {
+		t.Errorf("\nExpected: exit code 3; Got %v\n", res.Failure.ExitCode())
+	}
+}
+
+func Test8(t *testing.T) {
+	// programs generated in memory, no file involved
+	tests := []struct {
+		src     string
+		failure Failure
+	}{
+		{"package main;\nimport \"fmt\";\nfunc main() {\n\tvar a int;\n\ta = 1;\n\tfmt.Println(a);\n}\n", Success},
+		{"package main;\nimport \"fmt\";\nfunc main() {\n\ta = 1\n}\n", SyntaxError},
+		{"package main;\nimport \"fmt\";\nfunc main() {\n\ta = 1;\n}\n", SemanticError},
+	}
+	for i, test := range tests {
+		res := CompileString("gen.golite", test.src, Options{StopAfter: StageAssembly})
+		if res.Failure != test.failure {
+			t.Errorf("\nExpected: program %v fails with %v; Got %v %v\n", i, test.failure, res.Failure, res.Diagnostics)
+		}
+		for _, d := range res.Diagnostics {
+			if d.Pos.File != "gen.golite" {
+				t.Errorf("\nExpected: diagnostics in gen.golite; Got %v\n", d)
+			}
+		}
+	}
+
+	fsys := fstest.MapFS{"main.golite": &fstest.MapFile{Data: []byte(tests[0].src)}}
+	if res := CompileFS(fsys, "main.golite", Options{StopAfter: StageAssembly}); res.Failure != Success || len(res.Assembly) == 0 {
+		t.Errorf("\nExpected: assembly from the virtual filesystem; Got %v\n", res.Diagnostics)
+	}
+	if res := CompileFS(fsys, "other.golite", Options{StopAfter: StageAssembly}); res.Failure != InputError || res.Failure.ExitCode() != 4 {
+		t.Errorf("\nExpected: unreadable source; Got %v\n", res.Failure)
+	}
+}
+
+func Test9(t *testing.T) {
+	// concurrent compilations give the output of compiling each program alone
+	src := "package main;\nimport \"fmt\";\nfunc main() {\n\tvar s string;\n\ts = \"a\" + \"b\";\n\tfmt.Println(s, len(s));\n}\n"
+	expected := CompileString("gen.golite", src, Options{StopAfter: StageAssembly})
+	results := make([]*Result, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = CompileString("gen.golite", src, Options{StopAfter: StageAssembly})
+		}(i)
+	}
+	wg.Wait()
+	for i, res := range results {
+		if res.Failure != Success || strings.Join(res.Assembly, "\n") != strings.Join(expected.Assembly, "\n") {
+			t.Errorf("\nExpected: compilation %v gives the same Arm code; Got %v %v\n", i, res.Failure, res.Diagnostics)
+		}
+	}
+}
+
+func Test10(t *testing.T) {
+	// 600 values live across a call take a frame of more than 4095 bytes, whose slots are out of
+	// reach of the immediates of ldr and str, under every strategy
+	var src strings.Builder
+	src.WriteString("package main;\nimport \"fmt\";\nfunc f(a int, b int, c int, d int, e int, g int, h int, i int, j int, k int) int {\n\treturn a + k;\n}\nfunc main() {\n")
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&src, "\tvar v%v int;\n", i)
+	}
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&src, "\tv%v = %v;\n", i, i)
+	}
+	src.WriteString("\tv0 = f(v1, v2, v3, v4, v5, v6, v7, v8, v9, v599);\n")
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&src, "\tfmt.Println(v%v);\n", i)
+	}
+	src.WriteString("}\n")
+
+	for _, strategy := range []regalloc.Strategy{regalloc.Linear, regalloc.Graph, regalloc.None} {
+		for _, peephole := range []bool{false, true} {
+			res := CompileString("gen.golite", src.String(), Options{StopAfter: StageAssembly, RegAlloc: strategy, Peephole: peephole})
+			if res.Failure != Success {
+				t.Fatalf("\nExpected: Arm code with %v; Got %v %v\n", strategy, res.Failure, res.Diagnostics)
+			}
+			lines, err := asm.Parse(res.Assembly)
+			if err != nil {
+				t.Fatalf("\nExpected: Arm code with %v; Got %v\n", strategy, err)
+			}
+			for _, line := range lines {
+				instr, isInstr := line.(asm.Instr)
+				if !isInstr {
+					continue
+				}
+				for _, operand := range instr.Operands {
+					if mem, isMem := operand.(asm.Mem); isMem && mem.Base == asm.FP && !ir.SlotInReach(mem.Offset) {
+						t.Errorf("\nExpected: offsets from x29 of at least %v with %v; Got %v\n", ir.MinSlotOffset, strategy, instr)
+					}
+					if imm, isImm := operand.(asm.Imm); isImm && instr.Operands[0] == asm.SP && imm > 4095 {
+						t.Errorf("\nExpected: sp moved by at most 4095 with %v; Got %v\n", strategy, instr)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package compiler
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"proj/golite/arm/regalloc"
+	"proj/golite/interp"
+	"proj/golite/ir"
+	"proj/golite/ir/sim"
+	"proj/golite/ir/ssa"
+	"proj/golite/opt"
+	"proj/golite/toolchain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The golden test compiles every program of goldenDirs and compares the results with the files
+// next to it, named after the program:
+//
+//	<name>.iloc.expected  the ILOC of a program that compiles
+//	<name>.s.expected     its Arm code
+//	<name>.err.expected   the diagnostics of a program that does not compile
+//	<name>.out.expected   the standard-out and exit status of running the program
+//	<name>.stdin          the standard-in given to the program, empty if there is none
+//
+// The ILOC of every program that compiles must pass ir.Verify, and is also read back with
+// CompileILoc, which must give the same ILOC and Arm code.
+//
+// The programs having a .out.expected file are run by the interpreter of proj/golite/interp, and
+// their ILOC by the simulator of proj/golite/ir/sim before and after the optimizations of -O2,
+// and once converted into SSA form and back. If the toolchain of proj/golite/toolchain is
+// installed, the ones having a .stdin are also built and run, with and without the peephole
+// optimizer.
+//
+// The Arm code of every program that compiles is assembled, as are its variants of
+// goldenVariants, with the C compiler of the toolchain or else the first of fallbackAssemblers
+// found, so that an instruction out of the encodings of Armv8 fails the test even where nothing
+// runs.
+//
+// go test -run Golden -update rewrites the .expected files from the current compiler instead,
+// the .out.expected ones only from the built programs, never from the interpreter or the
+// simulator.
+var update = flag.Bool("update", false, "rewrite the .expected files of the golden test")
+
+var goldenDirs = []string{"../arm", "../iloc"}
+
+// goldenVariants are the flags and options of the other Arm code assembled for each program
+var goldenVariants = []struct {
+	flag string
+	opts Options
+}{
+	{"-regalloc=graph", Options{StopAfter: StageAssembly, RegAlloc: regalloc.Graph}},
+	{"-regalloc=none", Options{StopAfter: StageAssembly, RegAlloc: regalloc.None}},
+	{"-peephole", Options{StopAfter: StageAssembly, Peephole: true}},
+}
+
+// fallbackAssemblers are the commands assembling Armv8 code without the toolchain, followed by
+// -o, the object file and the assembly file
+var fallbackAssemblers = [][]string{
+	{"aarch64-linux-gnu-as"},
+	{"llvm-mc", "-triple=aarch64-linux-gnu", "-filetype=obj"},
+}
+
+// runTimeout bounds the time a program of the golden test may run, emulated programs are slow
+const runTimeout = 30 * time.Second
+
+func TestGolden(t *testing.T) {
+	config := toolchain.DefaultConfig()
+	canRun := toolAvailable(config.CC) && (len(config.Emulator) == 0 || toolAvailable(config.Emulator[0]))
+	if !canRun {
+		t.Logf("no toolchain %v, the programs are compiled but not run", config.CC)
+	}
+	assembler := findAssembler(config)
+	if assembler == nil {
+		t.Logf("no assembler for Armv8, the Arm code is not assembled")
+	}
+
+	for _, dir := range goldenDirs {
+		sourcePaths, err := filepath.Glob(filepath.Join(dir, "*.golite"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sourcePath := range sourcePaths {
+			sourcePath := sourcePath
+			t.Run(strings.TrimSuffix(filepath.Base(sourcePath), ".golite"), func(t *testing.T) {
+				res := CompileFS(os.DirFS(dir), filepath.Base(sourcePath), Options{StopAfter: StageAssembly})
+				outputs := map[string]string{".iloc": "", ".s": "", ".err": ""}
+				if res.HasErrors() {
+					outputs[".err"] = diagnosticsText(res)
+				} else {
+					outputs[".iloc"] = linesText(res.ILocLines())
+					outputs[".s"] = linesText(res.Assembly)
+				}
+				for _, ext := range []string{".iloc", ".s", ".err"} {
+					checkGolden(t, strings.TrimSuffix(sourcePath, ".golite")+ext+".expected", outputs[ext])
+				}
+
+				if !res.HasErrors() {
+					for _, err := range ir.Verify(res.FuncFrags) {
+						t.Errorf("\nExpected: ILOC that ir.Verify accepts; Got %v\n", err)
+					}
+					reparseGolden(t, res)
+					interpretGolden(t, res, sourcePath)
+				}
+				if assembler != nil && !res.HasErrors() {
+					assembleGolden(t, assembler, "by default", res.Assembly)
+					for _, variant := range goldenVariants {
+						variantRes := CompileFS(os.DirFS(dir), filepath.Base(sourcePath), variant.opts)
+						assembleGolden(t, assembler, "with "+variant.flag, variantRes.Assembly)
+					}
+				}
+				if canRun && !res.HasErrors() {
+					runGolden(t, config, sourcePath, res.Assembly)
+					if !*update {
+						optimized := CompileFS(os.DirFS(dir), filepath.Base(sourcePath), Options{StopAfter: StageAssembly, Peephole: true})
+						runGolden(t, config, sourcePath, optimized.Assembly)
+					}
+				}
+			})
+		}
+	}
+}
+
+// reparseGolden reads back the ILOC text of a program, which must give the same ILOC and Arm code
+func reparseGolden(t *testing.T, res *Result) {
+	reparsed := CompileILoc("reparsed.iloc", strings.NewReader(linesText(res.ILocLines())), Options{StopAfter: StageAssembly})
+	if reparsed.HasErrors() {
+		t.Errorf("\nExpected: the ILOC reads back; Got\n%v", diagnosticsText(reparsed))
+		return
+	}
+	if expected, got := linesText(res.ILocLines()), linesText(reparsed.ILocLines()); expected != got {
+		t.Errorf("\nthe ILOC read back differs:\n%v", lineDiff(expected, got))
+	}
+	if expected, got := linesText(res.Assembly), linesText(reparsed.Assembly); expected != got {
+		t.Errorf("\nthe Arm code of the ILOC read back differs:\n%v", lineDiff(expected, got))
+	}
+}
+
+// interpretGolden runs a program with the interpreter and its ILOC with the simulator, and
+// compares their outputs with its .out.expected
+func interpretGolden(t *testing.T, res *Result, sourcePath string) {
+	base := strings.TrimSuffix(sourcePath, ".golite")
+	expected, err := os.ReadFile(base + ".out.expected")
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	stdin, _ := os.ReadFile(base + ".stdin")
+
+	runners := map[string]func(stdout *bytes.Buffer) error{
+		"the interpreter": func(stdout *bytes.Buffer) error {
+			return interp.Run(res.Program, bytes.NewReader(stdin), stdout)
+		},
+		"the ILOC simulator": func(stdout *bytes.Buffer) error {
+			_, err := sim.Run(res.FuncFrags, bytes.NewReader(stdin), stdout)
+			return err
+		},
+		"the ILOC simulator at -O2": func(stdout *bytes.Buffer) error {
+			optimized := CompileFS(os.DirFS(filepath.Dir(sourcePath)), filepath.Base(sourcePath), Options{StopAfter: StageILoc, OptLevel: opt.O2})
+			if optimized.HasErrors() {
+				return fmt.Errorf("%v", diagnosticsText(optimized))
+			}
+			_, err := sim.Run(optimized.FuncFrags, bytes.NewReader(stdin), stdout)
+			return err
+		},
+		"the ILOC simulator after SSA": func(stdout *bytes.Buffer) error {
+			frags := []*ir.FuncFrag{}
+			for _, frag := range res.FuncFrags {
+				if frag.IsGlobal() {
+					frags = append(frags, frag)
+					continue
+				}
+				f := ssa.Build(frag)
+				if errors := f.Verify(); len(errors) > 0 {
+					return fmt.Errorf("invalid SSA form: %v", errors)
+				}
+				frags = append(frags, ssa.Destruct(f))
+			}
+			_, err := sim.Run(frags, bytes.NewReader(stdin), stdout)
+			return err
+		},
+	}
+	for name, run := range runners {
+		stdout := bytes.Buffer{}
+		if err := run(&stdout); err != nil {
+			t.Errorf("\nExpected: the program runs with %v; Got %v\n", name, err)
+		} else if got := stdout.String() + "--- exit status 0\n"; got != string(expected) {
+			t.Errorf("\n%v differs from %v:\n%v", name, filepath.Base(base+".out.expected"), lineDiff(string(expected), got))
+		}
+	}
+}
+
+// findAssembler returns the command assembling Armv8 code, followed by -o, the object file and
+// the assembly file, nil if there is none
+func findAssembler(config toolchain.Config) []string {
+	if toolAvailable(config.CC) {
+		return []string{config.CC, "-c"}
+	}
+	for _, assembler := range fallbackAssemblers {
+		if toolAvailable(assembler[0]) {
+			return assembler
+		}
+	}
+	return nil
+}
+
+// assembleGolden assembles the Arm code of a program, which must assemble without errors, the
+// first lines of the output of the assembler being reported otherwise
+func assembleGolden(t *testing.T, assembler []string, variant string, assembly []string) {
+	dir := t.TempDir()
+	asmPath := filepath.Join(dir, "prog.s")
+	if err := os.WriteFile(asmPath, []byte(linesText(assembly)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := append(append([]string{}, assembler[1:]...), "-o", filepath.Join(dir, "prog.o"), asmPath)
+	if output, err := exec.Command(assembler[0], args...).CombinedOutput(); err != nil {
+		lines := strings.SplitAfter(string(output), "\n")
+		if len(lines) > 10 {
+			lines = lines[:10]
+		}
+		t.Errorf("\nExpected: the Arm code assembles %v; Got %v\n%v", variant, err, strings.Join(lines, ""))
+	}
+}
+
+// runGolden builds and runs a program with its .stdin, and compares its output with its .out.expected
+func runGolden(t *testing.T, config toolchain.Config, sourcePath string, assembly []string) {
+	base := strings.TrimSuffix(sourcePath, ".golite")
+	stdin, errStdin := os.ReadFile(base + ".stdin")
+	_, errExpected := os.Stat(base + ".out.expected")
+	if os.IsNotExist(errStdin) && os.IsNotExist(errExpected) {
+		return
+	}
+
+	dir := t.TempDir()
+	asmPath, exePath := filepath.Join(dir, "prog.s"), filepath.Join(dir, "prog")
+	if err := os.WriteFile(asmPath, []byte(linesText(assembly)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range config.Build(asmPath, exePath) {
+		t.Errorf("\nExpected: the program builds; Got %v\n", d)
+	}
+	if t.Failed() {
+		return
+	}
+
+	cmd := config.Command(exePath)
+	stdout := bytes.Buffer{}
+	cmd.Stdin, cmd.Stdout = bytes.NewReader(stdin), &stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	timer := time.AfterFunc(runTimeout, func() { cmd.Process.Kill() })
+	err := cmd.Wait()
+	timer.Stop()
+	if err != nil {
+		if _, exited := err.(*exec.ExitError); !exited {
+			t.Fatal(err)
+		}
+	}
+	checkGolden(t, base+".out.expected", fmt.Sprintf("%v--- exit status %v\n", stdout.String(), cmd.ProcessState.ExitCode()))
+}
+
+// checkGolden compares got with the content of the golden file at path, an empty got meaning that
+// there should be no such file. With -update, the file is rewritten instead.
+func checkGolden(t *testing.T, path string, got string) {
+	if *update {
+		var err error
+		if got == "" {
+			if err = os.Remove(path); os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(path, []byte(got), 0644)
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if got != "" {
+			t.Errorf("\nExpected: no %v; Got\n%v(run go test -update to accept it)\n", filepath.Base(path), got)
+		}
+		return
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != got {
+		t.Errorf("\n%v differs:\n%v(run go test -update to accept the changes)\n", filepath.Base(path), lineDiff(string(expected), got))
+	}
+}
+
+// lineDiff lists the lines of expected and got from the first one that differs
+func lineDiff(expected string, got string) string {
+	expectedLines, gotLines := strings.Split(expected, "\n"), strings.Split(got, "\n")
+	first := 0
+	for first < len(expectedLines) && first < len(gotLines) && expectedLines[first] == gotLines[first] {
+		first++
+	}
+	out := bytes.Buffer{}
+	for i := first; i < len(expectedLines) && i < first+5; i++ {
+		out.WriteString(fmt.Sprintf("%4d - %v\n", i+1, expectedLines[i]))
+	}
+	for i := first; i < len(gotLines) && i < first+5; i++ {
+		out.WriteString(fmt.Sprintf("%4d + %v\n", i+1, gotLines[i]))
+	}
+	return out.String()
+}
+
+func diagnosticsText(res *Result) string {
+	out := bytes.Buffer{}
+	res.Diagnostics.Print(&out)
+	return out.String()
+}
+
+func linesText(lines []string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func toolAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
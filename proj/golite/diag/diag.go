@@ -0,0 +1,204 @@
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"proj/golite/token"
+	"sort"
+)
+
+// Severity tells how serious a diagnostic is
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// Code identifies the kind of a diagnostic, the first letter tells the stage that reports it
+type Code string
+
+const (
+	// scanner
+	IllegalChar Code = "L001" // a character that cannot start any token
+	BadString   Code = "L002" // a string literal with an unknown escape or not closed on its line
+
+	// parser
+	UnexpectedToken Code = "P001" // a token that does not fit in the grammar at this point
+	ExpectedEOF     Code = "P002" // tokens left after the last function
+
+	// reading ILOC back from its text
+	BadInstruction Code = "P003" // a line of an .iloc file that is not an ILOC instruction
+	UndefinedLabel Code = "P004" // a branch to a label or a call to a function that is not defined
+	InvalidILoc    Code = "P005" // ILOC rejected by ir.Verify, in the builds verifying it
+
+	// semantic analysis
+	PackageNotMain Code = "S001" // the package is not named main
+	Redeclared     Code = "S002" // a struct, variable or function declared twice in the same scope
+	Undefined      Code = "S003" // use of a name that has not been declared
+	TypeMismatch   Code = "S004" // an operand or value of the wrong type
+	NotAssignable  Code = "S005" // the left-hand side of an assignment cannot be assigned to
+	ArgCount       Code = "S006" // wrong number of arguments in a call
+	ArgType        Code = "S007" // an argument of the wrong type in a call
+	ReturnType     Code = "S008" // a returned value that does not match the signature
+	UnknownField   Code = "S009" // a selector naming a field that does not exist
+	BadIndex       Code = "S010" // an index of a value that is not an array or slice, or a constant one out of range
+	MissingReturn  Code = "S011" // a function returning a value whose body may end without a return
+
+	// toolchain building and running the executable
+	ToolNotFound Code = "T001" // the assembler, linker or emulator is not installed
+	ToolFailed   Code = "T002" // the assembler, linker or emulator reported an error
+
+	// running a program with the interpreter
+	Runtime Code = "R001" // the program failed, e.g. a division by zero or a nil pointer dereference
+
+	// anything else
+	Unreadable Code = "F001" // the source file or stream could not be read
+	Internal   Code = "I001" // the compiler failed on its own
+)
+
+// Pos is a location in a source file, Line and Col start at 1 and are 0 when unknown
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+// At returns the position of the given token, a nil token gives an unknown position
+func At(tok *token.Token) Pos {
+	if tok == nil {
+		return Pos{}
+	}
+	return Pos{Line: tok.LineNum, Col: tok.Col}
+}
+
+// AtSpan returns the position of the start of the given span
+func AtSpan(span token.Span) Pos {
+	return Pos{Line: span.Start.Line, Col: span.Start.Col}
+}
+
+func (pos Pos) String() string {
+	out := bytes.Buffer{}
+	out.WriteString(pos.File)
+	if pos.Line > 0 {
+		if out.Len() > 0 {
+			out.WriteString(":")
+		}
+		out.WriteString(fmt.Sprintf("%v", pos.Line))
+		if pos.Col > 0 {
+			out.WriteString(fmt.Sprintf(":%v", pos.Col))
+		}
+	}
+	return out.String()
+}
+
+// Diagnostic is a single message reported by a stage of the compiler
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Pos      Pos
+	Message  string
+	Notes    []string // additional lines giving context to the message
+}
+
+// Errorf creates an error diagnostic with a formatted message
+func Errorf(code Code, pos Pos, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Error, code, pos, fmt.Sprintf(format, args...), nil}
+}
+
+// WithNote returns a copy of the diagnostic with one more note attached
+func (d Diagnostic) WithNote(format string, args ...interface{}) Diagnostic {
+	notes := append([]string{}, d.Notes...)
+	d.Notes = append(notes, fmt.Sprintf(format, args...))
+	return d
+}
+
+// String returns the diagnostic in the form "file:line:col: error[CODE]: message" followed by its notes
+func (d Diagnostic) String() string {
+	out := bytes.Buffer{}
+	if pos := d.Pos.String(); pos != "" {
+		out.WriteString(pos)
+		out.WriteString(": ")
+	}
+	out.WriteString(fmt.Sprintf("%v[%v]: %v", d.Severity, d.Code, d.Message))
+	for _, note := range d.Notes {
+		out.WriteString("\n\tnote: ")
+		out.WriteString(note)
+	}
+	return out.String()
+}
+
+// List is a collection of diagnostics, sortable by position
+type List []Diagnostic
+
+func (l List) Len() int      { return len(l) }
+func (l List) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l List) Less(i, j int) bool {
+	a, b := l[i].Pos, l[j].Pos
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	if a.Col != b.Col {
+		return a.Col < b.Col
+	}
+	return l[i].Code < l[j].Code
+}
+
+// Sort orders the diagnostics by file, line, column and code, keeping the reporting order of equal ones
+func (l List) Sort() { sort.Stable(l) }
+
+// Dedup returns the list without the diagnostics that are identical to an earlier one
+func (l List) Dedup() List {
+	seen := make(map[string]bool)
+	res := List{}
+	for _, d := range l {
+		key := d.String()
+		if !seen[key] {
+			seen[key] = true
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// SetFile fills in the file of every diagnostic that does not have one yet
+func (l List) SetFile(file string) {
+	for i := range l {
+		if l[i].Pos.File == "" {
+			l[i].Pos.File = file
+		}
+	}
+}
+
+// HasErrors returns true if at least one diagnostic is an error
+func (l List) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the diagnostics to out, one per line
+func (l List) Print(out io.Writer) {
+	for _, d := range l {
+		fmt.Fprintln(out, d.String())
+	}
+}
@@ -0,0 +1,46 @@
+package diag
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test1(t *testing.T) {
+	d := Errorf(TypeMismatch, Pos{"a.golite", 3, 7}, "cannot add %v and %v", "int", "bool")
+	d = d.WithNote("the left operand is %v", "int")
+
+	expected := "a.golite:3:7: error[S004]: cannot add int and bool\n\tnote: the left operand is int"
+	if d.String() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, d.String())
+	}
+
+	// a position without a column or a file is printed without them
+	d = Errorf(Undefined, Pos{Line: 2}, "x has not been defined")
+	if d.String() != "2: error[S003]: x has not been defined" {
+		t.Errorf("\nExpected: position without file and column; Got %v\n", d.String())
+	}
+}
+
+func Test2(t *testing.T) {
+	l := List{
+		Errorf(Undefined, Pos{Line: 9}, "b has not been defined"),
+		Errorf(TypeMismatch, Pos{Line: 2, Col: 5}, "mismatch"),
+		Errorf(Undefined, Pos{Line: 9}, "b has not been defined"),
+		Errorf(Undefined, Pos{Line: 2, Col: 1}, "a has not been defined"),
+	}
+	l.SetFile("t.golite")
+	l.Sort()
+	l = l.Dedup()
+
+	out := bytes.Buffer{}
+	l.Print(&out)
+	expected := "t.golite:2:1: error[S003]: a has not been defined\n" +
+		"t.golite:2:5: error[S004]: mismatch\n" +
+		"t.golite:9: error[S003]: b has not been defined\n"
+	if out.String() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, out.String())
+	}
+	if !l.HasErrors() {
+		t.Errorf("\nExpected: errors; Got none\n")
+	}
+}
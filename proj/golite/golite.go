@@ -1,128 +1,451 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"proj/golite/arm"
-	ct "proj/golite/context"
+	"proj/golite/arm/regalloc"
+	"proj/golite/ast"
+	"proj/golite/compiler"
+	"proj/golite/diag"
+	"proj/golite/interp"
 	"proj/golite/ir"
-	ps "proj/golite/parser"
-	"proj/golite/sa"
-	sc "proj/golite/scanner"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+	"proj/golite/ir/sim"
+	"proj/golite/ir/ssa"
+	"proj/golite/opt"
+	"proj/golite/toolchain"
 	"strings"
 )
 
-// StartCompile starts the compilation process of the compiler
-func StartCompile(ctx ct.CompilerContext) []string {
-	scanner := sc.New(ctx)
-	parser := ps.New(*scanner)
-	ast := parser.Parse()
-	//fmt.Println(ast)
-	globalSymtabl := sa.PerformSA(ast)
-	globalFuncFrag := ast.TranslateToILocFunc([]*ir.FuncFrag{}, globalSymtabl)
-	//for _, funcFrag := range globalFuncFrag {
-	//	instructions := funcFrag.Body
-	//	for _, instruction := range instructions {
-	//		fmt.Println(instruction.String())
-	//	}
-	//}
-
-	armInstructString := arm.TranslateToAssembly(globalFuncFrag, globalSymtabl)
-	//for _, instruction := range armInstructString {
-	//	fmt.Println(instruction)
-	//}
-	return armInstructString
+// exitUsage is the status for a bad command line or an output that cannot be written, the
+// other statuses are given by compiler.Failure: 1 for syntax errors, 2 for semantic errors,
+// 3 for internal compiler errors and, like a bad command line, 4 for an unreadable source file
+const exitUsage = 4
+
+// exitToolchain is the status when the executable cannot be assembled, linked or started
+const exitToolchain = 5
+
+// exitRuntime is the status of golite run and -run-iloc when the program fails
+const exitRuntime = 6
+
+// artifact is one of the outputs of the compiler that golite can write
+type artifact struct {
+	ext   string                              // extension of the file it is written to
+	stage compiler.Stage                      // the stage producing it
+	lines func(res *compiler.Result) []string // returns nil if the stage has not been run
 }
 
+var (
+	tokensArtifact = artifact{".tokens", compiler.StageLex, func(res *compiler.Result) []string {
+		if res.Tokens == nil {
+			return nil
+		}
+		lines := []string{}
+		for _, tok := range res.Tokens {
+			lines = append(lines, tok.String())
+		}
+		return lines
+	}}
+	astArtifact = artifact{".ast", compiler.StageParse, func(res *compiler.Result) []string {
+		if res.Program == nil {
+			return nil
+		}
+		return []string{res.Program.String()}
+	}}
+	astSpansArtifact = artifact{".ast", compiler.StageParse, func(res *compiler.Result) []string {
+		if res.Program == nil {
+			return nil
+		}
+		return []string{strings.TrimSuffix(ast.Dump(res.Program), "\n")}
+	}}
+	ilocArtifact = artifact{".iloc", compiler.StageILoc, func(res *compiler.Result) []string {
+		if res.FuncFrags == nil {
+			return nil
+		}
+		return res.ILocLines()
+	}}
+	cfgArtifact = artifact{".dot", compiler.StageILoc, func(res *compiler.Result) []string {
+		if res.FuncFrags == nil {
+			return nil
+		}
+		return cfg.DOT(cfg.BuildAll(res.FuncFrags))
+	}}
+	livenessArtifact = artifact{".live", compiler.StageILoc, func(res *compiler.Result) []string {
+		if res.FuncFrags == nil {
+			return nil
+		}
+		return dataflow.DumpLiveness(cfg.BuildAll(res.FuncFrags))
+	}}
+	ssaArtifact = artifact{".ssa", compiler.StageILoc, func(res *compiler.Result) []string {
+		if res.FuncFrags == nil {
+			return nil
+		}
+		return ssa.Dump(ssa.BuildAll(res.FuncFrags))
+	}}
+	armArtifact = artifact{".s", compiler.StageAssembly, func(res *compiler.Result) []string {
+		return res.Assembly
+	}}
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runCommand(os.Args[2:]))
+	}
+
+	// report bad flags with our own exit status instead of the flag package's
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
 
 	// Define all optional flags for the compiler
 	lexOpt := flag.Bool("lex", false, "Send to standard-out the tokens from scanner.")
 	astOpt := flag.Bool("ast", false, "Send to standard-out the tokens from parser.")
+	spansOpt := flag.Bool("spans", false, "With -ast, send to standard-out the tree of nodes with their source spans.")
 	ilocOpt := flag.Bool("iloc", false, "Send to standard-out the tokens from IR")
-	armOpt := flag.Bool("S", false, "Send to standard-out the tokens of translating to Arm code")
-	flag.Parse()
+	cfgOpt := flag.Bool("cfg", false, "Send to standard-out the control-flow graph of each function, in the Graphviz DOT format")
+	livenessOpt := flag.Bool("dump-liveness", false, "Send to standard-out the ILOC of each function with the registers live after each instruction")
+	ssaOpt := flag.Bool("dump-ssa", false, "Send to standard-out the SSA form of the ILOC of each function, by block, with the errors of the SSA verifier")
+	armOpt := flag.Bool("S", false, "Write the Arm code of translating each program to <name>.s")
+	emitAllOpt := flag.Bool("emit-all", false, "Write the tokens, AST, ILOC and Arm code of each program to <name>.tokens, <name>.ast, <name>.iloc and <name>.s, as far as the compilation goes")
+	outOpt := flag.String("o", "", "Write the output to this file instead, - for standard-out. Only for a single program.")
+	outDirOpt := flag.String("outdir", ".", "Directory of the <name>.* files written by -S and -emit-all, and of the <name> executables written by -build")
+	buildOpt := flag.Bool("build", false, "Assemble and link each program into the executable <name>, or the file given by -o")
+	runOpt := flag.Bool("run", false, "Build each program and run it, with the standard-in and standard-out of golite")
+	runILocOpt := flag.Bool("run-iloc", false, "Run the ILOC of each program with the simulator, and report on standard-error the instructions executed per function")
+	regAllocOpt := flag.String("regalloc", regalloc.Linear.String(), "How the registers of the Arm code are allocated: "+regalloc.StrategyNames()+", none spilling every register to the stack")
+	flag.Bool("O0", false, "Do not optimize the ILOC, the default")
+	o1Opt := flag.Bool("O1", false, "Optimize the ILOC with one round of constant and copy propagation, dead-code elimination and CFG simplification")
+	o2Opt := flag.Bool("O2", false, "Optimize the ILOC with rounds of the -O1 passes until none changes it")
+	peepholeOpt := flag.Bool("peephole", false, "Rewrite the Arm code with the peephole optimizer, and report on standard-error the number of instructions it removed")
+	printAfterOpt := flag.String("print-after", "", "Send to standard-error the ILOC after each run of this optimization pass: "+strings.Join(opt.PassNames(), "|")+", or all")
+	config := toolchain.DefaultConfig()
+	flag.StringVar(&config.CC, "cc", config.CC, "C compiler assembling and linking the Arm code, also set by GOLITE_CC")
+	emulatorOpt := flag.String("emulator", strings.Join(config.Emulator, " "), "Command running the executables, empty to run them directly, also set by GOLITE_EMULATOR")
 	// Define the usage statement for the compiler
 	flag.Usage = func() {
 		out := flag.CommandLine.Output()
-		fmt.Fprintf(out, "Usage of golite: [flags] program.golite  \n")
+		fmt.Fprintf(out, "Usage of golite: [flags] program.golite [program.golite ...]  \n")
+		fmt.Fprintf(out, "       golite run program.golite  (interpret the program, without compiling it to Arm code)\n")
+		fmt.Fprintf(out, "A program is read from standard-in if its path is -, and a program.iloc file holds the ILOC printed by -iloc, given to the backend\n")
 		flag.PrintDefaults()
-		fmt.Fprintf(out, "Usage of golite: [flags] program.golite  \n")
+		fmt.Fprintf(out, "Exit status: 0 on success, 1 for syntax errors, 2 for semantic errors, 3 for internal compiler errors, 4 for usage errors or unreadable sources, 5 for toolchain failures and 6 for programs failing in -run-iloc or golite run\n")
+	}
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(exitUsage)
 	}
 
-	// Create the compiler configuration struct
-	ctx := ct.New(false, false, false, false, "")
-	//fmt.Println("os.Args    :", os.Args)
-	//fmt.Println("flag.Args():", flag.Args())
-
-	// Verify that the user provided the input source file
+	// Verify that the user provided the input source files
 	if flag.NArg() < 1 {
 		flag.Usage()
+		os.Exit(exitUsage)
+	}
+	// The sourcePaths are all the remaining arguments on the command line
+	sourcePaths := flag.Args()
+	config.Emulator = strings.Fields(*emulatorOpt)
+	regAlloc, validRegAlloc := regalloc.ParseStrategy(*regAllocOpt)
+	if !validRegAlloc {
+		fmt.Fprintf(os.Stderr, "error: -regalloc must be one of %v\n", regalloc.StrategyNames())
+		os.Exit(exitUsage)
+	}
+	// the highest level given wins
+	optLevel := opt.O0
+	if *o2Opt {
+		optLevel = opt.O2
+	} else if *o1Opt {
+		optLevel = opt.O1
+	}
+	if !validPassName(*printAfterOpt) {
+		fmt.Fprintf(os.Stderr, "error: -print-after must be one of %v or all\n", strings.Join(opt.PassNames(), "|"))
+		os.Exit(exitUsage)
+	}
+	building := *buildOpt || *runOpt
+
+	// Select the outputs, by default the listings go to standard-out and the Arm code to a file
+	var artifacts []artifact
+	toFiles := false
+	if *emitAllOpt {
+		artifacts = []artifact{tokensArtifact, astArtifact, ilocArtifact, armArtifact}
+		if *spansOpt {
+			artifacts[1] = astSpansArtifact
+		}
+		toFiles = true
+	} else if *lexOpt {
+		artifacts = []artifact{tokensArtifact}
+	} else if *astOpt && *spansOpt {
+		artifacts = []artifact{astSpansArtifact}
+	} else if *astOpt {
+		artifacts = []artifact{astArtifact}
+	} else if *ilocOpt {
+		artifacts = []artifact{ilocArtifact}
+	} else if *cfgOpt {
+		artifacts = []artifact{cfgArtifact}
+	} else if *livenessOpt {
+		artifacts = []artifact{livenessArtifact}
+	} else if *ssaOpt {
+		artifacts = []artifact{ssaArtifact}
+	} else if *armOpt {
+		artifacts = []artifact{armArtifact}
+		toFiles = true
+	} else if !building && !*runILocOpt && *printAfterOpt == "" {
 		return
-	} else {
-		// The sourcePath is always the first argument from the remaining arguments on the command line
-		ctx.SetSourcePath(flag.Arg(0))
-		ctx.SetLex(*lexOpt)
-		ctx.SetAst(*astOpt)
-		ctx.SetILoc(*ilocOpt)
-		ctx.SetArm(*armOpt)
-	}
-
-	// Check if the source file path exists
-	if _, err := os.Stat(ctx.SourcePath()); err != nil {
-		panic(err)
-	}
-
-	// TO-DO : Uncomment if ready to compile a file
-	//StartCompile(ctx)
-	if ctx.OutputLex() {
-		scanner := sc.New(*ctx)
-		scanner.Tokens()
-	} else if ctx.OutputAst() {
-		scanner := sc.New(*ctx)
-		parser := ps.New(*scanner)
-		ast := parser.Parse()
-		fmt.Println(ast.String())
-	} else if ctx.OutputILoc() {
-		scanner := sc.New(*ctx)
-		parser := ps.New(*scanner)
-		ast := parser.Parse()
-		//fmt.Println(ast)
-		globalSymtabl := sa.PerformSA(ast)
-		globalFuncFrag := ast.TranslateToILocFunc([]*ir.FuncFrag{}, globalSymtabl)
-		for _, funcFrag := range globalFuncFrag {
-			instructions := funcFrag.Body
-			for _, instruction := range instructions {
-				fmt.Println(instruction.String())
+	}
+	if *outOpt != "" && *outOpt != "-" && (len(sourcePaths) > 1 || len(artifacts) > 1) {
+		fmt.Fprintf(os.Stderr, "error: -o names a single file, it cannot be used with several programs or -emit-all\n")
+		os.Exit(exitUsage)
+	}
+	if *outOpt != "" && building && (len(artifacts) > 0 || *outOpt == "-") {
+		fmt.Fprintf(os.Stderr, "error: with -build or -run, -o names the executable and cannot be used with other outputs\n")
+		os.Exit(exitUsage)
+	}
+
+	// Only run the pipeline as far as the requested outputs need
+	opts := compiler.Options{StopAfter: compiler.StageLex, RegAlloc: regAlloc, OptLevel: optLevel, PrintAfter: *printAfterOpt, Peephole: *peepholeOpt}
+	if building {
+		opts.StopAfter = compiler.StageAssembly
+	} else if *runILocOpt || *printAfterOpt != "" {
+		opts.StopAfter = compiler.StageILoc
+	}
+	for _, a := range artifacts {
+		if a.stage > opts.StopAfter {
+			opts.StopAfter = a.stage
+		}
+	}
+
+	// Compile every program even if some fail, and exit with the status of the worst failure
+	status := 0
+	for _, sourcePath := range sourcePaths {
+		var res *compiler.Result
+		if sourcePath == "-" {
+			res = compiler.CompileReader("<stdin>", os.Stdin, opts)
+		} else {
+			res = compiler.Compile(sourcePath, opts)
+		}
+		res.Diagnostics.Print(os.Stderr)
+		for _, line := range res.PrintedAfter {
+			fmt.Fprintln(os.Stderr, line)
+		}
+		if *peepholeOpt && res.Assembly != nil {
+			fmt.Fprintf(os.Stderr, "peephole optimizer: %v instructions removed from %v\n", res.Removed, sourcePath)
+		}
+		if code := res.Failure.ExitCode(); code > status {
+			status = code
+		}
+
+		asmPath := "" // the Arm code written by -S or -emit-all is also the one built
+		for _, a := range artifacts {
+			// a failed compilation only leaves the intermediate artifacts of -emit-all
+			lines := a.lines(res)
+			if lines == nil || (res.Failure != compiler.Success && !*emitAllOpt) {
+				continue
+			}
+			dest := *outOpt
+			if building {
+				dest = ""
+			}
+			if dest == "" && !toFiles {
+				dest = "-"
+			} else if dest == "" {
+				dest = filepath.Join(*outDirOpt, outputName(sourcePath)+a.ext)
+			}
+			if sourcePath != "-" && dest != "-" && filepath.Clean(dest) == filepath.Clean(sourcePath) {
+				// the ILOC of an .iloc program is the program itself
+				continue
+			}
+			if err := writeLines(dest, lines); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				status = exitUsage
+			} else if a.ext == armArtifact.ext && dest != "-" {
+				asmPath = dest
 			}
 		}
-	} else if ctx.OutputArm() {
 
-		baseName := filepath.Base(ctx.SourcePath())
-		ext := filepath.Ext(ctx.SourcePath())
-		fileName := strings.TrimSuffix(baseName, ext) // get the file name with the extension removed
-		fileName = fileName + ".s"
+		if *runILocOpt && res.Failure == compiler.Success {
+			if code := runILoc(sourcePath, res.FuncFrags); code > status {
+				status = code
+			}
+		}
+		if building && res.Failure == compiler.Success {
+			exePath := *outOpt
+			if exePath == "" && *buildOpt {
+				exePath = filepath.Join(*outDirOpt, outputName(sourcePath))
+			}
+			if code := buildAndRun(config, outputName(sourcePath), res.Assembly, asmPath, exePath, *runOpt); code > status {
+				status = code
+			}
+		}
+	}
+	os.Exit(status)
+}
+
+// validPassName returns true if name may be given to -print-after, empty if it is not given
+func validPassName(name string) bool {
+	if name == "" || name == "all" {
+		return true
+	}
+	for _, pass := range opt.PassNames() {
+		if name == pass {
+			return true
+		}
+	}
+	return false
+}
+
+// runILoc runs the ILOC of a program with the simulator, then reports the number of instructions
+// executed per function. It returns the exit status of golite.
+func runILoc(sourcePath string, frags []*ir.FuncFrag) int {
+	m, err := sim.Run(frags, os.Stdin, os.Stdout)
+	if err != nil {
+		if _, isRuntime := err.(*sim.RuntimeError); !isRuntime {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		diag.List{diag.Errorf(diag.Runtime, diag.Pos{File: sourcePath}, "%v", err)}.Print(os.Stderr)
+	}
+
+	fmt.Fprintf(os.Stderr, "instructions executed in %v:\n", sourcePath)
+	total := 0
+	for _, frag := range frags {
+		if count, called := m.Counts[frag.Label]; called {
+			fmt.Fprintf(os.Stderr, "%12d  %v\n", count, frag.Label)
+			total += count
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%12d  total\n", total)
+	if err != nil {
+		return exitRuntime
+	}
+	return 0
+}
+
+// buildAndRun builds the Arm code of a program into the executable exePath, then runs it if run
+// is set. The code is taken from asmPath if it has already been written, and temporary files are
+// used for empty paths. It returns the exit status of golite.
+func buildAndRun(config toolchain.Config, name string, assembly []string, asmPath string, exePath string, run bool) int {
+	tmpDir, err := os.MkdirTemp("", "golite")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if asmPath == "" {
+		asmPath = filepath.Join(tmpDir, name+".s")
+		if err := writeLines(asmPath, assembly); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+	}
+	if exePath == "" {
+		exePath = filepath.Join(tmpDir, name)
+	}
+	diagnostics := diag.List(config.Build(asmPath, exePath))
+	if run && !diagnostics.HasErrors() {
+		diagnostics = append(diagnostics, config.CheckRun(exePath)...)
+	}
+	diagnostics.Print(os.Stderr)
+	if diagnostics.HasErrors() {
+		return exitToolchain
+	}
+	if !run {
+		return 0
+	}
 
-		armCode := StartCompile(*ctx)
-		armCodeStr := "" // dump arm code into a string
-		for _, line := range armCode {
-			armCodeStr = armCodeStr + line + "\n"
+	// the exit status of the program itself is not golite's, only failing to start it is reported
+	cmd := config.Command(exePath)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, exited := err.(*exec.ExitError); !exited {
+			diag.List{diag.Errorf(diag.ToolFailed, diag.Pos{}, "cannot run %v: %v", name, err)}.Print(os.Stderr)
+			return exitToolchain
 		}
+	}
+	return 0
+}
 
-		f, err := os.Create(fileName)
-		if err != nil {
-			log.Fatal(err)
+// runCommand interprets the program given by args, with the standard-in and standard-out of
+// golite, and returns the exit status of golite
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("golite run", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of golite run: program.golite, or - to read the program from standard-in\n")
+		fmt.Fprintf(flags.Output(), "Exit status: as for compiling the program, or 6 if it fails while running\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
 		}
-		defer f.Close()
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
 
-		_, err2 := f.WriteString(armCodeStr)
-		if err2 != nil {
-			log.Fatal(err2)
+	// a program read from standard-in leaves the input of the program empty
+	sourcePath := flags.Arg(0)
+	opts := compiler.Options{StopAfter: compiler.StageSemantic}
+	var res *compiler.Result
+	if sourcePath == "-" {
+		sourcePath = "<stdin>"
+		res = compiler.CompileReader(sourcePath, os.Stdin, opts)
+	} else {
+		res = compiler.Compile(sourcePath, opts)
+	}
+	res.Diagnostics.Print(os.Stderr)
+	if res.Failure != compiler.Success {
+		return res.Failure.ExitCode()
+	}
+	if err := interp.Run(res.Program, os.Stdin, os.Stdout); err != nil {
+		runtimeErr, isRuntime := err.(*interp.RuntimeError)
+		if !isRuntime {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
 		}
+		d := runtimeErr.Diagnostic()
+		d.Pos.File = sourcePath
+		diag.List{d}.Print(os.Stderr)
+		return exitRuntime
+	}
+	return 0
+}
+
+// outputName returns the name of the output files of a program: its file name with the extension removed
+func outputName(sourcePath string) string {
+	if sourcePath == "-" {
+		return "stdin"
+	}
+	baseName := filepath.Base(sourcePath)
+	return strings.TrimSuffix(baseName, filepath.Ext(baseName))
+}
+
+// writeLines writes the lines to the file at path, or to standard-out if path is -
+func writeLines(path string, lines []string) error {
+	if path == "-" {
+		return writeLinesTo(os.Stdout, lines)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeLinesTo(f, lines); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-		fmt.Println("Done!")
+func writeLinesTo(out io.Writer, lines []string) error {
+	w := bufio.NewWriter(out)
+	for _, line := range lines {
+		w.WriteString(line)
+		w.WriteString("\n")
 	}
+	return w.Flush()
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain runs golite instead of the tests when the test binary is started by golite
+func TestMain(m *testing.M) {
+	if os.Getenv("GOLITE_TEST_MAIN") == "1" {
+		os.Args = append([]string{"golite"}, os.Args[1:]...)
+		main()
+	}
+	os.Exit(m.Run())
+}
+
+// golite runs golite with the arguments and the standard-in, and returns its standard-out and
+// exit status
+func golite(t *testing.T, stdin string, args ...string) (string, int) {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GOLITE_TEST_MAIN=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	out := bytes.Buffer{}
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if exitErr, isExit := err.(*exec.ExitError); isExit {
+		return out.String(), exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), 0
+}
+
+func Test1(t *testing.T) {
+	// the listings of a program read from standard-in go to standard-out
+	src, err := os.ReadFile("iloc/test3_iloc.golite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("iloc/test3_iloc.iloc.expected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, status := golite(t, string(src), "-iloc", "-")
+	if status != 0 || out != string(expected) {
+		t.Errorf("\nExpected: %q, exit status 0\nGot: %q, exit status %v\n", expected, out, status)
+	}
+	for _, flag := range []string{"-lex", "-ast", "-cfg"} {
+		if out, status := golite(t, string(src), flag, "-"); status != 0 || out == "" {
+			t.Errorf("\nExpected: the listing of %v; Got %q, exit status %v\n", flag, out, status)
+		}
+	}
+}
+
+func Test2(t *testing.T) {
+	// golite run - interprets the program read from standard-in
+	src := "package main;\nimport \"fmt\";\nfunc main() {\n\tfmt.Println(6 * 7, true);\n}\n"
+	if out, status := golite(t, src, "run", "-"); status != 0 || out != "42 true\n" {
+		t.Errorf("\nExpected: %q, exit status 0\nGot: %q, exit status %v\n", "42 true\n", out, status)
+	}
+	if out, status := golite(t, "package main;\n", "run", "-"); status != 1 || out != "" {
+		t.Errorf("\nExpected: a syntax error, exit status 1\nGot: %q, exit status %v\n", out, status)
+	}
+}
@@ -283,4 +283,29 @@ func Test11(t *testing.T) {
 			fmt.Println(instruction.String())
 		}
 	}
-}
\ No newline at end of file
+}
+
+func Test12(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test12_iloc.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+	//fmt.Println("AST Printout:")
+	//fmt.Println(ast.String())
+
+	globalSymTable := sa.PerformSA(ast)
+	if globalSymTable == nil {
+		t.Errorf("\nExpected: returned symbol table; Got nil\n")
+	}
+
+	globalFuncFrag := ast.TranslateToILocFunc([]*ir.FuncFrag{}, globalSymTable)
+	if globalFuncFrag == nil {
+		t.Errorf("\nExpected: returned FuncFrag; Got nil\n")
+	}
+	for _, funcFrag := range globalFuncFrag {
+		instructions := funcFrag.Body
+		for _, instruction := range instructions {
+			fmt.Println(instruction.String())
+		}
+	}
+}
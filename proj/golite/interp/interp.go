@@ -0,0 +1,583 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"proj/golite/ast"
+	"proj/golite/diag"
+	"proj/golite/token"
+	"strconv"
+	"strings"
+)
+
+// maxDepth bounds the nesting of function calls, deeper recursions fail with a stack overflow
+const maxDepth = 100000
+
+// RuntimeError is an error of the interpreted program, such as a division by zero
+type RuntimeError struct {
+	Span    token.Span // the expression or statement that failed
+	Message string
+	Stack   []string // the calls leading to the error, innermost first
+}
+
+func (err *RuntimeError) Error() string {
+	return fmt.Sprintf("%v: runtime error: %v", err.Span.Start, err.Message)
+}
+
+// Diagnostic returns the error as a diagnostic, with the calls as notes
+func (err *RuntimeError) Diagnostic() diag.Diagnostic {
+	d := diag.Errorf(diag.Runtime, diag.AtSpan(err.Span), "%v", err.Message)
+	for _, call := range err.Stack {
+		d = d.WithNote("%v", call)
+	}
+	return d
+}
+
+// object is a struct allocated by new
+type object struct {
+	structName string
+	fields     map[string]interface{}
+	deleted    bool // set by delete, any later access is an error
+}
+
+// array is a value of an array type, copied when it is assigned, passed or returned. The values
+// of a slice type are []interface{}, appended to the way the ILOC of append does.
+type array struct {
+	elems []interface{}
+}
+
+// frame holds the parameters and local variables of a function call
+type frame struct {
+	function *ast.Function
+	vars     map[string]interface{}
+	call     token.Span // where the function has been called from
+}
+
+// Interpreter evaluates a program: the values of its variables are int64 for int, bool for
+// bool, *object for the pointers to structs, nil being a nil *object, *array for arrays and
+// []interface{} for slices
+type Interpreter struct {
+	program   *ast.Program
+	functions map[string]*ast.Function
+	structs   map[string]*ast.TypeDeclaration
+	globals   map[string]interface{}
+	frames    []*frame
+
+	in  *bufio.Reader
+	out *bufio.Writer
+}
+
+// returnSignal stops the execution of the statements of a function, with the value it returns
+type returnSignal struct {
+	value interface{}
+}
+
+// New returns an interpreter of a program that has passed semantic analysis, reading fmt.Scan
+// from stdin and writing fmt.Print and fmt.Println to stdout
+func New(program *ast.Program, stdin io.Reader, stdout io.Writer) *Interpreter {
+	interp := &Interpreter{
+		program:   program,
+		functions: map[string]*ast.Function{},
+		structs:   map[string]*ast.TypeDeclaration{},
+		globals:   map[string]interface{}{},
+		in:        bufio.NewReader(stdin),
+		out:       bufio.NewWriter(stdout),
+	}
+	if program.Types != nil {
+		for i := range program.Types.TypeDeclarations {
+			typeDecl := &program.Types.TypeDeclarations[i]
+			interp.structs[typeDecl.Ident.Id] = typeDecl
+		}
+	}
+	if program.Declarations != nil {
+		interp.declare(interp.globals, program.Declarations)
+	}
+	if program.Functions != nil {
+		for i := range program.Functions.Functions {
+			function := &program.Functions.Functions[i]
+			interp.functions[function.Ident.Id] = function
+		}
+	}
+	return interp
+}
+
+// Run interprets a program from its main function, see New
+func Run(program *ast.Program, stdin io.Reader, stdout io.Writer) error {
+	return New(program, stdin, stdout).Run()
+}
+
+// Run calls the main function of the program, the error is a *RuntimeError if the program failed
+func (interp *Interpreter) Run() (err error) {
+	defer func() {
+		if flushErr := interp.out.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, isRuntime := r.(*RuntimeError)
+			if !isRuntime {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+
+	main := interp.functions["main"]
+	if main == nil {
+		return &RuntimeError{Span: interp.program.Span, Message: "the program has no main function"}
+	}
+	interp.call(main, nil, main.Span)
+	return nil
+}
+
+// fail stops the program with a runtime error at the node
+func (interp *Interpreter) fail(span token.Span, format string, args ...interface{}) {
+	err := &RuntimeError{Span: span, Message: fmt.Sprintf(format, args...)}
+	for i := len(interp.frames) - 1; i >= 0; i-- {
+		f := interp.frames[i]
+		if f.function.Ident.Id == "main" {
+			err.Stack = append(err.Stack, "in main")
+		} else {
+			err.Stack = append(err.Stack, fmt.Sprintf("in %v called at %v", f.function.Ident.Id, f.call.Start))
+		}
+	}
+	panic(err)
+}
+
+// declare adds the variables of the declarations to vars, with their zero values
+func (interp *Interpreter) declare(vars map[string]interface{}, declarations *ast.Declarations) {
+	for _, declaration := range declarations.Declarations {
+		for _, ident := range declaration.Ids.Idents {
+			vars[ident.Id] = zeroValue(declaration.Ty)
+		}
+	}
+}
+
+func zeroValue(ty *ast.Type) interface{} {
+	return zeroLiteral(ty.TypeLiteral)
+}
+
+// zeroLiteral returns the zero value of a type literal, the literal of an array being "[N]elem"
+// and the one of a slice "[]elem"
+func zeroLiteral(literal string) interface{} {
+	switch {
+	case literal == "int":
+		return int64(0)
+	case literal == "bool":
+		return false
+	case literal == "string":
+		return ""
+	case strings.HasPrefix(literal, "[]"):
+		return []interface{}(nil)
+	case strings.HasPrefix(literal, "["):
+		end := strings.Index(literal, "]")
+		length, _ := strconv.Atoi(literal[1:end])
+		arr := &array{make([]interface{}, length)}
+		for i := range arr.elems {
+			arr.elems[i] = zeroLiteral(literal[end+1:])
+		}
+		return arr
+	default:
+		return (*object)(nil)
+	}
+}
+
+// copyValue returns a copy of an array, which has value semantics, and any other value as it is
+func copyValue(value interface{}) interface{} {
+	if arr, isArray := value.(*array); isArray {
+		return &array{append([]interface{}{}, arr.elems...)}
+	}
+	return value
+}
+
+// call runs a function with the values of its arguments and returns its result, nil if it has none
+func (interp *Interpreter) call(function *ast.Function, args []interface{}, at token.Span) interface{} {
+	if len(interp.frames) >= maxDepth {
+		interp.fail(at, "stack overflow: more than %v nested calls", maxDepth)
+	}
+	f := &frame{function, map[string]interface{}{}, at}
+	if function.Parameters != nil {
+		for i, param := range function.Parameters.Decls {
+			f.vars[param.Ident.Id] = args[i]
+		}
+	}
+	if function.Declarations != nil {
+		interp.declare(f.vars, function.Declarations)
+	}
+
+	interp.frames = append(interp.frames, f)
+	ret := interp.execStatements(function.Statements)
+	interp.frames = interp.frames[:len(interp.frames)-1]
+
+	if ret != nil {
+		return ret.value
+	}
+	if function.ReturnType != nil && function.ReturnType.Ty != nil {
+		// the end of a function returning a value is only reachable if the program has no return
+		return zeroValue(function.ReturnType.Ty)
+	}
+	return nil
+}
+
+// lookup returns the variables holding name: the ones of the current call, or else the globals
+func (interp *Interpreter) lookup(ident *ast.IdentLiteral) map[string]interface{} {
+	if len(interp.frames) > 0 {
+		vars := interp.frames[len(interp.frames)-1].vars
+		if _, exist := vars[ident.Id]; exist {
+			return vars
+		}
+	}
+	if _, exist := interp.globals[ident.Id]; exist {
+		return interp.globals
+	}
+	interp.fail(ident.Span, "%v has not been declared", ident.Id)
+	return nil
+}
+
+/******* Statements *******/
+
+// execStatements runs the statements in order, it returns non-nil if one of them returned
+func (interp *Interpreter) execStatements(stmts *ast.Statements) *returnSignal {
+	if stmts == nil {
+		return nil
+	}
+	for _, stmt := range stmts.Statements {
+		if ret := interp.exec(stmt.Stmt); ret != nil {
+			return ret
+		}
+	}
+	return nil
+}
+
+func (interp *Interpreter) exec(stmt ast.Stmt) *returnSignal {
+	switch s := stmt.(type) {
+	case *ast.Block:
+		return interp.execStatements(s.Statements)
+	case *ast.Assignment:
+		interp.assign(s.Lvalue, s.Expr)
+	case *ast.Read:
+		vars := interp.lookup(&s.Ident)
+		interp.out.Flush() // show the prompts printed so far before waiting for the input
+		if value, ok := interp.scanInt(); ok {
+			vars[s.Ident.Id] = value
+		}
+	case *ast.Print:
+		// the values are ints, bools and strings, spaced by fmt as in Go
+		values := []interface{}{}
+		for idx := range s.Args.Exprs {
+			values = append(values, interp.eval(&s.Args.Exprs[idx]))
+		}
+		if s.Method() == "Println" {
+			fmt.Fprintln(interp.out, values...)
+		} else {
+			fmt.Fprint(interp.out, values...)
+		}
+	case *ast.Conditional:
+		if interp.evalBool(s.Expr) {
+			return interp.exec(s.Block)
+		} else if s.ElseBlock != nil {
+			return interp.exec(s.ElseBlock)
+		}
+	case *ast.Loop:
+		for interp.evalBool(s.Expr) {
+			if ret := interp.exec(s.Block); ret != nil {
+				return ret
+			}
+		}
+	case *ast.Return:
+		if s.Expr == nil {
+			return &returnSignal{}
+		}
+		return &returnSignal{copyValue(interp.eval(s.Expr))}
+	case *ast.Invocation:
+		interp.invoke(&s.Ident, s.Args, s.Span)
+	default:
+		interp.fail(stmt.GetSpan(), "cannot run %T", stmt)
+	}
+	return nil
+}
+
+// assign stores the value of expr in the variable, field or element designated by the lvalue. As
+// in the ILOC of an assignment, the operands of the lvalue are evaluated before the value, and the
+// field or element is checked after it.
+func (interp *Interpreter) assign(lv *ast.LValue, expr *ast.Expression) {
+	vars := interp.lookup(&lv.Ident)
+	if len(lv.Selectors) == 0 {
+		vars[lv.Ident.Id] = copyValue(interp.eval(expr))
+		return
+	}
+	operand, span := vars[lv.Ident.Id], lv.Ident.Span
+	for i := range lv.Selectors[:len(lv.Selectors)-1] {
+		operand = interp.selectValue(operand, &lv.Selectors[i], span)
+		span = lv.Selectors[i].Span
+	}
+	last := &lv.Selectors[len(lv.Selectors)-1]
+	if last.Index == nil {
+		value := copyValue(interp.eval(expr))
+		obj := interp.deref(operand, span)
+		interp.field(obj, last.Ident)
+		obj.fields[last.Ident.Id] = value
+		return
+	}
+	index := interp.eval(last.Index).(int64)
+	elems := elemsOf(operand)
+	value := copyValue(interp.eval(expr))
+	interp.checkIndex(index, elems, last.Index.Span)
+	elems[index] = value
+}
+
+// invoke calls a function, or the built-in new, delete, len and append
+func (interp *Interpreter) invoke(ident *ast.IdentLiteral, args *ast.Arguments, at token.Span) interface{} {
+	switch ident.Id {
+	case "new":
+		structName := strings.TrimSpace(args.Exprs[0].String())
+		typeDecl := interp.structs[structName]
+		if typeDecl == nil {
+			interp.fail(at, "%v is not a struct", structName)
+		}
+		obj := &object{structName, map[string]interface{}{}, false}
+		for _, decl := range typeDecl.Fields.Decls {
+			obj.fields[decl.Ident.Id] = zeroValue(decl.Ty)
+		}
+		return obj
+	case "delete":
+		value := interp.eval(&args.Exprs[0])
+		if obj, _ := value.(*object); obj != nil {
+			interp.deref(obj, args.Exprs[0].Span).deleted = true
+		}
+		return nil
+	case "len":
+		value := interp.eval(&args.Exprs[0])
+		if str, isString := value.(string); isString {
+			return int64(len(str))
+		}
+		return int64(len(elemsOf(value)))
+	case "append":
+		values := []interface{}{}
+		for i := range args.Exprs {
+			values = append(values, interp.eval(&args.Exprs[i]))
+		}
+		elems := values[0].([]interface{})
+		for _, value := range values[1:] {
+			if len(elems) == cap(elems) {
+				// the block is full, the elements move to one twice as large, of at least one element
+				capacity := 2 * cap(elems)
+				if capacity == 0 {
+					capacity = 1
+				}
+				grown := make([]interface{}, len(elems), capacity)
+				copy(grown, elems)
+				elems = grown
+			}
+			elems = append(elems, value)
+		}
+		return elems
+	}
+
+	function := interp.functions[ident.Id]
+	if function == nil {
+		interp.fail(ident.Span, "function %v has not been defined", ident.Id)
+	}
+	values := []interface{}{}
+	if args != nil {
+		for i := range args.Exprs {
+			values = append(values, copyValue(interp.eval(&args.Exprs[i])))
+		}
+	}
+	return interp.call(function, values, at)
+}
+
+/******* Expressions *******/
+
+func (interp *Interpreter) evalBool(expr *ast.Expression) bool {
+	return interp.eval(expr).(bool)
+}
+
+func (interp *Interpreter) eval(expr *ast.Expression) interface{} {
+	value := interp.evalBoolTerm(expr.Left)
+	for i := range expr.Rights {
+		if value.(bool) {
+			return true // short-circuit of ||
+		}
+		value = interp.evalBoolTerm(&expr.Rights[i])
+	}
+	return value
+}
+
+func (interp *Interpreter) evalBoolTerm(term *ast.BoolTerm) interface{} {
+	value := interp.evalEqualTerm(term.Left)
+	for i := range term.Rights {
+		if !value.(bool) {
+			return false // short-circuit of &&
+		}
+		value = interp.evalEqualTerm(&term.Rights[i])
+	}
+	return value
+}
+
+func (interp *Interpreter) evalEqualTerm(term *ast.EqualTerm) interface{} {
+	value := interp.evalRelationTerm(term.Left)
+	for i, op := range term.EqualOperator {
+		right := interp.evalRelationTerm(&term.Rights[i])
+		equal := value == right
+		if op == "!=" {
+			equal = !equal
+		}
+		value = equal
+	}
+	return value
+}
+
+func (interp *Interpreter) evalRelationTerm(term *ast.RelationTerm) interface{} {
+	value := interp.evalSimpleTerm(term.Left)
+	for i, op := range term.RelationOperators {
+		left, right := value.(int64), interp.evalSimpleTerm(&term.Rights[i]).(int64)
+		switch op {
+		case ">":
+			value = left > right
+		case "<":
+			value = left < right
+		case ">=":
+			value = left >= right
+		default:
+			value = left <= right
+		}
+	}
+	return value
+}
+
+func (interp *Interpreter) evalSimpleTerm(term *ast.SimpleTerm) interface{} {
+	value := interp.evalTerm(term.Left)
+	if str, isString := value.(string); isString {
+		// + concatenates strings
+		for i := range term.Rights {
+			str += interp.evalTerm(&term.Rights[i]).(string)
+		}
+		return str
+	}
+	for i, op := range term.SimpleTermOperators {
+		right := interp.evalTerm(&term.Rights[i]).(int64)
+		if op == "+" {
+			value = value.(int64) + right
+		} else {
+			value = value.(int64) - right
+		}
+	}
+	return value
+}
+
+func (interp *Interpreter) evalTerm(term *ast.Term) interface{} {
+	value := interp.evalUnaryTerm(term.Left)
+	for i, op := range term.TermOperators {
+		right := interp.evalUnaryTerm(&term.Rights[i]).(int64)
+		if op == "*" {
+			value = value.(int64) * right
+		} else if right == 0 {
+			interp.fail(term.Rights[i].Span, "integer divide by zero")
+		} else {
+			value = value.(int64) / right
+		}
+	}
+	return value
+}
+
+func (interp *Interpreter) evalUnaryTerm(term *ast.UnaryTerm) interface{} {
+	value := interp.evalSelectorTerm(term.SelectorTerm)
+	switch term.UnaryOperator {
+	case "!":
+		return !value.(bool)
+	case "-":
+		return -value.(int64)
+	}
+	return value
+}
+
+func (interp *Interpreter) evalSelectorTerm(term *ast.SelectorTerm) interface{} {
+	value := interp.evalFactor(term.Fact)
+	span := term.Fact.Span
+	for i := range term.Selectors {
+		value = interp.selectValue(value, &term.Selectors[i], span)
+		span = term.Selectors[i].Span
+	}
+	return value
+}
+
+// selectValue returns the field or element sel selects from operand, span being the one of operand
+func (interp *Interpreter) selectValue(operand interface{}, sel *ast.Selector, span token.Span) interface{} {
+	if sel.Index == nil {
+		return interp.field(interp.deref(operand, span), sel.Ident)
+	}
+	index := interp.eval(sel.Index).(int64)
+	elems := elemsOf(operand)
+	interp.checkIndex(index, elems, sel.Index.Span)
+	return elems[index]
+}
+
+func (interp *Interpreter) evalFactor(factor *ast.Factor) interface{} {
+	switch e := factor.Expr.(type) {
+	case *ast.IntLiteral:
+		return e.Value
+	case *ast.BoolLiteral:
+		return e.Value
+	case *ast.StringLiteral:
+		return e.Value
+	case *ast.NilNode:
+		return (*object)(nil)
+	case *ast.IdentLiteral:
+		return interp.lookup(e)[e.Id]
+	case *ast.InvocExpr:
+		return interp.invoke(&e.Ident, e.InnerArgs, e.Span)
+	case *ast.PriorityExpression:
+		return interp.eval(e.InnerExpression)
+	}
+	interp.fail(factor.Span, "cannot evaluate %T", factor.Expr)
+	return nil
+}
+
+// deref returns the struct a pointer points to, it fails on nil and deleted structs
+func (interp *Interpreter) deref(value interface{}, span token.Span) *object {
+	obj, _ := value.(*object)
+	if obj == nil {
+		interp.fail(span, "nil pointer dereference")
+	} else if obj.deleted {
+		interp.fail(span, "use of a deleted %v", obj.structName)
+	}
+	return obj
+}
+
+// elemsOf returns the elements of an array or a slice
+func elemsOf(value interface{}) []interface{} {
+	if arr, isArray := value.(*array); isArray {
+		return arr.elems
+	}
+	return value.([]interface{})
+}
+
+// checkIndex fails if index is out of the elements, span being the one of the index
+func (interp *Interpreter) checkIndex(index int64, elems []interface{}, span token.Span) {
+	if index < 0 || index >= int64(len(elems)) {
+		interp.fail(span, "index out of range [%v] with length %v", index, len(elems))
+	}
+}
+
+func (interp *Interpreter) field(obj *object, ident *ast.IdentLiteral) interface{} {
+	value, exist := obj.fields[ident.Id]
+	if !exist {
+		interp.fail(ident.Span, "%v has no field %v", obj.structName, ident.Id)
+	}
+	return value
+}
+
+/******* Input and output *******/
+
+// scanInt reads an integer the way fmt.Scan does, skipping the spaces before it. It returns false,
+// leaving the variable unchanged, at the end of the input or if the next word is not an integer.
+func (interp *Interpreter) scanInt() (int64, bool) {
+	var value int64
+	if _, err := fmt.Fscan(interp.in, &value); err != nil {
+		return 0, false
+	}
+	return value, true
+}
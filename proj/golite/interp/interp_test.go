@@ -0,0 +1,108 @@
+package interp
+
+import (
+	"bytes"
+	"proj/golite/compiler"
+	"strings"
+	"testing"
+)
+
+// interpret runs a program with the given standard-in, it fails the test if the program does not compile
+func interpret(t *testing.T, res *compiler.Result, stdin string) (string, error) {
+	if res.HasErrors() {
+		t.Fatalf("\nExpected: no diagnostics; Got %v\n", res.Diagnostics)
+	}
+	out := bytes.Buffer{}
+	err := Run(res.Program, strings.NewReader(stdin), &out)
+	return out.String(), err
+}
+
+func Test1(t *testing.T) {
+	res := compiler.Compile("test1_interp.golite", compiler.Options{StopAfter: compiler.StageSemantic})
+	out, err := interpret(t, res, "10\n")
+	if err != nil {
+		t.Fatalf("\nExpected: no error; Got %v\n", err)
+	}
+	expected := "55\n385\ntrue\n-3"
+	if out != expected {
+		t.Errorf("\nExpected: %q\nGot: %q\n", expected, out)
+	}
+}
+
+func Test2(t *testing.T) {
+	res := compiler.Compile("test2_interp.golite", compiler.Options{StopAfter: compiler.StageSemantic})
+	out, err := interpret(t, res, "")
+	if out != "2\n" {
+		t.Errorf("\nExpected: the output before the error; Got %q\n", out)
+	}
+	runtimeErr, isRuntime := err.(*RuntimeError)
+	if !isRuntime {
+		t.Fatalf("\nExpected: a runtime error; Got %v\n", err)
+	}
+	expected := "11:16: error[R001]: integer divide by zero\n\tnote: in ratio called at 23:9\n\tnote: in main"
+	if runtimeErr.Diagnostic().String() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, runtimeErr.Diagnostic())
+	}
+}
+
+func Test3(t *testing.T) {
+	res := compiler.Compile("test3_interp.golite", compiler.Options{StopAfter: compiler.StageSemantic})
+	_, err := interpret(t, res, "")
+	if err == nil || err.Error() != "16:9: runtime error: use of a deleted Point" {
+		t.Errorf("\nExpected: use of a deleted struct; Got %v\n", err)
+	}
+}
+
+func Test4(t *testing.T) {
+	// an endless recursion is stopped before it exhausts the stack of the interpreter
+	src := "package main;\nimport \"fmt\";\n" +
+		"func down(n int) int {\n    return down(n - 1);\n}\n" +
+		"func main() {\n    var x int;\n    x = down(1);\n    fmt.Println(x);\n}\n"
+	res := compiler.CompileString("recursion.golite", src, compiler.Options{StopAfter: compiler.StageSemantic})
+	_, err := interpret(t, res, "")
+	if err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Errorf("\nExpected: stack overflow; Got %v\n", err)
+	}
+}
+
+func Test5(t *testing.T) {
+	// an index out of a slice, after the elements appended share the block of the ones before
+	src := "package main;\nimport \"fmt\";\n" +
+		"func main() {\n    var s, u []int;\n    var x int;\n" +
+		"    s = append(s, 1, 2, 3);\n    u = append(s, 4);\n    s = append(s, 5);\n" +
+		"    x = u[3];\n    fmt.Println(x);\n    x = s[len(u)];\n}\n"
+	res := compiler.CompileString("slices.golite", src, compiler.Options{StopAfter: compiler.StageSemantic})
+	out, err := interpret(t, res, "")
+	if out != "5\n" {
+		t.Errorf("\nExpected: 5\nGot: %q\n", out)
+	}
+	if err == nil || err.Error() != "11:11: runtime error: index out of range [4] with length 4" {
+		t.Errorf("\nExpected: index out of range; Got %v\n", err)
+	}
+}
+
+func Test6(t *testing.T) {
+	// strings start empty, in variables and fields, and + concatenates them
+	src := "package main;\nimport \"fmt\";\ntype P struct {\n    name string;\n};\n" +
+		"func main() {\n    var s string;\n    var p *P;\n    var n int;\n    p = new(P);\n" +
+		"    p.name = p.name + s + \"ab\";\n    s = p.name + \"\\tc\\n\";\n    n = len(s);\n" +
+		"    fmt.Print(\"s: \");\n    fmt.Print(s);\n    fmt.Println(n);\n}\n"
+	res := compiler.CompileString("strings.golite", src, compiler.Options{StopAfter: compiler.StageSemantic})
+	out, err := interpret(t, res, "")
+	if err != nil || out != "s: ab\tc\n5\n" {
+		t.Errorf("\nExpected: %q\nGot: %q, %v\n", "s: ab\tc\n5\n", out, err)
+	}
+}
+
+func Test7(t *testing.T) {
+	// Println spaces all its values, Print the ones next to no string, and bools print as words
+	src := "package main;\nimport \"fmt\";\nvar n int;\nfunc twice(x int) int {\n    return 2 * x;\n}\n" +
+		"func main() {\n    n = 3;\n    fmt.Println(n+1, twice(n), n > 2, \"x\", \"y\");\n" +
+		"    fmt.Print(1, 2, \"a\", 3, \"b\", \"c\", n == 3, false);\n    fmt.Println();\n}\n"
+	res := compiler.CompileString("print.golite", src, compiler.Options{StopAfter: compiler.StageSemantic})
+	out, err := interpret(t, res, "")
+	expected := "4 6 true x y\n1 2a3bctrue false\n"
+	if err != nil || out != expected {
+		t.Errorf("\nExpected: %q\nGot: %q, %v\n", expected, out, err)
+	}
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 // Add represents a ADD instruction in ILOC
@@ -79,46 +79,25 @@ func (instr *Add) String() string {
 
 }
 
-func (instr *Add) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-	var source1RegId int
-	var source2RegId int
-	var isParam1 bool
-	var isParam2 bool
+func (instr *Add) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
 	// load operand 1
-	if source1RegId, isParam1 = paramRegIds[instr.sourceReg]; !isParam1 {
-		source1Offset := funcVarDict[instr.sourceReg]
-		source1RegId = utility.NextAvailReg()
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", source1RegId, source1Offset))
-	}
+	source1RegId, load := regs.use(instr.sourceReg)
+	instruction = append(instruction, load...)
 
 	// load operand 2
-	if source2RegId, isParam2 = paramRegIds[instr.operand]; !isParam2 {
-		source2RegId = utility.NextAvailReg()
-		if instr.opty == REGISTER {
-			source2Offset := funcVarDict[instr.operand]
-			instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", source2RegId, source2Offset))
-		} else {
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", source2RegId, instr.operand))
-		}
-	}
+	source2RegId, load := regs.operand(instr.operand, instr.opty)
+	instruction = append(instruction, load...)
 
 	// add
-	targetRegId := utility.NextAvailReg()
-	instruction = append(instruction, fmt.Sprintf("\tadd x%v,x%v,x%v", targetRegId, source1RegId, source2RegId))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Add, targetRegId, source1RegId, source2RegId))
 
 	// store result
-	targetOffset := funcVarDict[instr.target]
-	instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", targetRegId, targetOffset))
-
-	utility.ReleaseReg(targetRegId)
-	if !isParam1 {
-		utility.ReleaseReg(source1RegId)
-	}
-	if !isParam2 {
-		utility.ReleaseReg(source2RegId)
-	}
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
 
 	return instruction
 }
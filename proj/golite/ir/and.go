@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 )
 
 type And struct {
@@ -71,34 +72,25 @@ func (instr *And) String() string {
 
 }
 
-func (instr *And) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	//// load operand 1
-	//sourceOffset := funcVarDict[instr.sourceReg]
-	//sourceRegId := NextAvailReg()
-	//instruction = append(instruction, fmt.Sprintf("ldr x%v, [x29, #%v]", sourceRegId, sourceOffset))
-	//
-	//// load operand 2
-	//source2RegId := NextAvailReg()
-	//if instr.opty == REGISTER {
-	//	source2Offset := funcVarDict[instr.operand]
-	//	instruction = append(instruction, fmt.Sprintf("ldr x%v, [x29, #%v]", source2RegId, source2Offset))
-	//} else {
-	//	instruction = append(instruction, fmt.Sprintf("mov x%v, #%v", source2RegId, instr.operand))
-	//}
-	//
-	//targetRegId := NextAvailReg()
-	//instruction = append(instruction, fmt.Sprintf("and x%v, x%v, x%v", targetRegId, sourceRegId, source2RegId))
-	//
-	//// store result
-	//targetOffset := funcVarDict[instr.target]
-	//instruction = append(instruction, fmt.Sprintf("str x%v, [x29, #%v]", targetRegId, targetOffset))
-	//
-	//// release
-	//ReleaseReg(sourceRegId)
-	//ReleaseReg(source2RegId)
-	//ReleaseReg(targetRegId)
+func (instr *And) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	// load operand 1
+	source1RegId, load := regs.use(instr.sourceReg)
+	instruction = append(instruction, load...)
+
+	// load operand 2
+	source2RegId, load := regs.operand(instr.operand, instr.opty)
+	instruction = append(instruction, load...)
+
+	// and
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.And, targetRegId, source1RegId, source2RegId))
+
+	// store result
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
 
 	return instruction
 }
@@ -0,0 +1,153 @@
+package ir
+
+import (
+	"proj/golite/arm/asm"
+	"proj/golite/utility"
+)
+
+// armRegs hands out the Arm registers an instruction works on. An ILOC register given a register
+// by the allocator of proj/golite/arm/regalloc is used in place; a spilled one lives in its stack
+// slot of funcVarDict, and goes through a scratch register of utility, loaded before the
+// instruction or stored after it.
+type armRegs struct {
+	funcVarDict map[int]int // the stack slot of each spilled register, as an offset from x29
+	regIds      map[int]int // the Arm register of each allocated register
+	scratch     []int       // the scratch registers taken, given back by release
+}
+
+func newArmRegs(funcVarDict map[int]int, regIds map[int]int) *armRegs {
+	return &armRegs{funcVarDict: funcVarDict, regIds: regIds}
+}
+
+func (a *armRegs) takeScratch() int {
+	regId := utility.NextAvailReg()
+	if regId < 0 {
+		panic("no scratch register left to translate the instruction")
+	}
+	a.scratch = append(a.scratch, regId)
+	return regId
+}
+
+// use returns the Arm register holding the value of reg, and the code loading it
+func (a *armRegs) use(reg int) (asm.Reg, []asm.Line) {
+	if regId, allocated := a.regIds[reg]; allocated {
+		return asm.X(regId), nil
+	}
+	regId := asm.X(a.takeScratch())
+	return regId, LoadSlot(regId, a.funcVarDict[reg])
+}
+
+// operand is use for a register operand, and moves an immediate one into a scratch register
+func (a *armRegs) operand(operand int, opty OperandTy) (asm.Reg, []asm.Line) {
+	if opty == REGISTER {
+		return a.use(operand)
+	}
+	regId := asm.X(a.takeScratch())
+	return regId, []asm.Line{asm.MovImm(regId, operand)}
+}
+
+// def returns the Arm register an instruction writes reg to, to be followed by store(reg)
+func (a *armRegs) def(reg int) asm.Reg {
+	if regId, allocated := a.regIds[reg]; allocated {
+		return asm.X(regId)
+	}
+	return asm.X(a.takeScratch())
+}
+
+// store returns the code saving the value written to the Arm register regId to the slot of reg,
+// if it is spilled
+func (a *armRegs) store(reg int, regId asm.Reg) []asm.Line {
+	if _, allocated := a.regIds[reg]; allocated {
+		return nil
+	}
+	offset := a.funcVarDict[reg]
+	if SlotInReach(offset) {
+		return StoreSlot(regId, offset, regId)
+	}
+	// the store follows the instruction, whose other scratch registers are free again
+	for _, scratch := range a.scratch {
+		if asm.X(scratch) != regId {
+			return StoreSlot(regId, offset, asm.X(scratch))
+		}
+	}
+	return StoreSlot(regId, offset, asm.X(a.takeScratch()))
+}
+
+// MinSlotOffset is the lowest offset from x29 ldr and str take as an immediate; a stack slot
+// below it is addressed by its index from x29 in a register, [x29,xI,lsl #3]
+const MinSlotOffset = -256
+
+// SlotInReach returns true if the slot at offset from x29 is addressed by an immediate
+func SlotInReach(offset int) bool {
+	return offset >= MinSlotOffset
+}
+
+// LoadSlot returns the code loading the stack slot at offset from x29 into regId, which holds
+// its index first if it is out of reach
+func LoadSlot(regId asm.Reg, offset int) []asm.Line {
+	if SlotInReach(offset) {
+		return []asm.Line{asm.NewInstr(asm.Ldr, regId, asm.Mem{Base: asm.FP, Offset: offset})}
+	}
+	return []asm.Line{
+		asm.MovImm(regId, offset/8),
+		asm.NewInstr(asm.Ldr, regId, asm.Indexed{Base: asm.FP, Index: regId}),
+	}
+}
+
+// StoreSlot returns the code storing regId to the stack slot at offset from x29, through the
+// index in indexRegId if it is out of reach
+func StoreSlot(regId asm.Reg, offset int, indexRegId asm.Reg) []asm.Line {
+	if SlotInReach(offset) {
+		return []asm.Line{asm.NewInstr(asm.Str, regId, asm.Mem{Base: asm.FP, Offset: offset})}
+	}
+	return []asm.Line{
+		asm.MovImm(indexRegId, offset/8),
+		asm.NewInstr(asm.Str, regId, asm.Indexed{Base: asm.FP, Index: indexRegId}),
+	}
+}
+
+// release gives back the scratch registers
+func (a *armRegs) release() {
+	for _, regId := range a.scratch {
+		utility.ReleaseReg(regId)
+	}
+	a.scratch = nil
+}
+
+// armCond returns the condition code of a flag, asm.AL for AL
+func armCond(flag ApsrFlag) asm.Cond {
+	switch flag {
+	case GT:
+		return asm.GT
+	case LT:
+		return asm.LT
+	case GE:
+		return asm.GE
+	case LE:
+		return asm.LE
+	case EQ:
+		return asm.EQ
+	case NE:
+		return asm.NE
+	}
+	return asm.AL
+}
+
+// invertFlag returns the flag holding exactly when flag does not
+func invertFlag(flag ApsrFlag) ApsrFlag {
+	switch flag {
+	case GT:
+		return LE
+	case LT:
+		return GE
+	case GE:
+		return LT
+	case LE:
+		return GT
+	case EQ:
+		return NE
+	case NE:
+		return EQ
+	}
+	return flag
+}
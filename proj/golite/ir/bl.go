@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 )
 
 type Bl struct {
@@ -33,10 +34,10 @@ func (instr *Bl) String() string {
 	return out.String()
 }
 
-func (instr *Bl) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *Bl) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
 
-	instruction = append(instruction, fmt.Sprintf("\tbl %v",instr.label))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol(instr.label)))
 
 	return instruction
 }
\ No newline at end of file
@@ -0,0 +1,106 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/utility"
+)
+
+// Bounds checks an index against the length of an array or a slice, the program failing with
+// "index out of range" unless 0 <= index < length. In Arm code it overwrites the flags, so it is
+// never placed between a cmp and the instructions reading them.
+type Bounds struct {
+	index   int
+	operand int // the length
+	opty    OperandTy
+}
+
+func NewBounds(index int, operand int, opty OperandTy) *Bounds {
+	return &Bounds{index, operand, opty}
+}
+
+func (instr *Bounds) GetTargets() []int { return []int{} }
+
+func (instr *Bounds) GetSources() []int {
+	sources := []int{}
+	if instr.opty == REGISTER {
+		sources = append(sources, instr.index, instr.operand)
+	} else if instr.opty == IMMEDIATE {
+		sources = append(sources, instr.index)
+	}
+	return sources
+}
+
+func (instr *Bounds) GetImmediate() *int {
+	if instr.opty == IMMEDIATE {
+		return &instr.operand
+	}
+	return nil
+}
+
+func (instr *Bounds) GetSourceString() string { return "" }
+
+func (instr *Bounds) GetLabel() string { return "" }
+
+func (instr *Bounds) SetLabel(newLabel string) {}
+
+func (instr *Bounds) String() string {
+	var out bytes.Buffer
+
+	indexReg := fmt.Sprintf("r%v", instr.index)
+	prefix := "r"
+	if instr.opty == IMMEDIATE {
+		prefix = "#"
+	}
+	length := fmt.Sprintf("%v%v", prefix, instr.operand)
+
+	out.WriteString(fmt.Sprintf("    bounds %s,%s", indexReg, length))
+
+	return out.String()
+}
+
+// BoundsArmRoutine returns the code the failed bounds of a program branch to, with the index in
+// x1 and the length in x2: it prints the error on standard-error and exits with status 2, as Go
+// does
+func BoundsArmRoutine() []asm.Line {
+	message := "panic: runtime error: index out of range [%ld] with length %ld\n"
+	return []asm.Line{
+		asm.Label(".BOUNDS"),
+		asm.NewInstr(asm.Mov, asm.X(3), asm.X(2)),
+		asm.NewInstr(asm.Mov, asm.X(2), asm.X(1)),
+		asm.NewInstr(asm.Adrp, asm.X(1), asm.Symbol(".BOUNDSMSG")),
+		asm.NewInstr(asm.Add, asm.X(1), asm.X(1), asm.Lo12(".BOUNDSMSG")),
+		asm.NewInstr(asm.Mov, asm.X(0), asm.Imm(2)),
+		asm.NewInstr(asm.Bl, asm.Symbol("dprintf")),
+		asm.NewInstr(asm.Mov, asm.X(0), asm.Imm(2)),
+		asm.NewInstr(asm.Bl, asm.Symbol("exit")),
+		asm.Label(".BOUNDSMSG"),
+		asm.NewDirective(".asciz", fmt.Sprintf("%q", message)),
+		asm.NewDirective(".size", ".BOUNDSMSG", fmt.Sprint(len(message)+1)),
+	}
+}
+
+func (instr *Bounds) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	utility.SetBounds()
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	// the index and the length go to x1 and x2 for the routine, which a negative index, compared
+	// unsigned, also reaches
+	indexRegId, load := regs.use(instr.index)
+	instruction = append(instruction, load...)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), indexRegId))
+	if instr.opty == REGISTER {
+		lengthRegId, load := regs.use(instr.operand)
+		instruction = append(instruction, load...)
+		instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(2), lengthRegId))
+	} else {
+		instruction = append(instruction, asm.MovImm(asm.X(2), instr.operand))
+	}
+	instruction = append(instruction, asm.NewInstr(asm.Cmp, asm.X(1), asm.X(2)))
+	instruction = append(instruction, asm.NewCondInstr(asm.B, asm.HS, asm.Symbol(".BOUNDS")))
+
+	return instruction
+}
@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 )
 
 type Branch struct {
@@ -28,6 +29,9 @@ func (instr *Branch) SetLabel(newLabel string) {
 	instr.label = newLabel
 }
 
+// GetFlag returns the condition under which the branch is taken, AL for always
+func (instr *Branch) GetFlag() ApsrFlag { return instr.flagVal }
+
 func (instr *Branch) String() string {
 	var out bytes.Buffer
 	var flag string
@@ -62,16 +66,14 @@ func (instr *Branch) String() string {
 	return out.String()
 }
 
-func (instr *Branch) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string{
-	instruction := []string{}
+func (instr *Branch) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
 
-	if instr.flagVal == NE {
-		instruction = append(instruction, fmt.Sprintf("\tb.ne %v", instr.label))
-	} else if instr.flagVal == EQ {
-		instruction = append(instruction, fmt.Sprintf("\tb.eq %v", instr.label))
+	if instr.flagVal == AL {
+		instruction = append(instruction, asm.NewInstr(asm.B, asm.Symbol(instr.label)))
 	} else {
-		instruction = append(instruction, fmt.Sprintf("\tb %v", instr.label))
+		instruction = append(instruction, asm.NewCondInstr(asm.B, armCond(instr.flagVal), asm.Symbol(instr.label)))
 	}
 
 	return instruction
-}
\ No newline at end of file
+}
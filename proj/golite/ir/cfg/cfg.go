@@ -0,0 +1,339 @@
+// Package cfg splits the ILOC of a function into basic blocks and computes its control-flow graph,
+// dominators, post-dominators and natural loops.
+package cfg
+
+import (
+	"fmt"
+	"proj/golite/ir"
+	"sort"
+)
+
+// Block is a basic block: a run of instructions entered at its first one and left after its last
+// one. A block starts at a label or after a branch, call or ret, and ends before a label or with a
+// branch, call or ret.
+type Block struct {
+	Index        int              // position in Graph.Blocks
+	Start        int              // position of the first instruction in the body of the FuncFrag
+	Instructions []ir.Instruction // empty for the exit block
+	Preds        []*Block
+	Succs        []*Block
+}
+
+// Name returns the name of the block in dumps, e.g. B3
+func (b *Block) Name() string {
+	return fmt.Sprintf("B%v", b.Index)
+}
+
+// Label returns the label starting the block, "" if it does not start with one
+func (b *Block) Label() string {
+	if len(b.Instructions) > 0 {
+		if label, isLabel := b.Instructions[0].(*ir.Label); isLabel {
+			return label.GetLabel()
+		}
+	}
+	return ""
+}
+
+// Last returns the instruction ending the block, nil for the exit block
+func (b *Block) Last() ir.Instruction {
+	if len(b.Instructions) == 0 {
+		return nil
+	}
+	return b.Instructions[len(b.Instructions)-1]
+}
+
+// Loop is a natural loop: the blocks that reach one of the back edges to its header without going
+// through the header, and the header itself
+type Loop struct {
+	Header  *Block
+	Blocks  []*Block // the blocks of the loop sorted by index, the header included
+	Latches []*Block // the blocks branching back to the header
+}
+
+// Contains returns true if the block is part of the loop
+func (l *Loop) Contains(b *Block) bool {
+	for _, block := range l.Blocks {
+		if block == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Graph is the control-flow graph of a FuncFrag
+type Graph struct {
+	Frag   *ir.FuncFrag
+	Blocks []*Block // Blocks[0] is the entry, the last one the exit
+	Entry  *Block   // the block starting with the label of the function
+	Exit   *Block   // an empty block following every ret and the end of the body
+
+	IDom     []*Block // the immediate dominator of each block, by index; nil for the entry and the unreachable blocks
+	IPostDom []*Block // the immediate post-dominator of each block, by index; nil for the exit and the blocks never reaching it
+	Loops    []*Loop  // the natural loops, sorted by the index of their header
+}
+
+// Build computes the control-flow graph of a FuncFrag, its dominators and its loops
+func Build(frag *ir.FuncFrag) *Graph {
+	g := &Graph{Frag: frag}
+	g.split()
+	g.link()
+	g.IDom = immediateDominators(g.Entry, len(g.Blocks),
+		func(b *Block) []*Block { return b.Preds }, func(b *Block) []*Block { return b.Succs })
+	g.IPostDom = immediateDominators(g.Exit, len(g.Blocks),
+		func(b *Block) []*Block { return b.Succs }, func(b *Block) []*Block { return b.Preds })
+	g.findLoops()
+	return g
+}
+
+// BuildAll computes the control-flow graph of every FuncFrag of a program
+func BuildAll(frags []*ir.FuncFrag) []*Graph {
+	graphs := []*Graph{}
+	for _, frag := range frags {
+		graphs = append(graphs, Build(frag))
+	}
+	return graphs
+}
+
+// endsBlock returns true for the instructions after which a new block starts
+func endsBlock(instruction ir.Instruction) bool {
+	switch instruction.(type) {
+	case *ir.Branch, *ir.Bl, *ir.Ret:
+		return true
+	}
+	return false
+}
+
+// split cuts the body of the FuncFrag into blocks, and adds the exit block
+func (g *Graph) split() {
+	var current *Block
+	for i, instruction := range g.Frag.Body {
+		if _, isLabel := instruction.(*ir.Label); isLabel || current == nil {
+			if current == nil || len(current.Instructions) > 0 {
+				current = g.newBlock(i)
+			}
+		}
+		current.Instructions = append(current.Instructions, instruction)
+		if endsBlock(instruction) {
+			current = nil
+		}
+	}
+	if len(g.Blocks) == 0 {
+		g.newBlock(0)
+	}
+	g.Entry = g.Blocks[0]
+	g.Exit = g.newBlock(len(g.Frag.Body))
+}
+
+func (g *Graph) newBlock(start int) *Block {
+	b := &Block{Index: len(g.Blocks), Start: start}
+	g.Blocks = append(g.Blocks, b)
+	return b
+}
+
+// link adds the edges: to the target of a branch, to the next block unless the block ends with an
+// unconditional branch or a ret, and to the exit after a ret or the last block
+func (g *Graph) link() {
+	labels := map[string]*Block{}
+	for _, b := range g.Blocks {
+		if label := b.Label(); label != "" {
+			labels[label] = b
+		}
+	}
+	for _, b := range g.Blocks {
+		if b == g.Exit {
+			continue
+		}
+		next := g.Blocks[b.Index+1]
+		switch last := b.Last().(type) {
+		case *ir.Branch:
+			// a branch to a label that is not in the function has no edge, ir/parse reports it
+			if target := labels[last.GetLabel()]; target != nil {
+				addEdge(b, target)
+			}
+			if last.GetFlag() != ir.AL {
+				addEdge(b, next)
+			}
+		case *ir.Ret:
+			addEdge(b, g.Exit)
+		default:
+			addEdge(b, next)
+		}
+	}
+}
+
+func addEdge(from *Block, to *Block) {
+	for _, succ := range from.Succs {
+		if succ == to {
+			return
+		}
+	}
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+// immediateDominators computes the immediate dominators of the blocks reachable from root, by the
+// iterative algorithm of Cooper, Harvey and Kennedy. With preds and succs swapped, starting from
+// the exit, it computes the immediate post-dominators.
+func immediateDominators(root *Block, count int, preds func(*Block) []*Block, succs func(*Block) []*Block) []*Block {
+	// number the blocks in postorder of a depth-first search from root
+	order := make([]int, count)
+	for i := range order {
+		order[i] = -1
+	}
+	postorder := []*Block{}
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		order[b.Index] = 0
+		for _, succ := range succs(b) {
+			if order[succ.Index] < 0 {
+				visit(succ)
+			}
+		}
+		order[b.Index] = len(postorder)
+		postorder = append(postorder, b)
+	}
+	visit(root)
+
+	idom := make([]*Block, count)
+	idom[root.Index] = root
+	intersect := func(a *Block, b *Block) *Block {
+		for a != b {
+			for order[a.Index] < order[b.Index] {
+				a = idom[a.Index]
+			}
+			for order[b.Index] < order[a.Index] {
+				b = idom[b.Index]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(postorder) - 2; i >= 0; i-- {
+			b := postorder[i]
+			var newIDom *Block
+			for _, pred := range preds(b) {
+				if idom[pred.Index] == nil {
+					continue
+				}
+				if newIDom == nil {
+					newIDom = pred
+				} else {
+					newIDom = intersect(pred, newIDom)
+				}
+			}
+			if idom[b.Index] != newIDom {
+				idom[b.Index] = newIDom
+				changed = true
+			}
+		}
+	}
+	idom[root.Index] = nil
+	return idom
+}
+
+// dominates walks up the tree of idom from b, looking for a
+func dominates(idom []*Block, a *Block, b *Block) bool {
+	for ; b != nil; b = idom[b.Index] {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Dominates returns true if every path from the entry to b goes through a; a block dominates
+// itself, and the unreachable blocks are dominated by no other block
+func (g *Graph) Dominates(a *Block, b *Block) bool {
+	if b != g.Entry && g.IDom[b.Index] == nil {
+		return a == b
+	}
+	return dominates(g.IDom, a, b)
+}
+
+// PostDominates returns true if every path from b to the exit goes through a; a block
+// post-dominates itself
+func (g *Graph) PostDominates(a *Block, b *Block) bool {
+	if b != g.Exit && g.IPostDom[b.Index] == nil {
+		return a == b
+	}
+	return dominates(g.IPostDom, a, b)
+}
+
+// DominanceFrontiers returns the dominance frontier of each block by index: the blocks having a
+// predecessor dominated by it without being strictly dominated by it themselves, where the
+// definitions of the block meet others. The unreachable blocks are in no frontier.
+func (g *Graph) DominanceFrontiers() [][]*Block {
+	frontiers := make([][]*Block, len(g.Blocks))
+	for _, b := range g.Blocks {
+		if len(b.Preds) < 2 || (b != g.Entry && g.IDom[b.Index] == nil) {
+			continue
+		}
+		for _, pred := range b.Preds {
+			if pred != g.Entry && g.IDom[pred.Index] == nil {
+				continue
+			}
+			for runner := pred; runner != nil && runner != g.IDom[b.Index]; runner = g.IDom[runner.Index] {
+				if !containsBlock(frontiers[runner.Index], b) {
+					frontiers[runner.Index] = append(frontiers[runner.Index], b)
+				}
+			}
+		}
+	}
+	return frontiers
+}
+
+func containsBlock(blocks []*Block, b *Block) bool {
+	for _, block := range blocks {
+		if block == b {
+			return true
+		}
+	}
+	return false
+}
+
+// findLoops collects the natural loop of every back edge, an edge to a block dominating its
+// source; the loops of the back edges to the same header are merged
+func (g *Graph) findLoops() {
+	byHeader := map[*Block]*Loop{}
+	for _, b := range g.Blocks {
+		for _, succ := range b.Succs {
+			if !g.Dominates(succ, b) {
+				continue
+			}
+			loop := byHeader[succ]
+			if loop == nil {
+				loop = &Loop{Header: succ, Blocks: []*Block{succ}}
+				byHeader[succ] = loop
+				g.Loops = append(g.Loops, loop)
+			}
+			loop.Latches = append(loop.Latches, b)
+			// walk back from the latch, the header stops the walk
+			work := []*Block{b}
+			for len(work) > 0 {
+				block := work[len(work)-1]
+				work = work[:len(work)-1]
+				if loop.Contains(block) || (block != g.Entry && g.IDom[block.Index] == nil) {
+					continue
+				}
+				loop.Blocks = append(loop.Blocks, block)
+				work = append(work, block.Preds...)
+			}
+		}
+	}
+	for _, loop := range g.Loops {
+		sort.Slice(loop.Blocks, func(i, j int) bool { return loop.Blocks[i].Index < loop.Blocks[j].Index })
+	}
+	sort.Slice(g.Loops, func(i, j int) bool { return g.Loops[i].Header.Index < g.Loops[j].Header.Index })
+}
+
+// LoopOf returns the innermost loop containing the block, nil if it is in no loop
+func (g *Graph) LoopOf(b *Block) *Loop {
+	var innermost *Loop
+	for _, loop := range g.Loops {
+		if loop.Contains(b) && (innermost == nil || len(loop.Blocks) < len(innermost.Blocks)) {
+			innermost = loop
+		}
+	}
+	return innermost
+}
@@ -0,0 +1,98 @@
+package cfg
+
+import (
+	"os"
+	"proj/golite/ir/parse"
+	"strings"
+	"testing"
+)
+
+func build(t *testing.T, path string) *Graph {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frags, errors := parse.Parse(string(src))
+	if len(errors) != 0 || len(frags) != 1 {
+		t.Fatalf("\nExpected: a single function; Got %v\n", errors)
+	}
+	return Build(frags[0])
+}
+
+// names lists the names of blocks, - for nil
+func names(blocks []*Block) string {
+	list := []string{}
+	for _, b := range blocks {
+		list = append(list, blockName(b))
+	}
+	return strings.Join(list, " ")
+}
+
+func Test1(t *testing.T) {
+	g := build(t, "test1_cfg.iloc")
+	if len(g.Blocks) != 10 || g.Exit != g.Blocks[9] || g.Blocks[1].Label() != "outerBody_L2" {
+		t.Fatalf("\nExpected: 9 blocks and the exit; Got %v\n", len(g.Blocks))
+	}
+
+	expectedSuccs := []string{"B5", "B3", "B3", "B2 B4", "B5", "B1 B6", "B8 B7", "B9", "B9", ""}
+	for i, b := range g.Blocks {
+		if names(b.Succs) != expectedSuccs[i] {
+			t.Errorf("\nExpected: %v -> %v; Got %v\n", b.Name(), expectedSuccs[i], names(b.Succs))
+		}
+	}
+	if names(g.IDom) != "- B5 B3 B1 B3 B0 B5 B6 B6 B6" {
+		t.Errorf("\nExpected: the dominators - B5 B3 B1 B3 B0 B5 B6 B6 B6; Got %v\n", names(g.IDom))
+	}
+	if names(g.IPostDom) != "B5 B3 B3 B4 B5 B6 B9 B9 B9 -" {
+		t.Errorf("\nExpected: the post-dominators B5 B3 B3 B4 B5 B6 B9 B9 B9 -; Got %v\n", names(g.IPostDom))
+	}
+	if !g.Dominates(g.Blocks[5], g.Blocks[2]) || g.Dominates(g.Blocks[1], g.Blocks[6]) || !g.PostDominates(g.Blocks[6], g.Blocks[0]) {
+		t.Errorf("\nExpected: B5 dominating B2, B1 not dominating B6 and B6 post-dominating B0\n")
+	}
+
+	if len(g.Loops) != 2 {
+		t.Fatalf("\nExpected: 2 loops; Got %v\n", len(g.Loops))
+	}
+	inner, outer := g.Loops[0], g.Loops[1]
+	if inner.Header != g.Blocks[3] || names(inner.Blocks) != "B2 B3" || names(inner.Latches) != "B2" {
+		t.Errorf("\nExpected: the inner loop B2 B3 headed by B3; Got %v headed by %v\n", names(inner.Blocks), inner.Header.Name())
+	}
+	if outer.Header != g.Blocks[5] || names(outer.Blocks) != "B1 B2 B3 B4 B5" || names(outer.Latches) != "B4" {
+		t.Errorf("\nExpected: the outer loop B1 B2 B3 B4 B5 headed by B5; Got %v headed by %v\n", names(outer.Blocks), outer.Header.Name())
+	}
+	if g.LoopOf(g.Blocks[2]) != inner || g.LoopOf(g.Blocks[4]) != outer || g.LoopOf(g.Blocks[6]) != nil {
+		t.Errorf("\nExpected: B2 in the inner loop, B4 in the outer one and B6 in none\n")
+	}
+}
+
+func Test2(t *testing.T) {
+	g := build(t, "test2_cfg.iloc")
+	// B0 ends with bne, B1 with ret, B2 is the unreachable b, B3 the else, B4 done_L2
+	unreachable := g.Blocks[2]
+	if len(unreachable.Preds) != 0 || g.IDom[unreachable.Index] != nil || g.Dominates(g.Entry, unreachable) {
+		t.Errorf("\nExpected: B2 unreachable and dominated by no other block\n")
+	}
+	if !g.PostDominates(g.Exit, unreachable) || names(g.Blocks[4].Succs) != "B5" {
+		t.Errorf("\nExpected: the end of the body falling into the exit\n")
+	}
+	if len(g.Loops) != 0 {
+		t.Errorf("\nExpected: no loops; Got %v\n", len(g.Loops))
+	}
+}
+
+func Test3(t *testing.T) {
+	dot := strings.Join(DOT([]*Graph{build(t, "test1_cfg.iloc")}), "\n")
+	expected := []string{
+		"digraph cfg {",
+		"\tsubgraph \"cluster_f\" {",
+		"\t\t\"f.B9\" [label=\"B9 exit\" shape=oval];",
+		"\t\t\"f.B2\" -> \"f.B3\" [style=dashed];",
+		"\t\t\"f.B3\" -> \"f.B2\";",
+		"    blt innerBody_L4\\lidom B1, ipostdom B4\\l\" style=bold];",
+	}
+	for _, line := range expected {
+		if !strings.Contains(dot, line) {
+			t.Errorf("\nExpected: %q in\n%v\n", line, dot)
+		}
+	}
+}
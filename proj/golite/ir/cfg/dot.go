@@ -0,0 +1,88 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DOT returns the control-flow graphs as a Graphviz digraph, one line per statement and one
+// cluster per function. The header of a loop is drawn bold, a back edge dashed, and each block
+// lists its immediate dominator and post-dominator under its instructions.
+func DOT(graphs []*Graph) []string {
+	lines := []string{"digraph cfg {", "\tnode [shape=box fontname=\"monospace\"];"}
+	for _, g := range graphs {
+		lines = append(lines, g.dotLines()...)
+	}
+	return append(lines, "}")
+}
+
+func (g *Graph) dotLines() []string {
+	name := g.Frag.Label
+	lines := []string{
+		fmt.Sprintf("\tsubgraph %v {", quote("cluster_"+name)),
+		fmt.Sprintf("\t\tlabel=%v;", quote(name)),
+	}
+	for _, b := range g.Blocks {
+		attrs := ""
+		if b == g.Exit {
+			attrs = " shape=oval"
+		} else if g.isHeader(b) {
+			attrs = " style=bold"
+		}
+		lines = append(lines, fmt.Sprintf("\t\t%v [label=\"%v\"%v];", g.nodeID(b), g.blockText(b), attrs))
+	}
+	for _, b := range g.Blocks {
+		for _, succ := range b.Succs {
+			attrs := ""
+			if g.Dominates(succ, b) {
+				attrs = " [style=dashed]"
+			}
+			lines = append(lines, fmt.Sprintf("\t\t%v -> %v%v;", g.nodeID(b), g.nodeID(succ), attrs))
+		}
+	}
+	return append(lines, "\t}")
+}
+
+// blockText lists the instructions of a block, escaped and left-aligned by the \l line ends of
+// Graphviz
+func (g *Graph) blockText(b *Block) string {
+	if b == g.Exit {
+		return b.Name() + " exit"
+	}
+	out := strings.Builder{}
+	out.WriteString(b.Name() + "\\l")
+	for _, instruction := range b.Instructions {
+		out.WriteString(escape(instruction.String()) + "\\l")
+	}
+	out.WriteString(fmt.Sprintf("idom %v, ipostdom %v\\l", blockName(g.IDom[b.Index]), blockName(g.IPostDom[b.Index])))
+	return out.String()
+}
+
+func (g *Graph) isHeader(b *Block) bool {
+	for _, loop := range g.Loops {
+		if loop.Header == b {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeID names a block uniquely among all the functions, e.g. "fib1.B3"
+func (g *Graph) nodeID(b *Block) string {
+	return quote(g.Frag.Label + "." + b.Name())
+}
+
+func blockName(b *Block) string {
+	if b == nil {
+		return "-"
+	}
+	return b.Name()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(s)
+}
+
+func quote(s string) string {
+	return "\"" + escape(s) + "\""
+}
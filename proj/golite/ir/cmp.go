@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Cmp struct {
@@ -64,40 +64,21 @@ func (instr *Cmp) String() string {
 	return out.String()
 }
 
-func (instr *Cmp) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	var operand1Reg, operand2Reg int
-	var isOperand1Param, isOperand2Param bool
+func (instr *Cmp) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
 	// get operand 1
-	if operand1Reg, isOperand1Param = paramRegIds[instr.sourceReg]; !isOperand1Param {
-		operand1Reg = utility.NextAvailReg()
-		operand1Offset := funcVarDict[instr.sourceReg]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",operand1Reg,operand1Offset))
-	}
+	operand1Reg, load := regs.use(instr.sourceReg)
+	instruction = append(instruction, load...)
 
 	// get operand 2
-	if operand2Reg, isOperand2Param = paramRegIds[instr.operand]; !isOperand2Param {
-		operand2Reg = utility.NextAvailReg()
-		if instr.opty == REGISTER {
-			operand2Offset := funcVarDict[instr.operand]
-			instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",operand2Reg,operand2Offset))
-		} else {
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v",operand2Reg,instr.operand))
-		}
-	}
+	operand2Reg, load := regs.operand(instr.operand, instr.opty)
+	instruction = append(instruction, load...)
 
 	// compare
-	instruction = append(instruction, fmt.Sprintf("\tcmp x%v,x%v",operand1Reg,operand2Reg))
-
-	// release registers
-	if !isOperand1Param {
-		utility.ReleaseReg(operand1Reg)
-	}
-	if !isOperand2Param {
-		utility.ReleaseReg(operand2Reg)
-	}
+	instruction = append(instruction, asm.NewInstr(asm.Cmp, operand1Reg, operand2Reg))
 
 	return instruction
-}
\ No newline at end of file
+}
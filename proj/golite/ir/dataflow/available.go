@@ -0,0 +1,133 @@
+package dataflow
+
+import (
+	"fmt"
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+)
+
+// Expression is the computation of an arithmetic or logic instruction, e.g. add r1,#2
+type Expression struct {
+	Op       string // add, sub, mul, div, and, or or not
+	Operands []string
+	Regs     []int // the registers among the operands
+}
+
+func (e Expression) String() string {
+	text := e.Op
+	for i, operand := range e.Operands {
+		if i == 0 {
+			text += " " + operand
+		} else {
+			text += "," + operand
+		}
+	}
+	return text
+}
+
+// ExpressionOf returns the expression computed by an instruction, false if it computes none
+func ExpressionOf(instr ir.Instruction) (Expression, bool) {
+	var op string
+	switch instr.(type) {
+	case *ir.Add:
+		op = "add"
+	case *ir.Sub:
+		op = "sub"
+	case *ir.Mul:
+		op = "mul"
+	case *ir.Div:
+		op = "div"
+	case *ir.And:
+		op = "and"
+	case *ir.Or:
+		op = "or"
+	case *ir.Not:
+		op = "not"
+	default:
+		return Expression{}, false
+	}
+	e := Expression{Op: op, Regs: instr.GetSources()}
+	for _, reg := range e.Regs {
+		e.Operands = append(e.Operands, fmt.Sprintf("r%v", reg))
+	}
+	if imm := instr.GetImmediate(); imm != nil {
+		e.Operands = append(e.Operands, fmt.Sprintf("#%v", *imm))
+	}
+	// the operands of a commutative operation are sorted, so that add r2,r1 is add r1,r2
+	if len(e.Regs) == 2 && (op == "add" || op == "mul" || op == "and" || op == "or") && e.Regs[0] > e.Regs[1] {
+		e.Regs = []int{e.Regs[1], e.Regs[0]}
+		e.Operands = []string{e.Operands[1], e.Operands[0]}
+	}
+	return e, true
+}
+
+// AvailableExpressions is the forward problem of the expressions computed on every path to a
+// point, without any of their registers being written since. Its facts are positions in Exprs.
+type AvailableExpressions struct {
+	Exprs    []Expression
+	numbers  map[string]int // the position of each expression in Exprs, by its text
+	uses     map[int][]int  // the expressions reading each register
+	computes map[int]int    // the expression computed by the instruction at each position in the body
+}
+
+// NewAvailableExpressions collects the expressions computed in a function
+func NewAvailableExpressions(g *cfg.Graph) *AvailableExpressions {
+	ae := &AvailableExpressions{numbers: map[string]int{}, uses: map[int][]int{}, computes: map[int]int{}}
+	for index, instr := range g.Frag.Body {
+		e, isExpr := ExpressionOf(instr)
+		if !isExpr {
+			continue
+		}
+		number, exist := ae.numbers[e.String()]
+		if !exist {
+			number = len(ae.Exprs)
+			ae.numbers[e.String()] = number
+			ae.Exprs = append(ae.Exprs, e)
+			for _, reg := range e.Regs {
+				ae.uses[reg] = append(ae.uses[reg], number)
+			}
+		}
+		ae.computes[index] = number
+	}
+	return ae
+}
+
+// AvailableExpressionsOf returns the expressions available at the start and the end of each block
+// of a function, the facts of the result being positions in the Exprs of the problem
+func AvailableExpressionsOf(g *cfg.Graph) (*Result, *AvailableExpressions) {
+	ae := NewAvailableExpressions(g)
+	return Solve(g, ae), ae
+}
+
+func (ae *AvailableExpressions) Backward() bool            { return false }
+func (ae *AvailableExpressions) Boundary(g *cfg.Graph) Set { return Set{} }
+func (ae *AvailableExpressions) Meet(a Set, b Set) Set     { return a.Intersect(b) }
+
+// Top returns all the expressions
+func (ae *AvailableExpressions) Top(g *cfg.Graph) Set {
+	all := Set{}
+	for number := range ae.Exprs {
+		all[number] = true
+	}
+	return all
+}
+
+// Step adds the expression computed by the instruction, then removes the ones reading a register
+// it writes, the expression itself included when it overwrites one of its operands
+func (ae *AvailableExpressions) Step(g *cfg.Graph, index int, available Set) Set {
+	instr := instruction(g, index)
+	number, computes := ae.computes[index]
+	if !computes && len(instr.GetTargets()) == 0 {
+		return available
+	}
+	available = available.Copy()
+	if computes {
+		available[number] = true
+	}
+	for _, reg := range instr.GetTargets() {
+		for _, use := range ae.uses[reg] {
+			delete(available, use)
+		}
+	}
+	return available
+}
@@ -0,0 +1,214 @@
+// Package dataflow solves dataflow problems over the control-flow graphs of proj/golite/ir/cfg
+// with a worklist, and provides liveness, reaching definitions and available expressions.
+package dataflow
+
+import (
+	"fmt"
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"sort"
+	"strings"
+)
+
+// Set is a set of small integers: registers, positions of instructions or numbers of expressions
+type Set map[int]bool
+
+// NewSet returns the set of the given elements
+func NewSet(elems ...int) Set {
+	s := Set{}
+	for _, elem := range elems {
+		s[elem] = true
+	}
+	return s
+}
+
+// Copy returns a set with the same elements
+func (s Set) Copy() Set {
+	c := Set{}
+	for elem := range s {
+		c[elem] = true
+	}
+	return c
+}
+
+// Union returns a new set with the elements of both sets
+func (s Set) Union(t Set) Set {
+	u := s.Copy()
+	for elem := range t {
+		u[elem] = true
+	}
+	return u
+}
+
+// Intersect returns a new set with the elements common to both sets
+func (s Set) Intersect(t Set) Set {
+	i := Set{}
+	for elem := range s {
+		if t[elem] {
+			i[elem] = true
+		}
+	}
+	return i
+}
+
+// Equal returns true if both sets have the same elements
+func (s Set) Equal(t Set) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for elem := range s {
+		if !t[elem] {
+			return false
+		}
+	}
+	return true
+}
+
+// Elements returns the elements in increasing order
+func (s Set) Elements() []int {
+	elems := []int{}
+	for elem := range s {
+		elems = append(elems, elem)
+	}
+	sort.Ints(elems)
+	return elems
+}
+
+// Format lists the elements in increasing order, each formatted by format, e.g. "r%v"
+func (s Set) Format(format string) string {
+	elems := []string{}
+	for _, elem := range s.Elements() {
+		elems = append(elems, fmt.Sprintf(format, elem))
+	}
+	return "{" + strings.Join(elems, " ") + "}"
+}
+
+func (s Set) String() string { return s.Format("%v") }
+
+// Analysis is a dataflow problem whose facts are sets
+type Analysis interface {
+	Backward() bool                             // true if the facts flow from the exit to the entry
+	Boundary(g *cfg.Graph) Set                  // the facts at the entry of a forward problem, or the exit of a backward one
+	Top(g *cfg.Graph) Set                       // the facts the other blocks start from, the identity of Meet
+	Meet(a Set, b Set) Set                      // combines the facts of the predecessors, or successors of a backward problem
+	Step(g *cfg.Graph, index int, fact Set) Set // the facts after the instruction at index in the body, given the facts before it; the other way round if Backward
+}
+
+// Result holds the solution of a problem: the facts at the start and the end of each block, in
+// program order whatever the direction of the problem
+type Result struct {
+	Graph    *cfg.Graph
+	Analysis Analysis
+	In       []Set // by block index
+	Out      []Set
+}
+
+// Solve computes the fixed point of a problem over a graph
+func Solve(g *cfg.Graph, a Analysis) *Result {
+	r := &Result{Graph: g, Analysis: a, In: make([]Set, len(g.Blocks)), Out: make([]Set, len(g.Blocks))}
+	for _, b := range g.Blocks {
+		r.In[b.Index], r.Out[b.Index] = a.Top(g), a.Top(g)
+	}
+	// start holds the facts entering each block in the direction of the problem, facts those leaving it
+	start, facts := r.In, r.Out
+	boundary, edges, into := g.Entry, func(b *cfg.Block) []*cfg.Block { return b.Preds }, func(b *cfg.Block) []*cfg.Block { return b.Succs }
+	if a.Backward() {
+		start, facts = r.Out, r.In
+		boundary, edges, into = g.Exit, into, edges
+	}
+
+	work := append([]*cfg.Block{}, g.Blocks...)
+	if a.Backward() {
+		for i, j := 0, len(work)-1; i < j; i, j = i+1, j-1 {
+			work[i], work[j] = work[j], work[i]
+		}
+	}
+	queued := map[*cfg.Block]bool{}
+	for _, b := range work {
+		queued[b] = true
+	}
+	for len(work) > 0 {
+		b := work[0]
+		work = work[1:]
+		queued[b] = false
+
+		fact := a.Top(g)
+		if b == boundary {
+			fact = a.Boundary(g)
+		}
+		for _, other := range edges(b) {
+			fact = a.Meet(fact, facts[other.Index])
+		}
+		start[b.Index] = fact
+		fact = r.transfer(b, fact)
+		if fact.Equal(facts[b.Index]) {
+			continue
+		}
+		facts[b.Index] = fact
+		for _, next := range into(b) {
+			if !queued[next] {
+				work = append(work, next)
+				queued[next] = true
+			}
+		}
+	}
+	return r
+}
+
+// transfer runs the facts through the instructions of a block, in the direction of the problem
+func (r *Result) transfer(b *cfg.Block, fact Set) Set {
+	for _, index := range r.order(b) {
+		fact = r.Analysis.Step(r.Graph, index, fact)
+	}
+	return fact
+}
+
+// order returns the positions in the body of the instructions of a block, in the direction of the problem
+func (r *Result) order(b *cfg.Block) []int {
+	indexes := []int{}
+	for i := range b.Instructions {
+		if r.Analysis.Backward() {
+			indexes = append([]int{b.Start + i}, indexes...)
+		} else {
+			indexes = append(indexes, b.Start+i)
+		}
+	}
+	return indexes
+}
+
+// Before returns the facts before each instruction of a block, in program order
+func (r *Result) Before(b *cfg.Block) []Set {
+	before, _ := r.facts(b)
+	return before
+}
+
+// After returns the facts after each instruction of a block, in program order
+func (r *Result) After(b *cfg.Block) []Set {
+	_, after := r.facts(b)
+	return after
+}
+
+func (r *Result) facts(b *cfg.Block) ([]Set, []Set) {
+	before, after := make([]Set, len(b.Instructions)), make([]Set, len(b.Instructions))
+	if r.Analysis.Backward() {
+		fact := r.Out[b.Index]
+		for i := len(b.Instructions) - 1; i >= 0; i-- {
+			after[i] = fact
+			fact = r.Analysis.Step(r.Graph, b.Start+i, fact)
+			before[i] = fact
+		}
+	} else {
+		fact := r.In[b.Index]
+		for i := range b.Instructions {
+			before[i] = fact
+			fact = r.Analysis.Step(r.Graph, b.Start+i, fact)
+			after[i] = fact
+		}
+	}
+	return before, after
+}
+
+// instruction returns the instruction at index in the body of the function of the graph
+func instruction(g *cfg.Graph, index int) ir.Instruction {
+	return g.Frag.Body[index]
+}
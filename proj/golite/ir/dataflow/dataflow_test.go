@@ -0,0 +1,83 @@
+package dataflow
+
+import (
+	"os"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/parse"
+	"testing"
+)
+
+func build(t *testing.T, path string) *cfg.Graph {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frags, errors := parse.Parse(string(src))
+	if len(errors) != 0 || len(frags) != 1 {
+		t.Fatalf("\nExpected: a single function; Got %v\n", errors)
+	}
+	return cfg.Build(frags[0])
+}
+
+func Test1(t *testing.T) {
+	g := build(t, "test1_dataflow.iloc")
+	live := Liveness(g)
+	expectedIn := []string{"{r1}", "{r1 r2 r3}", "{r1 r2 r3}", "{r2}", "{}"}
+	if len(g.Blocks) != len(expectedIn) {
+		t.Fatalf("\nExpected: %v blocks; Got %v\n", len(expectedIn), len(g.Blocks))
+	}
+	for i, b := range g.Blocks {
+		if in := live.In[b.Index].Format("r%v"); in != expectedIn[i] {
+			t.Errorf("\nExpected: %v live-in %v; Got %v\n", b.Name(), expectedIn[i], in)
+		}
+	}
+	if out := live.Out[2].Format("r%v"); out != "{r1 r2 r3}" {
+		t.Errorf("\nExpected: B2 live-out {r1 r2 r3}; Got %v\n", out)
+	}
+	// r3 dies at the cmp of the last iteration only, it is live after the add r3,r3,#1
+	after := live.After(g.Blocks[1])
+	if after[1].Format("r%v") != "{r1 r2 r3}" || after[2].Format("r%v") != "{r1 r2 r3}" {
+		t.Errorf("\nExpected: r1, r2 and r3 live after the adds; Got %v and %v\n", after[1], after[2])
+	}
+	if before := live.Before(g.Blocks[0]); before[1].Format("r%v") != "{r1}" {
+		t.Errorf("\nExpected: r1 live before mov r2,#0; Got %v\n", before[1])
+	}
+}
+
+func Test2(t *testing.T) {
+	g := build(t, "test2_dataflow.iloc")
+	defs := ReachingDefinitions(g)
+	// the body is f, mov, cmp, movgt, cmp, blt, mov, else_L1, ret
+	if out := defs.Out[0].String(); out != "{1 3}" {
+		t.Errorf("\nExpected: the definitions 1 and 3 leaving B0; Got %v\n", out)
+	}
+	if out := defs.Out[1].String(); out != "{6}" {
+		t.Errorf("\nExpected: the definition 6 leaving B1; Got %v\n", out)
+	}
+	if in := defs.In[2].String(); in != "{1 3 6}" {
+		t.Errorf("\nExpected: the definitions 1, 3 and 6 reaching else_L1; Got %v\n", in)
+	}
+}
+
+func Test3(t *testing.T) {
+	g := build(t, "test3_dataflow.iloc")
+	available, ae := AvailableExpressionsOf(g)
+	exprs := []string{}
+	for _, e := range ae.Exprs {
+		exprs = append(exprs, e.String())
+	}
+	if len(exprs) != 3 || exprs[0] != "add r1,r2" || exprs[1] != "mul r1,r2" || exprs[2] != "sub r2,#1" {
+		t.Fatalf("\nExpected: add r1,r2, mul r1,r2 and sub r2,#1; Got %v\n", exprs)
+	}
+	if out := available.Out[2].String(); out != "{0 1}" {
+		t.Errorf("\nExpected: add and mul available after other_L1; Got %v\n", out)
+	}
+	// the join keeps add r1,r2 alone, and the sub overwriting r2 leaves nothing available
+	join := g.Blocks[3]
+	if in := available.In[join.Index].String(); in != "{0}" {
+		t.Errorf("\nExpected: add available at join_L2; Got %v\n", in)
+	}
+	if after := available.After(join); after[1].String() != "{}" {
+		t.Errorf("\nExpected: nothing available after sub r2,r2,#1; Got %v\n", after[1])
+	}
+}
@@ -0,0 +1,32 @@
+package dataflow
+
+import (
+	"fmt"
+	"proj/golite/ir/cfg"
+	"strings"
+)
+
+// DumpLiveness lists the ILOC of each function by block, each block headed by the registers live
+// at its start and each instruction followed by the registers live after it
+func DumpLiveness(graphs []*cfg.Graph) []string {
+	lines := []string{}
+	for _, g := range graphs {
+		if g.Frag.IsGlobal() {
+			continue
+		}
+		live := Liveness(g)
+		lines = append(lines, fmt.Sprintf("%v:", g.Frag.Label))
+		for _, b := range g.Blocks {
+			if b == g.Exit {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("  %v live-in %v", b.Name(), live.In[b.Index].Format("r%v")))
+			after := live.After(b)
+			for i, instr := range b.Instructions {
+				text := strings.TrimSpace(instr.String())
+				lines = append(lines, fmt.Sprintf("    %-28v %v", text, after[i].Format("r%v")))
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,30 @@
+package dataflow
+
+import "proj/golite/ir/cfg"
+
+// liveness is the backward problem of the registers whose value may be read later on, before
+// being written again
+type liveness struct{}
+
+// Liveness returns the registers live at the start and the end of each block of a function
+func Liveness(g *cfg.Graph) *Result {
+	return Solve(g, liveness{})
+}
+
+func (liveness) Backward() bool            { return true }
+func (liveness) Boundary(g *cfg.Graph) Set { return Set{} }
+func (liveness) Top(g *cfg.Graph) Set      { return Set{} }
+func (liveness) Meet(a Set, b Set) Set     { return a.Union(b) }
+
+// Step removes the registers written by the instruction, then adds the ones it reads
+func (liveness) Step(g *cfg.Graph, index int, live Set) Set {
+	instr := instruction(g, index)
+	live = live.Copy()
+	for _, reg := range instr.GetTargets() {
+		delete(live, reg)
+	}
+	for _, reg := range instr.GetSources() {
+		live[reg] = true
+	}
+	return live
+}
@@ -0,0 +1,49 @@
+package dataflow
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+)
+
+// reachingDefinitions is the forward problem of the instructions whose value of a register may
+// still be held at a point. A definition is the position in the body of an instruction writing a
+// register. The parameters are defined before the body, and have no definition.
+type reachingDefinitions struct {
+	defs map[int][]int // the definitions of each register
+}
+
+// ReachingDefinitions returns the definitions reaching the start and the end of each block of a
+// function
+func ReachingDefinitions(g *cfg.Graph) *Result {
+	rd := reachingDefinitions{map[int][]int{}}
+	for index, instr := range g.Frag.Body {
+		for _, reg := range instr.GetTargets() {
+			rd.defs[reg] = append(rd.defs[reg], index)
+		}
+	}
+	return Solve(g, rd)
+}
+
+func (rd reachingDefinitions) Backward() bool            { return false }
+func (rd reachingDefinitions) Boundary(g *cfg.Graph) Set { return Set{} }
+func (rd reachingDefinitions) Top(g *cfg.Graph) Set      { return Set{} }
+func (rd reachingDefinitions) Meet(a Set, b Set) Set     { return a.Union(b) }
+
+// Step replaces the definitions of the registers written by the instruction with itself. A
+// conditional mov may leave its register as it is, and does not remove the other definitions.
+func (rd reachingDefinitions) Step(g *cfg.Graph, index int, defs Set) Set {
+	instr := instruction(g, index)
+	if len(instr.GetTargets()) == 0 {
+		return defs
+	}
+	defs = defs.Copy()
+	if mov, isMov := instr.(*ir.Mov); !isMov || mov.GetFlag() == ir.AL || mov.GetRetFlag() {
+		for _, reg := range instr.GetTargets() {
+			for _, def := range rd.defs[reg] {
+				delete(defs, def)
+			}
+		}
+	}
+	defs[index] = true
+	return defs
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Delete struct {
@@ -14,13 +14,12 @@ func NewDelete(sourceReg int) *Delete {
 	return &Delete{sourceReg}
 }
 
-func (instr *Delete) GetTargets() []int {
-	source := []int{}
-	source = append(source, instr.sourceReg)
-	return source }
+func (instr *Delete) GetTargets() []int { return []int{} }
 
 func (instr *Delete) GetSources() []int {
-	return []int{}
+	source := []int{}
+	source = append(source, instr.sourceReg)
+	return source
 }
 
 func (instr *Delete) GetImmediate() *int { return nil }
@@ -38,15 +37,15 @@ func (instr *Delete) String() string {
 	return out.String()
 }
 
-func (instr *Delete) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *Delete) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
-	targetRegId := utility.NextAvailReg()
-	delOffset := funcVarDict[instr.sourceReg]
-	instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",targetRegId,delOffset))
-	instruction = append(instruction, fmt.Sprintf("\tmov x0,x%v",targetRegId))
-	instruction = append(instruction, fmt.Sprintf("\tbl free"))
-	utility.ReleaseReg(targetRegId)
+	delRegId, load := regs.use(instr.sourceReg)
+	instruction = append(instruction, load...)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), delRegId))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("free")))
 
 	return instruction
-}
\ No newline at end of file
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Div struct{
@@ -53,42 +53,25 @@ func (instr *Div) String() string {
 
 }
 
-func (instr *Div) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	var source1RedId, source2RedId int
-	var isParam1, isParam2 bool
+func (instr *Div) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
 	// load operand 1
-	if source1RedId, isParam1 = paramRegIds[instr.sourceReg1]; !isParam1 {
-		source1Offset := funcVarDict[instr.sourceReg1]
-		source1RedId = utility.NextAvailReg()
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",source1RedId,source1Offset))
-	}
+	source1RegId, load := regs.use(instr.sourceReg1)
+	instruction = append(instruction, load...)
 
 	// load operand 2
-	if source2RedId, isParam2 = paramRegIds[instr.sourceReg2]; !isParam2 {
-		source2Offset := funcVarDict[instr.sourceReg2]
-		source2RedId = utility.NextAvailReg()
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",source2RedId,source2Offset))
-	}
+	source2RegId, load := regs.use(instr.sourceReg2)
+	instruction = append(instruction, load...)
 
 	// divide
-	targetRegId := utility.NextAvailReg()
-	instruction = append(instruction, fmt.Sprintf("\tsdiv x%v,x%v,x%v",targetRegId,source1RedId,source2RedId))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Sdiv, targetRegId, source1RegId, source2RegId))
 
 	// store result
-	targetOffset := funcVarDict[instr.target]
-	instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]",targetRegId,targetOffset))
-
-
-	if !isParam1 {
-		utility.ReleaseReg(source1RedId)
-	}
-	if !isParam2 {
-		utility.ReleaseReg(source2RedId)
-	}
-	utility.ReleaseReg(targetRegId)
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
 
 	return instruction
-}
\ No newline at end of file
+}
@@ -29,6 +29,28 @@ func NewLabel() string {
 	return retVal
 }
 
+// ResetGenerators restarts the numbering of registers and labels, so that compiling
+// the same program twice produces the same ILOC
+func ResetGenerators() {
+	rGen.count = 0
+	lGen.count = 0
+}
+
+// ReserveRegisters makes NewRegister only return registers above reg, for ILOC that was not
+// generated by this process, e.g. read back from its text
+func ReserveRegisters(reg int) {
+	if rGen.count <= reg {
+		rGen.count = reg + 1
+	}
+}
+
+// ReserveLabels makes NewLabel and NewLabelWithPre only number labels above n
+func ReserveLabels(n int) {
+	if lGen.count <= n {
+		lGen.count = n + 1
+	}
+}
+
 var rGen  *registerGen
 var lGen  *labelGen
 
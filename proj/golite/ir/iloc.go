@@ -1,5 +1,11 @@
 package ir
 
+import (
+	"fmt"
+	"proj/golite/arm/asm"
+	"strings"
+)
+
 type OperandTy int
 
 const (
@@ -24,9 +30,9 @@ const (
 )
 
 type Instruction interface {
-	GetTargets() []int // Get the registers targeted by this instruction
+	GetTargets() []int // Get the registers targeted by this instruction, i.e. the registers it writes
 
-	GetSources() []int // Get the source registers for this instruction
+	GetSources() []int // Get the source registers for this instruction, i.e. the registers it reads
 
 	GetImmediate() *int // Get the immediate value (i.e., constant) of this instruction
 
@@ -38,11 +44,47 @@ type Instruction interface {
 
 	String() string // Return a string representation of this instruction
 
-	TranslateToAssembly(map[int]int, map[int]int) []string
+	TranslateToAssembly(map[int]int, map[int]int) []asm.Line // Translate into Arm code, given the stack slots and Arm registers of the ILOC registers
 }
 
 type FuncFrag struct {
-	Label string        // Function name
-	Body  []Instruction // Function body of ILOC instructions
+	Label  string        // Function name
+	Params []int         // Registers holding the parameters, in the order of the arguments pushed
+	Body   []Instruction // Function body of ILOC instructions
 	//Frame   *codegen.Frame	 // Activation Records (i.e., stack frame) for this function
 }
+
+// GlobalFragPrefix starts the label of the fragment initializing the global variables, which
+// comes before the functions
+const GlobalFragPrefix = "Global Variable"
+
+// IsGlobal returns true for the fragment initializing the global variables
+func (frag *FuncFrag) IsGlobal() bool {
+	return strings.HasPrefix(frag.Label, GlobalFragPrefix)
+}
+
+// Lines returns the textual ILOC of the fragment, one instruction per line. The label of a
+// function is followed by a params line listing the registers of its parameters, so that
+// proj/golite/ir/parse can read the fragment back.
+func (frag *FuncFrag) Lines() []string {
+	lines := []string{}
+	for i, instruction := range frag.Body {
+		lines = append(lines, instruction.String())
+		if i == 0 && !frag.IsGlobal() {
+			lines = append(lines, fmt.Sprintf("    params {%v}", registerList(frag.Params)))
+		}
+	}
+	return lines
+}
+
+// registerList formats registers the way push and pop list them, e.g. r1,r2
+func registerList(regs []int) string {
+	list := ""
+	for i, reg := range regs {
+		if i > 0 {
+			list += ","
+		}
+		list += fmt.Sprintf("r%v", reg)
+	}
+	return list
+}
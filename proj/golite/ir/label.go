@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 )
 
 type Label struct {
@@ -35,10 +36,10 @@ func (instr *Label) String() string {
 	return out.String()
 }
 
-func (instr *Label) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *Label) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
 
-	instruction = append(instruction, fmt.Sprintf("%v:",instr.label))
+	instruction = append(instruction, asm.Label(instr.label))
 
 	return instruction
 }
\ No newline at end of file
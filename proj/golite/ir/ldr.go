@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Ldr struct {
@@ -78,16 +78,17 @@ func (instr *Ldr) String() string {
 	return out.String()
 }
 
-func (instr *Ldr) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *Ldr) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
 	if instr.opty == GLOBALVAR {
-		addrRegId := utility.NextAvailReg()
-		instruction = append(instruction, fmt.Sprintf("\tadrp x%v,%v",addrRegId,instr.globalVar))
-		instruction = append(instruction, fmt.Sprintf("\tadd x%v,x%v, :lo12:%v",addrRegId,addrRegId,instr.globalVar))
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x%v]",addrRegId,addrRegId))
-		targetOffset := funcVarDict[instr.target]
-		instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]",addrRegId,targetOffset))
-		utility.ReleaseReg(addrRegId)
+		addrRegId := regs.def(instr.target)
+		instruction = append(instruction, asm.NewInstr(asm.Adrp, addrRegId, asm.Symbol(instr.globalVar)))
+		instruction = append(instruction, asm.NewInstr(asm.Add, addrRegId, addrRegId, asm.Lo12(instr.globalVar)))
+		instruction = append(instruction, asm.NewInstr(asm.Ldr, addrRegId, asm.Mem{Base: addrRegId}))
+		instruction = append(instruction, regs.store(instr.target, addrRegId)...)
 	}
 
 	return instruction
@@ -0,0 +1,69 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+)
+
+// LoadIdx loads the element at an index of the block of the elements of an array or a slice, the
+// index having been checked by a bounds
+type LoadIdx struct {
+	target int
+	source int
+	index  int
+}
+
+func NewLoadIdx(target int, source int, index int) *LoadIdx {
+	return &LoadIdx{target, source, index}
+}
+
+func (instr *LoadIdx) GetTargets() []int {
+	targets := []int{}
+	targets = append(targets, instr.target)
+	return targets
+}
+
+// GetSources returns the register holding the block, then the register of the index
+func (instr *LoadIdx) GetSources() []int {
+	sources := []int{}
+	sources = append(sources, instr.source, instr.index)
+	return sources
+}
+
+func (instr *LoadIdx) GetImmediate() *int { return nil }
+
+func (instr *LoadIdx) GetSourceString() string { return "" }
+
+func (instr *LoadIdx) GetLabel() string { return "" }
+
+func (instr *LoadIdx) SetLabel(newLabel string) {}
+
+func (instr *LoadIdx) String() string {
+	var out bytes.Buffer
+
+	targetReg := fmt.Sprintf("r%v", instr.target)
+	sourceReg := fmt.Sprintf("r%v", instr.source)
+	indexReg := fmt.Sprintf("r%v", instr.index)
+
+	out.WriteString(fmt.Sprintf("    loadIdx %s,%s,%s", targetReg, sourceReg, indexReg))
+
+	return out.String()
+}
+
+func (instr *LoadIdx) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	blockRegId, load := regs.use(instr.source)
+	instruction = append(instruction, load...)
+	indexRegId, load := regs.use(instr.index)
+	instruction = append(instruction, load...)
+
+	loadToRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Ldr, loadToRegId, asm.Indexed{Base: blockRegId, Index: indexRegId}))
+	instruction = append(instruction, regs.store(instr.target, loadToRegId)...)
+
+	return instruction
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 // to access fields of a struct
@@ -40,33 +40,34 @@ func (instr *LoadRef) GetLabel() string { return "" }
 
 func (instr *LoadRef) SetLabel(newLabel string) {}
 
+// GetFieldIdx returns the position of the field in its struct, its offset is 8 bytes per field
+func (instr *LoadRef) GetFieldIdx() int { return instr.fieldIdx }
+
 func (instr *LoadRef) String() string {
 	var out bytes.Buffer
 
 	targetReg := fmt.Sprintf("r%v", instr.target)
 	sourceReg := fmt.Sprintf("r%v", instr.source)
 	strField := fmt.Sprintf("@%v", instr.field)
+	fieldIdx := fmt.Sprintf("#%v", instr.fieldIdx)
 
-	out.WriteString(fmt.Sprintf("    loadRef %s,%s,%s", targetReg, sourceReg, strField))
+	out.WriteString(fmt.Sprintf("    loadRef %s,%s,%s,%s", targetReg, sourceReg, strField, fieldIdx))
 
 	return out.String()
 }
 
-func (instr *LoadRef) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *LoadRef) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
-	loadToRegId := utility.NextAvailReg()
-	loadToOffset := funcVarDict[instr.target]
-	structRegId := utility.NextAvailReg()
-	structOffset := funcVarDict[instr.source]
+	structRegId, load := regs.use(instr.source)
+	instruction = append(instruction, load...)
 	fieldOffset := instr.fieldIdx * 8
 
-	instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", structRegId, structOffset))
-	instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x%v,#%v]", loadToRegId, structRegId, fieldOffset))
-	instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", loadToRegId, loadToOffset))
-
-	utility.ReleaseReg(loadToRegId)
-	utility.ReleaseReg(structRegId)
+	loadToRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Ldr, loadToRegId, asm.Mem{Base: structRegId, Offset: fieldOffset}))
+	instruction = append(instruction, regs.store(instr.target, loadToRegId)...)
 
 	return instruction
 }
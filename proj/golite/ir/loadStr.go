@@ -0,0 +1,75 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/utility"
+	"strconv"
+)
+
+// LoadStr sets its target to the address of a string literal, a NUL terminated string that is
+// never written to
+type LoadStr struct {
+	target int
+	value  string
+}
+
+func NewLoadStr(target int, value string) *LoadStr {
+	return &LoadStr{target, value}
+}
+
+func (instr *LoadStr) GetTargets() []int {
+	targets := []int{}
+	targets = append(targets, instr.target)
+	return targets
+}
+
+func (instr *LoadStr) GetSources() []int { return []int{} }
+
+func (instr *LoadStr) GetImmediate() *int { return nil }
+
+func (instr *LoadStr) GetSourceString() string { return "" }
+
+func (instr *LoadStr) GetLabel() string { return "" }
+
+func (instr *LoadStr) SetLabel(newLabel string) {}
+
+// GetValue returns the text of the literal, its escapes decoded
+func (instr *LoadStr) GetValue() string { return instr.value }
+
+func (instr *LoadStr) String() string {
+	var out bytes.Buffer
+
+	targetReg := fmt.Sprintf("r%v", instr.target)
+
+	out.WriteString(fmt.Sprintf("    loadStr %s,%s", targetReg, strconv.Quote(instr.value)))
+
+	return out.String()
+}
+
+// StringArmData returns the .asciz entries of the string literals of a program
+func StringArmData(literals []string) []asm.Line {
+	data := []asm.Line{}
+	for idx, literal := range literals {
+		label := utility.StringLabel(idx)
+		data = append(data, asm.Label(label))
+		data = append(data, asm.NewDirective(".asciz", strconv.Quote(literal)))
+		data = append(data, asm.NewDirective(".size", label, fmt.Sprint(len(literal)+1)))
+	}
+	return data
+}
+
+func (instr *LoadStr) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	label := utility.AddString(instr.value)
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Adrp, targetRegId, asm.Symbol(label)))
+	instruction = append(instruction, asm.NewInstr(asm.Add, targetRegId, targetRegId, asm.Lo12(label)))
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
+
+	return instruction
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Mov struct {
@@ -24,11 +24,20 @@ func (instr *Mov) GetTargets() []int {
 	return targets
 }
 
+// GetSources returns the register moved, if any, then the target of a conditional mov, which
+// keeps its value when the condition does not hold. The r0 of "mov rX,r0 @Return" is the value
+// returned by the call rather than a register of the function, and is not a source.
 func (instr *Mov) GetSources() []int {
 	sources := []int{}
+	if instr.retFlag {
+		return sources
+	}
 	if instr.opty == REGISTER {
 		sources = append(sources, instr.operand)
 	}
+	if instr.flag != AL {
+		sources = append(sources, instr.target)
+	}
 	return sources
 }
 
@@ -90,111 +99,32 @@ func (instr *Mov) String() string {
 	return out.String()
 }
 
-func (instr *Mov) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	if instr.flag == AL {
-		if instr.retFlag {
-			tempRegId := utility.NextAvailReg()
-			tempOffset := funcVarDict[instr.target]
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,x0", tempRegId))
-			instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", tempRegId, tempOffset))
-			utility.ReleaseReg(tempRegId)
-			return instruction
-		}
-		var sourceRegId, targetRegId int
-		var isSourceParam, isTargetParam bool
-
-		if targetRegId, isTargetParam = paramRegIds[instr.target]; !isTargetParam {
-			targetRegId = utility.NextAvailReg()
-		}
-
-		if instr.opty == REGISTER {
-			if sourceRegId, isSourceParam = paramRegIds[instr.operand]; !isSourceParam {
-				sourceOffset := funcVarDict[instr.operand]
-				sourceRegId = utility.NextAvailReg()
-				instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", sourceRegId, sourceOffset))
-			}
-		}
-
-		if instr.opty == REGISTER {
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,x%v", targetRegId, sourceRegId))
-		} else {
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", targetRegId, instr.operand))
-		}
-
-		if !isTargetParam {
-			targetOffset := funcVarDict[instr.target]
-			instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", targetRegId, targetOffset))
-		}
-
-		if instr.opty == REGISTER && !isSourceParam {
-			utility.ReleaseReg(sourceRegId)
-		}
-		if !isTargetParam {
-			utility.ReleaseReg(targetRegId)
-		}
+func (instr *Mov) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	// a conditional mov skips over the move when its condition does not hold
+	var label string
+	if instr.flag != AL {
+		label = NewLabelWithPre("skipMov")
+		instruction = append(instruction, asm.NewCondInstr(asm.B, armCond(invertFlag(instr.flag)), asm.Symbol(label)))
+	}
+
+	targetRegId := regs.def(instr.target)
+	if instr.retFlag {
+		instruction = append(instruction, asm.NewInstr(asm.Mov, targetRegId, asm.X(0)))
+	} else if instr.opty == REGISTER {
+		sourceRegId, load := regs.use(instr.operand)
+		instruction = append(instruction, load...)
+		instruction = append(instruction, asm.NewInstr(asm.Mov, targetRegId, sourceRegId))
 	} else {
-		label := NewLabelWithPre("skipMov")
-		cmpResReg := utility.NextAvailReg()
-		cmpResOffset := funcVarDict[instr.target]
-		tempReg := utility.NextAvailReg()
-		if instr.flag == LT {
-			instruction = append(instruction, fmt.Sprintf("\tb.ge %v", label))
-
-			if instr.opty == IMMEDIATE {
-				instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", tempReg, instr.operand))
-			} else {
-				operandOffset := funcVarDict[instr.operand]
-				instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", tempReg, operandOffset))
-			}
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,x%v", cmpResReg, tempReg))
-			instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", cmpResReg, cmpResOffset))
-
-			instruction = append(instruction, fmt.Sprintf("%v:", label))
-		} else if instr.flag == NE {
-			instruction = append(instruction, fmt.Sprintf("\tb.eq %v", label))
-
-			if instr.opty == IMMEDIATE {
-				instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", tempReg, instr.operand))
-			} else {
-				operandOffset := funcVarDict[instr.operand]
-				instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", tempReg, operandOffset))
-			}
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,x%v", cmpResReg, tempReg))
-			instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", cmpResReg, cmpResOffset))
-
-			instruction = append(instruction, fmt.Sprintf("%v:", label))
-		} else if instr.flag == LE {
-			instruction = append(instruction, fmt.Sprintf("\tb.gt %v", label))
-
-			if instr.opty == IMMEDIATE {
-				instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", tempReg, instr.operand))
-			} else {
-				operandOffset := funcVarDict[instr.operand]
-				instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", tempReg, operandOffset))
-			}
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,x%v", cmpResReg, tempReg))
-			instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", cmpResReg, cmpResOffset))
-
-			instruction = append(instruction, fmt.Sprintf("%v:", label))
-		} else if instr.flag == EQ {
-			instruction = append(instruction, fmt.Sprintf("\tb.ne %v", label))
-
-			if instr.opty == IMMEDIATE {
-				instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", tempReg, instr.operand))
-			} else {
-				operandOffset := funcVarDict[instr.operand]
-				instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", tempReg, operandOffset))
-			}
-			instruction = append(instruction, fmt.Sprintf("\tmov x%v,x%v", cmpResReg, tempReg))
-			instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", cmpResReg, cmpResOffset))
-
-			instruction = append(instruction, fmt.Sprintf("%v:", label))
-
-		}
-		utility.ReleaseReg(tempReg)
-		utility.ReleaseReg(cmpResReg)
+		instruction = append(instruction, asm.MovImm(targetRegId, instr.operand))
+	}
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
+
+	if instr.flag != AL {
+		instruction = append(instruction, asm.Label(label))
 	}
 	return instruction
 }
@@ -202,3 +132,9 @@ func (instr *Mov) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[i
 func (instr *Mov) SetRetFlag() {
 	instr.retFlag = true
 }
+
+// GetRetFlag returns true if the instruction moves the value returned by the last call
+func (instr *Mov) GetRetFlag() bool { return instr.retFlag }
+
+// GetFlag returns the condition under which the move happens, AL for always
+func (instr *Mov) GetFlag() ApsrFlag { return instr.flag }
@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 )
 
 type New struct {
@@ -33,35 +34,29 @@ func (instr *New) GetLabel() string { return "" }
 
 func (instr *New) SetLabel(newLabel string) {}
 
+// GetSize returns the number of fields of the struct allocated
+func (instr *New) GetSize() int { return instr.size }
+
 func (instr *New) String() string {
 	var out bytes.Buffer
 	targetReg := fmt.Sprintf("r%v",instr.target)
-	out.WriteString(fmt.Sprintf("    new %s,%s",targetReg,instr.dataType))
+	out.WriteString(fmt.Sprintf("    new %s,%s,#%v",targetReg,instr.dataType,instr.size))
 	return out.String()
 }
 
-func (instr *New) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	// prepare for malloc, push x0... to stack
-	offset := 16
-	for i := 0; i < len(paramRegIds); i++ {
-		instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,%v]",i,offset))
-		offset += 8
-	}
+func (instr *New) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
-	space := instr.size * 8
-	instruction = append(instruction, fmt.Sprintf("\tmov x0,#%v", space))
-	instruction = append(instruction, "\tbl malloc")
-	targetOffset := funcVarDict[instr.target]
-	instruction = append(instruction, fmt.Sprintf("\tstr x0,[x29,#%v]",targetOffset))
-
-	// restore registers after malloc
-	offset = 16
-	for i := 0; i < len(paramRegIds); i++ {
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,%v]",i,offset))
-		offset += 8
-	}
+	// calloc(fields,8) sets the fields to 0, nil and false, the registers live across it are
+	// callee-saved or spilled, none needs saving here
+	instruction = append(instruction, asm.MovImm(asm.X(0), instr.size))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), asm.Imm(8)))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("calloc")))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, targetRegId, asm.X(0)))
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
 
 	return instruction
-}
\ No newline at end of file
+}
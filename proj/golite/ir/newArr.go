@@ -0,0 +1,83 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+)
+
+// NewArr allocates a block of words set to 0, the elements of an array or a slice or the header
+// of a slice, its operand being the number of words
+type NewArr struct {
+	target  int
+	operand int
+	opty    OperandTy
+}
+
+func NewNewArr(target int, operand int, opty OperandTy) *NewArr {
+	return &NewArr{target, operand, opty}
+}
+
+func (instr *NewArr) GetTargets() []int {
+	targets := []int{}
+	targets = append(targets, instr.target)
+	return targets
+}
+
+func (instr *NewArr) GetSources() []int {
+	sources := []int{}
+	if instr.opty == REGISTER {
+		sources = append(sources, instr.operand)
+	}
+	return sources
+}
+
+func (instr *NewArr) GetImmediate() *int {
+	if instr.opty == IMMEDIATE {
+		return &instr.operand
+	}
+	return nil
+}
+
+func (instr *NewArr) GetSourceString() string { return "" }
+
+func (instr *NewArr) GetLabel() string { return "" }
+
+func (instr *NewArr) SetLabel(newLabel string) {}
+
+func (instr *NewArr) String() string {
+	var out bytes.Buffer
+
+	targetReg := fmt.Sprintf("r%v", instr.target)
+	prefix := "r"
+	if instr.opty == IMMEDIATE {
+		prefix = "#"
+	}
+	size := fmt.Sprintf("%v%v", prefix, instr.operand)
+
+	out.WriteString(fmt.Sprintf("    newArr %s,%s", targetReg, size))
+
+	return out.String()
+}
+
+func (instr *NewArr) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	// calloc(n,8) sets the words to 0, the registers live across it are callee-saved or spilled
+	if instr.opty == REGISTER {
+		sizeRegId, load := regs.use(instr.operand)
+		instruction = append(instruction, load...)
+		instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), sizeRegId))
+	} else {
+		instruction = append(instruction, asm.MovImm(asm.X(0), instr.operand))
+	}
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), asm.Imm(8)))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("calloc")))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, targetRegId, asm.X(0)))
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
+
+	return instruction
+}
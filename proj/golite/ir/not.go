@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Not struct {
@@ -72,31 +72,23 @@ func (instr *Not) String() string {
 
 }
 
-func (instr *Not) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *Not) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
 	// load operand
-	sourceRegId := utility.NextAvailReg()
-	if instr.opty == REGISTER {
-		source2Offset := funcVarDict[instr.operand]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", sourceRegId, source2Offset))
-	} else {
-		instruction = append(instruction, fmt.Sprintf("\tmov x%v,#%v", sourceRegId, instr.operand))
-	}
+	sourceRegId, load := regs.operand(instr.operand, instr.opty)
+	instruction = append(instruction, load...)
 
-	targetRegId := utility.NextAvailReg()
-	//instruction = append(instruction, fmt.Sprintf("neg x%v, x%v", targetRegId, sourceRegId))
-	tempRedId := utility.NextAvailReg()
-	instruction = append(instruction, fmt.Sprintf("\tmov x%v,#1",tempRedId))
-	instruction = append(instruction, fmt.Sprintf("\tsubs x%v,x%v,x%v",targetRegId,tempRedId,sourceRegId))
-	utility.ReleaseReg(tempRedId)
+	// not of a bool is 1 minus it
+	oneRegId := asm.X(regs.takeScratch())
+	instruction = append(instruction, asm.NewInstr(asm.Mov, oneRegId, asm.Imm(1)))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Subs, targetRegId, oneRegId, sourceRegId))
 
 	// store result
-	targetOffset := funcVarDict[instr.target]
-	instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x29,#%v]", targetRegId, targetOffset))
-
-	utility.ReleaseReg(sourceRegId)
-	utility.ReleaseReg(targetRegId)
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
 
 	return instruction
 }
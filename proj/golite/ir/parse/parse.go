@@ -0,0 +1,620 @@
+// Package parse reads the textual ILOC printed by golite -iloc back into fragments, so that the
+// backend can be run on hand-written ILOC without going through the front end.
+//
+// Every line holds a label, a params line or an instruction, in the form the String method of
+// the instruction prints it. A label followed by a params line starts a function, and so do the
+// first label of the file and the labels starting with ir.GlobalFragPrefix. Blank lines and the
+// text following // are ignored.
+package parse
+
+import (
+	"bufio"
+	"io"
+	"proj/golite/diag"
+	"proj/golite/ir"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// line is a line of ILOC stripped from its comment and spaces
+type line struct {
+	num  int
+	text string
+}
+
+type parser struct {
+	lines  []line
+	frags  []*ir.FuncFrag
+	frag   *ir.FuncFrag // the fragment being read, nil before the first label
+	errors []diag.Diagnostic
+	num    int // the number of the line being read
+
+	lineOf map[ir.Instruction]int // the line of every instruction, for the errors of checkLabels
+}
+
+// Parse reads the fragments of the ILOC text src. It returns the fragments read so far along
+// with the errors, one per line that is not ILOC and per undefined label or function.
+func Parse(src string) ([]*ir.FuncFrag, []diag.Diagnostic) {
+	return ParseReader(strings.NewReader(src))
+}
+
+// ParseReader is Parse for the ILOC text read from reader
+func ParseReader(reader io.Reader) ([]*ir.FuncFrag, []diag.Diagnostic) {
+	p := &parser{lineOf: map[ir.Instruction]int{}}
+	scanner := bufio.NewScanner(reader)
+	for num := 1; scanner.Scan(); num++ {
+		text := scanner.Text()
+		if comment := commentStart(text); comment >= 0 {
+			text = text[:comment]
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			p.lines = append(p.lines, line{num, text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, []diag.Diagnostic{diag.Errorf(diag.Unreadable, diag.Pos{}, "%v", err)}
+	}
+
+	for i := 0; i < len(p.lines); i++ {
+		p.num = p.lines[i].num
+		p.parseLine(p.lines[i].text, i+1 < len(p.lines) && strings.HasPrefix(p.lines[i+1].text, "params"))
+	}
+	p.checkLabels()
+	return p.frags, p.errors
+}
+
+// commentStart returns the index of the // starting the comment of a line, -1 if there is none,
+// skipping the string literals of loadStr and printf
+func commentStart(text string) int {
+	inString := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case inString && text[i] == '\\':
+			i++
+		case text[i] == '"':
+			inString = !inString
+		case !inString && strings.HasPrefix(text[i:], "//"):
+			return i
+		}
+	}
+	return -1
+}
+
+// quotedEnd returns the index following the quoted string text starts with, the length of text if
+// it does not start with a terminated one
+func quotedEnd(text string) int {
+	if !strings.HasPrefix(text, "\"") {
+		return len(text)
+	}
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+// Reserve makes the generators of proj/golite/ir skip the registers and label numbers used by the
+// parsed fragments, so that later passes creating registers or labels do not clash with them
+func Reserve(frags []*ir.FuncFrag) {
+	maxReg, maxLabel := -1, -1
+	for _, frag := range frags {
+		regs := append([]int{}, frag.Params...)
+		for _, instruction := range frag.Body {
+			regs = append(regs, instruction.GetTargets()...)
+			regs = append(regs, instruction.GetSources()...)
+			if match := labelNumber.FindStringSubmatch(instruction.GetLabel()); match != nil {
+				if n, _ := strconv.Atoi(match[1]); n > maxLabel {
+					maxLabel = n
+				}
+			}
+		}
+		for _, reg := range regs {
+			if reg > maxReg {
+				maxReg = reg
+			}
+		}
+	}
+	ir.ReserveRegisters(maxReg)
+	ir.ReserveLabels(maxLabel)
+}
+
+// labelNumber matches the number ending the labels of ir.NewLabel and ir.NewLabelWithPre
+var labelNumber = regexp.MustCompile(`L(\d+)$`)
+
+func (p *parser) errorf(code diag.Code, format string, args ...interface{}) {
+	p.errors = append(p.errors, diag.Errorf(code, diag.Pos{Line: p.num}, format, args...))
+}
+
+func (p *parser) parseLine(text string, beforeParams bool) {
+	if strings.HasSuffix(text, ":") {
+		label := strings.TrimSpace(strings.TrimSuffix(text, ":"))
+		if label == "" {
+			p.errorf(diag.BadInstruction, "a label needs a name")
+			return
+		}
+		if p.frag == nil || beforeParams || strings.HasPrefix(label, ir.GlobalFragPrefix) {
+			p.frag = &ir.FuncFrag{Label: label}
+			p.frags = append(p.frags, p.frag)
+		}
+		p.frag.Body = append(p.frag.Body, ir.NewLabelStmt(label))
+		return
+	}
+
+	if p.frag == nil {
+		p.errorf(diag.BadInstruction, "%v is outside of any function, a label must come first", text)
+		return
+	}
+	mnemonic, rest := text, ""
+	if space := strings.IndexFunc(text, unicode.IsSpace); space >= 0 {
+		mnemonic, rest = text[:space], strings.TrimSpace(text[space:])
+	}
+	if mnemonic == "params" {
+		if len(p.frag.Body) != 1 || p.frag.Params != nil {
+			p.errorf(diag.BadInstruction, "params must directly follow the label of the function")
+			return
+		}
+		p.frag.Params = []int{}
+		if regs, ok := p.regList(rest); ok {
+			p.frag.Params = regs
+		}
+		return
+	}
+	if instruction := p.instruction(mnemonic, rest); instruction != nil {
+		p.frag.Body = append(p.frag.Body, instruction)
+		p.lineOf[instruction] = p.num
+	}
+}
+
+// flags maps the suffixes of conditional movs and branches to their conditions
+var flags = map[string]ir.ApsrFlag{"": ir.AL, "gt": ir.GT, "lt": ir.LT, "ge": ir.GE, "le": ir.LE, "eq": ir.EQ, "ne": ir.NE}
+
+// instruction parses a single instruction, it returns nil after reporting an error
+func (p *parser) instruction(mnemonic string, rest string) ir.Instruction {
+	switch mnemonic {
+	case "push", "pop":
+		// push {r1,r2} @f
+		close := strings.Index(rest, "}")
+		if close < 0 {
+			p.errorf(diag.BadInstruction, "%v expects {registers} @function", mnemonic)
+			return nil
+		}
+		regs, ok := p.regList(rest[:close+1])
+		ops := p.operands(mnemonic, rest[close+1:], 1)
+		if !ok || ops == nil {
+			return nil
+		}
+		funcName, ok := p.name(ops[0])
+		if !ok {
+			return nil
+		}
+		if mnemonic == "push" {
+			return ir.NewPush(regs, funcName)
+		}
+		return ir.NewPop(regs, funcName)
+	}
+
+	var ops []string
+	switch mnemonic {
+	case "ret":
+		if rest == "" {
+			return ir.NewRet(-1, ir.VOID)
+		}
+		if ops = p.operands(mnemonic, rest, 1); ops == nil {
+			return nil
+		}
+		operand, opty, ok := p.regOrImm(ops[0])
+		if !ok {
+			return nil
+		}
+		return ir.NewRet(operand, opty)
+
+	case "add", "sub", "and", "or":
+		if ops = p.operands(mnemonic, rest, 3); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		source, ok2 := p.reg(ops[1])
+		operand, opty, ok3 := p.regOrImm(ops[2])
+		if !ok1 || !ok2 || !ok3 {
+			return nil
+		}
+		switch mnemonic {
+		case "add":
+			return ir.NewAdd(target, source, operand, opty)
+		case "sub":
+			return ir.NewSub(target, source, operand, opty)
+		case "and":
+			return ir.NewAnd(target, source, operand, opty)
+		default:
+			return ir.NewOr(target, source, operand, opty)
+		}
+
+	case "mul", "div":
+		if ops = p.operands(mnemonic, rest, 3); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		source1, ok2 := p.reg(ops[1])
+		source2, ok3 := p.reg(ops[2])
+		if !ok1 || !ok2 || !ok3 {
+			return nil
+		}
+		if mnemonic == "mul" {
+			return ir.NewMul(target, source1, source2)
+		}
+		return ir.NewDiv(target, source1, source2)
+
+	case "not":
+		if ops = p.operands(mnemonic, rest, 2); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		operand, opty, ok2 := p.regOrImm(ops[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewNot(target, operand, opty)
+
+	case "cmp":
+		if ops = p.operands(mnemonic, rest, 2); ops == nil {
+			return nil
+		}
+		source, ok1 := p.reg(ops[0])
+		operand, opty, ok2 := p.regOrImm(ops[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewCmp(source, operand, opty)
+
+	case "bl":
+		if ops = p.operands(mnemonic, rest, 1); ops == nil {
+			return nil
+		}
+		return ir.NewBl(ops[0])
+
+	case "ldr", "str":
+		// ldr r1,@g  ldr r1,r2  ldr r1,r2,#8  ldr r1,r2,r3
+		fields := p.split(rest)
+		if len(fields) != 2 && len(fields) != 3 {
+			p.errorf(diag.BadInstruction, "%v expects 2 or 3 operands, found %v", mnemonic, len(fields))
+			return nil
+		}
+		target, ok := p.reg(fields[0])
+		if !ok {
+			return nil
+		}
+		source, operand, globalVar, opty := -1, -1, "", ir.ONEOPERAND
+		if strings.HasPrefix(fields[1], "@") && len(fields) == 2 {
+			globalVar, opty = fields[1][1:], ir.GLOBALVAR
+		} else if source, ok = p.reg(fields[1]); !ok {
+			return nil
+		} else if len(fields) == 3 {
+			if operand, opty, ok = p.regOrImm(fields[2]); !ok {
+				return nil
+			}
+		}
+		if mnemonic == "ldr" {
+			return ir.NewLdr(target, source, operand, globalVar, opty)
+		}
+		return ir.NewStr(target, source, operand, globalVar, opty)
+
+	case "loadRef", "strRef":
+		// loadRef r1,r2,@field,#0
+		if ops = p.operands(mnemonic, rest, 4); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		source, ok2 := p.reg(ops[1])
+		field, ok3 := p.name(ops[2])
+		fieldIdx, ok4 := p.imm(ops[3])
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return nil
+		}
+		if mnemonic == "loadRef" {
+			return ir.NewLoadRef(target, source, field, fieldIdx)
+		}
+		return ir.NewStrRef(target, source, field, fieldIdx)
+
+	case "loadIdx", "strIdx":
+		// loadIdx r1,r2,r3
+		if ops = p.operands(mnemonic, rest, 3); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		source, ok2 := p.reg(ops[1])
+		index, ok3 := p.reg(ops[2])
+		if !ok1 || !ok2 || !ok3 {
+			return nil
+		}
+		if mnemonic == "loadIdx" {
+			return ir.NewLoadIdx(target, source, index)
+		}
+		return ir.NewStrIdx(target, source, index)
+
+	case "newArr":
+		// newArr r1,#5  newArr r1,r2
+		if ops = p.operands(mnemonic, rest, 2); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		operand, opty, ok2 := p.regOrImm(ops[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewNewArr(target, operand, opty)
+
+	case "bounds":
+		// bounds r1,#5  bounds r1,r2
+		if ops = p.operands(mnemonic, rest, 2); ops == nil {
+			return nil
+		}
+		index, ok1 := p.reg(ops[0])
+		operand, opty, ok2 := p.regOrImm(ops[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewBounds(index, operand, opty)
+
+	case "loadStr":
+		// loadStr r1,"text", the literal quoted as by strconv.Quote
+		comma := strings.Index(rest, ",")
+		if comma < 0 {
+			p.errorf(diag.BadInstruction, "%v expects 2 operands, found 1", mnemonic)
+			return nil
+		}
+		target, ok := p.reg(strings.TrimSpace(rest[:comma]))
+		if !ok {
+			return nil
+		}
+		value, err := strconv.Unquote(strings.TrimSpace(rest[comma+1:]))
+		if err != nil {
+			p.errorf(diag.BadInstruction, "expected a quoted string such as \"text\", found %v", strings.TrimSpace(rest[comma+1:]))
+			return nil
+		}
+		return ir.NewLoadStr(target, value)
+
+	case "strLen":
+		// strLen r1,r2
+		if ops = p.operands(mnemonic, rest, 2); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		source, ok2 := p.reg(ops[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewStrLen(target, source)
+
+	case "strCat":
+		// strCat r1,r2,r3
+		if ops = p.operands(mnemonic, rest, 3); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		source1, ok2 := p.reg(ops[1])
+		source2, ok3 := p.reg(ops[2])
+		if !ok1 || !ok2 || !ok3 {
+			return nil
+		}
+		return ir.NewStrCat(target, source1, source2)
+
+	case "new":
+		// new r1,Point,#2
+		if ops = p.operands(mnemonic, rest, 3); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		size, ok2 := p.imm(ops[2])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewNew(target, ops[1], size)
+
+	case "printf":
+		// printf "%ld %s\n",r1,r2, the format quoted as by strconv.Quote
+		end := quotedEnd(rest)
+		format, err := strconv.Unquote(rest[:end])
+		if err != nil {
+			p.errorf(diag.BadInstruction, "expected a quoted format such as \"%%ld\", found %v", rest)
+			return nil
+		}
+		sources := []int{}
+		if regs := strings.TrimSpace(rest[end:]); regs != "" {
+			if !strings.HasPrefix(regs, ",") {
+				p.errorf(diag.BadInstruction, "expected , after the format, found %v", regs)
+				return nil
+			}
+			for _, op := range strings.Split(regs[1:], ",") {
+				source, ok := p.reg(strings.TrimSpace(op))
+				if !ok {
+					return nil
+				}
+				sources = append(sources, source)
+			}
+		}
+		if conversions, valid := ir.Conversions(format); !valid || len(conversions) != len(sources) {
+			p.errorf(diag.BadInstruction, "the format of printf expects a %%ld or %%s for each of its %v registers", len(sources))
+			return nil
+		}
+		return ir.NewPrintf(format, sources)
+
+	case "delete", "print", "println":
+		if ops = p.operands(mnemonic, rest, 1); ops == nil {
+			return nil
+		}
+		source, ok := p.reg(ops[0])
+		if !ok {
+			return nil
+		}
+		switch mnemonic {
+		case "delete":
+			return ir.NewDelete(source)
+		case "print":
+			return ir.NewPrint(source)
+		default:
+			return ir.NewPrintln(source)
+		}
+
+	case "read":
+		// read r1 @variable
+		if ops = p.operands(mnemonic, rest, 2); ops == nil {
+			return nil
+		}
+		target, ok1 := p.reg(ops[0])
+		variable, ok2 := p.name(ops[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return ir.NewRead(target, variable, target)
+	}
+
+	if flag, isMov := flags[strings.TrimPrefix(mnemonic, "mov")]; isMov && strings.HasPrefix(mnemonic, "mov") {
+		// movgt r1,#1, and mov r1,r0 @Return after a call
+		fields := p.split(rest)
+		retFlag := len(fields) == 3 && fields[2] == "@Return"
+		if retFlag {
+			fields = fields[:2]
+		}
+		if len(fields) != 2 {
+			p.errorf(diag.BadInstruction, "%v expects 2 operands, found %v", mnemonic, len(fields))
+			return nil
+		}
+		target, ok1 := p.reg(fields[0])
+		operand, opty, ok2 := p.regOrImm(fields[1])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		mov := ir.NewMov(target, operand, flag, opty)
+		if retFlag {
+			mov.SetRetFlag()
+		}
+		return mov
+	}
+	if flag, isBranch := flags[strings.TrimPrefix(mnemonic, "b")]; isBranch && strings.HasPrefix(mnemonic, "b") {
+		ops = p.operands(mnemonic, rest, 1)
+		if ops == nil {
+			return nil
+		}
+		return ir.NewBranch(flag, ops[0])
+	}
+	p.errorf(diag.BadInstruction, "unknown instruction %v", mnemonic)
+	return nil
+}
+
+// split cuts the operands of an instruction at commas and spaces
+func (p *parser) split(rest string) []string {
+	return strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+}
+
+// operands splits the operands of an instruction, it returns nil if there are not count of them
+func (p *parser) operands(mnemonic string, rest string, count int) []string {
+	ops := p.split(rest)
+	if len(ops) != count {
+		p.errorf(diag.BadInstruction, "%v expects %v operand%v, found %v", mnemonic, count, plural(count), len(ops))
+		return nil
+	}
+	return ops
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
+}
+
+func (p *parser) reg(op string) (int, bool) {
+	if strings.HasPrefix(op, "r") {
+		if reg, err := strconv.Atoi(op[1:]); err == nil && reg >= 0 {
+			return reg, true
+		}
+	}
+	p.errorf(diag.BadInstruction, "expected a register such as r1, found %v", op)
+	return 0, false
+}
+
+func (p *parser) imm(op string) (int, bool) {
+	if strings.HasPrefix(op, "#") {
+		if value, err := strconv.Atoi(op[1:]); err == nil {
+			return value, true
+		}
+	}
+	p.errorf(diag.BadInstruction, "expected an immediate such as #1, found %v", op)
+	return 0, false
+}
+
+func (p *parser) regOrImm(op string) (int, ir.OperandTy, bool) {
+	if strings.HasPrefix(op, "#") {
+		value, ok := p.imm(op)
+		return value, ir.IMMEDIATE, ok
+	}
+	if strings.HasPrefix(op, "r") {
+		reg, ok := p.reg(op)
+		return reg, ir.REGISTER, ok
+	}
+	p.errorf(diag.BadInstruction, "expected a register or an immediate, found %v", op)
+	return 0, ir.REGISTER, false
+}
+
+func (p *parser) name(op string) (string, bool) {
+	if len(op) > 1 && op[0] == '@' {
+		return op[1:], true
+	}
+	p.errorf(diag.BadInstruction, "expected a name such as @x, found %v", op)
+	return "", false
+}
+
+// regList parses a list of registers between braces, e.g. {r1,r2}
+func (p *parser) regList(op string) ([]int, bool) {
+	op = strings.TrimSpace(op)
+	if !strings.HasPrefix(op, "{") || !strings.HasSuffix(op, "}") {
+		p.errorf(diag.BadInstruction, "expected registers between braces such as {r1,r2}, found %v", op)
+		return nil, false
+	}
+	regs := []int{}
+	for _, field := range p.split(op[1 : len(op)-1]) {
+		reg, ok := p.reg(field)
+		if !ok {
+			return nil, false
+		}
+		regs = append(regs, reg)
+	}
+	return regs, true
+}
+
+// checkLabels reports the branches to labels that are not in the same function, and the calls
+// to functions that are not defined
+func (p *parser) checkLabels() {
+	funcs := map[string]bool{}
+	for _, frag := range p.frags {
+		funcs[frag.Label] = true
+	}
+	for _, frag := range p.frags {
+		labels := map[string]bool{}
+		for _, instruction := range frag.Body {
+			if label, isLabel := instruction.(*ir.Label); isLabel {
+				labels[label.GetLabel()] = true
+			}
+		}
+		for _, instruction := range frag.Body {
+			switch instr := instruction.(type) {
+			case *ir.Branch:
+				if !labels[instr.GetLabel()] {
+					p.errors = append(p.errors, diag.Errorf(diag.UndefinedLabel, diag.Pos{Line: p.lineOf[instr]},
+						"%v branches to %v, which is not a label of %v", frag.Label, instr.GetLabel(), frag.Label))
+				}
+			case *ir.Bl:
+				if !funcs[instr.GetLabel()] {
+					p.errors = append(p.errors, diag.Errorf(diag.UndefinedLabel, diag.Pos{Line: p.lineOf[instr]},
+						"%v calls %v, which is not defined", frag.Label, instr.GetLabel()))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package parse
+
+import (
+	"bytes"
+	"os"
+	"proj/golite/diag"
+	"proj/golite/ir"
+	"proj/golite/ir/sim"
+	"strings"
+	"testing"
+)
+
+func parseFile(t *testing.T, path string) ([]*ir.FuncFrag, []diag.Diagnostic) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	return ParseReader(file)
+}
+
+func lines(frags []*ir.FuncFrag) []string {
+	lines := []string{}
+	for _, frag := range frags {
+		lines = append(lines, frag.Lines()...)
+	}
+	return lines
+}
+
+func Test1(t *testing.T) {
+	frags, errors := parseFile(t, "test1_parse.iloc")
+	if len(errors) != 0 {
+		t.Fatalf("\nExpected: no errors; Got %v\n", errors)
+	}
+
+	labels := []string{"Global Variable_L0", "max", "main"}
+	if len(frags) != len(labels) {
+		t.Fatalf("\nExpected: %v fragments; Got %v\n", len(labels), len(frags))
+	}
+	for i, label := range labels {
+		if frags[i].Label != label {
+			t.Errorf("\nExpected: fragment %v; Got %v\n", label, frags[i].Label)
+		}
+	}
+	if !frags[0].IsGlobal() || len(frags[1].Params) != 2 || frags[1].Params[1] != 3 || frags[2].Params == nil {
+		t.Errorf("\nExpected: the globals, then max with params r2,r3; Got %v and %v\n", frags[0].Label, frags[1].Params)
+	}
+
+	// the instructions print as written, and the label else_L1 stays inside max
+	expected := map[int]string{
+		4:  "    params {r2,r3}",
+		6:  "    movgt r4,#1",
+		8:  "    bne else_L1",
+		10: "else_L1: ",
+		16: "    strRef r6,r5,@a,#0",
+		17: "    ldr r7,@limit",
+		23: "    mov r10,r0 @Return",
+		27: "    ret",
+	}
+	printed := lines(frags)
+	if len(printed) != 28 {
+		t.Fatalf("\nExpected: 28 lines; Got %v\n", len(printed))
+	}
+	for i, line := range expected {
+		if printed[i] != line {
+			t.Errorf("\nExpected: line %v to be %q; Got %q\n", i, line, printed[i])
+		}
+	}
+
+	// reading the printed ILOC back gives the same ILOC
+	again, errors := Parse(strings.Join(printed, "\n"))
+	if len(errors) != 0 || strings.Join(lines(again), "\n") != strings.Join(printed, "\n") {
+		t.Errorf("\nExpected: the printed ILOC reads back; Got %v\n", errors)
+	}
+
+	out := bytes.Buffer{}
+	if _, err := sim.Run(frags, strings.NewReader(""), &out); err != nil || out.String() != "7\n" {
+		t.Errorf("\nExpected: 7; Got %q and %v\n", out.String(), err)
+	}
+}
+
+func Test2(t *testing.T) {
+	_, errors := parseFile(t, "test2_parse.iloc")
+	expected := []struct {
+		code diag.Code
+		line int
+	}{
+		{diag.BadInstruction, 1}, // outside of any function
+		{diag.BadInstruction, 4}, // a missing operand
+		{diag.BadInstruction, 5}, // a bad immediate
+		{diag.BadInstruction, 6}, // an unknown instruction
+		{diag.BadInstruction, 7}, // loadRef without its field index
+		{diag.BadInstruction, 8}, // params in the middle of a function
+		{diag.UndefinedLabel, 9},
+		{diag.UndefinedLabel, 10},
+	}
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, e := range expected {
+		if errors[i].Code != e.code || errors[i].Pos.Line != e.line {
+			t.Errorf("\nExpected: %v at line %v; Got %v\n", e.code, e.line, errors[i])
+		}
+	}
+}
+
+func Test3(t *testing.T) {
+	// the registers and labels read are not handed out again
+	frags, _ := Parse("main:\n    params {}\n    mov r41,#1\n    b done_L7\ndone_L7:\n    ret r41\n")
+	ir.ResetGenerators()
+	Reserve(frags)
+	if reg := ir.NewRegister(); reg != 42 {
+		t.Errorf("\nExpected: r42; Got r%v\n", reg)
+	}
+	if label := ir.NewLabel(); label != "L8" {
+		t.Errorf("\nExpected: L8; Got %v\n", label)
+	}
+}
+
+func Test4(t *testing.T) {
+	// a string literal keeps its commas, its // and its escapes
+	src := "main:\n    params {}\n    loadStr r1,\"a, b // \\\"c\\\"\\n\" // comment\n    loadStr r2,\"\"\n" +
+		"    strCat r3,r1,r2\n    strLen r4,r3\n    printf \"%s\\n\",r3\n    printf \"%s\",r2\n    loadStr r5,abc\n"
+	frags, errors := Parse(src)
+	if len(errors) != 1 || errors[0].Pos.Line != 9 {
+		t.Fatalf("\nExpected: an unquoted string at line 9; Got %v\n", errors)
+	}
+	expected := []string{"main: ", "    params {}", "    loadStr r1,\"a, b // \\\"c\\\"\\n\"", "    loadStr r2,\"\"",
+		"    strCat r3,r1,r2", "    strLen r4,r3", "    printf \"%s\\n\",r3", "    printf \"%s\",r2"}
+	if printed := lines(frags); strings.Join(printed, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("\nExpected: %q\nGot: %q\n", expected, printed)
+	}
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 	"strconv"
 )
 
@@ -18,13 +18,9 @@ func NewPop(sourceReg []int, funcName string) *Pop {
 
 func (instr *Pop) GetTargets() []int { return []int{} }
 
-func (instr *Pop) GetSources() []int {
-	sources := []int{}
-	for _, src := range instr.sourceReg {
-		sources = append(sources, src)
-	}
-	return sources
-}
+// GetSources returns no registers: pop only releases the stack space of the arguments pushed,
+// which are not read again
+func (instr *Pop) GetSources() []int { return []int{} }
 
 func (instr *Pop) GetImmediate() *int { return nil }
 
@@ -50,24 +46,10 @@ func (instr *Pop) String() string {
 	return out.String()
 }
 
-func (instr *Pop) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	instruction = append(instruction, fmt.Sprintf("\tldr x0,[x29,#24]"))
-	offset := len(instr.sourceReg) * 8
-	if offset % 16 != 0 {
-		offset += 8
-	}
-
-	instruction = append(instruction, fmt.Sprintf("\tadd sp,sp,#%v",offset))
-
-	iteration := 8
-	if len(instr.sourceReg) <= 8 {
-		iteration = len(instr.sourceReg)
-	}
-
-	for i := 1; i < iteration; i++ {
-		utility.ReleaseReg(i)
+func (instr *Pop) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	// the arguments past the eighth were passed on the stack, whose space is released
+	if len(instr.sourceReg) > RegArgs {
+		return []asm.Line{asm.NewInstr(asm.Add, asm.SP, asm.SP, asm.Imm(stackArgsSize(len(instr.sourceReg)-RegArgs)))}
 	}
-	return instruction
+	return nil
 }
@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 	"proj/golite/utility"
 )
 
@@ -37,36 +38,29 @@ func (instr *Print) String() string {
 	return out.String()
 }
 
-func PrintArmFormat() []string {
-	printInst := []string{}
-	printInst = append(printInst, ".PRINT:")
-	printInst = append(printInst, "\t.asciz\t\"%ld\"")
-	printInst = append(printInst, "\t.size\t.PRINT, 4")
-	return printInst
+func PrintArmFormat() []asm.Line {
+	return []asm.Line{
+		asm.Label(".PRINT"),
+		asm.NewDirective(".asciz", "\"%ld\""),
+		asm.NewDirective(".size", ".PRINT", "4"),
+	}
 }
 
-func (instr *Print) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
+func (instr *Print) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
 	utility.SetPrint()
-	instruction := []string{}
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
-	var targetRegId int
-	var isTargetParam bool
-	if targetRegId, isTargetParam = paramRegIds[instr.sourceReg]; !isTargetParam {
-		targetRegId = utility.NextAvailReg()
-		targetOffset := funcVarDict[instr.sourceReg]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",targetRegId,targetOffset))
-	}
+	targetRegId, load := regs.use(instr.sourceReg)
+	instruction = append(instruction, load...)
 
-	sourceRegId := utility.NextAvailReg()
-	instruction = append(instruction, fmt.Sprintf("\tadrp x%v, .PRINT", sourceRegId))
-	instruction = append(instruction, fmt.Sprintf("\tadd x%v,x%v, :lo12:.PRINT",sourceRegId, sourceRegId))
-	instruction = append(instruction, fmt.Sprintf("\tmov x1,x%v",targetRegId))
-	instruction = append(instruction, fmt.Sprintf("\tmov x0,x%v",sourceRegId))
-	instruction = append(instruction, "\tbl printf")
+	sourceRegId := asm.X(regs.takeScratch())
+	instruction = append(instruction, asm.NewInstr(asm.Adrp, sourceRegId, asm.Symbol(".PRINT")))
+	instruction = append(instruction, asm.NewInstr(asm.Add, sourceRegId, sourceRegId, asm.Lo12(".PRINT")))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), targetRegId))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), sourceRegId))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("printf")))
 
-	if !isTargetParam {
-		utility.ReleaseReg(targetRegId)
-	}
-	utility.ReleaseReg(sourceRegId)
 	return instruction
 }
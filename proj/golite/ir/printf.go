@@ -0,0 +1,113 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/utility"
+	"strconv"
+	"strings"
+)
+
+// Printf prints its sources with the format of the C printf, whose conversions are %ld for an
+// int and %s for a string, one per source and in their order, and %% for a %
+type Printf struct {
+	format    string
+	sourceReg []int
+}
+
+func NewPrintf(format string, sourceReg []int) *Printf {
+	return &Printf{format, sourceReg}
+}
+
+func (instr *Printf) GetTargets() []int { return []int{} }
+
+func (instr *Printf) GetSources() []int {
+	sources := []int{}
+	sources = append(sources, instr.sourceReg...)
+	return sources
+}
+
+func (instr *Printf) GetImmediate() *int { return nil }
+
+func (instr *Printf) GetSourceString() string { return "" }
+
+func (instr *Printf) GetLabel() string { return "" }
+
+func (instr *Printf) SetLabel(newLabel string) {}
+
+// GetFormat returns the format printed, its escapes decoded
+func (instr *Printf) GetFormat() string { return instr.format }
+
+func (instr *Printf) String() string {
+	var out bytes.Buffer
+	out.WriteString(fmt.Sprintf("    printf %s", strconv.Quote(instr.format)))
+	for _, reg := range instr.sourceReg {
+		out.WriteString(fmt.Sprintf(",r%v", reg))
+	}
+	return out.String()
+}
+
+// Conversions returns the conversions of a printf format, "ld" or "s" for each value printed,
+// and false if the format has another one
+func Conversions(format string) ([]string, bool) {
+	conversions := []string{}
+	for rest := format; ; {
+		percent := strings.Index(rest, "%")
+		if percent < 0 {
+			return conversions, true
+		}
+		rest = rest[percent+1:]
+		switch {
+		case strings.HasPrefix(rest, "%"):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "ld"):
+			conversions = append(conversions, "ld")
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "s"):
+			conversions = append(conversions, "s")
+			rest = rest[1:]
+		default:
+			return conversions, false
+		}
+	}
+}
+
+// printfRegArgs is the number of values printf takes in x1 to x7, the next ones being passed on
+// the stack
+const printfRegArgs = 7
+
+func (instr *Printf) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+
+	// the values past the seventh go to the stack, 8 bytes each from sp, which stays 16-byte aligned
+	stackSize := 0
+	if len(instr.sourceReg) > printfRegArgs {
+		stackSize = stackArgsSize(len(instr.sourceReg) - printfRegArgs)
+		instruction = append(instruction, asm.NewInstr(asm.Sub, asm.SP, asm.SP, asm.Imm(stackSize)))
+	}
+	for idx, reg := range instr.sourceReg {
+		// the registers of a value are released before the next one, a printf may have more
+		// spilled values than there are scratch registers
+		regs := newArmRegs(funcVarDict, regIds)
+		sourceRegId, load := regs.use(reg)
+		instruction = append(instruction, load...)
+		if idx < printfRegArgs {
+			instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(idx+1), sourceRegId))
+		} else {
+			slot := asm.Mem{Base: asm.SP, Offset: 8 * (idx - printfRegArgs)}
+			instruction = append(instruction, asm.NewInstr(asm.Str, sourceRegId, slot))
+		}
+		regs.release()
+	}
+
+	label := utility.AddString(instr.format)
+	instruction = append(instruction, asm.NewInstr(asm.Adrp, asm.X(0), asm.Symbol(label)))
+	instruction = append(instruction, asm.NewInstr(asm.Add, asm.X(0), asm.X(0), asm.Lo12(label)))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("printf")))
+	if stackSize > 0 {
+		instruction = append(instruction, asm.NewInstr(asm.Add, asm.SP, asm.SP, asm.Imm(stackSize)))
+	}
+
+	return instruction
+}
@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 	"proj/golite/utility"
 )
 
@@ -37,36 +38,29 @@ func (instr *Println) String() string {
 	return out.String()
 }
 
-func PrintLnArmFormat() []string {
-	printInst := []string{}
-	printInst = append(printInst, ".PRINT_LN:")
-	printInst = append(printInst, "\t.asciz\t\"%ld\\n\"")
-	printInst = append(printInst, "\t.size\t.PRINT_LN, 5")
-	return printInst
+func PrintLnArmFormat() []asm.Line {
+	return []asm.Line{
+		asm.Label(".PRINT_LN"),
+		asm.NewDirective(".asciz", "\"%ld\\n\""),
+		asm.NewDirective(".size", ".PRINT_LN", "5"),
+	}
 }
 
-func (instr *Println) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
+func (instr *Println) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
 	utility.SetPrintln()
-	instruction := []string{}
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
-	var targetRegId int
-	var isTargetParam bool
-	if targetRegId, isTargetParam = paramRegIds[instr.sourceReg]; !isTargetParam {
-		targetRegId = utility.NextAvailReg()
-		targetOffset := funcVarDict[instr.sourceReg]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",targetRegId,targetOffset))
-	}
+	targetRegId, load := regs.use(instr.sourceReg)
+	instruction = append(instruction, load...)
 
-	sourceRegId := utility.NextAvailReg()
-	instruction = append(instruction, fmt.Sprintf("\tadrp x%v, .PRINT_LN", sourceRegId))
-	instruction = append(instruction, fmt.Sprintf("\tadd x%v,x%v, :lo12:.PRINT_LN",sourceRegId, sourceRegId))
-	instruction = append(instruction, fmt.Sprintf("\tmov x1,x%v",targetRegId))
-	instruction = append(instruction, fmt.Sprintf("\tmov x0,x%v",sourceRegId))
-	instruction = append(instruction, "\tbl printf")
+	sourceRegId := asm.X(regs.takeScratch())
+	instruction = append(instruction, asm.NewInstr(asm.Adrp, sourceRegId, asm.Symbol(".PRINT_LN")))
+	instruction = append(instruction, asm.NewInstr(asm.Add, sourceRegId, sourceRegId, asm.Lo12(".PRINT_LN")))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), targetRegId))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), sourceRegId))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("printf")))
 
-	if !isTargetParam {
-		utility.ReleaseReg(targetRegId)
-	}
-	utility.ReleaseReg(sourceRegId)
 	return instruction
 }
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 	"strconv"
 )
 
@@ -50,28 +50,49 @@ func (instr *Push) String() string {
 	return out.String()
 }
 
-func (instr *Push) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+// RegArgs is the number of arguments passed in x0 to x7, the next ones being passed on the stack
+const RegArgs = 8
 
-	instruction = append(instruction, fmt.Sprintf("\tstr x0,[x29,#24]"))
-	offset := len(instr.sourceReg) * 8
-	if offset % 16 != 0 {
-		offset += 8
+// stackArgsSize returns the bytes of stack taken by words arguments, sp staying 16-byte aligned
+func stackArgsSize(words int) int {
+	return (8*words + 15) / 16 * 16
+}
+
+// StackParam returns the address of the parameter idx, from RegArgs on, in the frame of the
+// function called: the caller stores it at sp+8*(idx-RegArgs), right above the x29 and x30 saved
+// by the prologue of the function
+func StackParam(idx int) asm.Mem {
+	return asm.Mem{Base: asm.FP, Offset: 16 + 8*(idx-RegArgs)}
+}
+
+func (instr *Push) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+
+	// the arguments past the eighth go to the stack, released by the pop following the call
+	if len(instr.sourceReg) > RegArgs {
+		stackArgs := instr.sourceReg[RegArgs:]
+		instruction = append(instruction, asm.NewInstr(asm.Sub, asm.SP, asm.SP, asm.Imm(stackArgsSize(len(stackArgs)))))
+		for i, reg := range stackArgs {
+			regs := newArmRegs(funcVarDict, regIds)
+			argRegId, load := regs.use(reg)
+			instruction = append(instruction, load...)
+			instruction = append(instruction, asm.NewInstr(asm.Str, argRegId, asm.Mem{Base: asm.SP, Offset: 8 * i}))
+			regs.release()
+		}
 	}
-	instruction = append(instruction, fmt.Sprintf("\tsub sp,sp,#%v",offset))
 
-	iteration := 8
-	if len(instr.sourceReg) <= 8 {
+	// the first ones go to x0 to x7, none of which is allocated to a register
+	iteration := RegArgs
+	if len(instr.sourceReg) <= RegArgs {
 		iteration = len(instr.sourceReg)
 	}
 
 	for i := 0; i < iteration; i++ {
-		aggRegId := utility.NextAvailReg()
-		argOffset := funcVarDict[instr.sourceReg[i]]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",aggRegId,argOffset))
-		instruction = append(instruction, fmt.Sprintf("\tmov x%v,x%v",i,aggRegId))
-		utility.ReleaseReg(aggRegId)
-		utility.OccupyReg(i)
+		if argRegId, allocated := regIds[instr.sourceReg[i]]; allocated {
+			instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(i), asm.X(argRegId)))
+		} else {
+			instruction = append(instruction, LoadSlot(asm.X(i), funcVarDict[instr.sourceReg[i]])...)
+		}
 	}
 	return instruction
 }
@@ -3,6 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
+	"proj/golite/arm/asm"
 	"proj/golite/utility"
 )
 
@@ -40,32 +41,33 @@ func (instr *Read) String() string {
 	return out.String()
 }
 
-func ReadArmFormat() []string {
-	readInsts := []string{}
-	readInsts = append(readInsts, ".READ:")
-	readInsts = append(readInsts, "\t.asciz\t\"%ld\"")
-	readInsts = append(readInsts, "\t.size\t.READ, 4")
-	return readInsts
+func ReadArmFormat() []asm.Line {
+	return []asm.Line{
+		asm.Label(".READ"),
+		asm.NewDirective(".asciz", "\"%ld\""),
+		asm.NewDirective(".size", ".READ", "4"),
+	}
 }
 
-func (instr *Read) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
+func (instr *Read) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
 	utility.SetScan()
-	instruction := []string{}
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	// scanf writes the number to 16 bytes reserved on the stack
+	sourceReg := asm.X(regs.takeScratch())
+	instruction = append(instruction, asm.NewInstr(asm.Sub, asm.SP, asm.SP, asm.Imm(16)))
+	instruction = append(instruction, asm.NewInstr(asm.Adrp, sourceReg, asm.Symbol(".READ")))
+	instruction = append(instruction, asm.NewInstr(asm.Add, sourceReg, sourceReg, asm.Lo12(".READ")))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), asm.SP))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), sourceReg))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("scanf")))
+
+	varTargetRegId := regs.def(instr.targetReg)
+	instruction = append(instruction, asm.NewInstr(asm.Ldr, varTargetRegId, asm.Mem{Base: asm.SP}))
+	instruction = append(instruction, asm.NewInstr(asm.Add, asm.SP, asm.SP, asm.Imm(16)))
+	instruction = append(instruction, regs.store(instr.targetReg, varTargetRegId)...)
 
-	varTargetRegId := utility.NextAvailReg()
-	varTargetOffset := funcVarDict[instr.varReg]
-	instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",varTargetRegId, varTargetOffset))
-
-	sourceReg := utility.NextAvailReg()
-	instruction = append(instruction, fmt.Sprintf("\tadrp x%v, .READ",sourceReg))
-	instruction = append(instruction, fmt.Sprintf("\tadd x%v,x%v,:lo12:.READ",sourceReg,sourceReg))
-
-	instruction = append(instruction, fmt.Sprintf("\tadd x%v,x29,#%v",varTargetRegId,varTargetOffset))
-	instruction = append(instruction, fmt.Sprintf("\tmov x0,x%v", sourceReg))
-	instruction = append(instruction, "\tbl scanf")
-
-	utility.ReleaseReg(varTargetRegId)
-	utility.ReleaseReg(sourceReg)
-	
 	return instruction
 }
@@ -0,0 +1,8 @@
+//go:build !debug
+// +build !debug
+
+package ir
+
+// Debug is true in the builds with the debug tag, e.g. go build -tags debug, which verify the ILOC
+// of every program after its generation
+const Debug = false
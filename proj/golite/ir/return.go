@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Ret struct {
@@ -15,12 +15,9 @@ func NewRet(operand int, opty OperandTy) *Ret {
 	return &Ret{operand, opty}
 }
 
-func (instr *Ret) GetTargets() []int {
-	targets := []int{}
-	// Use LR (X30) if register is omitted, but can use other register (ARMv8_InstructionSetOverview.pdf)
-	targets = append(targets, 30) // TO-DO : Targets: Return register or (stack)
-	return targets
-}
+// GetTargets returns no registers: the value returned is read by the "mov rX,r0 @Return" of the
+// caller, which has its own registers
+func (instr *Ret) GetTargets() []int { return []int{} }
 
 func (instr *Ret) GetSources() []int {
 	sources := []int{}
@@ -52,7 +49,7 @@ func (instr *Ret) String() string {
 	var out bytes.Buffer
 
 	if instr.opty == VOID {
-		out.WriteString(fmt.Sprintf("ret"))
+		out.WriteString("    ret")
 	} else {
 
 		var sourceReg string
@@ -69,25 +66,17 @@ func (instr *Ret) String() string {
 
 }
 
-func (instr *Ret) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	var retRegId int
-	var isParam bool
+func (instr *Ret) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
 	if instr.opty == REGISTER {
-		if retRegId, isParam = paramRegIds[instr.operand]; !isParam {
-			operandOffset := funcVarDict[instr.operand]
-			retRegId = utility.NextAvailReg()
-			instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",retRegId,operandOffset))
-		}
-		instruction = append(instruction, fmt.Sprintf("\tmov x0,x%v",retRegId))
+		retRegId, load := regs.use(instr.operand)
+		instruction = append(instruction, load...)
+		instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), retRegId))
 	} else if instr.opty == IMMEDIATE {
-		instruction = append(instruction, fmt.Sprintf("\tmov x0,%v",instr.operand))
-	}
-
-	if !isParam {
-		utility.ReleaseReg(retRegId)
+		instruction = append(instruction, asm.MovImm(asm.X(0), instr.operand))
 	}
 
 	return instruction
@@ -0,0 +1,97 @@
+package ir
+
+// MapRegisters returns a copy of an instruction whose sources are renamed by use and whose targets
+// by def, for the passes rewriting the registers of a function. The target of a conditional mov,
+// which it also reads, is renamed by def, and the registers listed by a pop by use, as the ones
+// pushed.
+func MapRegisters(instr Instruction, use func(int) int, def func(int) int) Instruction {
+	operand := func(operand int, opty OperandTy) int {
+		if opty == REGISTER {
+			return use(operand)
+		}
+		return operand
+	}
+	address := func(sourceReg int, opty OperandTy) int {
+		if opty == REGISTER || opty == IMMEDIATE || opty == ONEOPERAND {
+			return use(sourceReg)
+		}
+		return sourceReg
+	}
+	useAll := func(regs []int) []int {
+		renamed := []int{}
+		for _, reg := range regs {
+			renamed = append(renamed, use(reg))
+		}
+		return renamed
+	}
+
+	switch instr := instr.(type) {
+	case *Add:
+		return &Add{def(instr.target), use(instr.sourceReg), operand(instr.operand, instr.opty), instr.opty}
+	case *Sub:
+		return &Sub{def(instr.target), use(instr.sourceReg), operand(instr.operand, instr.opty), instr.opty}
+	case *And:
+		return &And{def(instr.target), use(instr.sourceReg), operand(instr.operand, instr.opty), instr.opty}
+	case *Or:
+		return &Or{def(instr.target), use(instr.sourceReg), operand(instr.operand, instr.opty), instr.opty}
+	case *Mul:
+		return &Mul{def(instr.target), use(instr.sourceReg1), use(instr.sourceReg2)}
+	case *Div:
+		return &Div{def(instr.target), use(instr.sourceReg1), use(instr.sourceReg2)}
+	case *Not:
+		return &Not{def(instr.target), operand(instr.operand, instr.opty), instr.opty}
+	case *Mov:
+		if instr.retFlag {
+			return &Mov{instr.flag, def(instr.target), instr.operand, instr.opty, true}
+		}
+		return &Mov{instr.flag, def(instr.target), operand(instr.operand, instr.opty), instr.opty, false}
+	case *Cmp:
+		return &Cmp{use(instr.sourceReg), operand(instr.operand, instr.opty), instr.opty}
+	case *Ldr:
+		return &Ldr{def(instr.target), address(instr.sourceReg, instr.opty), operand(instr.operand, instr.opty), instr.globalVar, instr.opty}
+	case *Str:
+		return &Str{use(instr.target), address(instr.sourceReg, instr.opty), operand(instr.operand, instr.opty), instr.globalVar, instr.opty}
+	case *LoadRef:
+		return &LoadRef{def(instr.target), use(instr.source), instr.field, instr.fieldIdx}
+	case *StrRef:
+		return &StrRef{use(instr.target), use(instr.source), instr.field, instr.fieldIdx}
+	case *LoadIdx:
+		return &LoadIdx{def(instr.target), use(instr.source), use(instr.index)}
+	case *StrIdx:
+		return &StrIdx{use(instr.value), use(instr.source), use(instr.index)}
+	case *New:
+		return &New{def(instr.target), instr.dataType, instr.size}
+	case *NewArr:
+		return &NewArr{def(instr.target), operand(instr.operand, instr.opty), instr.opty}
+	case *Bounds:
+		return &Bounds{use(instr.index), operand(instr.operand, instr.opty), instr.opty}
+	case *LoadStr:
+		return &LoadStr{def(instr.target), instr.value}
+	case *StrLen:
+		return &StrLen{def(instr.target), use(instr.source)}
+	case *StrCat:
+		return &StrCat{def(instr.target), use(instr.sourceReg1), use(instr.sourceReg2)}
+	case *Delete:
+		return &Delete{use(instr.sourceReg)}
+	case *Print:
+		return &Print{use(instr.sourceReg)}
+	case *Println:
+		return &Println{use(instr.sourceReg)}
+	case *Printf:
+		return &Printf{instr.format, useAll(instr.sourceReg)}
+	case *Read:
+		target := def(instr.targetReg)
+		return &Read{target, instr.variable, target}
+	case *Push:
+		return &Push{useAll(instr.sourceReg), instr.funcName}
+	case *Pop:
+		return &Pop{useAll(instr.sourceReg), instr.funcName}
+	case *Ret:
+		return &Ret{operand(instr.operand, instr.opty), instr.opty}
+	}
+	// labels, branches and calls have no registers
+	return instr
+}
+
+// Identity is the renaming of MapRegisters leaving registers as they are
+func Identity(reg int) int { return reg }
@@ -0,0 +1,488 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"proj/golite/ir"
+	"strings"
+)
+
+// maxDepth bounds the nesting of calls, deeper recursions fail with a stack overflow
+const maxDepth = 100000
+
+// heapBase is the address of the first struct allocated by new, so that nil (0) is never valid
+const heapBase = 0x10000
+
+// RuntimeError is an error of the simulated program, located at the instruction that failed
+type RuntimeError struct {
+	Function    string // label of the FuncFrag
+	Index       int    // position of the instruction in the body of the FuncFrag
+	Instruction string
+	Message     string
+}
+
+func (err *RuntimeError) Error() string {
+	return fmt.Sprintf("%v+%v (%v): %v", err.Function, err.Index, strings.TrimSpace(err.Instruction), err.Message)
+}
+
+// block is a struct allocated by new
+type block struct {
+	size  int64 // in bytes, 8 per field
+	freed bool
+}
+
+// frame is the state of a call: its own register file and the flags set by the last cmp
+type frame struct {
+	frag *ir.FuncFrag
+	pc   int
+	regs map[int]int64
+	cmp  int     // sign of the difference of the operands of the last cmp
+	args []int64 // the values of the last push, for the next bl
+	ret  int64   // the value of the ret ending the call
+}
+
+// Machine runs the ILOC of a program. Each call has its own registers, initially 0; arguments
+// are passed by push and bound to the Params of the function called, and the value of ret is
+// read by the "mov rX,r0 @Return" following the call. Structs, and the blocks of newArr, live in a
+// heap of 8-byte words. A string is the address of a block of the heap holding its bytes and NUL,
+// whose text is kept aside.
+type Machine struct {
+	frags  []*ir.FuncFrag
+	funcs  map[string]*ir.FuncFrag
+	labels map[*ir.FuncFrag]map[string]int
+
+	globals map[string]int64
+	words   map[int64]int64 // heap memory, by address
+	owners  map[int64]int64 // the address of the struct holding each word of the heap
+	blocks  map[int64]*block
+	next    int64 // address of the next allocation
+
+	strs     map[int64]string // the text of the string at each address
+	literals map[string]int64 // the address of each string literal loaded so far
+
+	frames   []*frame
+	retValue int64          // the value returned by the last call
+	Counts   map[string]int // the number of instructions executed in each function, labels excepted
+
+	in  *bufio.Reader
+	out *bufio.Writer
+}
+
+// New returns a machine running the FuncFrags of a program, whose first FuncFrag initializes the
+// global variables. read takes integers from stdin, print and println write to stdout.
+func New(frags []*ir.FuncFrag, stdin io.Reader, stdout io.Writer) *Machine {
+	m := &Machine{
+		frags:    frags,
+		funcs:    map[string]*ir.FuncFrag{},
+		labels:   map[*ir.FuncFrag]map[string]int{},
+		globals:  map[string]int64{},
+		words:    map[int64]int64{},
+		owners:   map[int64]int64{},
+		blocks:   map[int64]*block{},
+		next:     heapBase,
+		strs:     map[int64]string{},
+		literals: map[string]int64{},
+		Counts:   map[string]int{},
+		in:       bufio.NewReader(stdin),
+		out:      bufio.NewWriter(stdout),
+	}
+	for _, frag := range frags {
+		m.funcs[frag.Label] = frag
+		labels := map[string]int{}
+		for i, instruction := range frag.Body {
+			if label, isLabel := instruction.(*ir.Label); isLabel {
+				labels[label.GetLabel()] = i
+			}
+		}
+		m.labels[frag] = labels
+	}
+	return m
+}
+
+// Run runs a program, see New
+func Run(frags []*ir.FuncFrag, stdin io.Reader, stdout io.Writer) (*Machine, error) {
+	m := New(frags, stdin, stdout)
+	return m, m.Run()
+}
+
+// Run initializes the global variables then calls main, the error is a *RuntimeError if the program failed
+func (m *Machine) Run() (err error) {
+	defer func() {
+		if flushErr := m.out.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, isRuntime := r.(*RuntimeError)
+			if !isRuntime {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+
+	if len(m.frags) > 0 && m.frags[0].IsGlobal() {
+		m.call(m.frags[0], nil)
+	}
+	main := m.funcs["main"]
+	if main == nil {
+		return &RuntimeError{Function: "main", Message: "the program has no main function"}
+	}
+	m.call(main, nil)
+	return nil
+}
+
+// fail stops the program with an error at the current instruction
+func (m *Machine) fail(format string, args ...interface{}) {
+	err := &RuntimeError{Message: fmt.Sprintf(format, args...)}
+	if len(m.frames) > 0 {
+		f := m.frames[len(m.frames)-1]
+		err.Function, err.Index = f.frag.Label, f.pc
+		if f.pc < len(f.frag.Body) {
+			err.Instruction = f.frag.Body[f.pc].String()
+		}
+	}
+	panic(err)
+}
+
+// call runs a function until its ret or the end of its body, and returns the value of its ret
+func (m *Machine) call(frag *ir.FuncFrag, args []int64) int64 {
+	if len(m.frames) >= maxDepth {
+		m.fail("stack overflow: more than %v nested calls", maxDepth)
+	}
+	if len(args) != len(frag.Params) {
+		m.fail("%v takes %v arguments, %v pushed", frag.Label, len(frag.Params), len(args))
+	}
+	f := &frame{frag: frag, regs: map[int]int64{}}
+	for i, reg := range frag.Params {
+		f.regs[reg] = args[i]
+	}
+	m.frames = append(m.frames, f)
+	for f.pc < len(frag.Body) && m.step(f, frag.Body[f.pc]) {
+	}
+	m.frames = m.frames[:len(m.frames)-1]
+	return f.ret
+}
+
+// operand returns the value of the second operand of an instruction: its immediate, or else its
+// source register number i
+func operand(f *frame, instruction ir.Instruction, i int) int64 {
+	if imm := instruction.GetImmediate(); imm != nil {
+		return int64(*imm)
+	}
+	return f.regs[instruction.GetSources()[i]]
+}
+
+// holds returns true if the flags set by the last cmp satisfy the condition
+func holds(flag ir.ApsrFlag, cmp int) bool {
+	switch flag {
+	case ir.GT:
+		return cmp > 0
+	case ir.LT:
+		return cmp < 0
+	case ir.GE:
+		return cmp >= 0
+	case ir.LE:
+		return cmp <= 0
+	case ir.EQ:
+		return cmp == 0
+	case ir.NE:
+		return cmp != 0
+	}
+	return true
+}
+
+// step executes one instruction of the current call, it returns false when the call returns
+func (m *Machine) step(f *frame, instruction ir.Instruction) bool {
+	next := f.pc + 1 // the pc is left on the instruction until it is done, for the errors
+	if _, isLabel := instruction.(*ir.Label); isLabel {
+		f.pc = next
+		return true
+	}
+	m.Counts[f.frag.Label]++
+
+	regs := f.regs
+	switch instr := instruction.(type) {
+	case *ir.Add:
+		regs[instr.GetTargets()[0]] = regs[instr.GetSources()[0]] + operand(f, instr, 1)
+	case *ir.Sub:
+		regs[instr.GetTargets()[0]] = regs[instr.GetSources()[0]] - operand(f, instr, 1)
+	case *ir.Mul:
+		regs[instr.GetTargets()[0]] = regs[instr.GetSources()[0]] * operand(f, instr, 1)
+	case *ir.Div:
+		divisor := operand(f, instr, 1)
+		if divisor == 0 {
+			m.fail("integer divide by zero")
+		}
+		regs[instr.GetTargets()[0]] = regs[instr.GetSources()[0]] / divisor
+	case *ir.And:
+		regs[instr.GetTargets()[0]] = boolValue(regs[instr.GetSources()[0]] != 0 && operand(f, instr, 1) != 0)
+	case *ir.Or:
+		regs[instr.GetTargets()[0]] = boolValue(regs[instr.GetSources()[0]] != 0 || operand(f, instr, 1) != 0)
+	case *ir.Not:
+		regs[instr.GetTargets()[0]] = boolValue(operand(f, instr, 0) == 0)
+	case *ir.Mov:
+		if instr.GetRetFlag() {
+			regs[instr.GetTargets()[0]] = m.retValue
+		} else if holds(instr.GetFlag(), f.cmp) {
+			regs[instr.GetTargets()[0]] = operand(f, instr, 0)
+		}
+	case *ir.Cmp:
+		left, right := regs[instr.GetSources()[0]], operand(f, instr, 1)
+		f.cmp = 0
+		if left < right {
+			f.cmp = -1
+		} else if left > right {
+			f.cmp = 1
+		}
+	case *ir.Branch:
+		if holds(instr.GetFlag(), f.cmp) {
+			target, exist := m.labels[f.frag][instr.GetLabel()]
+			if !exist {
+				m.fail("no label %v in %v", instr.GetLabel(), f.frag.Label)
+			}
+			next = target
+		}
+	case *ir.Push:
+		f.args = f.args[:0]
+		for _, reg := range instr.GetSources() {
+			f.args = append(f.args, regs[reg])
+		}
+	case *ir.Bl:
+		callee := m.funcs[instr.GetLabel()]
+		if callee == nil {
+			m.fail("no function %v", instr.GetLabel())
+		}
+		args := f.args
+		f.args = nil
+		m.retValue = m.call(callee, args)
+	case *ir.Pop:
+		// the arguments are not kept on a stack
+	case *ir.Ret:
+		if srcs, imm := instr.GetSources(), instr.GetImmediate(); imm != nil {
+			f.ret = int64(*imm)
+		} else if len(srcs) > 0 {
+			f.ret = regs[srcs[0]]
+		}
+		return false
+	case *ir.Ldr:
+		if global := instr.GetSourceString(); global != "" {
+			regs[instr.GetTargets()[0]] = m.globals[global]
+		} else {
+			regs[instr.GetTargets()[0]] = m.load(m.address(f, instr, instr.GetSources()))
+		}
+	case *ir.Str:
+		// the register stored comes before the registers of the address
+		srcs := instr.GetSources()
+		if global := instr.GetSourceString(); global != "" {
+			m.globals[global] = regs[srcs[0]]
+		} else {
+			m.store(m.address(f, instr, srcs[1:]), regs[srcs[0]])
+		}
+	case *ir.LoadRef:
+		base := regs[instr.GetSources()[0]]
+		regs[instr.GetTargets()[0]] = m.load(m.field(base, instr.GetFieldIdx(), instr.GetSourceString()))
+	case *ir.StrRef:
+		value, base := regs[instr.GetSources()[0]], regs[instr.GetSources()[1]]
+		m.store(m.field(base, instr.GetFieldIdx(), instr.GetSourceString()), value)
+	case *ir.LoadIdx:
+		base, index := regs[instr.GetSources()[0]], regs[instr.GetSources()[1]]
+		regs[instr.GetTargets()[0]] = m.load(m.element(base, index))
+	case *ir.StrIdx:
+		value, base, index := regs[instr.GetSources()[0]], regs[instr.GetSources()[1]], regs[instr.GetSources()[2]]
+		m.store(m.element(base, index), value)
+	case *ir.New:
+		regs[instr.GetTargets()[0]] = m.allocate(instr.GetSize())
+	case *ir.NewArr:
+		regs[instr.GetTargets()[0]] = m.allocate(int(operand(f, instr, 0)))
+	case *ir.Bounds:
+		index, length := regs[instr.GetSources()[0]], operand(f, instr, 1)
+		if index < 0 || index >= length {
+			m.fail("index out of range [%v] with length %v", index, length)
+		}
+	case *ir.LoadStr:
+		address, loaded := m.literals[instr.GetValue()]
+		if !loaded {
+			address = m.newString(instr.GetValue())
+			m.literals[instr.GetValue()] = address
+		}
+		regs[instr.GetTargets()[0]] = address
+	case *ir.StrLen:
+		regs[instr.GetTargets()[0]] = int64(len(m.str(regs[instr.GetSources()[0]])))
+	case *ir.StrCat:
+		left, right := m.str(regs[instr.GetSources()[0]]), m.str(regs[instr.GetSources()[1]])
+		regs[instr.GetTargets()[0]] = m.newString(left + right)
+	case *ir.Delete:
+		m.free(regs[instr.GetSources()[0]])
+	case *ir.Read:
+		m.out.Flush() // show the prompts printed so far before waiting for the input
+		var value int64
+		if _, err := fmt.Fscan(m.in, &value); err == nil {
+			regs[instr.GetTargets()[0]] = value
+		}
+	case *ir.Print:
+		fmt.Fprintf(m.out, "%d", regs[instr.GetSources()[0]])
+	case *ir.Println:
+		fmt.Fprintf(m.out, "%d\n", regs[instr.GetSources()[0]])
+	case *ir.Printf:
+		values := []int64{}
+		for _, reg := range instr.GetSources() {
+			values = append(values, regs[reg])
+		}
+		m.printf(instr.GetFormat(), values)
+	default:
+		m.fail("cannot simulate %T", instruction)
+	}
+	f.pc = next
+	return true
+}
+
+func boolValue(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// address returns the address of the memory forms of ldr and str: [rS], [rS,#imm] or [rS,rO],
+// given the registers rS and rO
+func (m *Machine) address(f *frame, instruction ir.Instruction, srcs []int) int64 {
+	if len(srcs) == 0 {
+		m.fail("no address")
+	}
+	address := f.regs[srcs[0]]
+	if imm := instruction.GetImmediate(); imm != nil {
+		address += int64(*imm)
+	} else if len(srcs) > 1 {
+		address += f.regs[srcs[1]]
+	}
+	return address
+}
+
+// field returns the address of a field of the struct at base
+func (m *Machine) field(base int64, fieldIdx int, name string) int64 {
+	b := m.blocks[base]
+	if base == 0 {
+		m.fail("nil pointer dereference of field %v", name)
+	} else if b == nil {
+		m.fail("%#x is not the address of a struct", base)
+	} else if int64(fieldIdx*8) >= b.size {
+		m.fail("field %v at %v is past the end of the struct of %v bytes", name, fieldIdx*8, b.size)
+	}
+	return base + int64(fieldIdx*8)
+}
+
+// element returns the address of the element at index of the block of words at base
+func (m *Machine) element(base int64, index int64) int64 {
+	b := m.blocks[base]
+	if base == 0 {
+		m.fail("nil pointer dereference of element %v", index)
+	} else if b == nil {
+		m.fail("%#x is not the address of a block", base)
+	} else if index < 0 || index*8 >= b.size {
+		m.fail("element %v is outside the block of %v bytes", index, b.size)
+	}
+	return base + index*8
+}
+
+// check fails if the 8 bytes at the address are not in a live struct
+func (m *Machine) check(address int64) {
+	if address == 0 {
+		m.fail("nil pointer dereference")
+	}
+	base, exist := m.owners[address]
+	if !exist {
+		m.fail("invalid address %#x", address)
+	} else if m.blocks[base].freed {
+		m.fail("use of the deleted struct at %#x", base)
+	}
+}
+
+func (m *Machine) load(address int64) int64 {
+	m.check(address)
+	return m.words[address]
+}
+
+func (m *Machine) store(address int64, value int64) {
+	m.check(address)
+	m.words[address] = value
+}
+
+// allocate returns the address of a new struct of the given number of fields, set to 0
+func (m *Machine) allocate(fields int) int64 {
+	address := m.next
+	size := int64(fields * 8)
+	if size == 0 {
+		size = 8 // calloc(0,8) still returns a unique address
+	}
+	m.blocks[address] = &block{size, false}
+	for offset := int64(0); offset < size; offset += 8 {
+		m.words[address+offset] = 0
+		m.owners[address+offset] = address
+	}
+	m.next += size + 8 // a gap between structs makes overruns invalid addresses
+	return address
+}
+
+// newString returns the address of a new string holding value
+func (m *Machine) newString(value string) int64 {
+	address := m.allocate(len(value)/8 + 1)
+	m.strs[address] = value
+	return address
+}
+
+// str returns the text of the string at address
+func (m *Machine) str(address int64) string {
+	value, isString := m.strs[address]
+	if address == 0 {
+		m.fail("nil pointer dereference of a string")
+	} else if !isString {
+		m.fail("%#x is not the address of a string", address)
+	}
+	return value
+}
+
+// printf prints values with the conversions %ld and %s of format, a %s value being the address
+// of a string
+func (m *Machine) printf(format string, values []int64) {
+	conversions, valid := ir.Conversions(format)
+	if !valid || len(conversions) != len(values) {
+		m.fail("printf format %q does not match its %v values", format, len(values))
+	}
+	for idx, text := 0, format; ; {
+		percent := strings.Index(text, "%")
+		if percent < 0 {
+			fmt.Fprint(m.out, text)
+			return
+		}
+		fmt.Fprint(m.out, text[:percent])
+		text = text[percent+1:]
+		switch {
+		case strings.HasPrefix(text, "%"):
+			fmt.Fprint(m.out, "%")
+			text = text[1:]
+		case strings.HasPrefix(text, "ld"):
+			fmt.Fprintf(m.out, "%d", values[idx])
+			text, idx = text[2:], idx+1
+		default:
+			fmt.Fprint(m.out, m.str(values[idx]))
+			text, idx = text[1:], idx+1
+		}
+	}
+}
+
+// free releases the struct at address, deleting nil does nothing
+func (m *Machine) free(address int64) {
+	if address == 0 {
+		return
+	}
+	b := m.blocks[address]
+	if b == nil {
+		m.fail("delete of %#x, which is not the address of a struct", address)
+	} else if b.freed {
+		m.fail("struct at %#x deleted twice", address)
+	}
+	b.freed = true
+}
@@ -0,0 +1,179 @@
+package sim
+
+import (
+	"bytes"
+	"proj/golite/ir"
+	"strings"
+	"testing"
+)
+
+// factorial is the ILOC of a recursive factorial of its parameter r1, called by main on the input
+func factorial() []*ir.FuncFrag {
+	fact := &ir.FuncFrag{Label: "fact", Params: []int{1}, Body: []ir.Instruction{
+		ir.NewLabelStmt("fact"),
+		ir.NewCmp(1, 1, ir.IMMEDIATE),
+		ir.NewBranch(ir.GT, "recurse_L1"),
+		ir.NewRet(1, ir.IMMEDIATE),
+		ir.NewLabelStmt("recurse_L1"),
+		ir.NewSub(2, 1, 1, ir.IMMEDIATE),
+		ir.NewPush([]int{2}, "fact"),
+		ir.NewBl("fact"),
+		ir.NewMov(3, 0, ir.AL, ir.REGISTER),
+		ir.NewPop([]int{2}, "fact"),
+		ir.NewMul(4, 1, 3),
+		ir.NewRet(4, ir.REGISTER),
+	}}
+	fact.Body[8].(*ir.Mov).SetRetFlag()
+	main := &ir.FuncFrag{Label: "main", Body: []ir.Instruction{
+		ir.NewLabelStmt("main"),
+		ir.NewRead(1, "n", 1),
+		ir.NewPush([]int{1}, "fact"),
+		ir.NewBl("fact"),
+		ir.NewMov(2, 0, ir.AL, ir.REGISTER),
+		ir.NewPop([]int{1}, "fact"),
+		ir.NewPrintln(2),
+	}}
+	main.Body[4].(*ir.Mov).SetRetFlag()
+	globals := &ir.FuncFrag{Label: "Global Variable_L0", Body: []ir.Instruction{ir.NewLabelStmt("Global Variable_L0")}}
+	return []*ir.FuncFrag{globals, fact, main}
+}
+
+func Test1(t *testing.T) {
+	out := bytes.Buffer{}
+	m, err := Run(factorial(), strings.NewReader("5\n"), &out)
+	if err != nil {
+		t.Fatalf("\nExpected: no error; Got %v\n", err)
+	}
+	if out.String() != "120\n" {
+		t.Errorf("\nExpected: 120\nGot: %q\n", out.String())
+	}
+	// 5 calls of fact, 4 of them recursing with 9 instructions and the last one returning after 3
+	expected := map[string]int{"fact": 4*9 + 3, "main": 6}
+	for label, count := range expected {
+		if m.Counts[label] != count {
+			t.Errorf("\nExpected: %v instructions in %v; Got %v\n", count, label, m.Counts[label])
+		}
+	}
+}
+
+func Test2(t *testing.T) {
+	// a struct in a global variable: its fields are set, read, then read again once deleted
+	frags := []*ir.FuncFrag{
+		{Label: "Global Variable_L0", Body: []ir.Instruction{
+			ir.NewLabelStmt("Global Variable_L0"),
+			ir.NewMov(1, 0, ir.AL, ir.IMMEDIATE),
+			ir.NewStr(1, -1, -1, "p", ir.GLOBALVAR),
+		}},
+		{Label: "main", Body: []ir.Instruction{
+			ir.NewLabelStmt("main"),
+			ir.NewNew(2, "Point", 2),
+			ir.NewStr(2, -1, -1, "p", ir.GLOBALVAR),
+			ir.NewLdr(3, -1, -1, "p", ir.GLOBALVAR),
+			ir.NewMov(4, 7, ir.AL, ir.IMMEDIATE),
+			ir.NewStrRef(4, 3, "y", 1),
+			ir.NewLoadRef(5, 3, "y", 1),
+			ir.NewPrint(5),
+			ir.NewDelete(3),
+			ir.NewLoadRef(6, 3, "x", 0),
+		}},
+	}
+	out := bytes.Buffer{}
+	_, err := Run(frags, strings.NewReader(""), &out)
+	if out.String() != "7" {
+		t.Errorf("\nExpected: 7\nGot: %q\n", out.String())
+	}
+	expected := "main+9 (loadRef r6,r3,@x,#0): use of the deleted struct at 0x10000"
+	if err == nil || err.Error() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, err)
+	}
+
+	// a field of nil
+	frags[1].Body = frags[1].Body[:1]
+	frags[1].Body = append(frags[1].Body, ir.NewLdr(3, -1, -1, "p", ir.GLOBALVAR), ir.NewLoadRef(5, 3, "y", 1))
+	if _, err := Run(frags, strings.NewReader(""), &out); err == nil || !strings.Contains(err.Error(), "nil pointer dereference") {
+		t.Errorf("\nExpected: nil pointer dereference; Got %v\n", err)
+	}
+}
+
+func Test3(t *testing.T) {
+	// a block of 2 words: an element is stored and loaded, then an index is checked against its length
+	frags := []*ir.FuncFrag{
+		{Label: "main", Body: []ir.Instruction{
+			ir.NewLabelStmt("main"),
+			ir.NewNewArr(1, 2, ir.IMMEDIATE),
+			ir.NewMov(2, 1, ir.AL, ir.IMMEDIATE),
+			ir.NewMov(3, 9, ir.AL, ir.IMMEDIATE),
+			ir.NewBounds(2, 2, ir.IMMEDIATE),
+			ir.NewStrIdx(3, 1, 2),
+			ir.NewLoadIdx(4, 1, 2),
+			ir.NewPrint(4),
+			ir.NewMov(2, 2, ir.AL, ir.IMMEDIATE),
+			ir.NewBounds(2, 2, ir.IMMEDIATE),
+		}},
+	}
+	out := bytes.Buffer{}
+	_, err := Run(frags, strings.NewReader(""), &out)
+	if out.String() != "9" {
+		t.Errorf("\nExpected: 9\nGot: %q\n", out.String())
+	}
+	expected := "main+9 (bounds r2,#2): index out of range [2] with length 2"
+	if err == nil || err.Error() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, err)
+	}
+
+	// an element outside the block, without bounds
+	frags[0].Body = append(frags[0].Body[:9], ir.NewLoadIdx(4, 1, 2))
+	if _, err := Run(frags, strings.NewReader(""), &out); err == nil || !strings.Contains(err.Error(), "outside the block") {
+		t.Errorf("\nExpected: an element outside the block; Got %v\n", err)
+	}
+}
+
+func Test4(t *testing.T) {
+	// a literal is loaded once, and strCat makes a new string
+	frags := []*ir.FuncFrag{
+		{Label: "main", Body: []ir.Instruction{
+			ir.NewLabelStmt("main"),
+			ir.NewLoadStr(1, "ab"),
+			ir.NewLoadStr(2, "ab"),
+			ir.NewStrCat(3, 1, 2),
+			ir.NewStrLen(4, 3),
+			ir.NewPrintf("%s", []int{3}),
+			ir.NewPrintln(4),
+			ir.NewSub(5, 2, 1, ir.REGISTER),
+			ir.NewPrintln(5),
+			ir.NewPrintf("%s\n", []int{5}),
+		}},
+	}
+	out := bytes.Buffer{}
+	_, err := Run(frags, strings.NewReader(""), &out)
+	if out.String() != "abab4\n0\n" {
+		t.Errorf("\nExpected: %q\nGot: %q\n", "abab4\n0\n", out.String())
+	}
+	expected := "main+9 (printf \"%s\\n\",r5): nil pointer dereference of a string"
+	if err == nil || err.Error() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, err)
+	}
+}
+
+func Test5(t *testing.T) {
+	// printf with ints, strings and %%, and a format its values do not match
+	frags := []*ir.FuncFrag{
+		{Label: "main", Body: []ir.Instruction{
+			ir.NewLabelStmt("main"),
+			ir.NewMov(1, -7, ir.AL, ir.IMMEDIATE),
+			ir.NewLoadStr(2, "ab"),
+			ir.NewPrintf("%ld%% %s|%ld\n", []int{1, 2, 1}),
+			ir.NewPrintf("done", []int{}),
+			ir.NewPrintf("%ld %ld", []int{1}),
+		}},
+	}
+	out := bytes.Buffer{}
+	_, err := Run(frags, strings.NewReader(""), &out)
+	if out.String() != "-7% ab|-7\ndone" {
+		t.Errorf("\nExpected: %q\nGot: %q\n", "-7% ab|-7\ndone", out.String())
+	}
+	expected := "main+5 (printf \"%ld %ld\",r1): printf format \"%ld %ld\" does not match its 1 values"
+	if err == nil || err.Error() != expected {
+		t.Errorf("\nExpected: %v\nGot: %v\n", expected, err)
+	}
+}
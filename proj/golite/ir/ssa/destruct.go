@@ -0,0 +1,126 @@
+package ssa
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+)
+
+// Destruct converts a function in SSA form back into plain ILOC, for the register allocation.
+// Each phi gets a register of its own, written by a copy of the argument at the end of each
+// predecessor and copied into the target of the phi at the start of its block, so that the phis
+// of a block still read all their arguments before writing any target. An edge from a block with a
+// conditional branch to a block with several predecessors has no end of its own for the copies, it
+// is split by a new block at the end of the function, jumped over by the end of the body if it
+// falls through. A select becomes a mov of its Else register
+// followed by the conditional mov of its operand.
+func Destruct(f *Func) *ir.FuncFrag {
+	g := f.Graph
+	// the copies placed before the branch ending each block, and after its last instruction
+	before := map[*cfg.Block][]ir.Instruction{}
+	after := map[*cfg.Block][]ir.Instruction{}
+	// the copies of the edges split, by predecessor and successor
+	type edge struct{ pred, succ *cfg.Block }
+	split := map[edge][]ir.Instruction{}
+	starts := map[*cfg.Block][]ir.Instruction{}
+
+	for _, b := range g.Blocks {
+		for _, instr := range b.Instructions {
+			phi, isPhi := instr.(*Phi)
+			if !isPhi {
+				continue
+			}
+			temp := ir.NewRegister()
+			starts[b] = append(starts[b], ir.NewMov(phi.Target, temp, ir.AL, ir.REGISTER))
+			for _, arg := range phi.Args {
+				pred := g.Blocks[arg.Pred]
+				copy := ir.NewMov(temp, arg.Reg, ir.AL, ir.REGISTER)
+				branch, endsWithBranch := pred.Last().(*ir.Branch)
+				switch {
+				case !endsWithBranch:
+					after[pred] = append(after[pred], copy)
+				case branch.GetFlag() == ir.AL || len(pred.Succs) == 1:
+					before[pred] = append(before[pred], copy)
+				case branch.GetLabel() == b.Label():
+					split[edge{pred, b}] = append(split[edge{pred, b}], copy)
+				default:
+					// the block is entered by falling through the conditional branch
+					after[pred] = append(after[pred], copy)
+				}
+			}
+		}
+	}
+
+	body := []ir.Instruction{}
+	splitBlocks := []ir.Instruction{}
+	for _, b := range g.Blocks {
+		// a block with phis has several predecessors, so it starts with a label
+		first := 0
+		if b.Label() != "" {
+			first = 1
+		}
+		for i, instr := range b.Instructions {
+			if i == first {
+				body = append(body, starts[b]...)
+			}
+			if i == len(b.Instructions)-1 {
+				if _, isBranch := instr.(*ir.Branch); isBranch {
+					body = append(body, before[b]...)
+				}
+			}
+			switch instr := instr.(type) {
+			case *Phi:
+				continue
+			case *Select:
+				body = append(body, ir.NewMov(instr.Target, instr.Else, ir.AL, ir.REGISTER))
+				body = append(body, ir.NewMov(instr.Target, instr.Operand, instr.Flag, instr.Opty))
+			case *ir.Branch:
+				if copies := split[edge{b, g.Blocks[indexOfLabel(g, instr.GetLabel())]}]; len(copies) > 0 {
+					label := ir.NewLabelWithPre("edge")
+					splitBlocks = append(splitBlocks, ir.NewLabelStmt(label))
+					splitBlocks = append(splitBlocks, copies...)
+					splitBlocks = append(splitBlocks, ir.NewBranch(ir.AL, instr.GetLabel()))
+					body = append(body, ir.NewBranch(instr.GetFlag(), label))
+				} else {
+					body = append(body, instr)
+				}
+			default:
+				body = append(body, instr)
+			}
+		}
+		body = append(body, after[b]...)
+	}
+	if len(splitBlocks) > 0 {
+		// a function without a ret at its end must still leave there rather than through the edges
+		end := ""
+		if len(body) == 0 || fallsThrough(body[len(body)-1]) {
+			end = ir.NewLabelWithPre("end")
+			body = append(body, ir.NewBranch(ir.AL, end))
+		}
+		body = append(body, splitBlocks...)
+		if end != "" {
+			body = append(body, ir.NewLabelStmt(end))
+		}
+	}
+	return &ir.FuncFrag{Label: f.Frag.Label, Params: f.Frag.Params, Body: body}
+}
+
+// fallsThrough returns true if the instruction may be followed by the next one
+func fallsThrough(instr ir.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ir.Ret:
+		return false
+	case *ir.Branch:
+		return instr.GetFlag() != ir.AL
+	}
+	return true
+}
+
+// indexOfLabel returns the index of the block starting with the label, the exit if there is none
+func indexOfLabel(g *cfg.Graph, label string) int {
+	for _, b := range g.Blocks {
+		if b.Label() == label {
+			return b.Index
+		}
+	}
+	return g.Exit.Index
+}
@@ -0,0 +1,51 @@
+package ssa
+
+import (
+	"fmt"
+	"proj/golite/ir"
+	"strings"
+)
+
+// BuildAll converts every function of a program into SSA form, the global variables left aside
+func BuildAll(frags []*ir.FuncFrag) []*Func {
+	funcs := []*Func{}
+	for _, frag := range frags {
+		if !frag.IsGlobal() {
+			funcs = append(funcs, Build(frag))
+		}
+	}
+	return funcs
+}
+
+// Dump lists the SSA form of each function by block, each block headed by its predecessors,
+// followed by the errors of Verify if there are any
+func Dump(funcs []*Func) []string {
+	lines := []string{}
+	for _, f := range funcs {
+		lines = append(lines, fmt.Sprintf("%v:", f.Frag.Label))
+		if len(f.Frag.Params) > 0 {
+			params := []string{}
+			for _, param := range f.Frag.Params {
+				params = append(params, fmt.Sprintf("r%v", param))
+			}
+			lines = append(lines, fmt.Sprintf("  params %v", strings.Join(params, " ")))
+		}
+		for _, b := range f.Graph.Blocks {
+			if b == f.Graph.Exit {
+				continue
+			}
+			preds := []string{}
+			for _, pred := range b.Preds {
+				preds = append(preds, pred.Name())
+			}
+			lines = append(lines, fmt.Sprintf("  %v preds {%v}", b.Name(), strings.Join(preds, " ")))
+			for _, instr := range b.Instructions {
+				lines = append(lines, "    "+strings.TrimSpace(instr.String()))
+			}
+		}
+		for _, err := range f.Verify() {
+			lines = append(lines, fmt.Sprintf("  error: %v", err))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,299 @@
+// Package ssa converts the ILOC of a function into static single assignment form, where every
+// register is written by a single instruction, and back. The definitions of a register meeting at
+// a block are merged by a phi instruction at its start, placed at the dominance frontiers of the
+// definitions where the register is live.
+package ssa
+
+import (
+	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+	"strings"
+)
+
+// PhiArg is the register a phi takes when its block is entered from the predecessor Pred, the
+// index of a block of the graph of the function
+type PhiArg struct {
+	Reg  int
+	Pred int
+}
+
+// Phi writes Target with the argument of the predecessor its block is entered from. The phis of a
+// block come right after its label, and all read their arguments before any of them is written.
+type Phi struct {
+	Target int
+	Args   []PhiArg
+}
+
+func (instr *Phi) GetTargets() []int { return []int{instr.Target} }
+
+func (instr *Phi) GetSources() []int {
+	sources := []int{}
+	for _, arg := range instr.Args {
+		sources = append(sources, arg.Reg)
+	}
+	return sources
+}
+
+func (instr *Phi) GetImmediate() *int { return nil }
+
+func (instr *Phi) GetSourceString() string { return "" }
+
+func (instr *Phi) GetLabel() string { return "" }
+
+func (instr *Phi) SetLabel(newLabel string) {}
+
+// String prints the phi with the block of each argument, e.g. phi r9,[r3,B0],[r8,B2]
+func (instr *Phi) String() string {
+	args := []string{}
+	for _, arg := range instr.Args {
+		args = append(args, fmt.Sprintf("[r%v,B%v]", arg.Reg, arg.Pred))
+	}
+	return fmt.Sprintf("    phi r%v,%v", instr.Target, strings.Join(args, ","))
+}
+
+func (instr *Phi) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	panic("phi instructions must be removed by ssa.Destruct before the translation into Arm code")
+}
+
+// Select is the SSA form of a conditional mov: it writes Target with the operand if the condition
+// holds, and with the register Else, the value the target of the mov had before, otherwise
+type Select struct {
+	Flag    ir.ApsrFlag
+	Target  int
+	Operand int
+	Opty    ir.OperandTy // ir.REGISTER or ir.IMMEDIATE
+	Else    int
+}
+
+func (instr *Select) GetTargets() []int { return []int{instr.Target} }
+
+func (instr *Select) GetSources() []int {
+	if instr.Opty == ir.REGISTER {
+		return []int{instr.Operand, instr.Else}
+	}
+	return []int{instr.Else}
+}
+
+func (instr *Select) GetImmediate() *int {
+	if instr.Opty == ir.IMMEDIATE {
+		return &instr.Operand
+	}
+	return nil
+}
+
+func (instr *Select) GetSourceString() string { return "" }
+
+func (instr *Select) GetLabel() string { return "" }
+
+func (instr *Select) SetLabel(newLabel string) {}
+
+// String prints the select in the order of the operands of the Arm csel, e.g. csel r9,#1,r8,gt
+func (instr *Select) String() string {
+	operand := fmt.Sprintf("r%v", instr.Operand)
+	if instr.Opty == ir.IMMEDIATE {
+		operand = fmt.Sprintf("#%v", instr.Operand)
+	}
+	return fmt.Sprintf("    csel r%v,%v,r%v,%v", instr.Target, operand, instr.Else, condName(instr.Flag))
+}
+
+func (instr *Select) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	panic("select instructions must be removed by ssa.Destruct before the translation into Arm code")
+}
+
+func condName(flag ir.ApsrFlag) string {
+	switch flag {
+	case ir.GT:
+		return "gt"
+	case ir.LT:
+		return "lt"
+	case ir.GE:
+		return "ge"
+	case ir.LE:
+		return "le"
+	case ir.EQ:
+		return "eq"
+	case ir.NE:
+		return "ne"
+	}
+	return "al"
+}
+
+// Func is a function in SSA form
+type Func struct {
+	Frag  *ir.FuncFrag // the ILOC of the function, with its phis and selects
+	Graph *cfg.Graph   // the control-flow graph of Frag, whose block indices the phis refer to
+}
+
+// Build converts a function into SSA form, leaving frag as it is. The blocks never reached are
+// dropped first. The parameters keep their registers, every other definition gets a new one,
+// and the reads of a register with no definition on some path keep its old one.
+func Build(frag *ir.FuncFrag) *Func {
+	g := cfg.Build(frag)
+	body := []ir.Instruction{}
+	for _, b := range g.Blocks {
+		if b == g.Entry || g.IDom[b.Index] != nil {
+			body = append(body, b.Instructions...)
+		}
+	}
+	g = cfg.Build(&ir.FuncFrag{Label: frag.Label, Params: frag.Params, Body: body})
+	reserveRegisters(g.Frag)
+
+	r := &renamer{g: g, stacks: map[int][]int{}, phis: placePhis(g), blocks: map[int][]ir.Instruction{}}
+	r.rename(g.Entry, dominatorTree(g))
+
+	ssaBody := []ir.Instruction{}
+	for _, b := range g.Blocks {
+		ssaBody = append(ssaBody, r.blocks[b.Index]...)
+	}
+	ssaFrag := &ir.FuncFrag{Label: frag.Label, Params: frag.Params, Body: ssaBody}
+	return &Func{ssaFrag, cfg.Build(ssaFrag)}
+}
+
+// reserveRegisters keeps ir.NewRegister from returning the registers of frag, which may have
+// been read from a file
+func reserveRegisters(frag *ir.FuncFrag) {
+	for _, reg := range frag.Params {
+		ir.ReserveRegisters(reg)
+	}
+	for _, instr := range frag.Body {
+		for _, reg := range append(instr.GetTargets(), instr.GetSources()...) {
+			ir.ReserveRegisters(reg)
+		}
+	}
+}
+
+// phi is a phi being built, for the register of the original function it merges
+type phi struct {
+	reg   int
+	instr *Phi
+}
+
+// placePhis returns the phis of each block by index: a register gets one at the iterated dominance
+// frontier of the blocks writing it, where it is live
+func placePhis(g *cfg.Graph) [][]*phi {
+	frontiers := g.DominanceFrontiers()
+	live := dataflow.Liveness(g)
+	defBlocks := map[int][]*cfg.Block{}
+	regs := []int{}
+	for _, b := range g.Blocks {
+		for _, instr := range b.Instructions {
+			for _, reg := range instr.GetTargets() {
+				if len(defBlocks[reg]) == 0 {
+					regs = append(regs, reg)
+				}
+				defBlocks[reg] = append(defBlocks[reg], b)
+			}
+		}
+	}
+
+	phis := make([][]*phi, len(g.Blocks))
+	for _, reg := range regs {
+		placed := map[*cfg.Block]bool{}
+		work := append([]*cfg.Block{}, defBlocks[reg]...)
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, join := range frontiers[b.Index] {
+				if placed[join] || !live.In[join.Index][reg] {
+					continue
+				}
+				placed[join] = true
+				phis[join.Index] = append(phis[join.Index], &phi{reg, &Phi{}})
+				work = append(work, join)
+			}
+		}
+	}
+	return phis
+}
+
+// dominatorTree returns the blocks each block immediately dominates, by index
+func dominatorTree(g *cfg.Graph) [][]*cfg.Block {
+	children := make([][]*cfg.Block, len(g.Blocks))
+	for _, b := range g.Blocks {
+		if idom := g.IDom[b.Index]; idom != nil {
+			children[idom.Index] = append(children[idom.Index], b)
+		}
+	}
+	return children
+}
+
+// renamer gives the definitions new registers, walking the dominator tree with a stack of the
+// current names of each register of the original function
+type renamer struct {
+	g      *cfg.Graph
+	stacks map[int][]int
+	phis   [][]*phi
+	blocks map[int][]ir.Instruction // the SSA instructions of each block by index
+}
+
+func (r *renamer) current(reg int) int {
+	if stack := r.stacks[reg]; len(stack) > 0 {
+		return stack[len(stack)-1]
+	}
+	return reg
+}
+
+func (r *renamer) rename(b *cfg.Block, children [][]*cfg.Block) {
+	pushed := []int{}
+	define := func(reg int) int {
+		name := ir.NewRegister()
+		r.stacks[reg] = append(r.stacks[reg], name)
+		pushed = append(pushed, reg)
+		return name
+	}
+
+	instrs := []ir.Instruction{}
+	rest := b.Instructions
+	if len(rest) > 0 {
+		if _, isLabel := rest[0].(*ir.Label); isLabel {
+			instrs, rest = append(instrs, rest[0]), rest[1:]
+		}
+	}
+	for _, p := range r.phis[b.Index] {
+		p.instr.Target = define(p.reg)
+		instrs = append(instrs, p.instr)
+	}
+	for _, instr := range rest {
+		if mov, isMov := instr.(*ir.Mov); isMov && mov.GetFlag() != ir.AL && !mov.GetRetFlag() {
+			s := &Select{Flag: mov.GetFlag(), Opty: ir.REGISTER}
+			if imm := mov.GetImmediate(); imm != nil {
+				s.Operand, s.Opty = *imm, ir.IMMEDIATE
+			} else {
+				s.Operand = r.current(mov.GetSources()[0])
+			}
+			s.Else = r.current(mov.GetTargets()[0])
+			s.Target = define(mov.GetTargets()[0])
+			instrs = append(instrs, s)
+			continue
+		}
+		// the sources are renamed before the targets are defined, which may be the same registers
+		defs := [][2]int{}
+		renamed := ir.MapRegisters(instr, r.current, func(reg int) int {
+			name := ir.NewRegister()
+			defs = append(defs, [2]int{reg, name})
+			return name
+		})
+		for _, def := range defs {
+			r.stacks[def[0]] = append(r.stacks[def[0]], def[1])
+			pushed = append(pushed, def[0])
+		}
+		instrs = append(instrs, renamed)
+	}
+	r.blocks[b.Index] = instrs
+
+	for _, succ := range b.Succs {
+		for _, p := range r.phis[succ.Index] {
+			p.instr.Args = append(p.instr.Args, PhiArg{r.current(p.reg), b.Index})
+		}
+	}
+	for _, child := range children[b.Index] {
+		r.rename(child, children)
+	}
+	for i := len(pushed) - 1; i >= 0; i-- {
+		reg := pushed[i]
+		r.stacks[reg] = r.stacks[reg][:len(r.stacks[reg])-1]
+	}
+}
@@ -0,0 +1,99 @@
+package ssa
+
+import (
+	"bytes"
+	"os"
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/parse"
+	"proj/golite/ir/sim"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, path string) []*ir.FuncFrag {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir.ResetGenerators()
+	frags, errors := parse.Parse(string(src))
+	if len(errors) != 0 || len(frags) != 1 {
+		t.Fatalf("\nExpected: a single function; Got %v\n", errors)
+	}
+	return frags
+}
+
+func Test1(t *testing.T) {
+	f := Build(load(t, "test1_ssa.iloc")[0])
+	if errors := f.Verify(); len(errors) != 0 {
+		t.Fatalf("\nExpected: a valid SSA form; Got %v\n", errors)
+	}
+	// r4 is not live at cond_L1, only r2 and r3 meet there
+	lines := f.Frag.Lines()
+	expected := []string{
+		"    csel r10,#1,r9,gt",
+		"    phi r7,[r5,B0],[r11,B1]",
+		"    phi r8,[r6,B0],[r12,B1]",
+		"    ret r7",
+	}
+	text := strings.Join(lines, "\n")
+	for _, line := range expected {
+		if !strings.Contains(text, line) {
+			t.Errorf("\nExpected: %v; Got\n%v\n", strings.TrimSpace(line), text)
+		}
+	}
+	if strings.Count(text, "phi") != 2 {
+		t.Errorf("\nExpected: 2 phis; Got\n%v\n", text)
+	}
+	if frontier := f.Graph.DominanceFrontiers()[1]; len(frontier) != 1 || frontier[0] != f.Graph.Blocks[2] {
+		t.Errorf("\nExpected: the dominance frontier of body_L2 is cond_L1; Got %v\n", frontier)
+	}
+}
+
+func Test2(t *testing.T) {
+	frags := load(t, "test2_ssa.iloc")
+	expected := bytes.Buffer{}
+	if _, err := sim.Run(frags, strings.NewReader(""), &expected); err != nil {
+		t.Fatal(err)
+	}
+	f := Build(frags[0])
+	if errors := f.Verify(); len(errors) != 0 {
+		t.Fatalf("\nExpected: a valid SSA form; Got %v\n", errors)
+	}
+	destructed := Destruct(f)
+	for _, instr := range destructed.Body {
+		if _, isPhi := instr.(*Phi); isPhi {
+			t.Fatalf("\nExpected: no phi left; Got %v\n", instr)
+		}
+	}
+	if !strings.Contains(strings.Join(destructed.Lines(), "\n"), "    blt edge_L") {
+		t.Errorf("\nExpected: the edge back to loop_L1 split\n")
+	}
+	got := bytes.Buffer{}
+	if _, err := sim.Run([]*ir.FuncFrag{destructed}, strings.NewReader(""), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != expected.String() {
+		t.Errorf("\nExpected: %q; Got %q\n", expected.String(), got.String())
+	}
+}
+
+func Test3(t *testing.T) {
+	frag := load(t, "test3_ssa.iloc")[0]
+	f := &Func{frag, cfg.Build(frag)}
+	errors := f.Verify()
+	expected := []string{
+		"f: r2 is defined more than once",
+		"f: r3 is read in B0 before its definition dominates it",
+		"f: the conditional mov of r2 in B0 is not a select",
+	}
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.Error() != expected[i] {
+			t.Errorf("\nExpected: %v; Got %v\n", expected[i], err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ssa
+
+import (
+	"fmt"
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+)
+
+// Verify checks the invariants of the SSA form, and returns an error for each one broken:
+//   - every register is written by at most one instruction, and a parameter by none,
+//   - every register read is a parameter or written in the function, and its definition dominates
+//     the read; for a phi argument, the end of the predecessor it comes from,
+//   - the phis of a block come before its other instructions, with one argument per predecessor,
+//   - no conditional mov is left, they are selects.
+func (f *Func) Verify() []error {
+	g := f.Graph
+	errors := []error{}
+	report := func(format string, args ...interface{}) {
+		errors = append(errors, fmt.Errorf("%v: "+format, append([]interface{}{f.Frag.Label}, args...)...))
+	}
+
+	// where each register is defined: its block, and its position in the block, -1 for the params
+	type def struct {
+		block *cfg.Block
+		pos   int
+	}
+	defs := map[int]def{}
+	for _, param := range f.Frag.Params {
+		defs[param] = def{g.Entry, -1}
+	}
+	for _, b := range g.Blocks {
+		for i, instr := range b.Instructions {
+			for _, reg := range instr.GetTargets() {
+				// the reads are checked against the first definition
+				if _, defined := defs[reg]; defined {
+					report("r%v is defined more than once", reg)
+				} else {
+					defs[reg] = def{b, i}
+				}
+			}
+		}
+	}
+	dominates := func(reg int, b *cfg.Block, pos int) bool {
+		d, defined := defs[reg]
+		if !defined {
+			report("r%v is read in %v but never defined", reg, b.Name())
+			return true
+		}
+		if d.block == b {
+			return d.pos < pos
+		}
+		return g.Dominates(d.block, b)
+	}
+
+	for _, b := range g.Blocks {
+		phisDone := false
+		for i, instr := range b.Instructions {
+			switch instr := instr.(type) {
+			case *ir.Label:
+				if i > 0 {
+					report("the label %v is not at the start of %v", instr.GetLabel(), b.Name())
+				}
+			case *Phi:
+				if phisDone {
+					report("the phi of r%v in %v comes after other instructions", instr.Target, b.Name())
+				}
+				verifyPhiArgs(g, b, instr, report)
+				for _, arg := range instr.Args {
+					if arg.Pred >= 0 && arg.Pred < len(g.Blocks) {
+						pred := g.Blocks[arg.Pred]
+						if !dominates(arg.Reg, pred, len(pred.Instructions)) {
+							report("r%v does not dominate the end of %v, for the phi of r%v", arg.Reg, pred.Name(), instr.Target)
+						}
+					}
+				}
+			default:
+				phisDone = true
+				if mov, isMov := instr.(*ir.Mov); isMov && mov.GetFlag() != ir.AL && !mov.GetRetFlag() {
+					report("the conditional mov of r%v in %v is not a select", mov.GetTargets()[0], b.Name())
+				}
+				for _, reg := range instr.GetSources() {
+					if !dominates(reg, b, i) {
+						report("r%v is read in %v before its definition dominates it", reg, b.Name())
+					}
+				}
+			}
+		}
+	}
+	return errors
+}
+
+// verifyPhiArgs checks that the phi has exactly one argument per predecessor of its block
+func verifyPhiArgs(g *cfg.Graph, b *cfg.Block, instr *Phi, report func(string, ...interface{})) {
+	seen := map[int]bool{}
+	for _, arg := range instr.Args {
+		isPred := false
+		for _, pred := range b.Preds {
+			isPred = isPred || pred.Index == arg.Pred
+		}
+		if !isPred {
+			report("the phi of r%v in %v has an argument for B%v, which is not a predecessor", instr.Target, b.Name(), arg.Pred)
+		} else if seen[arg.Pred] {
+			report("the phi of r%v in %v has two arguments for B%v", instr.Target, b.Name(), arg.Pred)
+		}
+		seen[arg.Pred] = true
+	}
+	for _, pred := range b.Preds {
+		if !seen[pred.Index] {
+			report("the phi of r%v in %v has no argument for %v", instr.Target, b.Name(), pred.Name())
+		}
+	}
+}
@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 type Str struct {
@@ -18,14 +18,12 @@ func NewStr(target int, sourceReg int, operand int, globalVar string, opty Opera
 	return &Str{target, sourceReg, operand, globalVar, opty}
 }
 
-func (instr *Str) GetTargets() []int {
-	targets := []int{}
-	targets = append(targets, instr.target)
-	return targets
-}
+// GetTargets returns no registers, str writes memory or a global variable
+func (instr *Str) GetTargets() []int { return []int{} }
 
+// GetSources returns the register stored first, then the registers of the address
 func (instr *Str) GetSources() []int {
-	sources := []int{}
+	sources := []int{instr.target}
 	if instr.opty == REGISTER {
 		sources = append(sources, instr.sourceReg, instr.operand)
 	} else if instr.opty == IMMEDIATE || instr.opty == ONEOPERAND {
@@ -78,19 +76,18 @@ func (instr *Str) String() string {
 	return out.String()
 }
 
-func (instr *Str) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
+func (instr *Str) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
 	if instr.opty == GLOBALVAR {
-		addrRegId := utility.NextAvailReg()
-		sourceRegId := utility.NextAvailReg()
-		sourceOffset := funcVarDict[instr.target]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",sourceRegId,sourceOffset))
-		instruction = append(instruction, fmt.Sprintf("\tadrp x%v,%v",addrRegId,instr.globalVar))
-		instruction = append(instruction, fmt.Sprintf("\tadd x%v,x%v, :lo12:%v",addrRegId,addrRegId,instr.globalVar))
-		instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x%v]",sourceRegId,addrRegId))
-		utility.ReleaseReg(sourceRegId)
-		utility.ReleaseReg(addrRegId)
+		sourceRegId, load := regs.use(instr.target)
+		instruction = append(instruction, load...)
+		addrRegId := asm.X(regs.takeScratch())
+		instruction = append(instruction, asm.NewInstr(asm.Adrp, addrRegId, asm.Symbol(instr.globalVar)))
+		instruction = append(instruction, asm.NewInstr(asm.Add, addrRegId, addrRegId, asm.Lo12(instr.globalVar)))
+		instruction = append(instruction, asm.NewInstr(asm.Str, sourceRegId, asm.Mem{Base: addrRegId}))
 	}
 
 	return instruction
@@ -0,0 +1,104 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+	"proj/golite/utility"
+)
+
+// StrCat sets its target to a new string, the string of its first source followed by the one of
+// its second source
+type StrCat struct {
+	target     int
+	sourceReg1 int
+	sourceReg2 int
+}
+
+func NewStrCat(target int, sourceReg1 int, sourceReg2 int) *StrCat {
+	return &StrCat{target, sourceReg1, sourceReg2}
+}
+
+func (instr *StrCat) GetTargets() []int {
+	targets := []int{}
+	targets = append(targets, instr.target)
+	return targets
+}
+
+func (instr *StrCat) GetSources() []int {
+	sources := []int{}
+	sources = append(sources, instr.sourceReg1, instr.sourceReg2)
+	return sources
+}
+
+func (instr *StrCat) GetImmediate() *int { return nil }
+
+func (instr *StrCat) GetSourceString() string { return "" }
+
+func (instr *StrCat) GetLabel() string { return "" }
+
+func (instr *StrCat) SetLabel(newLabel string) {}
+
+func (instr *StrCat) String() string {
+	var out bytes.Buffer
+
+	targetReg := fmt.Sprintf("r%v", instr.target)
+	sourceReg1 := fmt.Sprintf("r%v", instr.sourceReg1)
+	sourceReg2 := fmt.Sprintf("r%v", instr.sourceReg2)
+
+	out.WriteString(fmt.Sprintf("    strCat %s,%s,%s", targetReg, sourceReg1, sourceReg2))
+
+	return out.String()
+}
+
+// StrCatArmRoutine returns the code the concatenations of a program call, with the strings in x0
+// and x1: it allocates the bytes of both and their NUL with malloc, copies them there and returns
+// the new string in x0. It keeps the strings and the first length in x19 to x21, which it saves.
+func StrCatArmRoutine() []asm.Line {
+	return []asm.Line{
+		asm.Label(".STRCAT"),
+		asm.NewInstr(asm.Sub, asm.SP, asm.SP, asm.Imm(48)),
+		asm.NewInstr(asm.Stp, asm.FP, asm.LR, asm.Mem{Base: asm.SP}),
+		asm.NewInstr(asm.Stp, asm.X(19), asm.X(20), asm.Mem{Base: asm.SP, Offset: 16}),
+		asm.NewInstr(asm.Str, asm.X(21), asm.Mem{Base: asm.SP, Offset: 32}),
+		asm.NewInstr(asm.Mov, asm.FP, asm.SP),
+		asm.NewInstr(asm.Mov, asm.X(19), asm.X(0)),
+		asm.NewInstr(asm.Mov, asm.X(20), asm.X(1)),
+		asm.NewInstr(asm.Bl, asm.Symbol("strlen")),
+		asm.NewInstr(asm.Mov, asm.X(21), asm.X(0)),
+		asm.NewInstr(asm.Mov, asm.X(0), asm.X(20)),
+		asm.NewInstr(asm.Bl, asm.Symbol("strlen")),
+		asm.NewInstr(asm.Add, asm.X(0), asm.X(0), asm.X(21)),
+		asm.NewInstr(asm.Add, asm.X(0), asm.X(0), asm.Imm(1)),
+		asm.NewInstr(asm.Bl, asm.Symbol("malloc")),
+		asm.NewInstr(asm.Mov, asm.X(1), asm.X(19)),
+		asm.NewInstr(asm.Bl, asm.Symbol("strcpy")),
+		asm.NewInstr(asm.Mov, asm.X(1), asm.X(20)),
+		asm.NewInstr(asm.Bl, asm.Symbol("strcat")),
+		asm.NewInstr(asm.Ldr, asm.X(21), asm.Mem{Base: asm.SP, Offset: 32}),
+		asm.NewInstr(asm.Ldp, asm.X(19), asm.X(20), asm.Mem{Base: asm.SP, Offset: 16}),
+		asm.NewInstr(asm.Ldp, asm.FP, asm.LR, asm.Mem{Base: asm.SP}),
+		asm.NewInstr(asm.Add, asm.SP, asm.SP, asm.Imm(48)),
+		asm.NewInstr(asm.Ret),
+	}
+}
+
+func (instr *StrCat) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	utility.SetStrCat()
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	sourceRegId1, load := regs.use(instr.sourceReg1)
+	instruction = append(instruction, load...)
+	sourceRegId2, load := regs.use(instr.sourceReg2)
+	instruction = append(instruction, load...)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), sourceRegId1))
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(1), sourceRegId2))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol(".STRCAT")))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, targetRegId, asm.X(0)))
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
+
+	return instruction
+}
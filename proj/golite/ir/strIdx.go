@@ -0,0 +1,67 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+)
+
+// StrIdx stores to the element at an index of the block of the elements of an array or a slice,
+// the index having been checked by a bounds
+type StrIdx struct {
+	value  int
+	source int
+	index  int
+}
+
+func NewStrIdx(value int, source int, index int) *StrIdx {
+	return &StrIdx{value, source, index}
+}
+
+// GetTargets returns no registers, strIdx writes an element of a block
+func (instr *StrIdx) GetTargets() []int { return []int{} }
+
+// GetSources returns the register stored, then the register holding the block and the register
+// of the index
+func (instr *StrIdx) GetSources() []int {
+	sources := []int{}
+	sources = append(sources, instr.value, instr.source, instr.index)
+	return sources
+}
+
+func (instr *StrIdx) GetImmediate() *int { return nil }
+
+func (instr *StrIdx) GetSourceString() string { return "" }
+
+func (instr *StrIdx) GetLabel() string { return "" }
+
+func (instr *StrIdx) SetLabel(newLabel string) {}
+
+func (instr *StrIdx) String() string {
+	var out bytes.Buffer
+
+	valueReg := fmt.Sprintf("r%v", instr.value)
+	sourceReg := fmt.Sprintf("r%v", instr.source)
+	indexReg := fmt.Sprintf("r%v", instr.index)
+
+	out.WriteString(fmt.Sprintf("    strIdx %s,%s,%s", valueReg, sourceReg, indexReg))
+
+	return out.String()
+}
+
+func (instr *StrIdx) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	valueRegId, load := regs.use(instr.value)
+	instruction = append(instruction, load...)
+	blockRegId, load := regs.use(instr.source)
+	instruction = append(instruction, load...)
+	indexRegId, load := regs.use(instr.index)
+	instruction = append(instruction, load...)
+
+	instruction = append(instruction, asm.NewInstr(asm.Str, valueRegId, asm.Indexed{Base: blockRegId, Index: indexRegId}))
+
+	return instruction
+}
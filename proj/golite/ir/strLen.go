@@ -0,0 +1,64 @@
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"proj/golite/arm/asm"
+)
+
+// StrLen sets its target to the number of bytes of the string its source points to
+type StrLen struct {
+	target int
+	source int
+}
+
+func NewStrLen(target int, source int) *StrLen {
+	return &StrLen{target, source}
+}
+
+func (instr *StrLen) GetTargets() []int {
+	targets := []int{}
+	targets = append(targets, instr.target)
+	return targets
+}
+
+func (instr *StrLen) GetSources() []int {
+	sources := []int{}
+	sources = append(sources, instr.source)
+	return sources
+}
+
+func (instr *StrLen) GetImmediate() *int { return nil }
+
+func (instr *StrLen) GetSourceString() string { return "" }
+
+func (instr *StrLen) GetLabel() string { return "" }
+
+func (instr *StrLen) SetLabel(newLabel string) {}
+
+func (instr *StrLen) String() string {
+	var out bytes.Buffer
+
+	targetReg := fmt.Sprintf("r%v", instr.target)
+	sourceReg := fmt.Sprintf("r%v", instr.source)
+
+	out.WriteString(fmt.Sprintf("    strLen %s,%s", targetReg, sourceReg))
+
+	return out.String()
+}
+
+func (instr *StrLen) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
+
+	sourceRegId, load := regs.use(instr.source)
+	instruction = append(instruction, load...)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, asm.X(0), sourceRegId))
+	instruction = append(instruction, asm.NewInstr(asm.Bl, asm.Symbol("strlen")))
+	targetRegId := regs.def(instr.target)
+	instruction = append(instruction, asm.NewInstr(asm.Mov, targetRegId, asm.X(0)))
+	instruction = append(instruction, regs.store(instr.target, targetRegId)...)
+
+	return instruction
+}
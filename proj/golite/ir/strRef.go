@@ -3,7 +3,7 @@ package ir
 import (
 	"bytes"
 	"fmt"
-	"proj/golite/utility"
+	"proj/golite/arm/asm"
 )
 
 // to access fields of a struct
@@ -18,15 +18,13 @@ func NewStrRef(target int, source int, field string, fieldIdx int) *StrRef {
 	return &StrRef{target, source, field, fieldIdx}
 }
 
-func (instr *StrRef) GetTargets() []int {
-	targets := []int{}
-	targets = append(targets, instr.target)
-	return targets
-}
+// GetTargets returns no registers, strRef writes the field of a struct
+func (instr *StrRef) GetTargets() []int { return []int{} }
 
+// GetSources returns the register stored, then the register holding the struct
 func (instr *StrRef) GetSources() []int {
 	sources := []int{}
-	sources = append(sources, instr.source)
+	sources = append(sources, instr.target, instr.source)
 	return sources
 }
 
@@ -40,39 +38,34 @@ func (instr *StrRef) GetLabel() string { return "" }
 
 func (instr *StrRef) SetLabel(newLabel string) {}
 
+// GetFieldIdx returns the position of the field in its struct, its offset is 8 bytes per field
+func (instr *StrRef) GetFieldIdx() int { return instr.fieldIdx }
+
 func (instr *StrRef) String() string {
 	var out bytes.Buffer
 
 	targetReg := fmt.Sprintf("r%v", instr.target)
 	sourceReg := fmt.Sprintf("r%v", instr.source)
 	strField := fmt.Sprintf("@%v", instr.field)
+	fieldIdx := fmt.Sprintf("#%v", instr.fieldIdx)
 
-	out.WriteString(fmt.Sprintf("    strRef %s,%s,%s", targetReg, sourceReg, strField))
+	out.WriteString(fmt.Sprintf("    strRef %s,%s,%s,%s", targetReg, sourceReg, strField, fieldIdx))
 
 	return out.String()
 }
 
-func (instr *StrRef) TranslateToAssembly(funcVarDict map[int]int, paramRegIds map[int]int) []string {
-	instruction := []string{}
-
-	var targetRegId int
-	var istargetParam bool
-	if targetRegId, istargetParam = paramRegIds[instr.target]; !istargetParam {
-		targetRegId = utility.NextAvailReg()
-		targetOffSet := funcVarDict[instr.target]
-		instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]", targetRegId, targetOffSet))
-	}
+func (instr *StrRef) TranslateToAssembly(funcVarDict map[int]int, regIds map[int]int) []asm.Line {
+	instruction := []asm.Line{}
+	regs := newArmRegs(funcVarDict, regIds)
+	defer regs.release()
 
-	sourceRegId := utility.NextAvailReg()
-	sourceOffSet := funcVarDict[instr.source]
-	instruction = append(instruction, fmt.Sprintf("\tldr x%v,[x29,#%v]",sourceRegId,sourceOffSet))
+	targetRegId, load := regs.use(instr.target)
+	instruction = append(instruction, load...)
+	sourceRegId, load := regs.use(instr.source)
+	instruction = append(instruction, load...)
 
 	fieldOffset := instr.fieldIdx * 8
-	instruction = append(instruction, fmt.Sprintf("\tstr x%v,[x%v,#%v]",targetRegId,sourceRegId,fieldOffset))
+	instruction = append(instruction, asm.NewInstr(asm.Str, targetRegId, asm.Mem{Base: sourceRegId, Offset: fieldOffset}))
 
-	if !istargetParam {
-		utility.ReleaseReg(targetRegId)
-	}
-	utility.ReleaseReg(sourceRegId)
 	return instruction
 }
@@ -0,0 +1,279 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VerifyError is a malformed instruction found by Verify, or a malformed function if Instruction
+// is empty
+type VerifyError struct {
+	Function    string // label of the FuncFrag
+	Index       int    // position of the instruction in the body of the FuncFrag
+	Instruction string
+	Message     string
+}
+
+func (err *VerifyError) Error() string {
+	if err.Instruction == "" {
+		return fmt.Sprintf("%v: %v", err.Function, err.Message)
+	}
+	return fmt.Sprintf("%v+%v (%v): %v", err.Function, err.Index, strings.TrimSpace(err.Instruction), err.Message)
+}
+
+// Verify checks the ILOC of a program, and returns a *VerifyError for each problem found:
+//   - every branch goes to a label of its function, every bl to a function of the program, and
+//     every push lists as many registers as the parameters of the function it is for,
+//   - the labels of a function are unique,
+//   - the operand of every instruction is of a kind it takes, and its condition a valid one,
+//   - every register is written on all the paths to the instructions reading it, the parameters
+//     being written on entry,
+//   - no function falls off the end of its body, and either all its rets return a value or none
+//     does; the "mov rX,r0 @Return" after a call must not use the value of a function returning
+//     none.
+func Verify(frags []*FuncFrag) []error {
+	errors := []error{}
+	funcs := map[string]*FuncFrag{}
+	for _, frag := range frags {
+		funcs[frag.Label] = frag
+	}
+	returnsValue := map[string]bool{}
+	for _, frag := range frags {
+		for _, instr := range frag.Body {
+			if ret, isRet := instr.(*Ret); isRet && ret.opty != VOID {
+				returnsValue[frag.Label] = true
+			}
+		}
+	}
+	for _, frag := range frags {
+		v := &verifier{frag: frag, funcs: funcs, returnsValue: returnsValue}
+		v.verify()
+		errors = append(errors, v.errors...)
+	}
+	return errors
+}
+
+// verifier checks a single function
+type verifier struct {
+	frag         *FuncFrag
+	funcs        map[string]*FuncFrag
+	returnsValue map[string]bool // the functions with a ret returning a value
+	labels       map[string]int  // the position of each label in the body
+	errors       []error
+}
+
+func (v *verifier) report(index int, format string, args ...interface{}) {
+	instr := ""
+	if index >= 0 && index < len(v.frag.Body) {
+		instr = v.frag.Body[index].String()
+	}
+	v.errors = append(v.errors, &VerifyError{v.frag.Label, index, instr, fmt.Sprintf(format, args...)})
+}
+
+func (v *verifier) verify() {
+	v.labels = map[string]int{}
+	for i, instr := range v.frag.Body {
+		if label, isLabel := instr.(*Label); isLabel {
+			if _, duplicate := v.labels[label.label]; duplicate {
+				v.report(i, "the label %v is defined more than once", label.label)
+			}
+			v.labels[label.label] = i
+		}
+	}
+	hasValue, hasVoid := false, false
+	for i, instr := range v.frag.Body {
+		v.verifyInstruction(i, instr)
+		if ret, isRet := instr.(*Ret); isRet {
+			hasValue = hasValue || ret.opty != VOID
+			hasVoid = hasVoid || ret.opty == VOID
+		}
+	}
+	if hasValue && hasVoid {
+		v.report(-1, "some rets return a value and others none")
+	}
+	v.verifyDefinitions()
+}
+
+func (v *verifier) verifyInstruction(i int, instr Instruction) {
+	values := func(opty OperandTy, kinds ...OperandTy) {
+		for _, kind := range kinds {
+			if opty == kind {
+				return
+			}
+		}
+		v.report(i, "the operand kind %v is not one this instruction takes", operandKindName(opty))
+	}
+	condition := func(flag ApsrFlag) {
+		if flag < GT || flag > AL {
+			v.report(i, "the condition %v is not a valid one", flag)
+		}
+	}
+
+	switch instr := instr.(type) {
+	case *Add:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *Sub:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *And:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *Or:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *Not:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *Cmp:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *NewArr:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *Bounds:
+		values(instr.opty, REGISTER, IMMEDIATE)
+	case *Mov:
+		condition(instr.flag)
+		if instr.retFlag {
+			v.verifyReturnValue(i)
+		} else {
+			values(instr.opty, REGISTER, IMMEDIATE)
+		}
+	case *Ldr:
+		values(instr.opty, REGISTER, IMMEDIATE, ONEOPERAND, GLOBALVAR)
+		if instr.opty == GLOBALVAR && instr.globalVar == "" {
+			v.report(i, "the global variable has no name")
+		}
+	case *Str:
+		values(instr.opty, REGISTER, IMMEDIATE, ONEOPERAND, GLOBALVAR)
+		if instr.opty == GLOBALVAR && instr.globalVar == "" {
+			v.report(i, "the global variable has no name")
+		}
+	case *Ret:
+		values(instr.opty, REGISTER, IMMEDIATE, VOID)
+	case *Branch:
+		condition(instr.flagVal)
+		if _, found := v.labels[instr.label]; !found {
+			v.report(i, "no label %v in %v", instr.label, v.frag.Label)
+		}
+	case *Bl:
+		if callee := v.funcs[instr.GetLabel()]; callee == nil || callee.IsGlobal() {
+			v.report(i, "no function %v", instr.GetLabel())
+		}
+	case *Push:
+		if callee := v.funcs[instr.funcName]; callee != nil && len(callee.Params) != len(instr.sourceReg) {
+			v.report(i, "%v arguments pushed for %v, which has %v parameters", len(instr.sourceReg), instr.funcName, len(callee.Params))
+		}
+	case *Printf:
+		if conversions, valid := Conversions(instr.format); !valid || len(conversions) != len(instr.sourceReg) {
+			v.report(i, "the format %q does not have a %%ld or %%s for each of the %v registers printed", instr.format, len(instr.sourceReg))
+		}
+	}
+}
+
+func operandKindName(opty OperandTy) string {
+	switch opty {
+	case REGISTER:
+		return "REGISTER"
+	case IMMEDIATE:
+		return "IMMEDIATE"
+	case ONEOPERAND:
+		return "ONEOPERAND"
+	case GLOBALVAR:
+		return "GLOBALVAR"
+	case VOID:
+		return "VOID"
+	}
+	return fmt.Sprint(int(opty))
+}
+
+// verifyReturnValue checks that the "mov rX,r0 @Return" at i follows a call of a function
+// returning a value
+func (v *verifier) verifyReturnValue(i int) {
+	if i == 0 {
+		v.report(i, "the value returned is not that of a call")
+		return
+	}
+	bl, isBl := v.frag.Body[i-1].(*Bl)
+	if !isBl {
+		v.report(i, "the value returned is not that of a call")
+	} else if callee := v.funcs[bl.GetLabel()]; callee != nil && !v.returnsValue[callee.Label] {
+		v.report(i, "%v returns no value", callee.Label)
+	}
+}
+
+// successors returns the positions the instruction at i may be followed by, len(Body) for
+// falling off the end
+func (v *verifier) successors(i int) []int {
+	switch instr := v.frag.Body[i].(type) {
+	case *Ret:
+		return nil
+	case *Branch:
+		succs := []int{}
+		if target, found := v.labels[instr.label]; found {
+			succs = append(succs, target)
+		}
+		if instr.flagVal != AL {
+			succs = append(succs, i+1)
+		}
+		return succs
+	}
+	return []int{i + 1}
+}
+
+// verifyDefinitions finds the registers written on all the paths to each instruction, from the
+// entry of the function, and reports the reads of the others. It also reports a function whose
+// end is reached.
+func (v *verifier) verifyDefinitions() {
+	body := v.frag.Body
+	// defined[i] holds the registers written on all the paths to i, nil until a path reaches it
+	defined := make([]map[int]bool, len(body)+1)
+	defined[0] = map[int]bool{}
+	for _, param := range v.frag.Params {
+		defined[0][param] = true
+	}
+	work := []int{0}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i >= len(body) {
+			continue
+		}
+		out := map[int]bool{}
+		for reg := range defined[i] {
+			out[reg] = true
+		}
+		for _, reg := range body[i].GetTargets() {
+			out[reg] = true
+		}
+		for _, succ := range v.successors(i) {
+			if defined[succ] == nil {
+				defined[succ] = out
+				work = append(work, succ)
+				continue
+			}
+			// meet: only the registers written on every path
+			changed := false
+			met := map[int]bool{}
+			for reg := range defined[succ] {
+				if out[reg] {
+					met[reg] = true
+				} else {
+					changed = true
+				}
+			}
+			if changed {
+				defined[succ] = met
+				work = append(work, succ)
+			}
+		}
+	}
+
+	for i, instr := range body {
+		if defined[i] == nil {
+			continue
+		}
+		for _, reg := range instr.GetSources() {
+			if !defined[i][reg] {
+				v.report(i, "r%v may be read before being written", reg)
+			}
+		}
+	}
+	if defined[len(body)] != nil && !v.frag.IsGlobal() {
+		v.report(-1, "the end of the body is reached without a ret")
+	}
+}
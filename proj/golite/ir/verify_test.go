@@ -0,0 +1,88 @@
+package ir
+
+import "testing"
+
+func verifyMessages(frags []*FuncFrag) []string {
+	messages := []string{}
+	for _, err := range Verify(frags) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func movReturn(target int) *Mov {
+	mov := NewMov(target, 0, AL, REGISTER)
+	mov.SetRetFlag()
+	return mov
+}
+
+func Test1(t *testing.T) {
+	// f(r1) returns r1+1 if r1 > 0 and 0 otherwise, main prints f(3)
+	f := &FuncFrag{Label: "f", Params: []int{1}, Body: []Instruction{
+		NewLabelStmt("f"),
+		NewCmp(1, 0, IMMEDIATE),
+		NewBranch(LE, "else_L1"),
+		NewAdd(2, 1, 1, IMMEDIATE),
+		NewRet(2, REGISTER),
+		NewLabelStmt("else_L1"),
+		NewRet(0, IMMEDIATE),
+	}}
+	main := &FuncFrag{Label: "main", Body: []Instruction{
+		NewLabelStmt("main"),
+		NewMov(3, 3, AL, IMMEDIATE),
+		NewPush([]int{3}, "f"),
+		NewBl("f"),
+		movReturn(4),
+		NewPop([]int{3}, "f"),
+		NewPrintln(4),
+		NewRet(-1, VOID),
+	}}
+	if messages := verifyMessages([]*FuncFrag{f, main}); len(messages) != 0 {
+		t.Errorf("\nExpected: no errors; Got %v\n", messages)
+	}
+}
+
+func Test2(t *testing.T) {
+	// r2 is only written when the branch is not taken, g returns no value, main prints with a bad
+	// format and falls off its end
+	f := &FuncFrag{Label: "f", Params: []int{1}, Body: []Instruction{
+		NewLabelStmt("f"),
+		NewCmp(1, 0, IMMEDIATE),
+		NewBranch(GT, "done_L1"),
+		NewMov(2, 1, AL, REGISTER),
+		NewLabelStmt("done_L1"),
+		NewRet(2, REGISTER),
+		NewBranch(AL, "missing_L2"),
+		NewRet(-1, VOID),
+	}}
+	g := &FuncFrag{Label: "g", Body: []Instruction{NewLabelStmt("g"), NewRet(-1, VOID)}}
+	main := &FuncFrag{Label: "main", Body: []Instruction{
+		NewLabelStmt("main"),
+		NewPush([]int{}, "f"),
+		NewBl("g"),
+		movReturn(3),
+		NewMov(4, 0, AL, GLOBALVAR),
+		NewBl("h"),
+		NewPrintf("%ld %x", []int{3}),
+	}}
+	expected := []string{
+		"f+6 (b missing_L2): no label missing_L2 in f",
+		"f: some rets return a value and others none",
+		"f+5 (ret r2): r2 may be read before being written",
+		"main+1 (push {} @f): 0 arguments pushed for f, which has 1 parameters",
+		"main+3 (mov r3,r0 @Return): g returns no value",
+		"main+4 (mov r4,r0): the operand kind GLOBALVAR is not one this instruction takes",
+		"main+5 (bl h): no function h",
+		"main+6 (printf \"%ld %x\",r3): the format \"%ld %x\" does not have a %ld or %s for each of the 1 registers printed",
+		"main: the end of the body is reached without a ret",
+	}
+	messages := verifyMessages([]*FuncFrag{f, g, main})
+	if len(messages) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), messages)
+	}
+	for i, message := range messages {
+		if message != expected[i] {
+			t.Errorf("\nExpected: %v; Got %v\n", expected[i], message)
+		}
+	}
+}
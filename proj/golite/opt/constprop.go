@@ -0,0 +1,305 @@
+package opt
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+)
+
+// maybeUndefined is the forward problem of the registers that may still hold a value from before
+// the body: the parameters, and the registers read before being written on some path
+type maybeUndefined struct {
+	regs dataflow.Set // every register of the function
+}
+
+func (mu maybeUndefined) Backward() bool            { return false }
+func (mu maybeUndefined) Boundary(g *cfg.Graph) Set { return mu.regs }
+func (mu maybeUndefined) Top(g *cfg.Graph) Set      { return Set{} }
+func (mu maybeUndefined) Meet(a Set, b Set) Set     { return a.Union(b) }
+
+// Step removes the registers the instruction always writes
+func (mu maybeUndefined) Step(g *cfg.Graph, index int, undefined Set) Set {
+	instr := g.Frag.Body[index]
+	if len(instr.GetTargets()) == 0 || isConditionalMov(instr) {
+		return undefined
+	}
+	undefined = undefined.Copy()
+	for _, reg := range instr.GetTargets() {
+		delete(undefined, reg)
+	}
+	return undefined
+}
+
+// Set is the set of the facts of the dataflow problems
+type Set = dataflow.Set
+
+// constants finds the registers holding the same value whenever an instruction runs, from the
+// definitions reaching it: a register has a constant value if it is defined on every path and all
+// its definitions reaching the instruction give the same value.
+type constants struct {
+	g         *cfg.Graph
+	defs      []Set       // the definitions reaching each instruction
+	undefined []Set       // the registers that may be undefined before each instruction
+	values    map[int]int // the value written by each definition known to write a constant
+}
+
+func findConstants(g *cfg.Graph) *constants {
+	all := Set{}
+	for _, instr := range g.Frag.Body {
+		for _, reg := range append(instr.GetTargets(), instr.GetSources()...) {
+			all[reg] = true
+		}
+	}
+	for _, param := range g.Frag.Params {
+		all[param] = true
+	}
+	c := &constants{
+		g:         g,
+		defs:      factsBefore(dataflow.ReachingDefinitions(g)),
+		undefined: factsBefore(dataflow.Solve(g, maybeUndefined{all})),
+		values:    map[int]int{},
+	}
+	// a definition is known once its operands are, which may take several sweeps around loops
+	for changed := true; changed; {
+		changed = false
+		for index, instr := range g.Frag.Body {
+			if _, known := c.values[index]; known || c.defs[index] == nil {
+				continue
+			}
+			if value, known := c.evaluate(index, instr); known {
+				c.values[index] = value
+				changed = true
+			}
+		}
+	}
+	return c
+}
+
+// value returns the value of a register before the instruction at index, if it is constant
+func (c *constants) value(reg int, index int) (int, bool) {
+	if c.defs[index] == nil || c.undefined[index][reg] {
+		return 0, false
+	}
+	value, found := 0, false
+	for def := range c.defs[index] {
+		if !contains(c.g.Frag.Body[def].GetTargets(), reg) {
+			continue
+		}
+		defValue, known := c.values[def]
+		if !known || (found && defValue != value) {
+			return 0, false
+		}
+		value, found = defValue, true
+	}
+	return value, found
+}
+
+// operand returns the value of the operand at position i of the sources of an instruction, or of
+// its immediate when it has one and i is past its registers
+func (c *constants) operand(index int, instr ir.Instruction, i int) (int, bool) {
+	sources := instr.GetSources()
+	if i < len(sources) {
+		return c.value(sources[i], index)
+	}
+	if imm := instr.GetImmediate(); imm != nil {
+		return *imm, true
+	}
+	return 0, false
+}
+
+// evaluate returns the value the instruction at index writes, if it is constant
+func (c *constants) evaluate(index int, instr ir.Instruction) (int, bool) {
+	switch instr := instr.(type) {
+	case *ir.Mov:
+		if instr.GetRetFlag() {
+			return 0, false
+		}
+		if imm := instr.GetImmediate(); imm != nil {
+			return *imm, true
+		}
+		return c.value(instr.GetSources()[0], index)
+	case *ir.Not:
+		a, known := c.operand(index, instr, 0)
+		return boolValue(a == 0), known
+	case *ir.Add, *ir.Sub, *ir.Mul, *ir.Div, *ir.And, *ir.Or:
+		a, knownA := c.operand(index, instr, 0)
+		b, knownB := c.operand(index, instr, 1)
+		if !knownA || !knownB {
+			return 0, false
+		}
+		return fold(instr, a, b)
+	}
+	return 0, false
+}
+
+// fold computes an arithmetic or logic instruction as the ILOC simulator does, false for a
+// division by zero, left to fail when the program runs
+func fold(instr ir.Instruction, a int, b int) (int, bool) {
+	switch instr.(type) {
+	case *ir.Add:
+		return a + b, true
+	case *ir.Sub:
+		return a - b, true
+	case *ir.Mul:
+		return a * b, true
+	case *ir.Div:
+		if b == 0 {
+			return 0, false
+		}
+		return a / b, true
+	case *ir.And:
+		return boolValue(a != 0 && b != 0), true
+	case *ir.Or:
+		return boolValue(a != 0 || b != 0), true
+	}
+	return 0, false
+}
+
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// flags returns whether the condition of the conditional instruction at index holds, if the
+// cmp setting the flags before it in its block compares constants
+func (c *constants) flags(index int, flag ir.ApsrFlag) (bool, bool) {
+	for i := index - 1; i >= 0; i-- {
+		switch instr := c.g.Frag.Body[i].(type) {
+		case *ir.Cmp:
+			a, knownA := c.operand(i, instr, 0)
+			b, knownB := c.operand(i, instr, 1)
+			if !knownA || !knownB {
+				return false, false
+			}
+			return holds(flag, a, b), true
+		case *ir.Label, *ir.Bl, *ir.Branch, *ir.Ret:
+			return false, false
+		}
+	}
+	return false, false
+}
+
+// holds returns true if the condition holds after comparing a with b
+func holds(flag ir.ApsrFlag, a int, b int) bool {
+	switch flag {
+	case ir.GT:
+		return a > b
+	case ir.LT:
+		return a < b
+	case ir.GE:
+		return a >= b
+	case ir.LE:
+		return a <= b
+	case ir.EQ:
+		return a == b
+	case ir.NE:
+		return a != b
+	}
+	return true
+}
+
+// ConstantPropagation replaces the registers holding constants by immediates, the instructions
+// computing a constant by a mov of it, and the conditional instructions following a comparison of
+// constants by their unconditional form, or removes them if their condition never holds
+func ConstantPropagation(frag *ir.FuncFrag) bool {
+	g := cfg.Build(frag)
+	c := findConstants(g)
+	body := []ir.Instruction{}
+	for index, instr := range frag.Body {
+		if c.defs[index] == nil {
+			body = append(body, instr)
+			continue
+		}
+		if rewritten, keep := c.rewrite(index, instr); keep {
+			body = append(body, rewritten)
+		}
+	}
+	return replaceBody(frag, body)
+}
+
+// rewrite returns the instruction at index with the constants it reads, false if it never runs
+func (c *constants) rewrite(index int, instr ir.Instruction) (ir.Instruction, bool) {
+	flag := ir.AL
+	switch instr := instr.(type) {
+	case *ir.Branch:
+		flag = instr.GetFlag()
+	case *ir.Mov:
+		if !instr.GetRetFlag() {
+			flag = instr.GetFlag()
+		}
+	}
+	if flag != ir.AL {
+		if holds, known := c.flags(index, flag); known {
+			if !holds {
+				return nil, false
+			}
+			flag = ir.AL
+		}
+	}
+
+	if value, known := c.values[index]; known {
+		return ir.NewMov(instr.GetTargets()[0], value, flag, ir.IMMEDIATE), true
+	}
+	known := func(i int) (int, bool) {
+		sources := instr.GetSources()
+		if i >= len(sources) {
+			return 0, false
+		}
+		return c.value(sources[i], index)
+	}
+	switch instr := instr.(type) {
+	case *ir.Branch:
+		return ir.NewBranch(flag, instr.GetLabel()), true
+	case *ir.Mov:
+		if flag != instr.GetFlag() && !instr.GetRetFlag() {
+			return ir.NewMov(instr.GetTargets()[0], instr.GetSources()[0], flag, ir.REGISTER), true
+		}
+	case *ir.Add, *ir.Sub, *ir.And, *ir.Or:
+		sources := instr.GetSources()
+		if len(sources) < 2 {
+			break
+		}
+		target := instr.GetTargets()[0]
+		if b, isConst := known(1); isConst {
+			return newBinary(instr, target, sources[0], b), true
+		}
+		if a, isConst := known(0); isConst {
+			if _, isSub := instr.(*ir.Sub); !isSub {
+				return newBinary(instr, target, sources[1], a), true
+			}
+		}
+	case *ir.Cmp:
+		if b, isConst := known(1); isConst {
+			return ir.NewCmp(instr.GetSources()[0], b, ir.IMMEDIATE), true
+		}
+	case *ir.Ret:
+		if a, isConst := known(0); isConst {
+			return ir.NewRet(a, ir.IMMEDIATE), true
+		}
+	}
+	return instr, true
+}
+
+// newBinary returns the instruction of the kind of instr, with an immediate second operand
+func newBinary(instr ir.Instruction, target int, source int, imm int) ir.Instruction {
+	switch instr.(type) {
+	case *ir.Add:
+		return ir.NewAdd(target, source, imm, ir.IMMEDIATE)
+	case *ir.Sub:
+		return ir.NewSub(target, source, imm, ir.IMMEDIATE)
+	case *ir.And:
+		return ir.NewAnd(target, source, imm, ir.IMMEDIATE)
+	}
+	return ir.NewOr(target, source, imm, ir.IMMEDIATE)
+}
+
+func contains(list []int, elem int) bool {
+	for _, e := range list {
+		if e == elem {
+			return true
+		}
+	}
+	return false
+}
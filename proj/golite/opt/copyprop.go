@@ -0,0 +1,87 @@
+package opt
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+)
+
+// availableCopies is the forward problem of the copies "mov rT,rS" done on every path to a point,
+// with neither rT nor rS written since. The facts are the positions of the copies in the body.
+type availableCopies struct {
+	copies []int         // the positions of the copies
+	uses   map[int][]int // the copies reading or writing each register
+}
+
+func newAvailableCopies(g *cfg.Graph) *availableCopies {
+	ac := &availableCopies{uses: map[int][]int{}}
+	for index, instr := range g.Frag.Body {
+		if isCopy(instr) {
+			ac.copies = append(ac.copies, index)
+			for _, reg := range []int{instr.GetTargets()[0], instr.GetSources()[0]} {
+				ac.uses[reg] = append(ac.uses[reg], index)
+			}
+		}
+	}
+	return ac
+}
+
+func (ac *availableCopies) Backward() bool            { return false }
+func (ac *availableCopies) Boundary(g *cfg.Graph) Set { return Set{} }
+func (ac *availableCopies) Meet(a Set, b Set) Set     { return a.Intersect(b) }
+
+// Top returns all the copies
+func (ac *availableCopies) Top(g *cfg.Graph) Set {
+	all := Set{}
+	for _, index := range ac.copies {
+		all[index] = true
+	}
+	return all
+}
+
+// Step removes the copies of the registers the instruction writes, then adds the instruction if
+// it is a copy of another register
+func (ac *availableCopies) Step(g *cfg.Graph, index int, available Set) Set {
+	instr := g.Frag.Body[index]
+	if len(instr.GetTargets()) == 0 {
+		return available
+	}
+	available = available.Copy()
+	for _, reg := range instr.GetTargets() {
+		for _, copy := range ac.uses[reg] {
+			delete(available, copy)
+		}
+	}
+	if isCopy(instr) && instr.GetTargets()[0] != instr.GetSources()[0] {
+		available[index] = true
+	}
+	return available
+}
+
+// CopyPropagation replaces the registers read by an instruction with the registers they are
+// copies of, leaving the copies themselves to dead-code elimination
+func CopyPropagation(frag *ir.FuncFrag) bool {
+	g := cfg.Build(frag)
+	available := factsBefore(dataflow.Solve(g, newAvailableCopies(g)))
+	body := []ir.Instruction{}
+	for index, instr := range frag.Body {
+		if available[index] == nil || len(available[index]) == 0 {
+			body = append(body, instr)
+			continue
+		}
+		copyOf := map[int]int{}
+		for copy := range available[index] {
+			copyInstr := frag.Body[copy]
+			copyOf[copyInstr.GetTargets()[0]] = copyInstr.GetSources()[0]
+		}
+		use := func(reg int) int {
+			if source, isCopy := copyOf[reg]; isCopy {
+				return source
+			}
+			return reg
+		}
+		// the target of a conditional mov is read as well, and must stay as it is
+		body = append(body, ir.MapRegisters(instr, use, ir.Identity))
+	}
+	return replaceBody(frag, body)
+}
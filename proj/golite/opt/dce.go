@@ -0,0 +1,99 @@
+package opt
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+)
+
+// isPure returns true for the instructions whose only effect is writing their target. A division
+// may fail, a load from a struct may read a deleted one, and the other instructions have effects of
+// their own.
+func isPure(instr ir.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ir.Add, *ir.Sub, *ir.Mul, *ir.And, *ir.Or, *ir.Not, *ir.Ldr:
+		return true
+	case *ir.Mov:
+		return !instr.GetRetFlag()
+	}
+	return false
+}
+
+// readsFlags returns true for the conditional instructions
+func readsFlags(instr ir.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ir.Branch:
+		return instr.GetFlag() != ir.AL
+	case *ir.Mov:
+		return isConditionalMov(instr)
+	}
+	return false
+}
+
+// flagsLiveOut returns the blocks whose flags may be read by a conditional instruction of a
+// successor before a cmp sets them again
+func flagsLiveOut(g *cfg.Graph) map[*cfg.Block]bool {
+	liveIn := map[*cfg.Block]bool{}
+	liveOut := map[*cfg.Block]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range g.Blocks {
+			out := false
+			for _, succ := range b.Succs {
+				out = out || liveIn[succ]
+			}
+			in := out
+			for i := len(b.Instructions) - 1; i >= 0; i-- {
+				if _, isCmp := b.Instructions[i].(*ir.Cmp); isCmp {
+					in = false
+				} else if readsFlags(b.Instructions[i]) {
+					in = true
+				}
+			}
+			if in != liveIn[b] || out != liveOut[b] {
+				liveIn[b], liveOut[b] = in, out
+				changed = true
+			}
+		}
+	}
+	return liveOut
+}
+
+// DeadCodeElimination removes the pure instructions writing registers that are never read, and
+// the comparisons whose flags are never read
+func DeadCodeElimination(frag *ir.FuncFrag) bool {
+	g := cfg.Build(frag)
+	liveAfter := factsAfter(dataflow.Liveness(g))
+	flagsOut := flagsLiveOut(g)
+	blocks := blockOf(g)
+
+	dead := map[int]bool{}
+	for _, b := range g.Blocks {
+		flagsLive := flagsOut[b]
+		for i := len(b.Instructions) - 1; i >= 0; i-- {
+			index := b.Start + i
+			instr := b.Instructions[i]
+			if _, isCmp := instr.(*ir.Cmp); isCmp {
+				dead[index] = !flagsLive
+				flagsLive = false
+			} else if readsFlags(instr) {
+				flagsLive = true
+			}
+		}
+	}
+	body := []ir.Instruction{}
+	for index, instr := range frag.Body {
+		if liveAfter[index] != nil && isPure(instr) && !isConditionalMov(instr) {
+			dead[index] = true
+			for _, reg := range instr.GetTargets() {
+				if liveAfter[index][reg] {
+					dead[index] = false
+				}
+			}
+		}
+		if !dead[index] || !reachable(g, blocks[index]) {
+			body = append(body, instr)
+		}
+	}
+	return replaceBody(frag, body)
+}
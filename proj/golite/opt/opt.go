@@ -0,0 +1,161 @@
+// Package opt optimizes the ILOC of a program between its translation from the AST and its
+// translation into Arm code. A Manager runs the passes of an optimization level over every
+// function, and can print the ILOC after a given pass.
+package opt
+
+import (
+	"fmt"
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+	"proj/golite/ir/dataflow"
+)
+
+// Level is an optimization level, as given by -O0, -O1 and -O2
+type Level int
+
+const (
+	O0 Level = iota // no optimization
+	O1              // every pass once
+	O2              // the passes again and again, until none changes the program
+)
+
+// maxRounds bounds the rounds of passes of O2
+const maxRounds = 10
+
+// Pass rewrites the ILOC of a function, and returns true if it changed it
+type Pass struct {
+	Name string
+	Run  func(frag *ir.FuncFrag) bool
+}
+
+// Passes lists the passes in the order they run
+var Passes = []Pass{
+	{"constprop", ConstantPropagation},
+	{"copyprop", CopyPropagation},
+	{"dce", DeadCodeElimination},
+	{"simplifycfg", SimplifyCFG},
+}
+
+// PassNames returns the names of the passes, e.g. to check the name given to -print-after
+func PassNames() []string {
+	names := []string{}
+	for _, pass := range Passes {
+		names = append(names, pass.Name)
+	}
+	return names
+}
+
+// Manager runs the passes of a level over the functions of a program
+type Manager struct {
+	Level      Level
+	PrintAfter string   // the pass after which the ILOC of the program is printed, "all" for every pass
+	Printed    []string // the ILOC printed, each listing headed by a comment naming the pass
+}
+
+// Run optimizes the functions of a program in place, and returns the rounds of passes run. The
+// ILOC is checked by ir.Verify after every pass, which panics if a pass broke it; ILOC already
+// invalid is optimized without checks, as the errors could not be told apart.
+func (m *Manager) Run(frags []*ir.FuncFrag) int {
+	if m.Level == O0 {
+		return 0
+	}
+	verify := len(ir.Verify(frags)) == 0
+	rounds := 0
+	for changed := true; changed && rounds < maxRounds; {
+		changed = false
+		rounds++
+		for _, pass := range Passes {
+			for _, frag := range frags {
+				if !frag.IsGlobal() && pass.Run(frag) {
+					changed = true
+				}
+			}
+			if verify {
+				if errors := ir.Verify(frags); len(errors) > 0 {
+					panic(fmt.Sprintf("the %v pass produced invalid ILOC: %v", pass.Name, errors))
+				}
+			}
+			if m.PrintAfter == pass.Name || m.PrintAfter == "all" {
+				m.print(fmt.Sprintf("// after %v, round %v", pass.Name, rounds), frags)
+			}
+		}
+		if m.Level == O1 {
+			break
+		}
+	}
+	return rounds
+}
+
+func (m *Manager) print(header string, frags []*ir.FuncFrag) {
+	m.Printed = append(m.Printed, header)
+	for _, frag := range frags {
+		m.Printed = append(m.Printed, frag.Lines()...)
+	}
+}
+
+// factsBefore returns the facts of a solved problem before each instruction of the body, by
+// position in the body; nil for the instructions of the blocks never reached
+func factsBefore(r *dataflow.Result) []dataflow.Set {
+	facts := make([]dataflow.Set, len(r.Graph.Frag.Body))
+	for _, b := range r.Graph.Blocks {
+		if !reachable(r.Graph, b) {
+			continue
+		}
+		for i, fact := range r.Before(b) {
+			facts[b.Start+i] = fact
+		}
+	}
+	return facts
+}
+
+// factsAfter is factsBefore for the facts after each instruction
+func factsAfter(r *dataflow.Result) []dataflow.Set {
+	facts := make([]dataflow.Set, len(r.Graph.Frag.Body))
+	for _, b := range r.Graph.Blocks {
+		if !reachable(r.Graph, b) {
+			continue
+		}
+		for i, fact := range r.After(b) {
+			facts[b.Start+i] = fact
+		}
+	}
+	return facts
+}
+
+// reachable returns true if the block may run, the others having no immediate dominator
+func reachable(g *cfg.Graph, b *cfg.Block) bool {
+	return b == g.Entry || g.IDom[b.Index] != nil
+}
+
+// blockOf returns the block of each position in the body
+func blockOf(g *cfg.Graph) []*cfg.Block {
+	blocks := make([]*cfg.Block, len(g.Frag.Body))
+	for _, b := range g.Blocks {
+		for i := range b.Instructions {
+			blocks[b.Start+i] = b
+		}
+	}
+	return blocks
+}
+
+// isConditionalMov returns true for a mov writing its target only if its condition holds
+func isConditionalMov(instr ir.Instruction) bool {
+	mov, isMov := instr.(*ir.Mov)
+	return isMov && mov.GetFlag() != ir.AL && !mov.GetRetFlag()
+}
+
+// isCopy returns true for an unconditional mov of a register of the function
+func isCopy(instr ir.Instruction) bool {
+	mov, isMov := instr.(*ir.Mov)
+	return isMov && mov.GetFlag() == ir.AL && !mov.GetRetFlag() && mov.GetImmediate() == nil
+}
+
+// replaceBody installs a new body, and returns true if it differs from the old one
+func replaceBody(frag *ir.FuncFrag, body []ir.Instruction) bool {
+	changed := len(body) != len(frag.Body)
+	for i := 0; !changed && i < len(body); i++ {
+		changed = body[i].String() != frag.Body[i].String()
+	}
+	frag.Body = body
+	return changed
+}
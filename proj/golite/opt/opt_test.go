@@ -0,0 +1,74 @@
+package opt
+
+import (
+	"os"
+	"proj/golite/ir"
+	"proj/golite/ir/parse"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, path string) []*ir.FuncFrag {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frags, errors := parse.Parse(string(src))
+	if len(errors) != 0 || len(frags) != 1 {
+		t.Fatalf("\nExpected: a single function; Got %v\n", errors)
+	}
+	return frags
+}
+
+func body(frags []*ir.FuncFrag) string {
+	return strings.Join(frags[0].Lines(), "\n")
+}
+
+func Test1(t *testing.T) {
+	frags := load(t, "test1_opt.iloc")
+	m := &Manager{Level: O2}
+	if rounds := m.Run(frags); rounds < 2 {
+		t.Errorf("\nExpected: more rounds until nothing changes; Got %v\n", rounds)
+	}
+	expected := "f: \n    params {r1}\n    add r5,r1,#7\n    ret r5"
+	if got := body(frags); got != expected {
+		t.Errorf("\nExpected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
+
+func Test2(t *testing.T) {
+	frags := load(t, "test2_opt.iloc")
+	if !CopyPropagation(frags[0]) {
+		t.Fatalf("\nExpected: the copies of r1 and r3 propagated\n")
+	}
+	if lines := frags[0].Lines(); lines[4] != "    mul r6,r3,r1" || lines[6] != "    ret r3" {
+		t.Errorf("\nExpected: mul r6,r3,r1 and ret r3; Got %v and %v\n", lines[4], lines[6])
+	}
+	if !DeadCodeElimination(frags[0]) {
+		t.Fatalf("\nExpected: the copies and the mul removed\n")
+	}
+	expected := "g: \n    params {r1}\n    add r3,r1,#1\n    ret r3"
+	if got := body(frags); got != expected {
+		t.Errorf("\nExpected:\n%v\nGot:\n%v\n", expected, got)
+	}
+	if DeadCodeElimination(frags[0]) {
+		t.Errorf("\nExpected: nothing left to remove\n")
+	}
+}
+
+func Test3(t *testing.T) {
+	frags := load(t, "test3_opt.iloc")
+	m := &Manager{Level: O1, PrintAfter: "dce"}
+	m.Run(frags)
+	if len(m.Printed) == 0 || m.Printed[0] != "// after dce, round 1" {
+		t.Fatalf("\nExpected: the ILOC printed after dce; Got %v\n", m.Printed)
+	}
+	// constprop, copyprop and dce have nothing to do, the listing is the program itself
+	if !strings.Contains(strings.Join(m.Printed, "\n"), "    b done_L3\ndone_L3: ") {
+		t.Errorf("\nExpected: the jump to done_L3 kept until simplifycfg\n")
+	}
+	expected := "h: \n    params {r1}\n    cmp r1,#0\n    bgt then_L1\n    b end_L4\nthen_L1: \n    print r1\nend_L4: \n    ret r1"
+	if got := body(frags); got != expected {
+		t.Errorf("\nExpected:\n%v\nGot:\n%v\n", expected, got)
+	}
+}
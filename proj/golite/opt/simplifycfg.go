@@ -0,0 +1,110 @@
+package opt
+
+import (
+	"proj/golite/ir"
+	"proj/golite/ir/cfg"
+)
+
+// SimplifyCFG removes the blocks never reached, threads the branches to a label followed by an
+// unconditional branch to that branch's label, removes the unconditional branches to the labels
+// directly following them and the labels no branch goes to. Each of these may allow another, so
+// it repeats them until the function stops changing.
+func SimplifyCFG(frag *ir.FuncFrag) bool {
+	changed := false
+	for simplifyOnce(frag) {
+		changed = true
+	}
+	return changed
+}
+
+func simplifyOnce(frag *ir.FuncFrag) bool {
+	g := cfg.Build(frag)
+	body := []ir.Instruction{}
+	for _, b := range g.Blocks {
+		if reachable(g, b) {
+			body = append(body, b.Instructions...)
+		}
+	}
+
+	// where a branch to each label ends up, following the labels that only branch elsewhere
+	jumpsTo := map[string]string{}
+	for i, instr := range body {
+		if label, isLabel := instr.(*ir.Label); isLabel && i > 0 {
+			if next := nextBranch(body, i); next != nil {
+				jumpsTo[label.GetLabel()] = next.GetLabel()
+			}
+		}
+	}
+	resolve := func(label string) string {
+		seen := map[string]bool{}
+		for !seen[label] {
+			seen[label] = true
+			next, threaded := jumpsTo[label]
+			if !threaded {
+				break
+			}
+			label = next
+		}
+		return label
+	}
+
+	simplified := []ir.Instruction{body[0]}
+	for i := 1; i < len(body); i++ {
+		branch, isBranch := body[i].(*ir.Branch)
+		if !isBranch {
+			simplified = append(simplified, body[i])
+			continue
+		}
+		target := resolve(branch.GetLabel())
+		if branch.GetFlag() == ir.AL && labelFollows(body, i, target) {
+			continue
+		}
+		simplified = append(simplified, ir.NewBranch(branch.GetFlag(), target))
+	}
+
+	targets := map[string]bool{}
+	for _, instr := range simplified {
+		if branch, isBranch := instr.(*ir.Branch); isBranch {
+			targets[branch.GetLabel()] = true
+		}
+	}
+	body = []ir.Instruction{simplified[0]}
+	for _, instr := range simplified[1:] {
+		if label, isLabel := instr.(*ir.Label); !isLabel || targets[label.GetLabel()] {
+			body = append(body, instr)
+		}
+	}
+	return replaceBody(frag, body)
+}
+
+// nextBranch returns the unconditional branch following the label at i and any other labels, nil
+// if another instruction comes first
+func nextBranch(body []ir.Instruction, i int) *ir.Branch {
+	for i++; i < len(body); i++ {
+		switch instr := body[i].(type) {
+		case *ir.Label:
+			continue
+		case *ir.Branch:
+			if instr.GetFlag() == ir.AL {
+				return instr
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// labelFollows returns true if the label comes right after the instruction at i, possibly among
+// other labels
+func labelFollows(body []ir.Instruction, i int, label string) bool {
+	for i++; i < len(body); i++ {
+		next, isLabel := body[i].(*ir.Label)
+		if !isLabel {
+			return false
+		}
+		if next.GetLabel() == label {
+			return true
+		}
+	}
+	return false
+}
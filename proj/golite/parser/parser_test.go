@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"proj/golite/ast"
 	ct "proj/golite/context"
 	"proj/golite/scanner"
 	tk "proj/golite/token"
@@ -14,35 +15,35 @@ func Test1(t *testing.T) {
 
 	// The expected result struct represents the token stream for the input source
 	tokens := []tk.Token{
-		{tk.PACK, "package", 1},
-		{tk.ID, "main", 1},
-		{tk.SEMICOLON, ";", 1},
+		{Type: tk.PACK, Literal: "package", LineNum: 1},
+		{Type: tk.ID, Literal: "main", LineNum: 1},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 1},
 
-		{tk.IMPORT, "import", 2},
-		{tk.QTDMARK, "\"", 2},
-		{tk.FMT, "fmt", 2},
-		{tk.QTDMARK, "\"", 2},
-		{tk.SEMICOLON, ";", 2},
+		{Type: tk.IMPORT, Literal: "import", LineNum: 2},
+		{Type: tk.QTDMARK, Literal: "\"", LineNum: 2},
+		{Type: tk.FMT, Literal: "fmt", LineNum: 2},
+		{Type: tk.QTDMARK, Literal: "\"", LineNum: 2},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 2},
 
-		{tk.FUNC, "func", 3},
-		{tk.ID, "main", 3},
-		{tk.LPAREN, "(", 3},
-		{tk.RPAREN, ")", 3},
-		{tk.LBRACE, "{", 3},
+		{Type: tk.FUNC, Literal: "func", LineNum: 3},
+		{Type: tk.ID, Literal: "main", LineNum: 3},
+		{Type: tk.LPAREN, Literal: "(", LineNum: 3},
+		{Type: tk.RPAREN, Literal: ")", LineNum: 3},
+		{Type: tk.LBRACE, Literal: "{", LineNum: 3},
 
-		{tk.VAR, "var", 4},
-		{tk.ID, "a", 4},
-		{tk.INT, "int", 4},
-		{tk.SEMICOLON, ";", 4},
+		{Type: tk.VAR, Literal: "var", LineNum: 4},
+		{Type: tk.ID, Literal: "a", LineNum: 4},
+		{Type: tk.INT, Literal: "int", LineNum: 4},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 4},
 
-		{tk.ID, "a", 5},
-		{tk.ASSIGN, "=", 5},
-		{tk.NUM, "1", 5},
-		{tk.ADD, "+", 5},
-		{tk.NUM, "1", 5},
-		{tk.SEMICOLON, ";", 5},
+		{Type: tk.ID, Literal: "a", LineNum: 5},
+		{Type: tk.ASSIGN, Literal: "=", LineNum: 5},
+		{Type: tk.NUM, Literal: "1", LineNum: 5},
+		{Type: tk.ADD, Literal: "+", LineNum: 5},
+		{Type: tk.NUM, Literal: "1", LineNum: 5},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 5},
 
-		{tk.RBRACE, "}", 6},
+		{Type: tk.RBRACE, Literal: "}", LineNum: 6},
 	}
 
 	parser := New(*myScanner)
@@ -62,41 +63,41 @@ func Test2(t *testing.T) {
 
 	// The expected result struct represents the token stream for the input source
 	tokens := []tk.Token{
-		{tk.PACK, "package", 1},
-		{tk.ID, "main", 1},
-		{tk.SEMICOLON, ";", 1},
-
-		{tk.IMPORT, "import", 2},
-		{tk.QTDMARK, "\"", 2},
-		{tk.FMT, "fmt", 2},
-		{tk.QTDMARK, "\"", 2},
-		{tk.SEMICOLON, ";", 2},
-
-		{tk.FUNC, "func", 3},
-		{tk.ID, "main", 3},
-		{tk.LPAREN, "(", 3},
-		{tk.RPAREN, ")", 3},
-		{tk.LBRACE, "{", 3},
-
-		{tk.VAR, "var", 4},
-		{tk.ID, "b", 4},
-		{tk.INT, "bool", 4},
-		{tk.SEMICOLON, ";", 4},
-
-		{tk.ID, "b", 5},
-		{tk.ASSIGN, "=", 5},
-		{tk.TRUE, "true", 5},
-		{tk.OR, "||", 5},
-		{tk.FALSE, "false", 5},
-		{tk.AND, "&&", 5},
-		{tk.LPAREN, "(", 5},
-		{tk.TRUE, "true", 5},
-		{tk.EQUAL, "==", 5},
-		{tk.TRUE, "true", 5},
-		{tk.RPAREN, ")", 5},
-		{tk.SEMICOLON, ";", 5},
-
-		{tk.RBRACE, "}", 6},
+		{Type: tk.PACK, Literal: "package", LineNum: 1},
+		{Type: tk.ID, Literal: "main", LineNum: 1},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 1},
+
+		{Type: tk.IMPORT, Literal: "import", LineNum: 2},
+		{Type: tk.QTDMARK, Literal: "\"", LineNum: 2},
+		{Type: tk.FMT, Literal: "fmt", LineNum: 2},
+		{Type: tk.QTDMARK, Literal: "\"", LineNum: 2},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 2},
+
+		{Type: tk.FUNC, Literal: "func", LineNum: 3},
+		{Type: tk.ID, Literal: "main", LineNum: 3},
+		{Type: tk.LPAREN, Literal: "(", LineNum: 3},
+		{Type: tk.RPAREN, Literal: ")", LineNum: 3},
+		{Type: tk.LBRACE, Literal: "{", LineNum: 3},
+
+		{Type: tk.VAR, Literal: "var", LineNum: 4},
+		{Type: tk.ID, Literal: "b", LineNum: 4},
+		{Type: tk.INT, Literal: "bool", LineNum: 4},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 4},
+
+		{Type: tk.ID, Literal: "b", LineNum: 5},
+		{Type: tk.ASSIGN, Literal: "=", LineNum: 5},
+		{Type: tk.TRUE, Literal: "true", LineNum: 5},
+		{Type: tk.OR, Literal: "||", LineNum: 5},
+		{Type: tk.FALSE, Literal: "false", LineNum: 5},
+		{Type: tk.AND, Literal: "&&", LineNum: 5},
+		{Type: tk.LPAREN, Literal: "(", LineNum: 5},
+		{Type: tk.TRUE, Literal: "true", LineNum: 5},
+		{Type: tk.EQUAL, Literal: "==", LineNum: 5},
+		{Type: tk.TRUE, Literal: "true", LineNum: 5},
+		{Type: tk.RPAREN, Literal: ")", LineNum: 5},
+		{Type: tk.SEMICOLON, Literal: ";", LineNum: 5},
+
+		{Type: tk.RBRACE, Literal: "}", LineNum: 6},
 	}
 
 	parser := New(*myScanner)
@@ -148,3 +149,149 @@ func Test5(t *testing.T) {
 
 	fmt.Println(ast.String())
 }
+
+func Test6(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test1_parser.golite")
+	myScanner := scanner.New(*ctx)
+
+	parser := New(*myScanner)
+	ast := parser.Parse()
+	if ast == nil {
+		t.Fatalf("\nExpected: Valid AST; Got nil\n")
+	}
+
+	fun := ast.Functions.Functions[0]
+	assignment := fun.Statements.Statements[0]
+	spans := map[string]tk.Span{
+		"program":    ast.Span,
+		"function":   fun.Span,
+		"assignment": assignment.Span,
+	}
+	expected := map[string]string{
+		"program":    "1:1-6:2",
+		"function":   "3:1-6:2",
+		"assignment": "5:5-5:15",
+	}
+	for name, span := range spans {
+		if span.String() != expected[name] {
+			t.Errorf("\nExpected: %v spans %v; Got %v\n", name, expected[name], span)
+		}
+	}
+}
+
+func Test7(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test6_parser.golite")
+	myScanner := scanner.New(*ctx)
+
+	parser := New(*myScanner)
+	program := parser.Parse()
+	if program == nil {
+		t.Fatalf("\nExpected: partial AST; Got nil\n")
+	}
+
+	// every syntax error is reported, with the expected and found tokens
+	expected := []string{
+		`6:1: error[P001]: unexpected "}", expected ";"`,
+		`10:9: error[P001]: unexpected ";", expected expression`,
+		`11:8: error[P001]: unexpected identifier c, expected ";"`,
+		`12:10: error[P001]: unexpected ")", expected expression`,
+		`17:2: error[P001]: unexpected "}", expected ";"`,
+		`22:1: error[P001]: unexpected "}", expected ";"`,
+	}
+	errors := parser.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.String() != expected[i] {
+			t.Errorf("\nExpected: %v\nGot: %v\n", expected[i], err.String())
+		}
+	}
+
+	// both functions are kept, the statements with errors are replaced
+	if len(program.Functions.Functions) != 2 {
+		t.Fatalf("\nExpected: 2 functions; Got %v\n", len(program.Functions.Functions))
+	}
+	bad := 0
+	for _, stmt := range program.Functions.Functions[0].Statements.Statements {
+		if _, isBad := stmt.Stmt.(*ast.BadStmt); isBad {
+			bad++
+		}
+	}
+	if bad != 3 {
+		t.Errorf("\nExpected: 3 bad statements; Got %v\n", bad)
+	}
+}
+
+func Test8(t *testing.T) {
+	src := "package main;\nimport \"fmt\";\nvar a [3]int;\nvar s []*Node;\n" +
+		"func main() {\n    s[a[0]].next = s[len(s) - 1].next.next;\n}\n"
+	parser := New(*scanner.NewFromString(src))
+	program := parser.Parse()
+	if errors := parser.Errors(); len(errors) != 0 {
+		t.Fatalf("\nExpected: no error; Got %v\n", errors)
+	}
+
+	// the types of arrays and slices, and the selectors of any depth on both sides
+	decls := program.Declarations.Declarations
+	if decls[0].Ty.TypeLiteral != "[3]int" || decls[1].Ty.TypeLiteral != "[]*Node" {
+		t.Errorf("\nExpected: [3]int and []*Node; Got %v and %v\n", decls[0].Ty.TypeLiteral, decls[1].Ty.TypeLiteral)
+	}
+	assignment := program.Functions.Functions[0].Statements.Statements[0].Stmt.(*ast.Assignment)
+	if len(assignment.Lvalue.Selectors) != 2 || assignment.Lvalue.Selectors[0].Index == nil {
+		t.Errorf("\nExpected: an index then a field; Got %v\n", assignment.Lvalue.Selectors)
+	}
+	expected := "s[a[0]].next = s[len(s)-1].next.next;\n"
+	if assignment.String() != expected {
+		t.Errorf("\nExpected: %q\nGot: %q\n", expected, assignment.String())
+	}
+}
+
+func Test9(t *testing.T) {
+	src := "package main;\nimport \"fmt\";\nvar s string;\n" +
+		"func main() {\n    s = s + \"a\\tb\";\n    fmt.Print(\"label: \");\n    fmt.Println(s);\n}\n"
+	parser := New(*scanner.NewFromString(src))
+	program := parser.Parse()
+	if errors := parser.Errors(); len(errors) != 0 {
+		t.Fatalf("\nExpected: no error; Got %v\n", errors)
+	}
+
+	// the string type, a literal keeping its source text and its decoded value, and the print of a literal
+	if ty := program.Declarations.Declarations[0].Ty.TypeLiteral; ty != "string" {
+		t.Errorf("\nExpected: string; Got %v\n", ty)
+	}
+	stmts := program.Functions.Functions[0].Statements.Statements
+	assignment := stmts[0].Stmt.(*ast.Assignment)
+	if expected := "s = s+\"a\\tb\";\n"; assignment.String() != expected {
+		t.Errorf("\nExpected: %q\nGot: %q\n", expected, assignment.String())
+	}
+	literal := assignment.Expr.Left.Left.Left.Left.Rights[0].Left.SelectorTerm.Fact.Expr.(*ast.StringLiteral)
+	if literal.Value != "a\tb" {
+		t.Errorf("\nExpected: %q; Got %q\n", "a\tb", literal.Value)
+	}
+	print := stmts[1].Stmt.(*ast.Print)
+	if len(print.Args.Exprs) != 1 || print.String() != "fmt.Print(\"label: \");\n" {
+		t.Errorf("\nExpected: the print of \"label: \"; Got %q\n", print.String())
+	}
+}
+
+func Test10(t *testing.T) {
+	src := "package main;\nimport \"fmt\";\n" +
+		"func main() {\n    fmt.Println(x+1, p.next.value, f(3), \"a\");\n    fmt.Print();\n    fmt.Println(;\n}\n"
+	parser := New(*scanner.NewFromString(src))
+	program := parser.Parse()
+
+	// any number of expressions, and the error of a missing one
+	if errors := parser.Errors(); len(errors) != 1 || errors[0].Pos.Line != 6 {
+		t.Fatalf("\nExpected: an error at line 6; Got %v\n", errors)
+	}
+	stmts := program.Functions.Functions[0].Statements.Statements
+	print := stmts[0].Stmt.(*ast.Print)
+	expected := "fmt.Println(x+1,p.next.value,f(3),\"a\");\n"
+	if len(print.Args.Exprs) != 4 || print.String() != expected {
+		t.Errorf("\nExpected: %q\nGot: %q\n", expected, print.String())
+	}
+	if print := stmts[1].Stmt.(*ast.Print); len(print.Args.Exprs) != 0 || print.Method() != "Print" {
+		t.Errorf("\nExpected: a Print without arguments; Got %q\n", print.String())
+	}
+}
@@ -1,12 +1,12 @@
 package parser
 
 import (
-	"flag"
-	"fmt"
 	"proj/golite/ast"
+	"proj/golite/diag"
 	"proj/golite/scanner"
 	ct "proj/golite/token"
 	"strconv"
+	"strings"
 )
 
 //Parser includes all fields necessary to perform recursive decent parsing
@@ -14,31 +14,34 @@ type Parser struct {
 	tokens    []ct.Token
 	currToken ct.Token
 	currIndex int
-	errFound  bool
+	errors    []diag.Diagnostic
+
+	furthest  int            // index of the furthest token a match failed on, -1 if none
+	expected  []ct.TokenType // the token types expected at furthest
+	lastError int            // index of the token of the last syntax error reported
 }
 
 //New creates and initializes a new parser
 func New(scanner scanner.Scanner) *Parser {
-	parser := &Parser{}
-	parser.tokens = []ct.Token{}
 	// read all tokens from given scanner
-	for {
-		tok := scanner.NextToken()
-		if tok.Type == ct.EOF {
-			parser.tokens = append(parser.tokens, tok)
-			break
-		}
-		parser.tokens = append(parser.tokens, tok)
-	}
+	return NewFromTokens(scanner.ScanAll())
+}
+
+//NewFromTokens creates a parser over an already scanned token stream ending with EOF
+func NewFromTokens(tokens []ct.Token) *Parser {
+	parser := &Parser{}
+	parser.tokens = tokens
 	parser.currIndex = 0
 	parser.currToken = parser.tokens[parser.currIndex]
+	parser.furthest = -1
+	parser.lastError = -1
 	return parser
 }
 
 func (p *Parser) NextToken() ct.Token {
 	p.currIndex += 1
 	if p.currIndex >= len(p.tokens) {
-		return ct.Token{ct.ILLEGAL, "illegal", -1}
+		return ct.Token{Type: ct.ILLEGAL, Literal: "illegal", LineNum: -1}
 	}
 	for p.tokens[p.currIndex].Type == ct.COMMENT {
 		p.currIndex += 1
@@ -46,10 +49,13 @@ func (p *Parser) NextToken() ct.Token {
 	return p.tokens[p.currIndex]
 }
 
-func (p *Parser) parseError(msg string) {
-	out := flag.CommandLine.Output()
-	fmt.Fprintf(out, "syntax error: #{msg}\n")
-	p.errFound = true
+func (p *Parser) parseError(code diag.Code, tok ct.Token, format string, args ...interface{}) {
+	p.errors = append(p.errors, diag.Errorf(code, diag.At(&tok), format, args...))
+}
+
+//Errors returns the syntax errors found while parsing
+func (p *Parser) Errors() []diag.Diagnostic {
+	return p.errors
 }
 
 func (p *Parser) match(token ct.TokenType) (ct.Token, bool) {
@@ -59,52 +65,88 @@ func (p *Parser) match(token ct.TokenType) (ct.Token, bool) {
 		p.currToken = p.NextToken()
 		return token, true
 	}
-	return ct.Token{ct.ILLEGAL, "", lineNum}, false
+	p.fail(token)
+	return ct.Token{Type: ct.ILLEGAL, Literal: "", LineNum: lineNum}, false
 }
 
 func (p *Parser) expect(token ct.TokenType) bool {
 	if _, match := p.match(token); match {
 		return true
 	}
-	p.parseError("unexpected symbol error. Found: #{p.currToken.Type}, Expected: #{token.Type}")
+	p.parseError(diag.UnexpectedToken, p.currToken, "unexpected %v, expected %v", describe(p.currToken), describeExpected([]ct.TokenType{token}))
 	return false
 }
 
+// spanFrom returns the span from the token at index start up to the last token matched,
+// an empty span at the start token if nothing has been matched since
+func (p *Parser) spanFrom(start int) ct.Span {
+	if start >= len(p.tokens) {
+		start = len(p.tokens) - 1
+	}
+	last := p.currIndex - 1
+	if last >= len(p.tokens) {
+		last = len(p.tokens) - 1
+	}
+	for last > start && p.tokens[last].Type == ct.COMMENT {
+		last -= 1
+	}
+	if last < start {
+		pos := p.tokens[start].Pos()
+		return ct.Span{Start: pos, End: pos}
+	}
+	return ct.Span{Start: p.tokens[start].Pos(), End: p.tokens[last].End}
+}
+
+// wrapStatement wraps a parsed statement which started at the token at index start
+func (p *Parser) wrapStatement(stmt ast.Stmt, start int) *ast.Statement {
+	node := ast.NewStatement(stmt)
+	node.Span = p.spanFrom(start)
+	return node
+}
+
+// badStmt creates the node for a statement which started at index start and has been skipped
+func (p *Parser) badStmt(tok *ct.Token, start int) *ast.BadStmt {
+	node := ast.NewBadStmt(tok)
+	node.Span = p.spanFrom(start)
+	return node
+}
+
+// Parse returns the AST of the program, which is only partial if syntax errors have been found:
+// the statements that could not be parsed are replaced by BadStmt nodes and the other
+// constructs with errors are left out. Errors returns all the syntax errors.
 func (p *Parser) Parse() *ast.Program {
 	return program(p) // QI TOU starting
 }
 
 func program(p *Parser) *ast.Program {
+	start := p.currIndex
 	pac := packageStmt(p)
 	if pac == nil {
-		return nil
+		p.syntaxError()
+		if p.reset(start); p.currToken.Type != ct.IMPORT {
+			p.sync(start, true)
+		}
 	}
+	p.resetFailure()
+	impStart := p.currIndex
 	imp := importStmt(p)
 	if imp == nil {
-		return nil
+		p.syntaxError()
+		if p.reset(impStart); p.currToken.Type == ct.IMPORT {
+			p.sync(impStart, true)
+		}
 	}
 	typ := types(p)
-	if typ == nil {
-		return nil
-	}
-	decs := declarations(p)
-	if decs == nil {
-		return nil
-	}
+	decs := declarations(p, true)
 	funs := functions(p)
-	if funs == nil {
-		return nil
-	}
-	if p.currToken.Type != ct.EOF {
-		p.parseError(fmt.Sprintf("Expected end of file but found :#{p.currToken.Literal}"))
-	}
-	if p.errFound == false {
-		return ast.NewProgram(pac, imp, typ, decs, funs)
-	}
-	return nil
+
+	node := ast.NewProgram(pac, imp, typ, decs, funs)
+	node.Span = p.spanFrom(start)
+	return node
 }
 
 func packageStmt(p *Parser) *ast.Package {
+	start := p.currIndex
 	var pacTok, idTok ct.Token
 	var pacMatch, idMatch bool
 
@@ -121,10 +163,12 @@ func packageStmt(p *Parser) *ast.Package {
 	//node := ast.NewPackage(ast.IdentLiteral{&idTok, idTok.Literal, })
 	node := ast.NewPackage(ast.NewIdentLiteral(&idTok, idTok.Literal))
 	node.Token = &pacTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func importStmt(p *Parser) *ast.Import {
+	start := p.currIndex
 	var impTok, fmtTok ct.Token
 	var impMatch, fmtMatch bool
 
@@ -147,26 +191,33 @@ func importStmt(p *Parser) *ast.Import {
 	//node := ast.NewImport(ast.IdentLiteral{&fmtTok, fmtTok.Literal})
 	node := ast.NewImport(ast.NewIdentLiteral(&fmtTok, fmtTok.Literal))
 	node.Token = &impTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func types(p *Parser) *ast.Types {
+	start := p.currIndex
 	var typedecs []ast.TypeDeclaration
 
-	for {
+	for p.currToken.Type == ct.TYPE {
+		p.resetFailure()
+		typeStart := p.currIndex
 		typedec := typeDeclaration(p)
 		if typedec != nil {
 			typedecs = append(typedecs, *typedec)
 		} else {
-			break
+			p.syntaxError()
+			p.sync(typeStart, true)
 		}
 	}
 
 	node := ast.NewTypes(typedecs)
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func typeDeclaration(p *Parser) *ast.TypeDeclaration {
+	start := p.currIndex
 	var typTok, idTok ct.Token
 	var typMac, idMac, structMac, lbrMac, rbrMac, scMac bool
 	if typTok, typMac = p.match(ct.TYPE); !typMac {
@@ -195,10 +246,12 @@ func typeDeclaration(p *Parser) *ast.TypeDeclaration {
 	//node := ast.NewTypeDeclaration(ast.IdentLiteral{&idTok, idTok.Literal}, astFields)
 	node := ast.NewTypeDeclaration(ast.NewIdentLiteral(&idTok, idTok.Literal), astFields)
 	node.Token = &typTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func fields(p *Parser) *ast.Fields {
+	start := p.currIndex
 	declFirst := decl(p)
 	var decls []ast.Decl
 
@@ -223,10 +276,12 @@ func fields(p *Parser) *ast.Fields {
 	}
 
 	node := ast.NewFields(decls)
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func decl(p *Parser) *ast.Decl {
+	start := p.currIndex
 	var idTok ct.Token
 	var match bool
 	if idTok, match = p.match(ct.ID); !match {
@@ -239,10 +294,12 @@ func decl(p *Parser) *ast.Decl {
 	//node := ast.NewDecl(ast.IdentLiteral{&idTok, idTok.Literal}, astType)
 	node := ast.NewDecl(ast.NewIdentLiteral(&idTok, idTok.Literal), astType)
 	node.Token = &idTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func typeExpression(p *Parser) *ast.Type {
+	start := p.currIndex
 	var node *ast.Type
 	var typeTok ct.Token
 	var match bool
@@ -253,36 +310,58 @@ func typeExpression(p *Parser) *ast.Type {
 	} else if typeTok, match := p.match(ct.BOOL); match {
 		node = ast.NewType(typeTok.Literal)
 		node.Token = &typeTok
+	} else if typeTok, match := p.match(ct.STRING); match {
+		node = ast.NewType(typeTok.Literal)
+		node.Token = &typeTok
 	} else if typeTok, match := p.match(ct.MULTIPLY); match {
 		if idTok, idMatch := p.match(ct.ID); idMatch {
 			node = ast.NewType(typeTok.Literal + idTok.Literal)
 			node.Token = &typeTok
 		}
+	} else if typeTok, match := p.match(ct.LBRACKET); match {
+		// [N]Type for an array, []Type for a slice
+		length := ""
+		if numTok, numMatch := p.match(ct.NUM); numMatch {
+			length = numTok.Literal
+		}
+		if _, rbMatch := p.match(ct.RBRACKET); rbMatch {
+			if elem := typeExpression(p); elem != nil {
+				node = ast.NewType("[" + length + "]" + elem.TypeLiteral)
+				node.Token = &typeTok
+			}
+		}
 	}
 
 	if node != nil && node.Token != nil {
+		node.Span = p.spanFrom(start)
 		return node
 	}
 	return nil
 }
 
-func declarations(p *Parser) *ast.Declarations {
+func declarations(p *Parser, topLevel bool) *ast.Declarations {
+	start := p.currIndex
 	var decs []ast.Declaration
 
-	for {
+	for p.currToken.Type == ct.VAR {
+		p.resetFailure()
+		decStart := p.currIndex
 		dec := declaration(p)
 		if dec != nil {
 			decs = append(decs, *dec)
 		} else {
-			break
+			p.syntaxError()
+			p.sync(decStart, topLevel)
 		}
 	}
 
 	node := ast.NewDeclarations(decs)
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func declaration(p *Parser) *ast.Declaration {
+	start := p.currIndex
 	var varmatch, scmatch bool
 	var varTok ct.Token
 
@@ -303,10 +382,12 @@ func declaration(p *Parser) *ast.Declaration {
 
 	node := ast.NewDeclaration(idTok, typeTok)
 	node.Token = &varTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func ids(p *Parser) *ast.Ids {
+	start := p.currIndex
 	var idTokFirst ct.Token
 	var idMatchFirst bool
 	var ids []ast.IdentLiteral
@@ -331,25 +412,34 @@ func ids(p *Parser) *ast.Ids {
 
 	node := ast.NewIds(ids)
 	node.Token = &idTokFirst
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func functions(p *Parser) *ast.Functions {
+	start := p.currIndex
 	var funs []ast.Function
 
-	for {
-		if fun := function(p); fun != nil {
+	for p.currToken.Type != ct.EOF {
+		p.resetFailure()
+		funStart := p.currIndex
+		if p.currToken.Type != ct.FUNC {
+			p.fail(ct.FUNC)
+		} else if fun := function(p); fun != nil {
 			funs = append(funs, *fun)
-		} else {
-			break
+			continue
 		}
+		p.syntaxError()
+		p.sync(funStart, true)
 	}
 
 	node := ast.NewFunctions(funs)
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func function(p *Parser) *ast.Function {
+	start := p.currIndex
 	var funcTok, idTok ct.Token
 	var funcMatch, idMatch bool
 	if funcTok, funcMatch = p.match(ct.FUNC); !funcMatch {
@@ -369,25 +459,22 @@ func function(p *Parser) *ast.Function {
 	if _, lbraceMatch := p.match(ct.LBRACE); !lbraceMatch {
 		return nil
 	}
-	decls := declarations(p)
-	if decls == nil {
-		return nil
-	}
+	decls := declarations(p, false)
 	stmts := statements(p)
-	if stmts == nil {
-		return nil
-	}
+	// the body is kept even if its closing brace is missing
 	if _, rbraceMatch := p.match(ct.RBRACE); !rbraceMatch {
-		return nil
+		p.syntaxError()
 	}
 
 	//node := ast.NewFunction(ast.IdentLiteral{&idTok, idTok.Literal}, paras, retTyp, decls, stmts)
 	node := ast.NewFunction(ast.NewIdentLiteral(&idTok, idTok.Literal), paras, retTyp, decls, stmts)
 	node.Token = &funcTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func parameters(p *Parser) *ast.Parameters {
+	start := p.currIndex
 	var lParenTok ct.Token
 	var lParenMatch bool
 	if lParenTok, lParenMatch = p.match(ct.LPAREN); !lParenMatch {
@@ -416,31 +503,49 @@ func parameters(p *Parser) *ast.Parameters {
 
 	node := ast.NewParameters(decls)
 	node.Token = &lParenTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func returnType(p *Parser) *ast.ReturnType {
+	start := p.currIndex
 	var node *ast.ReturnType
 	typTok := typeExpression(p)
 
 	if typTok != nil {
-		node = ast.NewReturnType(typTok.TokenLiteral())
+		node = ast.NewReturnType(typTok.TypeLiteral)
+		node.Ty.Token = typTok.Token
+		node.Ty.Span = typTok.Span
 	} else {
 		node = ast.NewReturnType("")
 	}
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func statements(p *Parser) *ast.Statements {
+	start := p.currIndex
 	var stmts []ast.Statement
 	for {
+		// a statement list ends with the closing brace of its block, or at a new type or function
+		// if that brace is missing
+		ty := p.currToken.Type
+		if ty == ct.RBRACE || ty == ct.EOF || ty == ct.TYPE || ty == ct.FUNC {
+			break
+		}
+		p.resetFailure()
+		stmtStart := p.currIndex
 		if stmt := statement(p); stmt != nil {
 			stmts = append(stmts, *stmt)
-		} else {
-			break
+			continue
 		}
+		p.syntaxError()
+		badTok := p.tokens[stmtStart]
+		p.sync(stmtStart, false)
+		stmts = append(stmts, *p.wrapStatement(p.badStmt(&badTok, stmtStart), stmtStart))
 	}
 	node := ast.NewStatements(stmts)
+	node.Span = p.spanFrom(start)
 	return node
 }
 
@@ -449,80 +554,81 @@ func statement(p *Parser) *ast.Statement {
 	rollbackIdx := p.currIndex
 	bloc := block(p)
 	if bloc != nil {
-		return ast.NewStatement(bloc)
+		return p.wrapStatement(bloc, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	assi := assignment(p)
 	if assi != nil {
-		return ast.NewStatement(assi)
+		return p.wrapStatement(assi, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	prin := print(p)
 	if prin != nil {
-		return ast.NewStatement(prin)
+		return p.wrapStatement(prin, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	cond := conditional(p)
 	if cond != nil {
-		return ast.NewStatement(cond)
+		return p.wrapStatement(cond, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	loopAst := loop(p)
 	if loopAst != nil {
-		return ast.NewStatement(loopAst)
+		return p.wrapStatement(loopAst, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	ret := returnStmt(p)
 	if ret != nil {
-		return ast.NewStatement(ret)
+		return p.wrapStatement(ret, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	readAst := read(p)
 	if readAst != nil {
-		return ast.NewStatement(readAst)
+		return p.wrapStatement(readAst, rollbackIdx)
 	}
 	p.currIndex = rollbackIdx - 1
 	p.currToken = p.NextToken()
 
 	invoc := invocation(p)
 	if invoc != nil {
-		return ast.NewStatement(invoc)
+		return p.wrapStatement(invoc, rollbackIdx)
 	}
 	return nil
 }
 
 func block(p *Parser) *ast.Block {
+	start := p.currIndex
 	var lbraceTok ct.Token
 	var lbraceMatch bool
 	if lbraceTok, lbraceMatch = p.match(ct.LBRACE); !lbraceMatch {
 		return nil
 	}
 	stmtsTok := statements(p)
-	if stmtsTok == nil {
-		return nil
-	}
+	// the block is kept even if its closing brace is missing
 	if _, match := p.match(ct.RBRACE); !match {
-		return nil
+		p.syntaxError()
 	}
 
 	node := ast.NewBlock(stmtsTok)
 	node.Token = &lbraceTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func assignment(p *Parser) *ast.Assignment {
+	start := p.currIndex
 	lval := lValue(p)
 	if lval == nil {
 		return nil
@@ -538,10 +644,13 @@ func assignment(p *Parser) *ast.Assignment {
 		return nil
 	}
 	node := ast.NewAssignment(lval, expr)
+	node.Token = lval.Token
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func read(p *Parser) *ast.Read {
+	start := p.currIndex
 	var fmtTok, idTok ct.Token
 	var fmtMatch, idMatch bool
 
@@ -573,12 +682,14 @@ func read(p *Parser) *ast.Read {
 	//node := ast.NewRead(ast.IdentLiteral{&idTok, idTok.Literal})
 	node := ast.NewRead(ast.NewIdentLiteral(&idTok, idTok.Literal))
 	node.Token = &fmtTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func print(p *Parser) *ast.Print {
-	var fmtTok, printTok, idTok ct.Token
-	var fmtMatch, printMatch, idMatch bool
+	start := p.currIndex
+	var fmtTok, printTok ct.Token
+	var fmtMatch, printMatch bool
 
 	if fmtTok, fmtMatch = p.match(ct.FMT); !fmtMatch {
 		return nil
@@ -593,26 +704,23 @@ func print(p *Parser) *ast.Print {
 	if !printMatch {
 		return nil
 	}
-	if _, match := p.match(ct.LPAREN); !match {
-		return nil
-	}
-	if idTok, idMatch = p.match(ct.ID); !idMatch {
-		return nil
-	}
-	if _, match := p.match(ct.RPAREN); !match {
+	// the values printed, any number of expressions
+	args := arguments(p)
+	if args == nil {
 		return nil
 	}
 	if _, match := p.match(ct.SEMICOLON); !match {
 		return nil
 	}
 
-	//node := ast.NewPrint(printTok.Literal, ast.IdentLiteral{&idTok, idTok.Literal})
-	node := ast.NewPrint(printTok.Literal, ast.NewIdentLiteral(&idTok, idTok.Literal))
+	node := ast.NewPrint(printTok.Literal, args)
 	node.Token = &fmtTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func conditional(p *Parser) *ast.Conditional {
+	start := p.currIndex
 	var ifTok ct.Token
 	var ifMatch bool
 	var node *ast.Conditional
@@ -645,10 +753,12 @@ func conditional(p *Parser) *ast.Conditional {
 	node = ast.NewConditional(expr, bloc, elsBloc)
 	node.Token = &ifTok
 
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func loop(p *Parser) *ast.Loop {
+	start := p.currIndex
 	var forTok ct.Token
 	var forMatch bool
 
@@ -672,10 +782,12 @@ func loop(p *Parser) *ast.Loop {
 
 	node := ast.NewLoop(expr, bloc)
 	node.Token = &forTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func returnStmt(p *Parser) *ast.Return {
+	start := p.currIndex
 	var node *ast.Return
 	var retTok ct.Token
 	var retMatch bool
@@ -691,10 +803,12 @@ func returnStmt(p *Parser) *ast.Return {
 	node = ast.NewReturn(expr)
 	node.Token = &retTok
 
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func invocation(p *Parser) *ast.Invocation {
+	start := p.currIndex
 	var idTok ct.Token
 	var idMatch bool
 	if idTok, idMatch = p.match(ct.ID); !idMatch {
@@ -711,10 +825,12 @@ func invocation(p *Parser) *ast.Invocation {
 	//node := ast.NewInvocation(ast.IdentLiteral{&idTok, idTok.Literal}, arg)
 	node := ast.NewInvocation(ast.NewIdentLiteral(&idTok, idTok.Literal), arg)
 	node.Token = &idTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func arguments(p *Parser) *ast.Arguments {
+	start := p.currIndex
 	var lParentok ct.Token
 	var lParenMatch bool
 	var exprs []ast.Expression
@@ -744,36 +860,65 @@ func arguments(p *Parser) *ast.Arguments {
 	node := ast.NewArgs(exprs)
 	node.Token = &lParentok
 
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func lValue(p *Parser) *ast.LValue {
+	start := p.currIndex
 	var idTok ct.Token
 	var idMatch bool
-	var ids []ast.IdentLiteral
 
 	if idTok, idMatch = p.match(ct.ID); !idMatch {
 		return nil
 	}
-	for {
-		if _, match := p.match(ct.DOT); !match {
-			break
-		}
-		if id, match := p.match(ct.ID); match {
-			//ids = append(ids, ast.IdentLiteral{&id, id.Literal})
-			ids = append(ids, ast.NewIdentLiteral(&id, id.Literal))
-		} else {
-			return nil
-		}
+	sels, complete := selectors(p)
+	if !complete {
+		return nil
 	}
 
 	//node := ast.NewLvalue(ast.IdentLiteral{&idTok, idTok.Literal}, ids)
-	node := ast.NewLvalue(ast.NewIdentLiteral(&idTok, idTok.Literal), ids)
+	node := ast.NewLvalue(ast.NewIdentLiteral(&idTok, idTok.Literal), sels)
 	node.Token = &idTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
+// selectors parses the fields ".id" and the indexes "[expression]" following the factor of a
+// selector term or the identifier of an lvalue, false if one of them is incomplete
+func selectors(p *Parser) ([]ast.Selector, bool) {
+	var sels []ast.Selector
+	for {
+		start := p.currIndex
+		var node *ast.Selector
+		if dotTok, match := p.match(ct.DOT); match {
+			idTok, idMatch := p.match(ct.ID)
+			if !idMatch {
+				return nil, false
+			}
+			ident := ast.NewIdentLiteral(&idTok, idTok.Literal)
+			node = ast.NewFieldSelector(&ident)
+			node.Token = &dotTok
+		} else if lbTok, match := p.match(ct.LBRACKET); match {
+			expr := expression(p)
+			if expr == nil {
+				return nil, false
+			}
+			if _, rbMatch := p.match(ct.RBRACKET); !rbMatch {
+				return nil, false
+			}
+			node = ast.NewIndexSelector(expr)
+			node.Token = &lbTok
+		} else {
+			return sels, true
+		}
+		node.Span = p.spanFrom(start)
+		sels = append(sels, *node)
+	}
+}
+
 func expression(p *Parser) *ast.Expression {
+	start := p.currIndex
 	var bts []ast.BoolTerm
 	currTok := p.currToken
 	btLeft := boolTerm(p)
@@ -794,10 +939,12 @@ func expression(p *Parser) *ast.Expression {
 
 	node := ast.NewExpression(btLeft, bts)
 	node.Token = &currTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func boolTerm(p *Parser) *ast.BoolTerm {
+	start := p.currIndex
 	var ets []ast.EqualTerm
 	etLeft := equalTerm(p)
 	currTok := p.currToken
@@ -819,10 +966,12 @@ func boolTerm(p *Parser) *ast.BoolTerm {
 
 	node := ast.NewBoolTerm(etLeft, ets)
 	node.Token = &currTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func equalTerm(p *Parser) *ast.EqualTerm {
+	start := p.currIndex
 	var eqOps []string
 	var rts []ast.RelationTerm
 	var eqTok ct.Token
@@ -851,10 +1000,12 @@ func equalTerm(p *Parser) *ast.EqualTerm {
 
 	node := ast.NewEqualTerm(rtLeft, eqOps, rts)
 	node.Token = &currTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func relationTerm(p *Parser) *ast.RelationTerm {
+	start := p.currIndex
 	var rlOps []string
 	var sts []ast.SimpleTerm
 	var rlTok ct.Token
@@ -887,10 +1038,12 @@ func relationTerm(p *Parser) *ast.RelationTerm {
 
 	node := ast.NewRelationTerm(stLeft, rlOps, sts)
 	node.Token = &currTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func simpleTerm(p *Parser) *ast.SimpleTerm {
+	start := p.currIndex
 	var stOps []string
 	var tms []ast.Term
 	var stTok ct.Token
@@ -918,10 +1071,12 @@ func simpleTerm(p *Parser) *ast.SimpleTerm {
 
 	node := ast.NewSimpleTerm(termLeft, stOps, tms)
 	node.Token = &currTok
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func term(p *Parser) *ast.Term {
+	start := p.currIndex
 	var tmOps []string
 	var uts []ast.UnaryTerm
 	var tmTok ct.Token
@@ -949,10 +1104,12 @@ func term(p *Parser) *ast.Term {
 
 	node := ast.NewTerm(utLeft, tmOps, uts)
 	node.Token = &currTok // TO-DO : bind token, delete or not
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func unaryTerm(p *Parser) *ast.UnaryTerm {
+	start := p.currIndex
 	op := ""
 	var uniOp ct.Token
 	var match bool
@@ -973,60 +1130,66 @@ func unaryTerm(p *Parser) *ast.UnaryTerm {
 		node.Token = &uniOp
 	}
 
+	node.Span = p.spanFrom(start)
 	return node
 }
 
 func selectorTerm(p *Parser) *ast.SelectorTerm {
-	var ids []ast.IdentLiteral
-	var idTok ct.Token
-	var match bool
+	start := p.currIndex
 
 	facTok := factor(p)
 	if facTok == nil {
 		return nil
 	}
-
-	for {
-		if _, match = p.match(ct.DOT); !match {
-			break
-		}
-		if idTok, match = p.match(ct.ID); !match {
-			return nil
-		}
-		//ids = append(ids, ast.IdentLiteral{&idTok, idTok.Literal})
-		ids = append(ids, ast.NewIdentLiteral(&idTok, idTok.Literal))
+	sels, complete := selectors(p)
+	if !complete {
+		return nil
 	}
 
-	node := ast.NewSelectorTerm(facTok, ids)
+	node := ast.NewSelectorTerm(facTok, sels)
+	node.Span = p.spanFrom(start)
 	return node
 }
 
+// stringLiteral returns the node of a string literal token, whose escapes are the ones of Go; the
+// scanner has reported the malformed literals, which keep the text between their quotes
+func stringLiteral(strTok ct.Token) *ast.StringLiteral {
+	value, err := strconv.Unquote(strTok.Literal)
+	if err != nil {
+		value = strings.TrimSuffix(strings.TrimPrefix(strTok.Literal, `"`), `"`)
+	}
+	return &ast.StringLiteral{Token: &strTok, Span: strTok.Span(), Value: value}
+}
+
 func factor(p *Parser) *ast.Factor {
+	start := p.currIndex
 	var node ast.Expr
 	currTok := p.currToken
 
 	if numTok, match := p.match(ct.NUM); match {
 		val, _ := strconv.ParseInt(numTok.Literal, 10, 64)
-		node = &ast.IntLiteral{Token: &numTok, Value: val}
+		node = &ast.IntLiteral{Token: &numTok, Span: numTok.Span(), Value: val}
 	} else if truTok, match := p.match(ct.TRUE); match {
-		node = &ast.BoolLiteral{Token: &truTok, Value: true}
+		node = &ast.BoolLiteral{Token: &truTok, Span: truTok.Span(), Value: true}
 	} else if flsTok, match := p.match(ct.FALSE); match {
-		node = &ast.BoolLiteral{Token: &flsTok, Value: false}
+		node = &ast.BoolLiteral{Token: &flsTok, Span: flsTok.Span(), Value: false}
 	} else if nilTok, match := p.match(ct.NIL); match {
-		node = &ast.NilNode{Token: &nilTok}
+		node = &ast.NilNode{Token: &nilTok, Span: nilTok.Span()}
+	} else if strTok, match := p.match(ct.STRLIT); match {
+		node = stringLiteral(strTok)
 	} else if identTok, match := p.match(ct.ID); match {
 		argu := arguments(p)
-		idl := &ast.IdentLiteral{Token: &identTok, Id: identTok.Literal}
+		idl := &ast.IdentLiteral{Token: &identTok, Span: identTok.Span(), Id: identTok.Literal}
 		if argu == nil {
 			node = idl
 		} else {
-			node = &ast.InvocExpr{Token: &identTok, Ident: *idl, InnerArgs: argu}
+			node = &ast.InvocExpr{Token: &identTok, Span: p.spanFrom(start), Ident: *idl, InnerArgs: argu}
 		}
 	} else if lpTok, match := p.match(ct.LPAREN); match {
 		expr := expression(p)
 		if expr != nil {
 			if _, match := p.match(ct.RPAREN); match {
-				node = &ast.PriorityExpression{Token: &lpTok, InnerExpression: expr}
+				node = &ast.PriorityExpression{Token: &lpTok, Span: p.spanFrom(start), InnerExpression: expr}
 			}
 		}
 	}
@@ -1036,6 +1199,7 @@ func factor(p *Parser) *ast.Factor {
 		// TO-DO : bind a token to Factor?
 		factor := ast.NewFactor(&node)
 		factor.Token = &currTok
+		factor.Span = p.spanFrom(start)
 		return factor
 	} else {
 		return nil
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"fmt"
+	"proj/golite/diag"
+	ct "proj/golite/token"
+	"strings"
+)
+
+// tokenNames gives how the tokens are named in syntax errors, the others are named by their type
+var tokenNames = map[ct.TokenType]string{
+	ct.EOF: "end of file", ct.ID: "identifier", ct.NUM: "number", ct.STRLIT: "string literal",
+	ct.INT: `"int"`, ct.BOOL: `"bool"`, ct.STRING: `"string"`, ct.TRUE: `"true"`, ct.FALSE: `"false"`, ct.NIL: `"nil"`,
+	ct.PRINT: `"Print"`, ct.PRINTLN: `"Println"`, ct.RETURN: `"return"`, ct.PACK: `"package"`,
+	ct.IMPORT: `"import"`, ct.FMT: `"fmt"`, ct.TYPE: `"type"`, ct.STRUCT: `"struct"`, ct.SCAN: `"Scan"`,
+	ct.IF: `"if"`, ct.ELSE: `"else"`, ct.FOR: `"for"`, ct.FUNC: `"func"`, ct.VAR: `"var"`,
+	ct.DOT: `"."`, ct.COMMA: `","`, ct.QTDMARK: `"\""`, ct.LBRACE: `"{"`, ct.RBRACE: `"}"`,
+	ct.LPAREN: `"("`, ct.RPAREN: `")"`, ct.LBRACKET: `"["`, ct.RBRACKET: `"]"`,
+	ct.ASSIGN: `"="`, ct.AMPERS: `"&"`, ct.SEMICOLON: `";"`,
+	ct.ADD: `"+"`, ct.MINUS: `"-"`, ct.MULTIPLY: `"*"`, ct.DIVIDE: `"/"`, ct.OR: `"||"`, ct.AND: `"&&"`,
+	ct.NOT: `"!"`, ct.EQUAL: `"=="`, ct.NEQUAL: `"!="`, ct.GT: `">"`, ct.GE: `">="`, ct.LT: `"<"`, ct.LE: `"<="`,
+}
+
+// the tokens that can start an expression
+var exprStarts = map[ct.TokenType]bool{
+	ct.NOT: true, ct.MINUS: true, ct.NUM: true, ct.TRUE: true, ct.FALSE: true, ct.NIL: true, ct.ID: true, ct.LPAREN: true,
+	ct.STRLIT: true,
+}
+
+// the tokens that can continue an expression which is already complete
+var exprContinuations = map[ct.TokenType]bool{
+	ct.OR: true, ct.AND: true, ct.EQUAL: true, ct.NEQUAL: true, ct.GT: true, ct.GE: true, ct.LT: true, ct.LE: true,
+	ct.ADD: true, ct.MINUS: true, ct.MULTIPLY: true, ct.DIVIDE: true, ct.DOT: true, ct.LPAREN: true,
+	ct.LBRACKET: true,
+}
+
+// describe returns how a token is named in a syntax error
+func describe(tok ct.Token) string {
+	switch tok.Type {
+	case ct.ID, ct.NUM, ct.STRLIT:
+		return fmt.Sprintf("%v %v", tokenNames[tok.Type], tok.Literal)
+	case ct.EOF:
+		return tokenNames[tok.Type]
+	}
+	return fmt.Sprintf("%q", tok.Literal)
+}
+
+// describeExpected returns the expected tokens of a syntax error, the starts of an expression are
+// named "expression" and the operators are left out when the expression could also have ended there
+func describeExpected(expected []ct.TokenType) string {
+	isExpr, canEnd := false, false
+	for _, ty := range expected {
+		isExpr = isExpr || ty == ct.NUM
+		canEnd = canEnd || !exprStarts[ty] && !exprContinuations[ty]
+	}
+	names := []string{}
+	exprAdded := false
+	for _, ty := range expected {
+		if isExpr && exprStarts[ty] {
+			if !exprAdded {
+				names = append(names, "expression")
+				exprAdded = true
+			}
+			continue
+		}
+		if canEnd && exprContinuations[ty] {
+			continue
+		}
+		if name, exist := tokenNames[ty]; exist {
+			names = append(names, name)
+		} else {
+			names = append(names, string(ty))
+		}
+	}
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " or " + names[1]
+	}
+	return "one of " + strings.Join(names, ", ")
+}
+
+// fail records that the current token did not match the expected type, only the failures
+// on the furthest token are kept as they give the most accurate syntax error
+func (p *Parser) fail(expected ct.TokenType) {
+	if p.currIndex < p.furthest {
+		return
+	}
+	if p.currIndex > p.furthest {
+		p.furthest = p.currIndex
+		p.expected = nil
+	}
+	for _, ty := range p.expected {
+		if ty == expected {
+			return
+		}
+	}
+	p.expected = append(p.expected, expected)
+}
+
+// resetFailure forgets the failures recorded so far, before parsing a new construct
+func (p *Parser) resetFailure() {
+	p.furthest = -1
+	p.expected = nil
+}
+
+// syntaxError reports the furthest failure since the last resetFailure,
+// nothing is reported if an error has already been reported at or after that token
+func (p *Parser) syntaxError() {
+	idx := p.furthest
+	if idx < 0 || idx >= len(p.tokens) {
+		idx = p.currIndex
+	}
+	if idx >= len(p.tokens) {
+		idx = len(p.tokens) - 1
+	}
+	if idx <= p.lastError {
+		return
+	}
+	p.lastError = idx
+	tok := p.tokens[idx]
+	if expected := describeExpected(p.expected); expected != "" {
+		p.parseError(diag.UnexpectedToken, tok, "unexpected %v, expected %v", describe(tok), expected)
+	} else {
+		p.parseError(diag.UnexpectedToken, tok, "unexpected %v", describe(tok))
+	}
+}
+
+// reset moves back to the token at index idx
+func (p *Parser) reset(idx int) {
+	p.currIndex = idx - 1
+	p.currToken = p.NextToken()
+}
+
+// sync skips the tokens of a construct with syntax errors which started at index start.
+// At the top level it stops before the next import, type, var or func. Otherwise it stops
+// after a ";" or a block, or before the "}" closing the enclosing block or a new type or func.
+// At least one token is skipped so that parsing always moves forward.
+func (p *Parser) sync(start int, topLevel bool) {
+	p.reset(start)
+	depth := 0
+	for p.currToken.Type != ct.EOF {
+		moved := p.currIndex > start
+		switch p.currToken.Type {
+		case ct.IMPORT, ct.VAR:
+			if topLevel && moved {
+				return
+			}
+		case ct.TYPE, ct.FUNC:
+			if moved {
+				return
+			}
+		case ct.SEMICOLON:
+			if !topLevel && depth == 0 {
+				p.currToken = p.NextToken()
+				return
+			}
+		case ct.LBRACE:
+			depth += 1
+		case ct.RBRACE:
+			if !topLevel {
+				if depth == 0 {
+					if moved {
+						return
+					}
+				} else if depth -= 1; depth == 0 {
+					p.currToken = p.NextToken()
+					return
+				}
+			}
+		}
+		p.currToken = p.NextToken()
+	}
+}
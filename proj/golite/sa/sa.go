@@ -2,8 +2,8 @@ package sa
 
 import (
 	"flag"
-	"fmt"
 	"proj/golite/ast"
+	"proj/golite/diag"
 	st "proj/golite/symboltable"
 )
 
@@ -12,34 +12,37 @@ import (
 //	return !(len(errors) == 0)
 //}
 
-func reportErrors(errors []string) bool {
+func reportErrors(errors []diag.Diagnostic) bool {
 	// return true if there exists any error
 	if len(errors) > 0 {
-		for _, err := range errors {
-			out := flag.CommandLine.Output()
-			fmt.Fprintf(out, "semantic error: %s}\n", err)
-		}
+		diag.List(errors).Print(flag.CommandLine.Output())
 		return true
 	}
 	return false
 }
 
 func PerformSA(program *ast.Program) *st.SymbolTable {
+	globalST, errors := Analyze(program)
+	if reportErrors(errors) {
+		return nil
+	}
+	return globalST
+}
+
+// Analyze builds the symbol tables and type checks the program without reporting anything,
+// the global symbol table is only meaningful if no error is returned
+func Analyze(program *ast.Program) (*st.SymbolTable, []diag.Diagnostic) {
 	// Define a new global table
 	globalST := st.New(nil, "global")
-	errors := make([]string, 0)
+	errors := make([]diag.Diagnostic, 0)
 
 	// First Build the Symbol Table(s) for all declarations
 	errors = program.PerformSABuild(errors, globalST)
-
-	// Report errors
-	if !reportErrors(errors) {
-		// second perform type checking
-		errors := make([]string, 0)
-		errors = program.TypeCheck(errors, globalST)
-		if !reportErrors(errors) { // finally, no error
-			return globalST
-		}
+	if len(errors) > 0 {
+		return globalST, errors
 	}
-	return nil
+
+	// second perform type checking
+	errors = program.TypeCheck(errors, globalST)
+	return globalST, errors
 }
@@ -3,6 +3,7 @@ package sa
 import (
 	"fmt"
 	ct "proj/golite/context"
+	"proj/golite/diag"
 	"proj/golite/parser"
 	"proj/golite/scanner"
 	"testing"
@@ -77,3 +78,148 @@ func Test4(t *testing.T) {
 	//aEnt := xEnt.GetScopeST().Contains("a")
 	//fmt.Println(aEnt) // Check the values by debugging
 }
+
+func Test5(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test5_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// *Point and *Node are different types, even with fields of the same types
+	_, errors := Analyze(ast)
+	if len(errors) != 1 || errors[0].Code != diag.TypeMismatch {
+		t.Errorf("\nExpected: a single %v error; Got %v\n", diag.TypeMismatch, errors)
+	}
+}
+
+func Test6(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test6_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// struct parameters and results, nil and fields pointing to the same struct
+	symTable, errors := Analyze(ast)
+	if len(errors) != 0 {
+		t.Errorf("\nExpected: no error; Got %v\n", errors)
+	}
+	funcTy := symTable.Contains("push").GetEntryType()
+	if funcTy.GetName() != "func(*Node, int) *Node" {
+		t.Errorf("\nExpected: push of type func(*Node, int) *Node; Got %v\n", funcTy.GetName())
+	}
+}
+
+func Test7(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test7_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// invalid elements, indexes and arguments of len and append
+	_, errors := Analyze(ast)
+	expected := []diag.Code{diag.TypeMismatch, diag.BadIndex, diag.BadIndex, diag.ArgType, diag.ArgType, diag.TypeMismatch,
+		diag.TypeMismatch, diag.TypeMismatch}
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.Code != expected[i] {
+			t.Errorf("\nExpected: %v; Got %v\n", expected[i], err)
+		}
+	}
+}
+
+func Test8(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test8_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// arrays and slices as fields, parameters and results
+	symTable, errors := Analyze(ast)
+	if len(errors) != 0 {
+		t.Errorf("\nExpected: no error; Got %v\n", errors)
+	}
+	funcTy := symTable.Contains("collect").GetEntryType()
+	if funcTy.GetName() != "func([4]int, []*Node) []bool" {
+		t.Errorf("\nExpected: collect of type func([4]int, []*Node) []bool; Got %v\n", funcTy.GetName())
+	}
+}
+
+func Test9(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test9_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// strings as fields, parameters and results, and the operations strings do not have
+	_, errors := Analyze(ast)
+	expected := []diag.Code{diag.TypeMismatch, diag.TypeMismatch, diag.TypeMismatch, diag.TypeMismatch, diag.TypeMismatch}
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.Code != expected[i] {
+			t.Errorf("\nExpected: %v; Got %v\n", expected[i], err)
+		}
+	}
+}
+
+func Test10(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test10_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// any int, bool or string expression prints, pointers, arrays and slices do not
+	_, errors := Analyze(ast)
+	expected := []diag.Code{diag.TypeMismatch, diag.TypeMismatch, diag.TypeMismatch, diag.TypeMismatch, diag.Undefined}
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.Code != expected[i] {
+			t.Errorf("\nExpected: %v; Got %v\n", expected[i], err)
+		}
+	}
+}
+
+func Test11(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test11_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// a function returning a value must end with a return, or an if and else both ending with one
+	_, errors := Analyze(ast)
+	expectedLines := []int{11, 16}
+	if len(errors) != len(expectedLines) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expectedLines), errors)
+	}
+	for i, err := range errors {
+		if err.Code != diag.MissingReturn || err.Pos.Line != expectedLines[i] {
+			t.Errorf("\nExpected: a missing return at line %v; Got %v\n", expectedLines[i], err)
+		}
+	}
+}
+
+func Test12(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test12_sa.golite")
+	myScanner := scanner.New(*ctx)
+	myParser := parser.New(*myScanner)
+	ast := myParser.Parse()
+
+	// every statement is checked, whatever the errors of the statements before it
+	_, errors := Analyze(ast)
+	expected := []diag.Code{diag.TypeMismatch, diag.ReturnType, diag.TypeMismatch, diag.TypeMismatch, diag.TypeMismatch,
+		diag.TypeMismatch, diag.TypeMismatch}
+	expectedLines := []int{5, 6, 11, 12, 13, 16, 17}
+	if len(errors) != len(expected) {
+		t.Fatalf("\nExpected: %v errors; Got %v\n", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.Code != expected[i] || err.Pos.Line != expectedLines[i] {
+			t.Errorf("\nExpected: %v at line %v; Got %v\n", expected[i], expectedLines[i], err)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	ct "proj/golite/context"
+	"proj/golite/diag"
 	"proj/golite/token"
 	"regexp"
+	"strings"
 )
 
 type Scanner struct {
@@ -25,27 +27,54 @@ type Scanner struct {
 	keywords map[string]token.TokenType
 	symbols  map[string]token.TokenType
 
-	isComment  bool
-	lineNumber int
+	isComment bool
+	inImport  bool           // between import and its ';', where '"' quotes the package name
+	pos       token.Position // position of the next rune to read
+	prev      token.Position // position of the last rune read, restored by unread
+	start     token.Position // position of the first rune of the lexeme
+
+	errors []diag.Diagnostic // illegal characters and read errors found so far
 }
 
+// New creates a scanner over the source file of the compiler context, the file is read
+// at once so no handle is left open. If it cannot be read, the scanner only returns EOF
+// and Errors reports why.
 func New(ctx ct.CompilerContext) *Scanner {
 	sourcePath := ctx.SourcePath()
+	src, err := os.ReadFile(sourcePath)
+	return newFromContent(src, sourcePath, err)
+}
+
+// NewFromFS creates a scanner over the file name of the filesystem fsys, e.g. an embed.FS
+// or a fstest.MapFS, with the same error handling as New
+func NewFromFS(fsys fs.FS, name string) *Scanner {
+	src, err := fs.ReadFile(fsys, name)
+	return newFromContent(src, name, err)
+}
+
+// NewFromString creates a scanner over the golite source src
+func NewFromString(src string) *Scanner {
+	return NewFromReader(strings.NewReader(src))
+}
 
-	// Create a *bufio.Reader based on the source path of the input compilerContext
-	fileObj, err := os.Open(sourcePath)
-	if err != nil { // the filename should be valid
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+func newFromContent(src []byte, name string, err error) *Scanner {
+	if err != nil {
+		scanner := NewFromString("")
+		scanner.errors = append(scanner.errors, diag.Errorf(diag.Unreadable, diag.Pos{File: name}, "cannot read the source: %v", err))
+		return scanner
 	}
-	reader := bufio.NewReader(fileObj)
+	return NewFromString(string(src))
+}
 
-	scanner := &Scanner{reader: reader, lexeme: ""}
+// NewFromReader creates a scanner reading the golite source from reader as it goes,
+// a read error ends the tokens with EOF and is reported by Errors
+func NewFromReader(reader io.Reader) *Scanner {
+	scanner := &Scanner{reader: bufio.NewReader(reader), lexeme: ""}
 	scanner.numberCompiled, _ = regexp.Compile("^[0-9]+$")
 	scanner.idCompiled, _ = regexp.Compile("^[a-zA-Z][a-zA-Z0-9]*$")
 	scanner.whitespaces, _ = regexp.Compile("\\s+")
 	scanner.isComment = false
-	scanner.lineNumber = 1
+	scanner.pos = token.Position{Offset: 0, Line: 1, Col: 1}
 
 	keywordsMap := map[string]token.TokenType{
 		"int":    token.INT,
@@ -55,6 +84,7 @@ func New(ctx ct.CompilerContext) *Scanner {
 		"false":  token.FALSE,
 		"id":     token.ID,
 		"nil":    token.NIL,
+		"string": token.STRING,
 
 		"let":     token.LET,
 		"Print":   token.PRINT,
@@ -81,6 +111,8 @@ func New(ctx ct.CompilerContext) *Scanner {
 		"}":  token.RBRACE,
 		"(":  token.LPAREN,
 		")":  token.RPAREN,
+		"[":  token.LBRACKET,
+		"]":  token.RBRACKET,
 
 		"=":  token.ASSIGN,
 		"&":  token.AMPERS,
@@ -107,99 +139,167 @@ func New(ctx ct.CompilerContext) *Scanner {
 	return scanner
 }
 
+// read returns the next rune of the source and moves the position past it
+func (l *Scanner) read() (rune, error) {
+	r, size, err := l.reader.ReadRune()
+	if err != nil {
+		return r, err
+	}
+	l.prev = l.pos
+	l.pos.Offset += size
+	if r == '\n' {
+		l.pos.Line++
+		l.pos.Col = 1
+	} else {
+		l.pos.Col += size
+	}
+	return r, nil
+}
+
+// unread puts back the last rune returned by read
+func (l *Scanner) unread() {
+	l.reader.UnreadRune()
+	l.pos = l.prev
+}
+
+// newToken creates a token covering the source from start to end
+func newToken(ty token.TokenType, literal string, start token.Position, end token.Position) token.Token {
+	return token.Token{Type: ty, Literal: literal, LineNum: start.Line, Col: start.Col, Offset: start.Offset, End: end}
+}
+
+// illegal creates an ILLEGAL token and records the error for it
+func (l *Scanner) illegal(literal string, start token.Position, end token.Position) token.Token {
+	tok := newToken(token.ILLEGAL, literal, start, end)
+	l.errors = append(l.errors, diag.Errorf(diag.IllegalChar, diag.At(&tok), "illegal character %q", literal))
+	return tok
+}
+
 func (l *Scanner) NextToken() token.Token {
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, err := l.read()
 		if err != nil {
-			if err == io.EOF {
-				// return 'eof' if we have not processed any chars as current literal (lexeme)
-				if len(l.lexeme) == 0 {
-					return token.Token{Type: token.EOF, Literal: "eof", LineNum: l.lineNumber}
-				}
-				if l.numberCompiled.MatchString(l.lexeme) {
-					tempLexeme := l.lexeme
-					l.lexeme = ""
-					return token.Token{Type: token.NUM, Literal: tempLexeme, LineNum: l.lineNumber}
-				}
-				if tok, exist := l.symbols[l.lexeme]; exist {
-					tempLexeme := l.lexeme
-					l.lexeme = ""
-					return token.Token{Type: tok, Literal: tempLexeme, LineNum: l.lineNumber}
-				}
-				if l.idCompiled.MatchString(l.lexeme) {
-					tempLexeme := l.lexeme
-					// check if it matches with some keywords (e.g. print, var)
-					if tok, exist := l.keywords[l.lexeme]; exist {
-						l.lexeme = ""
-						return token.Token{Type: tok, Literal: tempLexeme, LineNum: l.lineNumber}
-					}
-					l.lexeme = ""
-					return token.Token{Type: token.ID, Literal: tempLexeme, LineNum: l.lineNumber}
-				}
-
-			} else {
-				// unknown error
-				log.Fatal(err)
+			if err != io.EOF {
+				// end the source there, so that the error is only reported once
+				l.errors = append(l.errors, diag.Errorf(diag.Unreadable, diag.Pos{}, "cannot read the source: %v", err))
+				l.reader = bufio.NewReader(strings.NewReader(""))
 			}
-		} else {
-			tempLineNum := l.lineNumber
-			if l.isComment {
-				if r == '\n' || r == '\r' { // newline
-					l.isComment = false
-					rNext, _, _ := l.reader.ReadRune()
-					if rNext != '\n' {
-						l.reader.UnreadRune()
-					}
-					l.lineNumber++
-				}
-				continue
+			// return 'eof' if we have not processed any chars as current literal (lexeme)
+			if len(l.lexeme) == 0 {
+				return newToken(token.EOF, "eof", l.pos, l.pos)
 			}
-			currLexeme := l.lexeme + string(r)
-			if currLexeme == "|" { // for the special case "||"
-				l.lexeme = currLexeme
-				continue
+			return l.flush(l.pos)
+		}
+
+		if l.isComment {
+			if r == '\n' {
+				l.isComment = false
 			}
-			_, exist := l.symbols[currLexeme]
-			if l.numberCompiled.MatchString(currLexeme) || l.idCompiled.MatchString(currLexeme) || exist {
-				l.lexeme = currLexeme
-				continue
+			continue
+		}
+		if r == '"' && len(l.lexeme) == 0 && !l.inImport {
+			l.start = l.prev
+			return l.stringLiteral()
+		}
+		currLexeme := l.lexeme + string(r)
+		_, exist := l.symbols[currLexeme]
+		// "|" is only valid as the start of "||"
+		if currLexeme == "|" || l.numberCompiled.MatchString(currLexeme) || l.idCompiled.MatchString(currLexeme) || exist {
+			if len(l.lexeme) == 0 {
+				l.start = l.prev
 			}
+			l.lexeme = currLexeme
+			continue
+		}
 
-			if len(l.lexeme) == 0 && !l.whitespaces.MatchString(string(r)) {
-				return token.Token{Type: token.ILLEGAL, Literal: "ILLEGAL", LineNum: tempLineNum}
+		isSpace := l.whitespaces.MatchString(string(r))
+		if len(l.lexeme) == 0 {
+			if !isSpace {
+				return l.illegal(string(r), l.prev, l.pos)
 			}
+			continue
+		}
 
-			// if r != ' ' && r != '\n' && r != '\t' {
-			// 	l.reader.UnreadRune() // rollback
-			// }
-			// rollback if not a whitespace/newline/carriage return/etc
-			if !l.whitespaces.MatchString(string(r)) {
-				l.reader.UnreadRune()
-			} else if r == '\n' || r == '\r' { // newline
-				rNext, _, _ := l.reader.ReadRune()
-				if rNext != '\n' {
-					l.reader.UnreadRune()
-				}
-				l.lineNumber++
-			}
+		// the current rune ends the lexeme, rollback so that it is scanned again by the next call
+		l.unread()
+		return l.flush(l.pos)
+	}
+}
 
-			tempLexeme := l.lexeme
-			l.lexeme = ""
-			if l.numberCompiled.MatchString(tempLexeme) {
-				return token.Token{Type: token.NUM, Literal: tempLexeme, LineNum: tempLineNum}
+// flush returns the token for the accumulated lexeme, which ends at the given position
+func (l *Scanner) flush(end token.Position) token.Token {
+	lexeme := l.lexeme
+	l.lexeme = ""
+	if l.numberCompiled.MatchString(lexeme) {
+		return newToken(token.NUM, lexeme, l.start, end)
+	}
+	if l.idCompiled.MatchString(lexeme) {
+		// check if it matches with some keywords (e.g. print, var)
+		if tok, exist := l.keywords[lexeme]; exist {
+			if tok == token.IMPORT {
+				l.inImport = true
 			}
-			if l.idCompiled.MatchString(tempLexeme) {
-				if tok, exist := l.keywords[tempLexeme]; exist {
-					return token.Token{Type: tok, Literal: tempLexeme, LineNum: tempLineNum}
-				}
-				return token.Token{Type: token.ID, Literal: tempLexeme, LineNum: tempLineNum}
+			return newToken(tok, lexeme, l.start, end)
+		}
+		return newToken(token.ID, lexeme, l.start, end)
+	}
+	if tok, exist := l.symbols[lexeme]; exist {
+		if tok == token.COMMENT {
+			l.isComment = true
+		} else if tok == token.SEMICOLON {
+			l.inImport = false
+		}
+		return newToken(tok, lexeme, l.start, end)
+	}
+	// only the prefix of a symbol, e.g. a single '|'
+	return l.illegal(lexeme, l.start, end)
+}
+
+// stringLiteral scans a string literal after its opening '"', its literal is the source text
+// with the quotes. The escapes are \n, \t, \\ and \", an unknown escape or a literal not closed
+// on its line is reported and still returned as a STRLIT.
+func (l *Scanner) stringLiteral() token.Token {
+	var literal strings.Builder
+	literal.WriteRune('"')
+	for {
+		r, err := l.read()
+		if err != nil || r == '\n' {
+			if err == nil {
+				l.unread()
 			}
-			if tok, exist := l.symbols[tempLexeme]; exist {
-				if tok == token.COMMENT {
-					l.isComment = true
-				}
-				return token.Token{Type: tok, Literal: tempLexeme, LineNum: tempLineNum}
+			tok := newToken(token.STRLIT, literal.String(), l.start, l.pos)
+			l.errors = append(l.errors, diag.Errorf(diag.BadString, diag.At(&tok), "string literal not terminated"))
+			return tok
+		}
+		literal.WriteRune(r)
+		if r == '"' {
+			return newToken(token.STRLIT, literal.String(), l.start, l.pos)
+		}
+		if r != '\\' {
+			continue
+		}
+		escapeStart := l.prev
+		r, err = l.read()
+		if err != nil || r == '\n' {
+			if err == nil {
+				l.unread()
 			}
+			continue
+		}
+		literal.WriteRune(r)
+		if !strings.ContainsRune(`nt\"`, r) {
+			l.errors = append(l.errors, diag.Errorf(diag.BadString, diag.Pos{Line: escapeStart.Line, Col: escapeStart.Col}, "unknown escape sequence \\%c", r))
+		}
+	}
+}
+
+// ScanAll returns all the remaining tokens, the last one being EOF
+func (l *Scanner) ScanAll() []token.Token {
+	tokens := []token.Token{}
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
 		}
 	}
 }
@@ -213,3 +313,8 @@ func (l *Scanner) Tokens() {
 		fmt.Println(tok)
 	}
 }
+
+// Errors returns the diagnostics for the illegal characters scanned so far and the source read errors
+func (l *Scanner) Errors() []diag.Diagnostic {
+	return l.errors
+}
@@ -1,9 +1,12 @@
 package scanner
 
 import (
+	"errors"
 	ct "proj/golite/context"
+	"proj/golite/diag"
 	"proj/golite/token"
 	"testing"
+	"testing/fstest"
 )
 
 type ExpectedResult struct {
@@ -229,3 +232,103 @@ func Test4(t *testing.T) {
 
 	VerifyTest(t, expected, scanner)
 }
+
+func Test5(t *testing.T) {
+	ctx := ct.New(false, false, false, false, "test2.golite")
+	scanner := New(*ctx)
+
+	// the positions of " package main;\n import"
+	expected := []token.Span{
+		{Start: token.Position{Offset: 1, Line: 1, Col: 2}, End: token.Position{Offset: 8, Line: 1, Col: 9}},
+		{Start: token.Position{Offset: 9, Line: 1, Col: 10}, End: token.Position{Offset: 13, Line: 1, Col: 14}},
+		{Start: token.Position{Offset: 13, Line: 1, Col: 14}, End: token.Position{Offset: 14, Line: 1, Col: 15}},
+		{Start: token.Position{Offset: 16, Line: 2, Col: 2}, End: token.Position{Offset: 22, Line: 2, Col: 8}},
+	}
+	for i, span := range expected {
+		tok := scanner.NextToken()
+		if tok.Span() != span {
+			t.Fatalf("FAILED[%d] - incorrect token span.\nexpected=%v\ngot=%v\n", i, span, tok.Span())
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) { return 0, errors.New("disk on fire") }
+
+func Test6(t *testing.T) {
+	expected := []ExpectedResult{
+		{token.VAR, "var"},
+		{token.ID, "a"},
+		{token.INT, "int"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, "eof"},
+	}
+	VerifyTest(t, expected, NewFromString("var a int;"))
+
+	fsys := fstest.MapFS{"src/a.golite": &fstest.MapFile{Data: []byte("var a int;")}}
+	VerifyTest(t, expected, NewFromFS(fsys, "src/a.golite"))
+
+	// a missing file or a failing reader only gives EOF, and the error is reported
+	for _, scanner := range []*Scanner{NewFromFS(fsys, "missing.golite"), NewFromReader(failingReader{})} {
+		VerifyTest(t, []ExpectedResult{{token.EOF, "eof"}}, scanner)
+		if errs := scanner.Errors(); len(errs) != 1 || errs[0].Code != diag.Unreadable {
+			t.Errorf("\nExpected: 1 read error; Got %v\n", errs)
+		}
+	}
+}
+
+func Test7(t *testing.T) {
+	expected := []ExpectedResult{
+		{token.VAR, "var"},
+		{token.ID, "a"},
+		{token.LBRACKET, "["},
+		{token.NUM, "3"},
+		{token.RBRACKET, "]"},
+		{token.INT, "int"},
+		{token.SEMICOLON, ";"},
+		{token.ID, "s"},
+		{token.LBRACKET, "["},
+		{token.ID, "i"},
+		{token.RBRACKET, "]"},
+		{token.ASSIGN, "="},
+		{token.EOF, "eof"},
+	}
+	VerifyTest(t, expected, NewFromString("var a[3]int;\ns[i]="))
+}
+
+func Test8(t *testing.T) {
+	// the quotes of the import stay QTDMARK, the others start string literals
+	src := "import \"fmt\";\nvar s string;\ns = \"a \\\"b\\\"\\n\" + \"//c\";\n"
+	expected := []ExpectedResult{
+		{token.IMPORT, "import"},
+		{token.QTDMARK, "\""},
+		{token.FMT, "fmt"},
+		{token.QTDMARK, "\""},
+		{token.SEMICOLON, ";"},
+		{token.VAR, "var"},
+		{token.ID, "s"},
+		{token.STRING, "string"},
+		{token.SEMICOLON, ";"},
+		{token.ID, "s"},
+		{token.ASSIGN, "="},
+		{token.STRLIT, "\"a \\\"b\\\"\\n\""},
+		{token.ADD, "+"},
+		{token.STRLIT, "\"//c\""},
+		{token.SEMICOLON, ";"},
+		{token.EOF, "eof"},
+	}
+	scanner := NewFromString(src)
+	VerifyTest(t, expected, scanner)
+	if errs := scanner.Errors(); len(errs) != 0 {
+		t.Errorf("\nExpected: no error; Got %v\n", errs)
+	}
+
+	// an unknown escape, and a literal not closed on its line
+	scanner = NewFromString("\"a\\qb\" \"cd\nx")
+	VerifyTest(t, []ExpectedResult{{token.STRLIT, "\"a\\qb\""}, {token.STRLIT, "\"cd"}, {token.ID, "x"}}, scanner)
+	errs := scanner.Errors()
+	if len(errs) != 2 || errs[0].Code != diag.BadString || errs[0].Pos.Col != 3 || errs[1].Code != diag.BadString || errs[1].Pos.Col != 8 {
+		t.Errorf("\nExpected: 2 string errors at 1:3 and 1:8; Got %v\n", errs)
+	}
+}
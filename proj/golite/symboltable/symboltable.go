@@ -50,7 +50,8 @@ func (st *SymbolTable) PowerContains(varName string) Entry {
 	return entry
 }
 
-// CheckGlobalVariable returns true if the given variable name is a global variable name, otherwise false
+// CheckGlobalVariable returns true if the given variable name is a global variable name, otherwise false.
+// A local variable or parameter of the same name hides the global one.
 func (st *SymbolTable) CheckGlobalVariable(varName string) bool {
 	currSymtable := st
 	for {
@@ -62,6 +63,9 @@ func (st *SymbolTable) CheckGlobalVariable(varName string) bool {
 			} else {
 				return false
 			}
+		} else if currSymtable.Contains(varName) != nil {
+			// declared in a function
+			return false
 		} else {
 			if currSymtable.Parent != nil {
 				currSymtable = currSymtable.Parent
@@ -72,35 +76,6 @@ func (st *SymbolTable) CheckGlobalVariable(varName string) bool {
 	}
 }
 
-// GetCopy input st *SymbolTable as the prototype struct declared before main
-// return a deep-copied *SymbolTable to be attached with any instance of the prototype
-func (st *SymbolTable) GetCopy(scopeName string, parentSt *SymbolTable) *SymbolTable {
-	var entry Entry
-	instanceSt := New(parentSt, scopeName)
-	for key, e := range st.htable {
-		entry = *e
-		var duplicateEntry Entry
-		// TO-DO : here we assume no struct field exists in a struct; delete this line later
-		duplicateEntry = NewVarEntry()
-
-		// Here we copy fields of depth only 1. That is, ignore struct containing struct as field
-		if entry.GetEntryType().GetName() == "int" { // var entry int
-			duplicateEntry = NewVarEntry()
-			duplicateEntry.SetType(types.IntTySig)
-		} else if entry.GetEntryType().GetName() == "bool" { // var entry bool
-			duplicateEntry = NewVarEntry()
-			duplicateEntry.SetType(types.BoolTySig)
-		} else if entry.GetEntryType().GetName() == "struct" { // struct entry
-			// TO-DO
-		} // else do nothing
-		instanceSt.htable[key] = &duplicateEntry
-	}
-	for _, fieldName := range st.ScopeParamNames {
-		instanceSt.ScopeParamNames = append(instanceSt.ScopeParamNames, fieldName)
-	}
-	return instanceSt
-}
-
 func (st *SymbolTable) HashTable() map[string]*Entry {
 	return st.htable
 }
@@ -112,7 +87,6 @@ type Entry interface {
 	GetScopeST() *SymbolTable
 	GetReturnTy() types.Type // Only implement for funcEntry
 	GetRegId() int
-	//GetCopy(parentSt *SymbolTable) *Entry
 }
 
 type VarEntry struct {
@@ -144,47 +118,45 @@ func (ve *VarEntry) GetRegId() int {
 	return ve.regId
 }
 
-//func (ve *VarEntry) GetCopy(parentSt *SymbolTable) *VarEntry {
-//	return &VarEntry{ve.ty, ve.value, ir.NewRegister()}
-//}
-
 type FuncEntry struct {
 	ty         types.Type
 	returnType types.Type // expected return type
 	scopeSt    *SymbolTable
 }
 
+// NewFuncEntry returns the entry of a function returning retTy, whose signature is completed by
+// SetType once its parameters are type checked
 func NewFuncEntry(retTy types.Type, symTable *SymbolTable) *FuncEntry {
-	return &FuncEntry{types.FuncTySig, retTy, symTable}
+	return &FuncEntry{&types.FuncType{Params: []types.Type{}, Result: retTy}, retTy, symTable}
 }
 func (fe *FuncEntry) GetEntryType() types.Type {
-	return fe.ty // types.FuncTySig
+	return fe.ty // *types.FuncType
 }
 func (fe *FuncEntry) GetScopeST() *SymbolTable {
 	return fe.scopeSt
 }
-func (fe *FuncEntry) SetType(t types.Type) {}
-func (fe *FuncEntry) SetValue(s string)    {}
+func (fe *FuncEntry) SetType(t types.Type) {
+	fe.ty = t
+}
+func (fe *FuncEntry) SetValue(s string) {}
 func (fe *FuncEntry) GetReturnTy() types.Type {
 	return fe.returnType
 }
 func (fe *FuncEntry) GetRegId() int { return -1 }
 
-//func (fe *FuncEntry) GetCopy(parentSt *SymbolTable) *Entry {  // cannot copy a function when initialize a struct
-//	return nil
-//}
-
 type StructEntry struct {
 	ty      types.Type
 	scopeSt *SymbolTable
 	regId   int
 }
 
-func NewStructEntry(symTable *SymbolTable) *StructEntry {
-	return &StructEntry{types.StructTySig, symTable, ir.NewRegister()}
+// NewStructEntry returns the entry of the declaration of a struct, ty being its
+// *types.NamedStructType, with the fields in symTable
+func NewStructEntry(ty types.Type, symTable *SymbolTable) *StructEntry {
+	return &StructEntry{ty, symTable, ir.NewRegister()}
 }
 func (se *StructEntry) GetEntryType() types.Type {
-	return se.ty // types.StructTySig
+	return se.ty
 }
 func (se *StructEntry) GetScopeST() *SymbolTable {
 	return se.scopeSt
@@ -196,7 +168,3 @@ func (se *StructEntry) GetReturnTy() types.Type {
 	return types.UnknownTySig
 }
 func (se *StructEntry) GetRegId() int { return se.regId }
-
-//func (se *StructEntry) GetCopy(parentSt *SymbolTable) *StructEntry {
-//	return &StructEntry{se.ty, se.scopeSt.GetCopy(se.scopeSt.ProtoName, parentSt)}
-//}
@@ -16,6 +16,8 @@ const (
 	FALSE   = "FALSE"
 	ID      = "ID"
 	NIL     = "NIL"
+	STRING  = "STRING"
+	STRLIT  = "STRLIT" // a string literal, its quotes included
 
 	LET     = "LET"
 	PRINT   = "PRINT"
@@ -33,13 +35,15 @@ const (
 	FUNC    = "FUNC"
 	VAR     = "VAR"
 
-	DOT     = "DOT"
-	COMMA   = "COMMA"
-	QTDMARK = "QTDMARK"
-	LBRACE  = "LBRACE"
-	RBRACE  = "RBRACE"
-	LPAREN  = "LPAREN"
-	RPAREN  = "RPAREN"
+	DOT      = "DOT"
+	COMMA    = "COMMA"
+	QTDMARK  = "QTDMARK"
+	LBRACE   = "LBRACE"
+	RBRACE   = "RBRACE"
+	LPAREN   = "LPAREN"
+	RPAREN   = "RPAREN"
+	LBRACKET = "LBRACKET"
+	RBRACKET = "RBRACKET"
 
 	ASSIGN    = "ASSIGN"
 	AMPERS    = "AMPERS" // for getting address
@@ -60,10 +64,50 @@ const (
 	COMMENT   = "COMMENT"
 )
 
+// Position is a location in the source, Offset counts bytes from the start of the source
+// while Line and Col start at 1 (Col counts bytes in the line)
+type Position struct {
+	Offset int
+	Line   int
+	Col    int
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%v:%v", pos.Line, pos.Col)
+}
+
+// Span is the range of source from Start up to (but not including) End
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (span Span) String() string {
+	return fmt.Sprintf("%v-%v", span.Start, span.End)
+}
+
+// IsValid returns true if the span has been set
+func (span Span) IsValid() bool {
+	return span.Start.Line > 0
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
 	LineNum int
+	Col     int      // column of the first character, starting at 1
+	Offset  int      // byte offset of the first character
+	End     Position // position just after the last character
+}
+
+// Pos returns the position of the first character of the token
+func (tok Token) Pos() Position {
+	return Position{tok.Offset, tok.LineNum, tok.Col}
+}
+
+// Span returns the range of source covered by the token
+func (tok Token) Span() Span {
+	return Span{tok.Pos(), tok.End}
 }
 
 func (tok Token) String() string {
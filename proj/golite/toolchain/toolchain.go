@@ -0,0 +1,106 @@
+package toolchain
+
+import (
+	"os"
+	"os/exec"
+	"proj/golite/diag"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Config names the programs turning the Armv8 assembly produced by golite into an executable
+type Config struct {
+	CC       string   // the C compiler driver assembling and linking against libc, e.g. aarch64-linux-gnu-gcc
+	CFlags   []string // extra flags given to CC, e.g. -static
+	Emulator []string // the command running an executable, e.g. qemu-aarch64; empty to run it directly
+}
+
+// DefaultConfig returns the toolchain of the host: its cc on arm64, and otherwise the GNU cross
+// compiler with qemu-aarch64. The environment variables GOLITE_CC, GOLITE_CFLAGS and
+// GOLITE_EMULATOR override the defaults, the last two are split on spaces.
+func DefaultConfig() Config {
+	config := Config{CC: "cc"}
+	if runtime.GOARCH != "arm64" {
+		// static executables run under qemu without an aarch64 sysroot
+		config = Config{"aarch64-linux-gnu-gcc", []string{"-static"}, []string{"qemu-aarch64"}}
+	}
+	if cc := os.Getenv("GOLITE_CC"); cc != "" {
+		config.CC = cc
+	}
+	if flags, exist := os.LookupEnv("GOLITE_CFLAGS"); exist {
+		config.CFlags = strings.Fields(flags)
+	}
+	if emulator, exist := os.LookupEnv("GOLITE_EMULATOR"); exist {
+		config.Emulator = strings.Fields(emulator)
+	}
+	return config
+}
+
+// Build assembles the assembly file asmPath and links it into the executable exePath. The errors
+// and warnings of the toolchain are returned as diagnostics, there is no error if the build worked.
+func (config Config) Build(asmPath string, exePath string) []diag.Diagnostic {
+	cc, err := exec.LookPath(config.CC)
+	if err != nil {
+		return []diag.Diagnostic{diag.Errorf(diag.ToolNotFound, diag.Pos{}, "cannot find the assembler and linker %v", config.CC).
+			WithNote("set GOLITE_CC or -cc to a C compiler targeting Armv8, e.g. aarch64-linux-gnu-gcc")}
+	}
+
+	args := append(append([]string{}, config.CFlags...), "-o", exePath, asmPath)
+	output, err := exec.Command(cc, args...).CombinedOutput()
+	diagnostics := parseOutput(string(output))
+	if err != nil {
+		failure := diag.Errorf(diag.ToolFailed, diag.Pos{}, "%v failed: %v", config.CC, err)
+		if len(diagnostics) == 0 {
+			// no message in a known format, give the whole output
+			for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+				if line != "" {
+					failure = failure.WithNote("%v", line)
+				}
+			}
+		}
+		diagnostics = append(diagnostics, failure)
+	}
+	return diagnostics
+}
+
+// Command returns the command running the executable with the given arguments, through the emulator if there is one
+func (config Config) Command(exePath string, args ...string) *exec.Cmd {
+	if len(config.Emulator) == 0 {
+		return exec.Command(exePath, args...)
+	}
+	emulatorArgs := append(append(append([]string{}, config.Emulator[1:]...), exePath), args...)
+	return exec.Command(config.Emulator[0], emulatorArgs...)
+}
+
+// CheckRun returns a diagnostic if the command running an executable cannot be found
+func (config Config) CheckRun(exePath string) []diag.Diagnostic {
+	cmd := config.Command(exePath)
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return []diag.Diagnostic{diag.Errorf(diag.ToolNotFound, diag.Pos{}, "cannot run %v: %v", exePath, err).
+			WithNote("set GOLITE_EMULATOR or -emulator to a command running Armv8 executables, e.g. qemu-aarch64")}
+	}
+	return nil
+}
+
+// messageRegexp matches the messages of the GNU assembler and linker, "file:line: Error: message"
+var messageRegexp = regexp.MustCompile(`^(.+?):(\d+):(?:\d+:)? *(?i:(error|warning)): *(.*)$`)
+
+// parseOutput turns the messages of the toolchain with a position into diagnostics
+func parseOutput(output string) []diag.Diagnostic {
+	diagnostics := []diag.Diagnostic{}
+	for _, line := range strings.Split(output, "\n") {
+		match := messageRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(match[2])
+		d := diag.Errorf(diag.ToolFailed, diag.Pos{File: match[1], Line: lineNum}, "%v", match[4])
+		if strings.ToLower(match[3]) == "warning" {
+			d.Severity = diag.Warning
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
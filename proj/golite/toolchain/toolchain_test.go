@@ -0,0 +1,100 @@
+package toolchain
+
+import (
+	"os"
+	"path/filepath"
+	"proj/golite/diag"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTool writes a shell script standing for the C compiler into dir
+func fakeTool(t *testing.T, dir string, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake toolchain is a shell script")
+	}
+	path := filepath.Join(dir, "fake-cc")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test1(t *testing.T) {
+	config := Config{CC: "golite-no-such-cc"}
+	diagnostics := config.Build("a.s", "a")
+	if len(diagnostics) != 1 || diagnostics[0].Code != diag.ToolNotFound {
+		t.Fatalf("\nExpected: a T001 diagnostic; Got %v\n", diagnostics)
+	}
+
+	config = Config{CC: "cc", Emulator: []string{"golite-no-such-emulator"}}
+	if diagnostics := config.CheckRun("a"); len(diagnostics) != 1 || diagnostics[0].Code != diag.ToolNotFound {
+		t.Errorf("\nExpected: a T001 diagnostic for the emulator; Got %v\n", diagnostics)
+	}
+}
+
+func Test2(t *testing.T) {
+	dir := t.TempDir()
+	// the GNU assembler reports its errors as "file:line: Error: message"
+	cc := fakeTool(t, dir, `echo "$3: Assembler messages:"
+echo "$3:12: Error: unknown mnemonic 'mvo' -- 'mvo x1,#7'"
+echo "$3:13: Warning: end of file not at end of a line"
+exit 1
+`)
+	config := Config{CC: cc}
+	asmPath := filepath.Join(dir, "a.s")
+	diagnostics := diag.List(config.Build(asmPath, filepath.Join(dir, "a")))
+
+	expected := []string{
+		asmPath + ":12: error[T002]: unknown mnemonic 'mvo' -- 'mvo x1,#7'",
+		asmPath + ":13: warning[T002]: end of file not at end of a line",
+		"error[T002]: " + cc + " failed: exit status 1",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("\nExpected: %v diagnostics; Got %v\n", len(expected), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("\nExpected: %v\nGot: %v\n", expected[i], d.String())
+		}
+	}
+
+	// output in an unknown format is kept as notes
+	config.CC = fakeTool(t, dir, "echo 'collect2: ld returned 1 exit status'\nexit 1\n")
+	diagnostics = config.Build(asmPath, filepath.Join(dir, "a"))
+	if len(diagnostics) != 1 || len(diagnostics[0].Notes) != 1 || !strings.Contains(diagnostics[0].Notes[0], "collect2") {
+		t.Errorf("\nExpected: one diagnostic with the linker output as note; Got %v\n", diagnostics)
+	}
+}
+
+func Test3(t *testing.T) {
+	dir := t.TempDir()
+	// the fake compiler is given "-static -o exe asm" and makes the executable print its flag
+	cc := fakeTool(t, dir, `printf '#!/bin/sh\necho built with %s "$@"\n' "$1" > "$3"
+chmod +x "$3"
+`)
+	config := Config{CC: cc, CFlags: []string{"-static"}}
+	exePath := filepath.Join(dir, "prog")
+	if diagnostics := config.Build(filepath.Join(dir, "prog.s"), exePath); len(diagnostics) != 0 {
+		t.Fatalf("\nExpected: no diagnostics; Got %v\n", diagnostics)
+	}
+	if diagnostics := config.CheckRun(exePath); len(diagnostics) != 0 {
+		t.Fatalf("\nExpected: no diagnostics; Got %v\n", diagnostics)
+	}
+
+	out, err := config.Command(exePath, "x").Output()
+	if err != nil || string(out) != "built with -static x\n" {
+		t.Errorf("\nExpected: built with -static x; Got %q, %v\n", out, err)
+	}
+
+	// through an emulator, the executable is its argument
+	config.Emulator = []string{"sh", "-e"}
+	cmd := config.Command(exePath, "x")
+	if strings.Join(cmd.Args, " ") != "sh -e "+exePath+" x" {
+		t.Errorf("\nExpected: the emulator running %v; Got %v\n", exePath, cmd.Args)
+	}
+	if out, err := cmd.Output(); err != nil || string(out) != "built with -static x\n" {
+		t.Errorf("\nExpected: built with -static x; Got %q, %v\n", out, err)
+	}
+}
@@ -1,31 +1,32 @@
 package types
 
+import (
+	"bytes"
+	"fmt"
+)
+
 type Type interface {
 	GetName() string
 }
 
-type IntTy struct {}
+type IntTy struct{}
 
 func (intTy *IntTy) GetName() string {
 	return "int"
 }
 
-type BoolTy struct {}
+type BoolTy struct{}
 
 func (boolTy *BoolTy) GetName() string {
 	return "bool"
 }
 
-type FuncTy struct {}
-
-func (funcTy *FuncTy) GetName() string {
-	return "function"
-}
-
-type StructTy struct {}
+// StringTy is the type of the string literals, a value points to bytes ending with a NUL that
+// are never written to, and + concatenates two strings into new ones
+type StringTy struct{}
 
-func (structTy *StructTy) GetName() string {
-	return "struct"
+func (stringTy *StringTy) GetName() string {
+	return "string"
 }
 
 type UnknownTy struct{}
@@ -34,25 +35,193 @@ func (unknownTy *UnknownTy) GetName() string {
 	return "unknownType"
 }
 
-type VoidTy struct {}
+type VoidTy struct{}
 
 func (voidTy *VoidTy) GetName() string {
 	return "void"
 }
 
+// NilTy is the type of nil, which can be assigned to and compared with any pointer
+type NilTy struct{}
+
+func (nilTy *NilTy) GetName() string {
+	return "nil"
+}
+
+// Field is a field of a struct
+type Field struct {
+	Name string
+	Ty   Type
+}
+
+// NamedStructType is the type declared by "type Name struct {...}", only identical to itself.
+// Its fields are set once every struct has been declared, as they may point to any struct.
+// A value takes 8 bytes per field, in the order of Fields, the index of a field being the one
+// of its loadRef and strRef.
+type NamedStructType struct {
+	Name   string
+	Fields []Field
+}
+
+func (structTy *NamedStructType) GetName() string {
+	return structTy.Name
+}
+
+// Field returns the field called name and its index in the struct, false if there is none
+func (structTy *NamedStructType) Field(name string) (Field, int, bool) {
+	for idx, field := range structTy.Fields {
+		if field.Name == name {
+			return field, idx, true
+		}
+	}
+	return Field{}, -1, false
+}
+
+// PointerType is the type *Elem, identical to the pointers to an identical type
+type PointerType struct {
+	Elem Type
+}
+
+func (pointerTy *PointerType) GetName() string {
+	return "*" + pointerTy.Elem.GetName()
+}
+
+// ArrayType is the type [Len]Elem, identical to the arrays of the same length of an identical type.
+// A value is a block of Len words, copied when it is assigned, passed or returned.
+type ArrayType struct {
+	Len  int
+	Elem Type
+}
+
+func (arrayTy *ArrayType) GetName() string {
+	return fmt.Sprintf("[%v]%v", arrayTy.Len, arrayTy.Elem.GetName())
+}
+
+// SliceType is the type []Elem, identical to the slices of an identical type. A value points to
+// a header of 3 words, the length, the capacity and the block of the elements, which append
+// shares with the slice it returns while the capacity allows.
+type SliceType struct {
+	Elem Type
+}
+
+func (sliceTy *SliceType) GetName() string {
+	return "[]" + sliceTy.Elem.GetName()
+}
+
+// FuncType is the signature of a function, Result being VoidTySig for a function returning nothing
+type FuncType struct {
+	Params []Type
+	Result Type
+}
+
+func (funcTy *FuncType) GetName() string {
+	out := bytes.Buffer{}
+	out.WriteString("func(")
+	for idx, param := range funcTy.Params {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(param.GetName())
+	}
+	out.WriteString(")")
+	if funcTy.Result != VoidTySig {
+		out.WriteString(" ")
+		out.WriteString(funcTy.Result.GetName())
+	}
+	return out.String()
+}
+
+// Identical returns true if t1 and t2 are the same type: a named struct is only identical to
+// itself, pointer, array, slice and function types are identical if their parts are
+func Identical(t1 Type, t2 Type) bool {
+	switch t1 := t1.(type) {
+	case *PointerType:
+		if t2, isPointer := t2.(*PointerType); isPointer {
+			return Identical(t1.Elem, t2.Elem)
+		}
+		return false
+	case *ArrayType:
+		if t2, isArray := t2.(*ArrayType); isArray {
+			return t1.Len == t2.Len && Identical(t1.Elem, t2.Elem)
+		}
+		return false
+	case *SliceType:
+		if t2, isSlice := t2.(*SliceType); isSlice {
+			return Identical(t1.Elem, t2.Elem)
+		}
+		return false
+	case *FuncType:
+		t2, isFunc := t2.(*FuncType)
+		if !isFunc || len(t1.Params) != len(t2.Params) || !Identical(t1.Result, t2.Result) {
+			return false
+		}
+		for idx := range t1.Params {
+			if !Identical(t1.Params[idx], t2.Params[idx]) {
+				return false
+			}
+		}
+		return true
+	}
+	return t1 == t2
+}
+
+// AssignableTo returns true if a value of type value can be assigned to a variable of type
+// target: the types are identical, or value is nil and target a pointer
+func AssignableTo(value Type, target Type) bool {
+	if _, isPointer := target.(*PointerType); isPointer && value == NilTySig {
+		return true
+	}
+	return Identical(value, target)
+}
+
+// Comparable returns true if == and != can compare values of the types t1 and t2, which cannot
+// be arrays, slices or strings
+func Comparable(t1 Type, t2 Type) bool {
+	if _, isElems := ElemOf(t1); isElems || t1 == StringTySig {
+		return false
+	}
+	return AssignableTo(t1, t2) || AssignableTo(t2, t1)
+}
+
+// StructOf returns the struct ty points to, false if ty is not a pointer to a struct
+func StructOf(ty Type) (*NamedStructType, bool) {
+	if pointerTy, isPointer := ty.(*PointerType); isPointer {
+		structTy, isStruct := pointerTy.Elem.(*NamedStructType)
+		return structTy, isStruct
+	}
+	return nil, false
+}
+
+// ElemOf returns the type of the elements of an array or a slice, false if ty is neither
+func ElemOf(ty Type) (Type, bool) {
+	switch ty := ty.(type) {
+	case *ArrayType:
+		return ty.Elem, true
+	case *SliceType:
+		return ty.Elem, true
+	}
+	return nil, false
+}
+
+// IsElem returns true if ty can be the type of the elements of an array or a slice: int, bool or
+// a pointer, which all take a word
+func IsElem(ty Type) bool {
+	_, isPointer := ty.(*PointerType)
+	return ty == IntTySig || ty == BoolTySig || isPointer
+}
 
 var IntTySig *IntTy
 var BoolTySig *BoolTy
-var FuncTySig *FuncTy
-var StructTySig *StructTy
+var StringTySig *StringTy
 var UnknownTySig *UnknownTy
 var VoidTySig *VoidTy
+var NilTySig *NilTy
 
 func init() {
 	IntTySig = &IntTy{}
 	BoolTySig = &BoolTy{}
-	FuncTySig = &FuncTy{}
-	StructTySig = &StructTy{}
+	StringTySig = &StringTy{}
 	UnknownTySig = &UnknownTy{}
 	VoidTySig = &VoidTy{}
-}
\ No newline at end of file
+	NilTySig = &NilTy{}
+}
@@ -1,11 +1,18 @@
 package utility
 
-var printExist, printlnExist, scanExist bool
+import "fmt"
+
+var printExist, printlnExist, scanExist, boundsExist bool
+var strCatExist bool
+var stringLits []string
 
 func IOInit() {
 	printExist = false
 	printlnExist = false
 	scanExist = false
+	boundsExist = false
+	strCatExist = false
+	stringLits = nil
 }
 
 func SetPrint() {
@@ -30,4 +37,45 @@ func SetScan() {
 
 func GetScan() bool {
 	return scanExist
-}
\ No newline at end of file
+}
+
+// SetBounds records that the program checks the bounds of arrays and slices, whose failure
+// branches to the routine of ir.BoundsArmRoutine
+func SetBounds() {
+	boundsExist = true
+}
+
+func GetBounds() bool {
+	return boundsExist
+}
+
+// SetStrCat records that the program concatenates strings, which calls the routine of
+// ir.StrCatArmRoutine
+func SetStrCat() {
+	strCatExist = true
+}
+
+func GetStrCat() bool {
+	return strCatExist
+}
+
+// AddString records a string literal of the program and returns the label of its .asciz entry,
+// a literal used twice keeping the same label
+func AddString(value string) string {
+	for idx, lit := range stringLits {
+		if lit == value {
+			return StringLabel(idx)
+		}
+	}
+	stringLits = append(stringLits, value)
+	return StringLabel(len(stringLits) - 1)
+}
+
+// GetStrings returns the string literals recorded, the one at idx being labelled StringLabel(idx)
+func GetStrings() []string {
+	return stringLits
+}
+
+func StringLabel(idx int) string {
+	return fmt.Sprintf(".STR%v", idx)
+}
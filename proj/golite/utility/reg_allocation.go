@@ -2,9 +2,14 @@ package utility
 
 var regList map[int]bool
 
+// ScratchRegs are the Arm registers the instruction translators load spilled values into: x8 and
+// the intra-procedure-call registers x16 and x17. The register allocator never hands them out,
+// and x0 to x7 are left to the passing of arguments and results.
+var ScratchRegs = []int{8, 16, 17}
+
 func RegInit() {
 	regList = make(map[int]bool)
-	for i := 0; i < 32; i++ {
+	for _, i := range ScratchRegs {
 		regList[i] = true
 	}
 }
@@ -25,5 +30,7 @@ func OccupyReg(regId int) {
 }
 
 func ReleaseReg(regId int) {
-	regList[regId] = true
-}
\ No newline at end of file
+	if _, isScratch := regList[regId]; isScratch {
+		regList[regId] = true
+	}
+}